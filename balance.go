@@ -11,6 +11,11 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// BalanceReader returns the balance of an account, Balance implements it
+type BalanceReader interface {
+	Balance(ctx context.Context, account string) (*AccountResponse, error)
+}
+
 //Balance ...
 type Balance struct {
 	session        Session
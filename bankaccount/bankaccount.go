@@ -0,0 +1,287 @@
+package bankaccount
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+var (
+	// ErrInvalidBranch ...
+	ErrInvalidBranch = errors.New("invalid branch number")
+	// ErrInvalidAccount ...
+	ErrInvalidAccount = errors.New("invalid account number")
+)
+
+// Bank codes with check digit rules
+const (
+	BancoDoBrasil = "001"
+	Santander     = "033"
+	Caixa         = "104"
+	Bradesco      = "237"
+	Bankly        = "332"
+	Itau          = "341"
+)
+
+// Rule describes the branch and account of a bank. The account is the number
+// followed by its check digit, as sent to Bankly.
+type Rule struct {
+	// BranchLength is the max length of the branch without the check digit
+	BranchLength int
+	// BranchDigit returns the check digit of the padded branch, nil when the bank has no branch digit
+	BranchDigit func(branch string) string
+	// AccountLength is the max length of the account without the check digit
+	AccountLength int
+	// AccountDigit returns the check digit of the padded branch and account number.
+	// It returns an empty string when the digit can not be checked offline.
+	AccountDigit func(branch, number string) string
+}
+
+// Account is a normalized bank account
+type Account struct {
+	BankCode    string
+	Branch      string
+	BranchDigit string
+	Number      string
+	Digit       string
+}
+
+var (
+	mutex sync.RWMutex
+	rules = map[string]Rule{
+		BancoDoBrasil: {
+			BranchLength:  4,
+			BranchDigit:   bancoDoBrasilDigit,
+			AccountLength: 8,
+			AccountDigit:  func(branch, number string) string { return bancoDoBrasilDigit(number) },
+		},
+		Santander: {
+			BranchLength:  4,
+			AccountLength: 8,
+			AccountDigit:  santanderAccountDigit,
+		},
+		Caixa: {
+			BranchLength:  4,
+			AccountLength: 12,
+			AccountDigit:  caixaAccountDigit,
+		},
+		Bradesco: {
+			BranchLength:  4,
+			BranchDigit:   bradescoDigit,
+			AccountLength: 7,
+			AccountDigit:  func(branch, number string) string { return bradescoDigit(number) },
+		},
+		Bankly: {
+			BranchLength:  4,
+			AccountLength: 10,
+			AccountDigit:  func(branch, number string) string { return mod11Digit(number, 9, "0", "0") },
+		},
+		Itau: {
+			BranchLength:  4,
+			AccountLength: 5,
+			AccountDigit:  itauAccountDigit,
+		},
+	}
+)
+
+// Register adds or replaces the rule of a bank.
+func Register(bankCode string, rule Rule) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	rules[bankCode] = rule
+}
+
+// Lookup returns the rule of the bank.
+func Lookup(bankCode string) (Rule, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	rule, ok := rules[bankCode]
+	return rule, ok
+}
+
+// Parse normalizes and validates the branch and the account of the bank.
+// The branch may have its check digit, the account must end with its check digit.
+// Banks without a rule pass through, only the separators are removed.
+func Parse(bankCode, branch, account string) (*Account, error) {
+	branch = clean(branch)
+	account = clean(account)
+
+	// the check digit is the last character, which may not be ascii
+	response := &Account{BankCode: bankCode, Branch: branch}
+	if runes := []rune(account); len(runes) > 0 {
+		response.Number = string(runes[:len(runes)-1])
+		response.Digit = string(runes[len(runes)-1:])
+	}
+
+	rule, ok := Lookup(bankCode)
+	if !ok {
+		return response, nil
+	}
+
+	// the branch is given with its check digit
+	if runes := []rune(branch); rule.BranchDigit != nil && len(runes) == rule.BranchLength+1 {
+		response.Branch = string(runes[:rule.BranchLength])
+		response.BranchDigit = string(runes[rule.BranchLength:])
+	}
+
+	if response.Branch == "" || len(response.Branch) > rule.BranchLength || !isDigits(response.Branch) {
+		return nil, ErrInvalidBranch
+	}
+
+	if response.BranchDigit != "" && rule.BranchDigit(response.Branch) != response.BranchDigit {
+		return nil, ErrInvalidBranch
+	}
+
+	if response.Number == "" || !isDigits(response.Number) {
+		return nil, ErrInvalidAccount
+	}
+	response.Branch = pad(response.Branch, rule.BranchLength)
+
+	if response.BranchDigit == "" && rule.BranchDigit != nil {
+		response.BranchDigit = rule.BranchDigit(response.Branch)
+	}
+
+	number := strings.TrimLeft(response.Number, "0")
+	if len(number) > rule.AccountLength {
+		return nil, ErrInvalidAccount
+	}
+
+	if rule.AccountDigit != nil {
+		digit := rule.AccountDigit(response.Branch, pad(number, rule.AccountLength))
+		if digit != "" && digit != response.Digit {
+			return nil, ErrInvalidAccount
+		}
+	}
+
+	return response, nil
+}
+
+// Validate returns ErrInvalidBranch or ErrInvalidAccount when the check digits do not match.
+func Validate(bankCode, branch, account string) error {
+	_, err := Parse(bankCode, branch, account)
+	return err
+}
+
+// Format returns the branch and account as 0001-9 / 12345-6
+func (a Account) Format() (string, string) {
+	branch := a.Branch
+	if a.BranchDigit != "" {
+		branch += "-" + a.BranchDigit
+	}
+	return branch, a.Number + "-" + a.Digit
+}
+
+// String ...
+func (a Account) String() string {
+	branch, account := a.Format()
+	return branch + " / " + account
+}
+
+// clean removes the separators and upper cases the X and P check digits
+func clean(value string) string {
+	var builder strings.Builder
+	for _, c := range strings.ToUpper(value) {
+		if unicode.IsDigit(c) || unicode.IsLetter(c) {
+			builder.WriteRune(c)
+		}
+	}
+	return builder.String()
+}
+
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pad(value string, length int) string {
+	if len(value) >= length {
+		return value
+	}
+	return strings.Repeat("0", length-len(value)) + value
+}
+
+// weightedSum multiplies the digits from the right by the weights, restarting them when they end
+func weightedSum(value string, weights []int) int {
+	sum := 0
+	for i := 0; i < len(value); i++ {
+		digit := int(value[len(value)-1-i] - '0')
+		sum += digit * weights[i%len(weights)]
+	}
+	return sum
+}
+
+// mod11Digit uses the weights 2 to maxWeight from the right, ten and eleven are
+// replaced by the given digits
+func mod11Digit(value string, maxWeight int, ten, eleven string) string {
+	weights := []int{}
+	for w := 2; w <= maxWeight; w++ {
+		weights = append(weights, w)
+	}
+
+	digit := 11 - weightedSum(value, weights)%11
+	switch digit {
+	case 10:
+		return ten
+	case 11:
+		return eleven
+	}
+	return string(rune('0' + digit))
+}
+
+func bancoDoBrasilDigit(value string) string {
+	return mod11Digit(value, 9, "X", "0")
+}
+
+func bradescoDigit(value string) string {
+	return mod11Digit(value, 7, "P", "0")
+}
+
+// itauAccountDigit is the mod 10 of the branch and account number
+func itauAccountDigit(branch, number string) string {
+	sum := 0
+	for i, c := range branch + number {
+		product := int(c-'0') * (2 - i%2)
+		sum += product/10 + product%10
+	}
+	return string(rune('0' + (10-sum%10)%10))
+}
+
+// santanderAccountDigit weights the branch, two zeros and the account number,
+// summing the units of each product
+func santanderAccountDigit(branch, number string) string {
+	weights := []int{9, 7, 3, 1, 0, 0, 9, 7, 1, 3, 1, 9, 7, 3}
+	value := branch + "00" + number
+
+	sum := 0
+	for i := range value {
+		sum += (int(value[i]-'0') * weights[i]) % 10
+	}
+	return string(rune('0' + (10-sum%10)%10))
+}
+
+// caixaAccountDigit checks the accounts with operation code and eight digits. The
+// accounts with nine digits of the new format can not be checked offline.
+func caixaAccountDigit(branch, number string) string {
+	if number[0] != '0' {
+		return ""
+	}
+
+	weights := []int{8, 7, 6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	value := branch + number[1:]
+
+	sum := 0
+	for i := range value {
+		sum += int(value[i]-'0') * weights[i]
+	}
+
+	digit := sum * 10 % 11
+	if digit == 10 {
+		digit = 0
+	}
+	return string(rune('0' + digit))
+}
@@ -0,0 +1,74 @@
+package bankaccount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(Bankly, "0001", "189162"))
+	assert.NoError(t, Validate(Bankly, "0001", "190420")) // ten is zero
+	assert.NoError(t, Validate(Bankly, "0001", "190470")) // eleven is zero
+	assert.Equal(t, ErrInvalidAccount, Validate(Bankly, "0001", "189163"))
+
+	assert.NoError(t, Validate(BancoDoBrasil, "0001-9", "12345-5"))
+	assert.Equal(t, ErrInvalidBranch, Validate(BancoDoBrasil, "0001-8", "12345-5"))
+	assert.Equal(t, ErrInvalidAccount, Validate(BancoDoBrasil, "0001", "12345-6"))
+
+	assert.NoError(t, Validate(Itau, "2545", "02366-1"))
+	assert.Equal(t, ErrInvalidAccount, Validate(Itau, "2545", "02366-2"))
+
+	assert.NoError(t, Validate(Bradesco, "1425-7", "0238069-2"))
+	assert.Equal(t, ErrInvalidBranch, Validate(Bradesco, "1425-1", "0238069-2"))
+
+	assert.NoError(t, Validate(Santander, "0189", "01017417-9"))
+	assert.Equal(t, ErrInvalidAccount, Validate(Santander, "0189", "01017417-8"))
+
+	assert.NoError(t, Validate(Caixa, "0266", "001.00001234-9"))
+	assert.NoError(t, Validate(Caixa, "0266", "1288.00001234-5")) // new format is not checked
+
+	assert.Equal(t, ErrInvalidBranch, Validate(Bankly, "00001", "189162"))
+	assert.Equal(t, ErrInvalidAccount, Validate(Bankly, "0001", "1A9162"))
+}
+
+func TestValidate_UnknownBank(t *testing.T) {
+	assert.NoError(t, Validate("999", "ABC", "1"))
+}
+
+func TestParse(t *testing.T) {
+	account, err := Parse(BancoDoBrasil, "1", " 1234-3 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "0001", account.Branch)
+	assert.Equal(t, "9", account.BranchDigit)
+	assert.Equal(t, "1234", account.Number)
+	assert.Equal(t, "3", account.Digit)
+	assert.Equal(t, "0001-9 / 1234-3", account.String())
+
+	account, err = Parse(Bradesco, "1425", "0000006-p")
+	assert.NoError(t, err)
+	assert.Equal(t, "P", account.Digit)
+
+	// the check digit is a whole character, even when it is not ascii
+	account, err = Parse("999", "0001", "1234-É")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", account.Number)
+	assert.Equal(t, "É", account.Digit)
+
+	_, err = Parse(BancoDoBrasil, "0001-É", "12345-5")
+	assert.Equal(t, ErrInvalidBranch, err)
+	_, err = Parse(BancoDoBrasil, "0001", "12345-É")
+	assert.Equal(t, ErrInvalidAccount, err)
+}
+
+func TestRegister(t *testing.T) {
+	Register("998", Rule{BranchLength: 4, AccountLength: 6, AccountDigit: func(branch, number string) string { return "0" }})
+	defer func() {
+		mutex.Lock()
+		delete(rules, "998")
+		mutex.Unlock()
+	}()
+
+	assert.NoError(t, Validate("998", "0001", "1234560"))
+	assert.Equal(t, ErrInvalidAccount, Validate("998", "0001", "1234561"))
+}
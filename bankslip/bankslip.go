@@ -0,0 +1,516 @@
+// Package bankslip renders the boletos offline as the FEBRABAN PDF: the recibo do
+// pagador and the ficha de compensação, with the linha digitável, the ITF barcode
+// and, at the bolepix, the Pix QR code.
+package bankslip
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/boleto"
+	"github.com/jung-kurt/gofpdf"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+var (
+	// ErrNoBoletos ...
+	ErrNoBoletos = errors.New("no boletos to render")
+	// ErrInvalidLogo ...
+	ErrInvalidLogo = errors.New("invalid logo, expected a PNG or JPEG image")
+)
+
+// DefaultPaymentPlace is the local de pagamento printed when the config has none
+const DefaultPaymentPlace = "Pagável em qualquer banco até o vencimento"
+
+// Page layout in millimeters
+const (
+	margin      = 10.0
+	pageWidth   = 210.0
+	right       = pageWidth - margin
+	column      = 150.0
+	rowHeight   = 9.0
+	headHeight  = 10.0
+	reciboTop   = margin
+	cutLine     = 150.0
+	fichaTop    = 158.0
+	barHeight   = 13.0
+	narrowBar   = 0.25
+	wideBar     = 0.75
+	qrCodeSize  = 38.0
+	labelSize   = 6.0
+	valueSize   = 9.0
+	logoName    = "logo"
+	dateLayout  = "02/01/2006"
+	defaultBank = "Bankly"
+)
+
+// Config customizes the rendered boletos
+type Config struct {
+	// Logo is a PNG or JPEG image printed at the header instead of the bank name
+	Logo []byte
+	// BankName is printed at the header without a logo, Bankly by default
+	BankName string
+	// PaymentPlace is the local de pagamento
+	PaymentPlace string
+	// Instructions are printed after the fine, interest and discount instructions
+	Instructions []string
+}
+
+// Slip is a boleto to render. PixQRCode is the BR Code of the bolepix, printed as a
+// QR code at the recibo do pagador.
+type Slip struct {
+	Boleto    *bankly.BoletoDetailedResponse
+	PixQRCode string
+}
+
+// Renderer ...
+type Renderer struct {
+	config Config
+}
+
+// NewRenderer ...
+func NewRenderer(config Config) *Renderer {
+	if config.BankName == "" {
+		config.BankName = defaultBank
+	}
+	if config.PaymentPlace == "" {
+		config.PaymentPlace = DefaultPaymentPlace
+	}
+	return &Renderer{config: config}
+}
+
+// Render writes the PDF of a boleto, the reprints need no call to Bankly.
+func (r *Renderer) Render(w io.Writer, slip *Slip) error {
+	return r.RenderBatch(w, []*Slip{slip})
+}
+
+// RenderBatch writes the boletos to one PDF, a page for each of them.
+func (r *Renderer) RenderBatch(w io.Writer, slips []*Slip) error {
+	if len(slips) == 0 {
+		return ErrNoBoletos
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetCreator("bankly-sdk", true)
+
+	logo := ""
+	if len(r.config.Logo) > 0 {
+		imageType, ok := imageTypes[http.DetectContentType(r.config.Logo)]
+		if !ok {
+			return ErrInvalidLogo
+		}
+
+		pdf.RegisterImageOptionsReader(logoName, gofpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(r.config.Logo))
+		if pdf.Err() {
+			return ErrInvalidLogo
+		}
+		logo = logoName
+	}
+
+	for _, slip := range slips {
+		if slip == nil || slip.Boleto == nil {
+			return ErrNoBoletos
+		}
+
+		code, err := decode(slip.Boleto)
+		if err != nil {
+			return err
+		}
+
+		p := &page{
+			pdf:    pdf,
+			tr:     pdf.UnicodeTranslatorFromDescriptor(""),
+			config: r.config,
+			logo:   logo,
+			slip:   slip,
+			code:   code,
+		}
+		if err := p.draw(); err != nil {
+			return err
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+var imageTypes = map[string]string{
+	"image/png":  "PNG",
+	"image/jpeg": "JPG",
+}
+
+// decode reads the barcode or, without it, the linha digitável of the boleto
+func decode(response *bankly.BoletoDetailedResponse) (*boleto.Boleto, error) {
+	code := response.Barcode
+	if code == "" {
+		code = response.Digitable
+	}
+
+	decoded, err := boleto.Parse(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded.Kind != boleto.Bank {
+		return nil, boleto.ErrInvalidCode
+	}
+
+	return decoded, nil
+}
+
+type page struct {
+	pdf    *gofpdf.Fpdf
+	tr     func(string) string
+	config Config
+	logo   string
+	slip   *Slip
+	code   *boleto.Boleto
+}
+
+func (p *page) draw() error {
+	p.pdf.AddPage()
+	p.pdf.SetLineWidth(0.2)
+
+	p.drawRecibo()
+	p.drawCutLine()
+	p.drawFicha()
+
+	if p.slip.PixQRCode != "" {
+		if err := p.drawPix(); err != nil {
+			return err
+		}
+	}
+
+	return p.pdf.Error()
+}
+
+func (p *page) drawRecibo() {
+	b := p.slip.Boleto
+	y := reciboTop
+
+	p.drawHeader(y, "Recibo do Pagador")
+	y += headHeight
+
+	p.field(margin, y, column-margin, "Beneficiário", beneficiary(b), "L")
+	p.field(column, y, right-column, "Agência/Código do Beneficiário", agency(b), "R")
+	y += rowHeight
+
+	p.field(margin, y, column-margin, "Pagador", party(b.Payer), "L")
+	p.field(column, y, right-column, "Nosso Número", b.OurNumber, "R")
+	y += rowHeight
+
+	p.field(margin, y, 70, "Número do Documento", alias(b), "L")
+	p.field(margin+70, y, column-margin-70, "Data do Documento", formatDate(emission(b)), "L")
+	p.field(column, y, right-column, "Vencimento", formatDate(b.DueDate), "R")
+	y += rowHeight
+
+	p.field(margin, y, column-margin, "Linha Digitável", boleto.FormatDigitable(p.code.Digitable), "L")
+	p.field(column, y, right-column, "(=) Valor do Documento", amount(b, p.code).String(), "R")
+	y += rowHeight
+
+	p.label(column, y+1, right-column, "Autenticação Mecânica", "R")
+}
+
+func (p *page) drawCutLine() {
+	p.pdf.SetDashPattern([]float64{1, 1}, 0)
+	p.pdf.Line(margin, cutLine, right, cutLine)
+	p.pdf.SetDashPattern([]float64{}, 0)
+	p.label(margin, cutLine+0.5, right-margin, "Corte na linha pontilhada", "R")
+}
+
+func (p *page) drawFicha() {
+	b := p.slip.Boleto
+	y := fichaTop
+
+	p.drawHeader(y, boleto.FormatDigitable(p.code.Digitable))
+	y += headHeight
+
+	p.field(margin, y, column-margin, "Local de Pagamento", p.config.PaymentPlace, "L")
+	p.field(column, y, right-column, "Vencimento", formatDate(b.DueDate), "R")
+	y += rowHeight
+
+	p.field(margin, y, column-margin, "Beneficiário", beneficiary(b), "L")
+	p.field(column, y, right-column, "Agência/Código do Beneficiário", agency(b), "R")
+	y += rowHeight
+
+	p.field(margin, y, 30, "Data do Documento", formatDate(emission(b)), "L")
+	p.field(40, y, 35, "Número do Documento", alias(b), "L")
+	p.field(75, y, 20, "Espécie Doc.", "DM", "L")
+	p.field(95, y, 15, "Aceite", "N", "L")
+	p.field(110, y, column-110, "Data do Processamento", formatDate(emission(b)), "L")
+	p.field(column, y, right-column, "Nosso Número", b.OurNumber, "R")
+	y += rowHeight
+
+	p.field(margin, y, 30, "Uso do Banco", "", "L")
+	p.field(40, y, 20, "Carteira", "", "L")
+	p.field(60, y, 20, "Espécie", "R$", "L")
+	p.field(80, y, 30, "Quantidade", "", "L")
+	p.field(110, y, column-110, "Valor", "", "L")
+	p.field(column, y, right-column, "(=) Valor do Documento", amount(b, p.code).String(), "R")
+	y += rowHeight
+
+	lines := append(Instructions(b), p.config.Instructions...)
+	p.pdf.Rect(margin, y, column-margin, 5*rowHeight, "D")
+	p.label(margin, y, column-margin, "Instruções (texto de responsabilidade do beneficiário)", "L")
+	p.pdf.SetFont("Helvetica", "", valueSize-1)
+	p.pdf.SetXY(margin+1, y+3.5)
+	p.pdf.MultiCell(column-margin-2, 4, p.tr(strings.Join(lines, "\n")), "", "L", false)
+
+	for i, title := range []string{"(-) Desconto/Abatimento", "(-) Outras Deduções", "(+) Mora/Multa", "(+) Outros Acréscimos", "(=) Valor Cobrado"} {
+		p.field(column, y+float64(i)*rowHeight, right-column, title, "", "R")
+	}
+	y += 5 * rowHeight
+
+	p.pdf.Rect(margin, y, right-margin, 2*rowHeight, "D")
+	p.label(margin, y, right-margin, "Pagador", "L")
+	p.value(margin, y+3, right-margin, party(b.Payer), "L")
+	p.value(margin, y+7.5, right-margin, address(b.Payer), "L")
+	y += 2 * rowHeight
+
+	p.label(column-40, y+0.5, right-column+40, "Autenticação Mecânica - Ficha de Compensação", "R")
+	p.drawBarcode(margin+2, y+4)
+}
+
+func (p *page) drawHeader(y float64, title string) {
+	if p.logo != "" {
+		p.pdf.ImageOptions(p.logo, margin, y+1, 0, headHeight-2, false, gofpdf.ImageOptions{}, 0, "")
+	} else {
+		p.pdf.SetFont("Helvetica", "B", 12)
+		p.pdf.SetXY(margin, y)
+		p.pdf.CellFormat(40, headHeight, p.tr(p.config.BankName), "", 0, "L", false, 0, "")
+	}
+
+	p.pdf.SetFont("Helvetica", "B", 14)
+	p.pdf.SetXY(52, y)
+	p.pdf.CellFormat(22, headHeight, p.code.BankCode+"-"+bankCodeDigit(p.code.BankCode), "LR", 0, "C", false, 0, "")
+
+	p.pdf.SetFont("Helvetica", "B", 11)
+	p.pdf.SetXY(76, y)
+	p.pdf.CellFormat(right-76, headHeight, p.tr(title), "", 0, "R", false, 0, "")
+
+	p.pdf.Line(margin, y+headHeight, right, y+headHeight)
+}
+
+// drawBarcode draws the bars of the ITF, skipping the spaces
+func (p *page) drawBarcode(x, y float64) {
+	elements, _ := boleto.ITF(p.code.Barcode)
+
+	for i, wide := range elements {
+		width := narrowBar
+		if wide {
+			width = wideBar
+		}
+		if i%2 == 0 {
+			p.pdf.Rect(x, y, width, barHeight, "F")
+		}
+		x += width
+	}
+}
+
+func (p *page) drawPix() error {
+	code, err := qrcode.New(p.slip.PixQRCode, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+	code.DisableBorder = true
+
+	bitmap := code.Bitmap()
+	module := qrCodeSize / float64(len(bitmap))
+	x, y := margin, reciboTop+headHeight+4*rowHeight+8
+
+	for row, modules := range bitmap {
+		for col, dark := range modules {
+			if dark {
+				p.pdf.Rect(x+float64(col)*module, y+float64(row)*module, module, module, "F")
+			}
+		}
+	}
+
+	p.pdf.SetFont("Helvetica", "B", valueSize)
+	p.pdf.SetXY(x+qrCodeSize+4, y)
+	p.pdf.CellFormat(90, 5, p.tr("Pague com Pix"), "", 2, "L", false, 0, "")
+	p.pdf.SetFont("Helvetica", "", valueSize-1)
+	p.pdf.MultiCell(90, 4, p.tr("Aponte a câmera do app do seu banco para o QR code ou use o Pix Copia e Cola:"), "", "L", false)
+	p.pdf.SetX(x + qrCodeSize + 4)
+	p.pdf.SetFont("Courier", "", labelSize)
+	p.pdf.MultiCell(90, 3, p.slip.PixQRCode, "", "L", false)
+
+	return nil
+}
+
+// field draws a box with its label at the top and its value below
+func (p *page) field(x, y, width float64, title, value, align string) {
+	p.pdf.Rect(x, y, width, rowHeight, "D")
+	p.label(x, y, width, title, "L")
+	p.value(x, y+3.5, width, value, align)
+}
+
+func (p *page) label(x, y, width float64, title, align string) {
+	p.pdf.SetFont("Helvetica", "", labelSize)
+	p.pdf.SetXY(x+0.5, y+0.5)
+	p.pdf.CellFormat(width-1, 2.5, p.tr(title), "", 0, align, false, 0, "")
+}
+
+func (p *page) value(x, y, width float64, value, align string) {
+	p.pdf.SetFont("Helvetica", "", valueSize)
+	p.pdf.SetXY(x+0.5, y)
+	p.pdf.CellFormat(width-1, 5, p.tr(value), "", 0, align, false, 0, "")
+}
+
+// Instructions returns the instructions to the cashier of the fine, the interest,
+// the discount and the payment limit of the boleto.
+func Instructions(b *bankly.BoletoDetailedResponse) []string {
+	instructions := []string{}
+
+	if b.Fine != nil && b.Fine.Value > 0 {
+		value := formatPercent(b.Fine.Value)
+		if b.Fine.Type == bankly.FixedAmountFineType {
+			value = bankly.MoneyFromFloat(b.Fine.Value).String()
+		}
+		instructions = append(instructions, "A partir de "+formatDate(b.Fine.StartDate)+", cobrar multa de "+value+".")
+	}
+
+	if b.Interest != nil && b.Interest.Value > 0 {
+		value := formatPercent(b.Interest.Value) + " ao mês"
+		if b.Interest.Type == bankly.FixedAmountInterestType {
+			value = bankly.MoneyFromFloat(b.Interest.Value).String() + " ao dia"
+		}
+		instructions = append(instructions, "A partir de "+formatDate(b.Interest.StartDate)+", cobrar juros de "+value+".")
+	}
+
+	discount := b.Discount
+	if discount == nil {
+		discount = b.Discounts
+	}
+	if discount != nil && discount.Value > 0 {
+		value := bankly.MoneyFromFloat(discount.Value).String()
+		if discount.Type == bankly.FixedPercentDiscountType {
+			value = formatPercent(discount.Value)
+		}
+		instructions = append(instructions, "Até "+formatDate(discount.LimitDate)+", conceder desconto de "+value+".")
+	}
+
+	if b.ClosePayment != nil && !b.ClosePayment.IsZero() {
+		instructions = append(instructions, "Não receber após "+formatDate(*b.ClosePayment)+".")
+	}
+
+	return instructions
+}
+
+func beneficiary(b *bankly.BoletoDetailedResponse) string {
+	if b.RecipientFinal != nil {
+		return party(b.RecipientFinal)
+	}
+	return party(b.RecipientOrigin)
+}
+
+func party(payer *bankly.Payer) string {
+	if payer == nil {
+		return ""
+	}
+
+	name := payer.Name
+	if name == "" {
+		name = payer.TradeName
+	}
+
+	return name + " - " + documentLabel(payer.Document)
+}
+
+func address(payer *bankly.Payer) string {
+	if payer == nil || payer.Address == nil {
+		return ""
+	}
+
+	a := payer.Address
+	zipCode := bankly.OnlyDigits(a.ZipCode)
+	if len(zipCode) == 8 {
+		zipCode = zipCode[:5] + "-" + zipCode[5:]
+	}
+
+	return a.AddressLine + ", " + a.Neighborhood + ", " + a.City + "/" + a.State + " - CEP " + zipCode
+}
+
+// documentLabel formats the CPF as 000.000.000-00 and the CNPJ as 00.000.000/0000-00
+func documentLabel(document string) string {
+	digits := bankly.OnlyDigits(document)
+
+	switch len(digits) {
+	case 11:
+		return "CPF " + digits[0:3] + "." + digits[3:6] + "." + digits[6:9] + "-" + digits[9:11]
+	case 14:
+		return "CNPJ " + digits[0:2] + "." + digits[2:5] + "." + digits[5:8] + "/" + digits[8:12] + "-" + digits[12:14]
+	}
+
+	return document
+}
+
+func agency(b *bankly.BoletoDetailedResponse) string {
+	if b.Account == nil {
+		return ""
+	}
+
+	number := b.Account.Number
+	if len(number) > 1 {
+		number = number[:len(number)-1] + "-" + number[len(number)-1:]
+	}
+
+	return b.Account.Branch + " / " + number
+}
+
+func alias(b *bankly.BoletoDetailedResponse) string {
+	if b.Alias == nil {
+		return ""
+	}
+	return *b.Alias
+}
+
+func emission(b *bankly.BoletoDetailedResponse) time.Time {
+	if b.EmissionDate == nil {
+		return time.Time{}
+	}
+	return *b.EmissionDate
+}
+
+// amount is the amount of the boleto or, without it, the amount of the barcode
+func amount(b *bankly.BoletoDetailedResponse, code *boleto.Boleto) bankly.Money {
+	if b.Amount != nil {
+		return b.Amount.Money()
+	}
+	return bankly.NewMoney(code.Amount)
+}
+
+// formatDate keeps the date as returned by Bankly, without converting its time zone
+func formatDate(date time.Time) string {
+	if date.IsZero() {
+		return ""
+	}
+	return date.Format(dateLayout)
+}
+
+func formatPercent(value float64) string {
+	return strings.Replace(strconv.FormatFloat(value, 'f', 2, 64), ".", ",", 1) + "%"
+}
+
+// bankCodeDigit is the mod 11 of the bank code printed at the header, as 001-9 and 341-7
+func bankCodeDigit(bankCode string) string {
+	sum := 0
+	weight := 2
+	for i := len(bankCode) - 1; i >= 0; i-- {
+		sum += int(bankCode[i]-'0') * weight
+		weight++
+	}
+
+	digit := 11 - sum%11
+	if digit >= 10 {
+		return "0"
+	}
+	return strconv.Itoa(digit)
+}
@@ -0,0 +1,136 @@
+package bankslip
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/boleto"
+	"github.com/stretchr/testify/assert"
+)
+
+var pages = regexp.MustCompile(`/Type /Page\b[^s]`)
+
+func buildBoleto(t *testing.T) *bankly.BoletoDetailedResponse {
+	dueDate := time.Date(2026, time.November, 10, 0, 0, 0, 0, time.UTC)
+	emissionDate := time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	closePayment := dueDate.AddDate(0, 0, 30)
+	alias := "NF-1001"
+
+	barcode, err := boleto.NewBarcode(bankly.InternalBankCode, dueDate, 125050, "0000100000000000001234567")
+	assert.NoError(t, err)
+
+	return &bankly.BoletoDetailedResponse{
+		Alias:        &alias,
+		Barcode:      barcode,
+		DueDate:      dueDate,
+		EmissionDate: &emissionDate,
+		ClosePayment: &closePayment,
+		OurNumber:    "1234567",
+		Amount:       &bankly.BoletoAmount{Value: 1250.5, Currency: bankly.CurrencyBRL},
+		Account:      &bankly.Account{Branch: "0001", Number: "189162"},
+		RecipientFinal: &bankly.Payer{
+			Name:     "Empresa Cobradora",
+			Document: "12345678000195",
+		},
+		Payer: &bankly.Payer{
+			Name:     "João da Silva",
+			Document: "76385230056",
+			Address: &bankly.BoletoAddress{
+				ZipCode:      "01001000",
+				AddressLine:  "Rua das Flores 100",
+				Neighborhood: "Centro",
+				State:        "SP",
+				City:         "São Paulo",
+			},
+		},
+		Fine:     &bankly.BoletoFine{StartDate: dueDate.AddDate(0, 0, 1), Value: 2, Type: bankly.PercentFineType},
+		Interest: &bankly.BoletoInterest{StartDate: dueDate.AddDate(0, 0, 1), Value: 0.41, Type: bankly.FixedAmountInterestType},
+		Discount: &bankly.BoletoDiscounts{LimitDate: dueDate.AddDate(0, 0, -5), Value: 10, Type: bankly.FixedAmountDiscountType},
+	}
+}
+
+func TestRender(t *testing.T) {
+	renderer := NewRenderer(Config{})
+
+	var buffer bytes.Buffer
+	err := renderer.Render(&buffer, &Slip{Boleto: buildBoleto(t)})
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(buffer.Bytes(), []byte("%PDF-")))
+	assert.Len(t, pages.FindAll(buffer.Bytes(), -1), 1)
+}
+
+func TestRenderBatch(t *testing.T) {
+	logo := image.NewRGBA(image.Rect(0, 0, 40, 10))
+	logo.Set(1, 1, color.Black)
+
+	var encoded bytes.Buffer
+	assert.NoError(t, png.Encode(&encoded, logo))
+
+	renderer := NewRenderer(Config{Logo: encoded.Bytes(), Instructions: []string{"Referente à NF 1001."}})
+
+	digitable := buildBoleto(t)
+	digitable.Digitable, _ = boleto.ToDigitable(digitable.Barcode)
+	digitable.Barcode = ""
+
+	slips := []*Slip{
+		{Boleto: buildBoleto(t), PixQRCode: "00020101021226850014br.gov.bcb.pix2563pix.example.com/qr/v2/cobv/9d36b84f-c70b-478f-b95c-12729b90ca255204000053039865406" +
+			"1250.505802BR5917EMPRESA COBRADORA6009SAO PAULO62070503***6304ABCD"},
+		{Boleto: digitable},
+		{Boleto: buildBoleto(t)},
+	}
+
+	var buffer bytes.Buffer
+	err := renderer.RenderBatch(&buffer, slips)
+	assert.NoError(t, err)
+	assert.Len(t, pages.FindAll(buffer.Bytes(), -1), 3)
+}
+
+func TestRender_Errors(t *testing.T) {
+	var buffer bytes.Buffer
+
+	err := NewRenderer(Config{}).RenderBatch(&buffer, nil)
+	assert.Equal(t, ErrNoBoletos, err)
+
+	err = NewRenderer(Config{Logo: []byte("not an image")}).Render(&buffer, &Slip{Boleto: buildBoleto(t)})
+	assert.Equal(t, ErrInvalidLogo, err)
+
+	invalid := buildBoleto(t)
+	invalid.Barcode = invalid.Barcode[:4] + "0" + invalid.Barcode[5:]
+	err = NewRenderer(Config{}).Render(&buffer, &Slip{Boleto: invalid})
+	assert.Equal(t, boleto.ErrInvalidCheckDigit, err)
+
+	assert.Equal(t, 0, buffer.Len())
+}
+
+func TestInstructions(t *testing.T) {
+	b := buildBoleto(t)
+
+	assert.Equal(t, []string{
+		"A partir de 11/11/2026, cobrar multa de 2,00%.",
+		"A partir de 11/11/2026, cobrar juros de R$ 0,41 ao dia.",
+		"Até 05/11/2026, conceder desconto de R$ 10,00.",
+		"Não receber após 10/12/2026.",
+	}, Instructions(b))
+
+	b.Fine, b.ClosePayment = nil, nil
+	b.Interest.Type, b.Interest.Value = bankly.PercentInterestType, 1
+	b.Discount, b.Discounts = nil, &bankly.BoletoDiscounts{LimitDate: b.DueDate, Value: 5, Type: bankly.FixedPercentDiscountType}
+
+	assert.Equal(t, []string{
+		"A partir de 11/11/2026, cobrar juros de 1,00% ao mês.",
+		"Até 10/11/2026, conceder desconto de 5,00%.",
+	}, Instructions(b))
+}
+
+func TestBankCodeDigit(t *testing.T) {
+	assert.Equal(t, "9", bankCodeDigit("001"))
+	assert.Equal(t, "7", bankCodeDigit("341"))
+	assert.Equal(t, "2", bankCodeDigit("237"))
+	assert.Equal(t, "8", bankCodeDigit(bankly.InternalBankCode))
+}
@@ -0,0 +1,391 @@
+// Package boleto decodes and validates the boleto codes offline: the 44 digits
+// barcode and the linha digitável of the bank boletos (47 digits) and of the
+// arrecadação, the bills of utilities and taxes (48 digits).
+package boleto
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+)
+
+var (
+	// ErrInvalidCode ...
+	ErrInvalidCode = errors.New("invalid boleto code")
+	// ErrInvalidFieldDigit ...
+	ErrInvalidFieldDigit = errors.New("invalid check digit of a field of the linha digitavel")
+	// ErrInvalidCheckDigit ...
+	ErrInvalidCheckDigit = errors.New("invalid check digit of the boleto")
+	// ErrInvalidDueDate ...
+	ErrInvalidDueDate = errors.New("due date out of the factor range")
+)
+
+// Lengths of the codes
+const (
+	BarcodeLength              = 44
+	DigitableLength            = 47
+	CollectionDigitableLength  = 48
+	collectionBlockLength      = 11
+	collectionBlockDigitLength = collectionBlockLength + 1
+)
+
+// Kind of the boleto
+type Kind string
+
+const (
+	// Bank is a boleto of cobrança issued by a bank
+	Bank Kind = "BANK"
+	// Collection is a bill of arrecadação, identified by the 8 at the start
+	Collection Kind = "COLLECTION"
+)
+
+// CurrencyReal is the currency code of the bank boletos in reais
+const CurrencyReal = "9"
+
+// Value identifiers of the arrecadação, setting the amount kind and the check digit module
+const (
+	ValueAmountMod10    = "6"
+	ValueReferenceMod10 = "7"
+	ValueAmountMod11    = "8"
+	ValueReferenceMod11 = "9"
+)
+
+// Boleto is a decoded code. Amount is in centavos, zero for the boletos without
+// amount and for the arrecadação with a reference value.
+type Boleto struct {
+	Kind      Kind
+	Barcode   string
+	Digitable string
+	Amount    int64
+
+	// bank boletos
+	BankCode  string
+	Currency  string
+	Factor    int
+	DueDate   time.Time
+	FreeField string
+
+	// arrecadação
+	Segment   string
+	ValueID   string
+	Reference string
+	CompanyID string
+}
+
+// factorBase is the date of the factor 1000, the factor returns to 1000 after
+// 9999 at every 9000 days, as at 2025-02-22
+var factorBase = time.Date(2000, time.July, 3, 0, 0, 0, 0, calendar.Location())
+
+const factorCycle = 9000
+
+// Parse decodes and validates a barcode or linha digitável, the separators are ignored.
+// The due date is resolved around the current date.
+func Parse(code string) (*Boleto, error) {
+	return ParseAt(code, time.Now())
+}
+
+// ParseAt decodes the code resolving the due date factor at the cycle closest to the reference date.
+func ParseAt(code string, reference time.Time) (*Boleto, error) {
+	code = onlyDigits(code)
+
+	barcode := code
+	switch len(code) {
+	case BarcodeLength:
+	case DigitableLength, CollectionDigitableLength:
+		var err error
+		if barcode, err = ToBarcode(code); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrInvalidCode
+	}
+
+	if barcode[0] == '8' {
+		return parseCollection(barcode)
+	}
+	return parseBank(barcode, reference)
+}
+
+// Validate checks the length and the check digits of the code.
+func Validate(code string) error {
+	_, err := Parse(code)
+	return err
+}
+
+func parseBank(barcode string, reference time.Time) (*Boleto, error) {
+	if bankDigit(barcode) != barcode[4:5] {
+		return nil, ErrInvalidCheckDigit
+	}
+
+	factor, _ := strconv.Atoi(barcode[5:9])
+	amount, _ := strconv.ParseInt(barcode[9:19], 10, 64)
+
+	boleto := &Boleto{
+		Kind:      Bank,
+		Barcode:   barcode,
+		Amount:    amount,
+		BankCode:  barcode[0:3],
+		Currency:  barcode[3:4],
+		Factor:    factor,
+		DueDate:   FactorDate(factor, reference),
+		FreeField: barcode[19:44],
+	}
+	boleto.Digitable, _ = ToDigitable(barcode)
+
+	return boleto, nil
+}
+
+func parseCollection(barcode string) (*Boleto, error) {
+	valueID := barcode[2:3]
+	if collectionDigit(barcode, valueID) != barcode[3:4] {
+		return nil, ErrInvalidCheckDigit
+	}
+
+	boleto := &Boleto{
+		Kind:      Collection,
+		Barcode:   barcode,
+		Segment:   barcode[1:2],
+		ValueID:   valueID,
+		CompanyID: barcode[15:19],
+	}
+
+	// the segment 6 identifies the company by the 8 first digits of its CNPJ
+	if boleto.Segment == "6" {
+		boleto.CompanyID = barcode[15:23]
+	}
+
+	if valueID == ValueAmountMod10 || valueID == ValueAmountMod11 {
+		boleto.Amount, _ = strconv.ParseInt(barcode[4:15], 10, 64)
+	} else {
+		boleto.Reference = barcode[4:15]
+	}
+
+	boleto.Digitable, _ = ToDigitable(barcode)
+
+	return boleto, nil
+}
+
+// ToBarcode converts a linha digitável to the barcode, checking the digits of its fields.
+func ToBarcode(digitable string) (string, error) {
+	digitable = onlyDigits(digitable)
+
+	switch len(digitable) {
+	case DigitableLength:
+		fields := []string{digitable[0:10], digitable[10:21], digitable[21:32]}
+		for _, field := range fields {
+			if mod10(field[:len(field)-1]) != field[len(field)-1:] {
+				return "", ErrInvalidFieldDigit
+			}
+		}
+
+		// bank and currency, general digit, factor and amount, free field
+		return digitable[0:4] + digitable[32:33] + digitable[33:47] +
+			digitable[4:9] + digitable[10:20] + digitable[21:31], nil
+
+	case CollectionDigitableLength:
+		if digitable[0] != '8' {
+			return "", ErrInvalidCode
+		}
+
+		valueID := digitable[2:3]
+		barcode := ""
+		for i := 0; i < 4; i++ {
+			block := digitable[i*collectionBlockDigitLength : (i+1)*collectionBlockDigitLength]
+			if collectionBlockDigit(block[:collectionBlockLength], valueID) != block[collectionBlockLength:] {
+				return "", ErrInvalidFieldDigit
+			}
+			barcode += block[:collectionBlockLength]
+		}
+		return barcode, nil
+	}
+
+	return "", ErrInvalidCode
+}
+
+// ToDigitable converts a barcode to the linha digitável, without separators.
+func ToDigitable(barcode string) (string, error) {
+	barcode = onlyDigits(barcode)
+	if len(barcode) != BarcodeLength {
+		return "", ErrInvalidCode
+	}
+
+	if barcode[0] == '8' {
+		valueID := barcode[2:3]
+		digitable := ""
+		for i := 0; i < 4; i++ {
+			block := barcode[i*collectionBlockLength : (i+1)*collectionBlockLength]
+			digitable += block + collectionBlockDigit(block, valueID)
+		}
+		return digitable, nil
+	}
+
+	free := barcode[19:44]
+	fields := []string{barcode[0:4] + free[0:5], free[5:15], free[15:25]}
+
+	digitable := ""
+	for _, field := range fields {
+		digitable += field + mod10(field)
+	}
+
+	return digitable + barcode[4:5] + barcode[5:19], nil
+}
+
+// FormatDigitable writes the linha digitável with the separators printed at the boletos,
+// as 00190.50095 40144.816069 06809.350314 3 37370000000100 or, at the arrecadação,
+// as 83620000002-1 35510040318-5 23431917203-2 10018184169-1.
+func FormatDigitable(digitable string) string {
+	digitable = onlyDigits(digitable)
+
+	switch len(digitable) {
+	case DigitableLength:
+		return digitable[0:5] + "." + digitable[5:10] + " " +
+			digitable[10:15] + "." + digitable[15:21] + " " +
+			digitable[21:26] + "." + digitable[26:32] + " " +
+			digitable[32:33] + " " + digitable[33:47]
+	case CollectionDigitableLength:
+		blocks := []string{}
+		for i := 0; i < 4; i++ {
+			block := digitable[i*collectionBlockDigitLength : (i+1)*collectionBlockDigitLength]
+			blocks = append(blocks, block[:collectionBlockLength]+"-"+block[collectionBlockLength:])
+		}
+		return strings.Join(blocks, " ")
+	}
+
+	return digitable
+}
+
+// NewBarcode builds the barcode of a bank boleto, with its general check digit.
+// The amount is in centavos and a zero due date is written as the factor 0000.
+func NewBarcode(bankCode string, dueDate time.Time, amount int64, freeField string) (string, error) {
+	if len(bankCode) != 3 || !isDigits(bankCode) || len(freeField) != 25 || !isDigits(freeField) {
+		return "", ErrInvalidCode
+	}
+
+	value := strconv.FormatInt(amount, 10)
+	if amount < 0 || len(value) > 10 {
+		return "", ErrInvalidCode
+	}
+
+	factor := 0
+	if !dueDate.IsZero() {
+		var err error
+		if factor, err = Factor(dueDate); err != nil {
+			return "", err
+		}
+	}
+
+	barcode := bankCode + CurrencyReal + "0" + pad(strconv.Itoa(factor), 4) + pad(value, 10) + freeField
+	return barcode[:4] + bankDigit(barcode) + barcode[5:], nil
+}
+
+// Factor returns the due date factor of the date, from 1000 to 9999 at each cycle.
+func Factor(dueDate time.Time) (int, error) {
+	days := daysBetween(factorBase, dueDate)
+	if days < 0 {
+		return 0, ErrInvalidDueDate
+	}
+	return 1000 + days%factorCycle, nil
+}
+
+// FactorDate returns the due date of the factor at the cycle closest to the reference,
+// or the zero time to the factor 0000 of the boletos without due date.
+func FactorDate(factor int, reference time.Time) time.Time {
+	if factor < 1000 {
+		return time.Time{}
+	}
+
+	date := factorBase.AddDate(0, 0, factor-1000)
+	for daysBetween(reference, date) < -factorCycle/2 {
+		date = date.AddDate(0, 0, factorCycle)
+	}
+
+	return date
+}
+
+// daysBetween counts the calendar days from one date to the other at UTC, where the
+// days have 24 hours also at the daylight saving time of Sao Paulo
+func daysBetween(from, to time.Time) int {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours() / 24)
+}
+
+// bankDigit is the mod 11 of the barcode without its digit, 0, 10 and 11 are 1
+func bankDigit(barcode string) string {
+	rest := weightedSum(barcode[:4]+barcode[5:], 9) % 11
+	digit := 11 - rest
+	if digit == 0 || digit >= 10 {
+		return "1"
+	}
+	return strconv.Itoa(digit)
+}
+
+func collectionDigit(barcode, valueID string) string {
+	return collectionBlockDigit(barcode[:3]+barcode[4:], valueID)
+}
+
+func collectionBlockDigit(block, valueID string) string {
+	if valueID == ValueAmountMod11 || valueID == ValueReferenceMod11 {
+		rest := weightedSum(block, 9) % 11
+		if rest <= 1 {
+			return "0"
+		}
+		return strconv.Itoa(11 - rest)
+	}
+	return mod10(block)
+}
+
+// weightedSum multiplies the digits from the right by the weights from 2 to max
+func weightedSum(value string, max int) int {
+	sum := 0
+	weight := 2
+	for i := len(value) - 1; i >= 0; i-- {
+		sum += int(value[i]-'0') * weight
+		weight++
+		if weight > max {
+			weight = 2
+		}
+	}
+	return sum
+}
+
+// mod10 multiplies the digits from the right by 2 and 1, adding the digits of the products
+func mod10(value string) string {
+	sum := 0
+	weight := 2
+	for i := len(value) - 1; i >= 0; i-- {
+		product := int(value[i]-'0') * weight
+		sum += product/10 + product%10
+		weight = 3 - weight
+	}
+	return strconv.Itoa((10 - sum%10) % 10)
+}
+
+func onlyDigits(value string) string {
+	var builder strings.Builder
+	for _, c := range value {
+		if c >= '0' && c <= '9' {
+			builder.WriteRune(c)
+		}
+	}
+	return builder.String()
+}
+
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
+
+func pad(value string, length int) string {
+	if len(value) >= length {
+		return value
+	}
+	return strings.Repeat("0", length-len(value)) + value
+}
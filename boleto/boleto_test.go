@@ -0,0 +1,145 @@
+package boleto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	bankDigitable = "00190.50095 40144.816069 06809.350314 3 37370000000100"
+	bankBarcode   = "00193373700000001000500940144816060680935031"
+
+	collectionDigitable = "836200000021355100403185234319172032100181841691"
+)
+
+func TestParse_Bank(t *testing.T) {
+	boleto, err := ParseAt(bankDigitable, date(2008, time.January, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, Bank, boleto.Kind)
+	assert.Equal(t, bankBarcode, boleto.Barcode)
+	assert.Equal(t, onlyDigits(bankDigitable), boleto.Digitable)
+	assert.Equal(t, "001", boleto.BankCode)
+	assert.Equal(t, CurrencyReal, boleto.Currency)
+	assert.Equal(t, 3737, boleto.Factor)
+	assert.Equal(t, date(2007, time.December, 31), boleto.DueDate)
+	assert.Equal(t, int64(100), boleto.Amount)
+	assert.Equal(t, "0500940144816060680935031", boleto.FreeField)
+
+	again, err := ParseAt(bankBarcode, date(2008, time.January, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, boleto, again)
+}
+
+func TestParse_Collection(t *testing.T) {
+	boleto, err := Parse(collectionDigitable)
+	assert.NoError(t, err)
+	assert.Equal(t, Collection, boleto.Kind)
+	assert.Equal(t, "3", boleto.Segment)
+	assert.Equal(t, ValueAmountMod10, boleto.ValueID)
+	assert.Equal(t, int64(23551), boleto.Amount)
+	assert.Equal(t, "0040", boleto.CompanyID)
+	assert.Len(t, boleto.Barcode, BarcodeLength)
+
+	digitable, err := ToDigitable(boleto.Barcode)
+	assert.NoError(t, err)
+	assert.Equal(t, collectionDigitable, digitable)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(bankDigitable))
+	assert.NoError(t, Validate(collectionDigitable))
+
+	assert.Equal(t, ErrInvalidCode, Validate("123"))
+	assert.Equal(t, ErrInvalidCode, Validate(""))
+
+	// field digit of the first field changed
+	assert.Equal(t, ErrInvalidFieldDigit, Validate("00190.50096 40144.816069 06809.350314 3 37370000000100"))
+	// general digit changed
+	assert.Equal(t, ErrInvalidCheckDigit, Validate("00190.50095 40144.816069 06809.350314 4 37370000000100"))
+	assert.Equal(t, ErrInvalidCheckDigit, Validate("00194373700000001000500940144816060680935031"))
+	// block digit of the arrecadação changed
+	assert.Equal(t, ErrInvalidFieldDigit, Validate("836200000022355100403185234319172032100181841691"))
+}
+
+func TestNewBarcode(t *testing.T) {
+	barcode, err := NewBarcode("332", date(2026, time.November, 10), 125050, "0000000000000000001234567")
+	assert.NoError(t, err)
+
+	boleto, err := ParseAt(barcode, date(2026, time.October, 19))
+	assert.NoError(t, err)
+	assert.Equal(t, "332", boleto.BankCode)
+	assert.Equal(t, date(2026, time.November, 10), boleto.DueDate)
+	assert.Equal(t, int64(125050), boleto.Amount)
+
+	barcode, err = NewBarcode("332", time.Time{}, 0, "0000000000000000001234567")
+	assert.NoError(t, err)
+	assert.Equal(t, "0000", barcode[5:9])
+
+	_, err = NewBarcode("33", time.Time{}, 0, "0000000000000000001234567")
+	assert.Equal(t, ErrInvalidCode, err)
+}
+
+func TestFactor(t *testing.T) {
+	factor, err := Factor(date(2025, time.February, 21))
+	assert.NoError(t, err)
+	assert.Equal(t, 9999, factor)
+
+	factor, err = Factor(date(2025, time.February, 22))
+	assert.NoError(t, err)
+	assert.Equal(t, 1000, factor)
+
+	_, err = Factor(date(1999, time.January, 1))
+	assert.Equal(t, ErrInvalidDueDate, err)
+
+	assert.Equal(t, date(2025, time.February, 22), FactorDate(1000, date(2025, time.January, 1)))
+	assert.Equal(t, date(2000, time.July, 3), FactorDate(1000, date(2001, time.January, 1)))
+	assert.Equal(t, date(2025, time.February, 21), FactorDate(9999, date(2025, time.March, 1)))
+	assert.True(t, FactorDate(0, date(2025, time.March, 1)).IsZero())
+}
+
+func TestFactor_DaylightSavingTime(t *testing.T) {
+	for _, dueDate := range []time.Time{date(2000, time.October, 9), date(2018, time.December, 1), date(2019, time.February, 16)} {
+		factor, err := Factor(dueDate)
+		assert.NoError(t, err)
+		assert.Equal(t, dueDate, FactorDate(factor, dueDate))
+	}
+
+	factor, err := Factor(date(2000, time.October, 9))
+	assert.NoError(t, err)
+	assert.Equal(t, 1098, factor)
+}
+
+func date(year int, month time.Month, dayOfMonth int) time.Time {
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, calendar.Location())
+}
+
+func TestITF(t *testing.T) {
+	elements, err := ITF(bankBarcode)
+	assert.NoError(t, err)
+	assert.Len(t, elements, 4+BarcodeLength*5+3)
+	assert.Equal(t, []bool{false, false, false, false}, elements[:4])
+	assert.Equal(t, []bool{true, false, false}, elements[len(elements)-3:])
+
+	// the pair 00 interleaves narrow, narrow, wide, wide and narrow
+	assert.Equal(t, []bool{false, false, false, false, true, true, true, true, false, false}, elements[4:14])
+
+	wide := 0
+	for _, element := range elements {
+		if element {
+			wide++
+		}
+	}
+	assert.Equal(t, BarcodeLength*2+1, wide)
+
+	_, err = ITF(onlyDigits(bankDigitable))
+	assert.Equal(t, ErrInvalidCode, err)
+}
+
+func TestFormatDigitable(t *testing.T) {
+	assert.Equal(t, bankDigitable, FormatDigitable(onlyDigits(bankDigitable)))
+	assert.Equal(t, "83620000002-1 35510040318-5 23431917203-2 10018184169-1", FormatDigitable(collectionDigitable))
+	assert.Equal(t, "123", FormatDigitable("123"))
+}
@@ -0,0 +1,187 @@
+package boleto
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+)
+
+var (
+	// ErrPaymentClosed ...
+	ErrPaymentClosed = errors.New("boleto can not be paid after its close payment date")
+	// ErrInvalidCharge ...
+	ErrInvalidCharge = errors.New("invalid charge type")
+)
+
+// ChargeType ...
+type ChargeType string
+
+const (
+	// FixedAmount charges a value in centavos, by day at the interest
+	FixedAmount ChargeType = "FixedAmount"
+	// Percent charges a rate of the amount, by month at the interest
+	Percent ChargeType = "Percent"
+)
+
+// daysInMonth is the commercial month of the monthly interest rates
+const daysInMonth = 30
+
+// PercentScale is the value of a rate of 1%, the rates are kept in millionths of percent
+const PercentScale int64 = 1000000
+
+// Charge is a fine, an interest or a discount of the boleto. The value is in centavos
+// to the fixed amounts and in millionths of percent to the rates, 2500000 is 2.5%.
+// Date is the first day of the fine and the interest and the last day of the discount.
+type Charge struct {
+	Type  ChargeType
+	Date  time.Time
+	Value int64
+}
+
+// Terms are the amount and the charges of a boleto. The dates are taken as days,
+// without converting their time zone, and the due date on a holiday or a weekend
+// is moved to the next business day of the calendar, the national one by default.
+type Terms struct {
+	Amount       int64
+	DueDate      time.Time
+	ClosePayment time.Time
+	Fine         *Charge
+	Interest     *Charge
+	Discount     *Charge
+	Calendar     calendar.Calendar
+}
+
+// Charges are the amounts in centavos charged over the amount of the boleto
+type Charges struct {
+	Fine     int64
+	Interest int64
+	Discount int64
+}
+
+// Due is the amount due of a boleto at a payment date
+type Due struct {
+	Charges
+	PaymentDate time.Time
+	Amount      int64
+	Total       int64
+}
+
+// ChargesMismatchError is returned when the charges calculated by Bankly differ from the local ones
+type ChargesMismatchError struct {
+	Local  Charges
+	Remote Charges
+}
+
+func (e *ChargesMismatchError) Error() string {
+	return fmt.Sprintf("charges mismatch: local fine %d, interest %d and discount %d, remote fine %d, interest %d and discount %d",
+		e.Local.Fine, e.Local.Interest, e.Local.Discount, e.Remote.Fine, e.Remote.Interest, e.Remote.Discount)
+}
+
+// AmountDue applies the fine, the interest and the discount of the terms at the payment date.
+// The fine and the interest are charged after the due date from their dates, the interest
+// for each day up to the payment, and the discount is granted until its date.
+func AmountDue(terms Terms, paymentDate time.Time) (*Due, error) {
+	paidAt := day(paymentDate)
+
+	if !terms.ClosePayment.IsZero() && paidAt.After(day(terms.ClosePayment)) {
+		return nil, ErrPaymentClosed
+	}
+
+	cal := terms.Calendar
+	if cal == nil {
+		cal = calendar.NewNational()
+	}
+
+	dueDate := day(terms.DueDate)
+	overdue := !dueDate.IsZero() && paidAt.After(calendar.NextBusinessDay(cal, dueDate))
+	late := dueDate.AddDate(0, 0, 1)
+
+	due := &Due{PaymentDate: paidAt, Amount: terms.Amount}
+
+	if fine := terms.Fine; fine != nil && overdue && !paidAt.Before(chargeDay(fine, late)) {
+		switch fine.Type {
+		case FixedAmount:
+			due.Fine = fine.Value
+		case Percent:
+			due.Fine = rate(terms.Amount, fine.Value, 1)
+		default:
+			return nil, ErrInvalidCharge
+		}
+	}
+
+	if interest := terms.Interest; interest != nil && overdue {
+		start := chargeDay(interest, late)
+		if !paidAt.Before(start) {
+			days := int64(paidAt.Sub(start).Hours()/24+0.5) + 1
+
+			switch interest.Type {
+			case FixedAmount:
+				due.Interest = interest.Value * days
+			case Percent:
+				due.Interest = rate(terms.Amount*days, interest.Value, daysInMonth)
+			default:
+				return nil, ErrInvalidCharge
+			}
+		}
+	}
+
+	if discount := terms.Discount; discount != nil && !paidAt.After(chargeDay(discount, dueDate)) {
+		switch discount.Type {
+		case FixedAmount:
+			due.Discount = discount.Value
+		case Percent:
+			due.Discount = rate(terms.Amount, discount.Value, 1)
+		default:
+			return nil, ErrInvalidCharge
+		}
+		if due.Discount > terms.Amount {
+			due.Discount = terms.Amount
+		}
+	}
+
+	due.Total = terms.Amount + due.Fine + due.Interest - due.Discount
+
+	return due, nil
+}
+
+// Check compares the charges calculated by Bankly, accepting a centavo of rounding in each of them.
+func (d *Due) Check(remote Charges) error {
+	if differ(d.Fine, remote.Fine) || differ(d.Interest, remote.Interest) || differ(d.Discount, remote.Discount) {
+		return &ChargesMismatchError{Local: d.Charges, Remote: remote}
+	}
+	return nil
+}
+
+func differ(local, remote int64) bool {
+	difference := local - remote
+	return difference > 1 || difference < -1
+}
+
+// rate applies the millionths of percent to the amount divided by the divisor, rounding half up
+func rate(amount, millionths, divisor int64) int64 {
+	denominator := big.NewInt(100 * PercentScale * divisor)
+
+	value := big.NewInt(amount)
+	value.Mul(value, big.NewInt(millionths))
+	value.Add(value, new(big.Int).Quo(denominator, big.NewInt(2)))
+	return value.Quo(value, denominator).Int64()
+}
+
+// chargeDay returns the day of the charge or, without it, the default one
+func chargeDay(charge *Charge, otherwise time.Time) time.Time {
+	if charge.Date.IsZero() {
+		return otherwise
+	}
+	return day(charge.Date)
+}
+
+// day keeps the year, month and day of the date at the time zone of the calendar
+func day(date time.Time) time.Time {
+	if date.IsZero() {
+		return date
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, calendar.Location())
+}
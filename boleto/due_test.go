@@ -0,0 +1,93 @@
+package boleto
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildTerms() Terms {
+	dueDate := date(2026, time.November, 10)
+
+	return Terms{
+		Amount:       100000,
+		DueDate:      dueDate,
+		ClosePayment: dueDate.AddDate(0, 0, 30),
+		Fine:         &Charge{Type: Percent, Date: dueDate.AddDate(0, 0, 1), Value: 2 * PercentScale},
+		Interest:     &Charge{Type: Percent, Date: dueDate.AddDate(0, 0, 1), Value: PercentScale},
+		Discount:     &Charge{Type: FixedAmount, Date: dueDate.AddDate(0, 0, -5), Value: 5000},
+	}
+}
+
+func TestAmountDue(t *testing.T) {
+	terms := buildTerms()
+
+	due, err := AmountDue(terms, date(2026, time.November, 5))
+	assert.NoError(t, err)
+	assert.Equal(t, Charges{Discount: 5000}, due.Charges)
+	assert.Equal(t, int64(95000), due.Total)
+
+	due, err = AmountDue(terms, date(2026, time.November, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, Charges{}, due.Charges)
+	assert.Equal(t, int64(100000), due.Total)
+
+	// 2% of fine and 1% a month for 15 days, from 11/11 to 25/11
+	due, err = AmountDue(terms, date(2026, time.November, 25))
+	assert.NoError(t, err)
+	assert.Equal(t, Charges{Fine: 2000, Interest: 500}, due.Charges)
+	assert.Equal(t, int64(102500), due.Total)
+
+	terms.Interest = &Charge{Type: FixedAmount, Value: 33}
+	terms.Fine = &Charge{Type: FixedAmount, Value: 1000}
+	due, err = AmountDue(terms, date(2026, time.November, 13))
+	assert.NoError(t, err)
+	assert.Equal(t, Charges{Fine: 1000, Interest: 99}, due.Charges)
+
+	_, err = AmountDue(terms, date(2026, time.December, 11))
+	assert.Equal(t, ErrPaymentClosed, err)
+
+	terms.Fine.Type = "Unknown"
+	_, err = AmountDue(terms, date(2026, time.November, 13))
+	assert.Equal(t, ErrInvalidCharge, err)
+}
+
+func TestAmountDue_FractionalRate(t *testing.T) {
+	terms := buildTerms()
+	terms.Fine = nil
+	// 0.033% a month for 30 days, from 11/11 to 10/12
+	terms.Interest = &Charge{Type: Percent, Value: 33000}
+	terms.ClosePayment = time.Time{}
+
+	due, err := AmountDue(terms, date(2026, time.December, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, Charges{Interest: 33}, due.Charges)
+	assert.NoError(t, due.Check(Charges{Interest: 33}))
+}
+
+func TestAmountDue_Holiday(t *testing.T) {
+	terms := buildTerms()
+	// 15/11/2026 is a holiday on a sunday, moving the payment without charges to monday
+	terms.DueDate = date(2026, time.November, 15)
+	terms.Fine.Date, terms.Interest.Date = date(2026, time.November, 16), date(2026, time.November, 16)
+
+	due, err := AmountDue(terms, time.Date(2026, time.November, 16, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, Charges{}, due.Charges)
+
+	due, err = AmountDue(terms, date(2026, time.November, 17))
+	assert.NoError(t, err)
+	assert.Equal(t, Charges{Fine: 2000, Interest: 67}, due.Charges)
+}
+
+func TestDue_Check(t *testing.T) {
+	due, err := AmountDue(buildTerms(), date(2026, time.November, 25))
+	assert.NoError(t, err)
+
+	assert.NoError(t, due.Check(Charges{Fine: 2000, Interest: 501}))
+	assert.Equal(t, &ChargesMismatchError{
+		Local:  Charges{Fine: 2000, Interest: 500},
+		Remote: Charges{Fine: 2000, Interest: 600},
+	}, due.Check(Charges{Fine: 2000, Interest: 600}))
+}
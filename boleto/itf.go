@@ -0,0 +1,39 @@
+package boleto
+
+// itfDigits has the widths of the five elements of each digit, true for the wide ones
+var itfDigits = [10][5]bool{
+	{false, false, true, true, false},
+	{true, false, false, false, true},
+	{false, true, false, false, true},
+	{true, true, false, false, false},
+	{false, false, true, false, true},
+	{true, false, true, false, false},
+	{false, true, true, false, false},
+	{false, false, false, true, true},
+	{true, false, false, true, false},
+	{false, true, false, true, false},
+}
+
+// ITF encodes the barcode as the interleaved 2 of 5 of the FEBRABAN boletos. It returns
+// the widths of the elements, alternating bars and spaces from a bar, true for the wide
+// ones: the start, the pairs of digits, the first at the bars and the second at the
+// spaces, and the stop.
+func ITF(barcode string) ([]bool, error) {
+	if len(barcode) != BarcodeLength || !isDigits(barcode) {
+		return nil, ErrInvalidCode
+	}
+
+	// start with four narrow elements
+	elements := []bool{false, false, false, false}
+
+	for i := 0; i < len(barcode); i += 2 {
+		bars := itfDigits[barcode[i]-'0']
+		spaces := itfDigits[barcode[i+1]-'0']
+		for j := 0; j < 5; j++ {
+			elements = append(elements, bars[j], spaces[j])
+		}
+	}
+
+	// stop with a wide bar, a narrow space and a narrow bar
+	return append(elements, true, false, false), nil
+}
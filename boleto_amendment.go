@@ -0,0 +1,429 @@
+package bankly
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/contbank/grok"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// BoletoAmendmentType ...
+type BoletoAmendmentType string
+
+const (
+	// BoletoAmendDueDate ...
+	BoletoAmendDueDate BoletoAmendmentType = "DUE_DATE"
+	// BoletoAmendAmount ...
+	BoletoAmendAmount BoletoAmendmentType = "AMOUNT"
+	// BoletoAmendFine ...
+	BoletoAmendFine BoletoAmendmentType = "FINE"
+	// BoletoAmendInterest ...
+	BoletoAmendInterest BoletoAmendmentType = "INTEREST"
+	// BoletoAmendDiscount ...
+	BoletoAmendDiscount BoletoAmendmentType = "DISCOUNT"
+	// BoletoAmendWriteOff is the instruction to write off the boleto, a cancel at Bankly
+	BoletoAmendWriteOff BoletoAmendmentType = "WRITE_OFF"
+	// BoletoAmendProtest is the instruction to protest the boleto, not supported by Bankly
+	BoletoAmendProtest BoletoAmendmentType = "PROTEST"
+)
+
+// BoletoAmendmentStatus ...
+type BoletoAmendmentStatus string
+
+const (
+	// BoletoAmendmentApplied ...
+	BoletoAmendmentApplied BoletoAmendmentStatus = "APPLIED"
+	// BoletoAmendmentFailed is an amendment refused by Bankly or not sent by an error
+	BoletoAmendmentFailed BoletoAmendmentStatus = "FAILED"
+	// BoletoAmendmentRejected is an amendment not supported, never sent to Bankly
+	BoletoAmendmentRejected BoletoAmendmentStatus = "REJECTED"
+)
+
+// AmendBoletoRequest changes a registered boleto, only the fields set are changed.
+// Author and Reason are kept at the history of the amendments, not sent to Bankly.
+type AmendBoletoRequest struct {
+	APIVersion         *string          `validate:"required" json:"api_version,omitempty"`
+	AuthenticationCode string           `validate:"required" json:"authenticationCode,omitempty"`
+	Account            *Account         `validate:"required" json:"account,omitempty"`
+	DueDate            *time.Time       `json:"dueDate,omitempty"`
+	Amount             *float64         `validate:"omitempty,gt=0" json:"amount,omitempty"`
+	Fine               *BoletoFine      `json:"fine,omitempty"`
+	Interest           *BoletoInterest  `json:"interest,omitempty"`
+	Discount           *BoletoDiscounts `json:"discount,omitempty"`
+	Discounts          *BoletoDiscounts `json:"discounts,omitempty"` // deprecated (api-version 1.0)
+	Author             string           `json:"-"`
+	Reason             string           `json:"-"`
+}
+
+// BoletoInstructionRequest sends a write off or a protest instruction to a registered boleto
+type BoletoInstructionRequest struct {
+	APIVersion         *string             `validate:"required" json:"api_version,omitempty"`
+	AuthenticationCode string              `validate:"required" json:"authenticationCode,omitempty"`
+	Account            *Account            `validate:"required" json:"account,omitempty"`
+	Instruction        BoletoAmendmentType `validate:"required,oneof=WRITE_OFF PROTEST" json:"instruction,omitempty"`
+	Author             string              `json:"-"`
+	Reason             string              `json:"-"`
+}
+
+// BoletoAmendmentValues are the values of the amended fields of a boleto
+type BoletoAmendmentValues struct {
+	DueDate  *time.Time       `bson:"dueDate,omitempty" json:"dueDate,omitempty"`
+	Amount   *float64         `bson:"amount,omitempty" json:"amount,omitempty"`
+	Fine     *BoletoFine      `bson:"fine,omitempty" json:"fine,omitempty"`
+	Interest *BoletoInterest  `bson:"interest,omitempty" json:"interest,omitempty"`
+	Discount *BoletoDiscounts `bson:"discount,omitempty" json:"discount,omitempty"`
+}
+
+// BoletoAmendment is an entry of the history of amendments of a boleto, with the
+// values before and after it
+type BoletoAmendment struct {
+	ID                 string                 `bson:"_id" json:"id"`
+	AuthenticationCode string                 `bson:"authenticationCode" json:"authenticationCode"`
+	Branch             string                 `bson:"branch" json:"branch"`
+	AccountNumber      string                 `bson:"accountNumber" json:"accountNumber"`
+	Types              []BoletoAmendmentType  `bson:"types" json:"types"`
+	Previous           *BoletoAmendmentValues `bson:"previous,omitempty" json:"previous,omitempty"`
+	Requested          BoletoAmendmentValues  `bson:"requested" json:"requested"`
+	APIVersion         string                 `bson:"apiVersion" json:"apiVersion"`
+	Author             string                 `bson:"author,omitempty" json:"author,omitempty"`
+	Reason             string                 `bson:"reason,omitempty" json:"reason,omitempty"`
+	Status             BoletoAmendmentStatus  `bson:"status" json:"status"`
+	Error              string                 `bson:"error,omitempty" json:"error,omitempty"`
+	CreatedAt          time.Time              `bson:"createdAt" json:"createdAt"`
+}
+
+// amendBoletoBody is the body of the amendment sent to Bankly, with the discount
+// at the field of the api version
+type amendBoletoBody struct {
+	DueDate   *time.Time       `json:"dueDate,omitempty"`
+	Amount    *float64         `json:"amount,omitempty"`
+	Fine      *BoletoFine      `json:"fine,omitempty"`
+	Interest  *BoletoInterest  `json:"interest,omitempty"`
+	Discount  *BoletoDiscounts `json:"discount,omitempty"`
+	Discounts *BoletoDiscounts `json:"discounts,omitempty"`
+}
+
+// SetAmendmentStore keeps the history of the amendments at the store, in memory by default.
+func (b *Boletos) SetAmendmentStore(store BoletoAmendmentStore) {
+	b.amendments = store
+}
+
+// EnableAmendments allows AmendBankslip to PATCH the registered boletos. The route is not at
+// the public docs of Bankly, it must be confirmed with Bankly before enabling it. Without it,
+// AmendBankslip keeps the amendment as rejected and returns ErrBoletoAmendmentNotSupported.
+func (b *Boletos) EnableAmendments() {
+	b.amendable = true
+}
+
+// ListAmendments returns the history of amendments of the boleto, oldest first.
+func (b *Boletos) ListAmendments(ctx context.Context, authenticationCode string) ([]*BoletoAmendment, error) {
+	return b.amendments.ListByAuthenticationCode(ctx, authenticationCode)
+}
+
+// AmendBankslip changes the due date, the amount, the fine, the interest or the discount of a
+// registered boleto. The previous values are read from Bankly and the amendment is kept at the
+// history, applied or failed. It is refused with ErrBoletoAmendmentNotSupported until
+// EnableAmendments is called.
+func (b *Boletos) AmendBankslip(ctx context.Context, model *AmendBoletoRequest) (*BoletoAmendment, error) {
+
+	// api version
+	if model.APIVersion == nil {
+		model.APIVersion = aws.String(b.session.APIVersion)
+	}
+
+	fields := logrus.Fields{
+		"request_id":  GetRequestID(ctx),
+		"api_version": model.APIVersion,
+		"object":      model,
+	}
+
+	// validator
+	if err := grok.Validator.Struct(model); err != nil {
+		return nil, grok.FromValidationErros(err)
+	}
+
+	discount := model.Discount
+	if discount == nil {
+		discount = model.Discounts
+	}
+
+	amendment := &BoletoAmendment{
+		ID:                 uuid.New().String(),
+		AuthenticationCode: model.AuthenticationCode,
+		Branch:             model.Account.Branch,
+		AccountNumber:      model.Account.Number,
+		Types:              []BoletoAmendmentType{},
+		Requested: BoletoAmendmentValues{
+			DueDate:  model.DueDate,
+			Amount:   model.Amount,
+			Fine:     model.Fine,
+			Interest: model.Interest,
+			Discount: discount,
+		},
+		APIVersion: *model.APIVersion,
+		Author:     model.Author,
+		Reason:     model.Reason,
+		CreatedAt:  time.Now(),
+	}
+
+	for _, change := range []struct {
+		set        bool
+		changeType BoletoAmendmentType
+	}{
+		{model.DueDate != nil, BoletoAmendDueDate},
+		{model.Amount != nil, BoletoAmendAmount},
+		{model.Fine != nil, BoletoAmendFine},
+		{model.Interest != nil, BoletoAmendInterest},
+		{discount != nil, BoletoAmendDiscount},
+	} {
+		if change.set {
+			amendment.Types = append(amendment.Types, change.changeType)
+		}
+	}
+
+	if len(amendment.Types) == 0 {
+		return nil, ErrBoletoAmendmentEmpty
+	}
+
+	if !b.amendable {
+		amendment.Status = BoletoAmendmentRejected
+		amendment.Error = ErrBoletoAmendmentNotSupported.Error()
+		if err := b.amendments.Create(ctx, amendment); err != nil {
+			return nil, err
+		}
+		return nil, ErrBoletoAmendmentNotSupported
+	}
+
+	current, err := b.FindBankslip(ctx, &FindBoletoRequest{
+		APIVersion:         model.APIVersion,
+		AuthenticationCode: model.AuthenticationCode,
+		Account:            model.Account,
+	})
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error finding boleto to amend")
+		return nil, b.recordAmendment(ctx, amendment, err)
+	}
+	amendment.Previous = amendmentValues(current)
+
+	body := amendBoletoBody{
+		DueDate:  model.DueDate,
+		Amount:   model.Amount,
+		Fine:     model.Fine,
+		Interest: model.Interest,
+	}
+
+	// discount
+	if *model.APIVersion == "1.0" {
+		body.Discounts = discount // api-version 1.0
+	} else {
+		body.Discount = discount // api-version 2.0
+	}
+
+	if err := b.patchBankslip(ctx, model.APIVersion, model.Account, model.AuthenticationCode, body, fields); err != nil {
+		return nil, b.recordAmendment(ctx, amendment, err)
+	}
+
+	if err := b.recordAmendment(ctx, amendment, nil); err != nil {
+		return amendment, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("amendment_id", amendment.ID).Info("boleto amended")
+
+	return amendment, nil
+}
+
+// InstructBankslip sends a write off, as a cancel of the boleto, or a protest, refused with
+// ErrBoletoProtestNotSupported as Bankly does not protest boletos. Both are kept at the history.
+func (b *Boletos) InstructBankslip(ctx context.Context, model *BoletoInstructionRequest) (*BoletoAmendment, error) {
+
+	// api version
+	if model.APIVersion == nil {
+		model.APIVersion = aws.String(b.session.APIVersion)
+	}
+
+	// validator
+	if err := grok.Validator.Struct(model); err != nil {
+		return nil, grok.FromValidationErros(err)
+	}
+
+	amendment := &BoletoAmendment{
+		ID:                 uuid.New().String(),
+		AuthenticationCode: model.AuthenticationCode,
+		Branch:             model.Account.Branch,
+		AccountNumber:      model.Account.Number,
+		Types:              []BoletoAmendmentType{model.Instruction},
+		APIVersion:         *model.APIVersion,
+		Author:             model.Author,
+		Reason:             model.Reason,
+		CreatedAt:          time.Now(),
+	}
+
+	if model.Instruction == BoletoAmendProtest {
+		amendment.Status = BoletoAmendmentRejected
+		amendment.Error = ErrBoletoProtestNotSupported.Error()
+		if err := b.amendments.Create(ctx, amendment); err != nil {
+			return nil, err
+		}
+		return nil, ErrBoletoProtestNotSupported
+	}
+
+	err := b.CancelBankslip(ctx, &CancelBoletoRequest{
+		APIVersion:         model.APIVersion,
+		AuthenticationCode: model.AuthenticationCode,
+		Account:            model.Account,
+	})
+	if err != nil {
+		return nil, b.recordAmendment(ctx, amendment, err)
+	}
+
+	if err := b.recordAmendment(ctx, amendment, nil); err != nil {
+		return amendment, err
+	}
+
+	return amendment, nil
+}
+
+// recordAmendment saves the amendment with the status of its error, returning the error
+// or, when the amendment is applied, the error of the store
+func (b *Boletos) recordAmendment(ctx context.Context, amendment *BoletoAmendment, err error) error {
+	amendment.Status = BoletoAmendmentApplied
+	if err != nil {
+		amendment.Status = BoletoAmendmentFailed
+		amendment.Error = err.Error()
+	}
+
+	if storeErr := b.amendments.Create(ctx, amendment); storeErr != nil {
+		logrus.WithField("amendment_id", amendment.ID).
+			WithError(storeErr).Error("error saving boleto amendment history")
+		if err == nil {
+			return storeErr
+		}
+	}
+
+	return err
+}
+
+func amendmentValues(response *BoletoDetailedResponse) *BoletoAmendmentValues {
+	values := &BoletoAmendmentValues{
+		Fine:     response.Fine,
+		Interest: response.Interest,
+		Discount: response.Discount,
+	}
+
+	// api-version 1.0
+	if values.Discount == nil {
+		values.Discount = response.Discounts
+	}
+
+	if !response.DueDate.IsZero() {
+		dueDate := response.DueDate
+		values.DueDate = &dueDate
+	}
+
+	if response.Amount != nil {
+		amount := response.Amount.Value
+		values.Amount = &amount
+	}
+
+	return values
+}
+
+// patchBankslip sends the amendment to bankslip/branch/{branch}/number/{number}/{authenticationCode}.
+// The PATCH of a registered boleto is not at the public docs of Bankly, the path follows
+// the one of the boleto read by GET. The boleto was just found, so a 404 is a missing route.
+func (b *Boletos) patchBankslip(ctx context.Context, apiVersion *string, account *Account, authenticationCode string,
+	body amendBoletoBody, fields logrus.Fields) error {
+
+	u, err := url.Parse(b.session.APIEndpoint)
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error parsing api endpoint")
+		return err
+	}
+
+	u.Path = path.Join(u.Path, BoletosPath)
+	u.Path = path.Join(u.Path, "branch")
+	u.Path = path.Join(u.Path, account.Branch)
+	u.Path = path.Join(u.Path, "number")
+	u.Path = path.Join(u.Path, account.Number)
+	u.Path = path.Join(u.Path, authenticationCode)
+	endpoint := u.String()
+
+	reqbyte, err := json.Marshal(body)
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error encoding model to json")
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", endpoint, bytes.NewReader(reqbyte))
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error creating request")
+		return err
+	}
+
+	token, err := b.authentication.Token(ctx)
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error in authentication request")
+		return err
+	}
+
+	req.Header.Add("Authorization", token)
+	req.Header.Add("Content-type", "application/json")
+	req.Header.Add("api-version", *apiVersion)
+	req.Header.Add("x-correlation-id", GetRequestID(ctx))
+
+	// call bankly
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error performing the request")
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		logrus.WithFields(fields).Error("boleto amendment route not found")
+		return ErrBoletoAmendmentRouteNotFound
+	}
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	var bodyErr []*ErrorResponse
+
+	if err := json.Unmarshal(respBody, &bodyErr); err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error decoding json response")
+		return ErrDefaultBoletos
+	}
+
+	if len(bodyErr) > 0 {
+		errModel := bodyErr[0]
+		if err := FindError(errModel.Code, errModel.Message); err != nil {
+			logrus.WithField("bankly_error", bodyErr).WithFields(fields).
+				WithError(err).Error("bankly amend boleto error")
+			return err
+		}
+	}
+
+	logrus.WithFields(fields).Error("error default - amend boletos")
+
+	return ErrDefaultBoletos
+}
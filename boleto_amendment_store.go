@@ -0,0 +1,87 @@
+package bankly
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BoletoAmendmentStore keeps the history of the amendments of the boletos for audit
+type BoletoAmendmentStore interface {
+	Create(ctx context.Context, amendment *BoletoAmendment) error
+	// ListByAuthenticationCode returns the amendments of a boleto, oldest first
+	ListByAuthenticationCode(ctx context.Context, authenticationCode string) ([]*BoletoAmendment, error)
+}
+
+// memoryBoletoAmendmentStore ...
+type memoryBoletoAmendmentStore struct {
+	mutex      sync.Mutex
+	amendments []*BoletoAmendment
+}
+
+// NewMemoryBoletoAmendmentStore returns a store for a single instance or tests, the history is lost on restarts.
+func NewMemoryBoletoAmendmentStore() BoletoAmendmentStore {
+	return &memoryBoletoAmendmentStore{amendments: []*BoletoAmendment{}}
+}
+
+func (m *memoryBoletoAmendmentStore) Create(ctx context.Context, amendment *BoletoAmendment) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := *amendment
+	response.Types = append([]BoletoAmendmentType{}, amendment.Types...)
+	m.amendments = append(m.amendments, &response)
+	return nil
+}
+
+func (m *memoryBoletoAmendmentStore) ListByAuthenticationCode(ctx context.Context, authenticationCode string) ([]*BoletoAmendment, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := []*BoletoAmendment{}
+	for _, amendment := range m.amendments {
+		if amendment.AuthenticationCode == authenticationCode {
+			copied := *amendment
+			response = append(response, &copied)
+		}
+	}
+
+	sort.SliceStable(response, func(i, j int) bool { return response[i].CreatedAt.Before(response[j].CreatedAt) })
+	return response, nil
+}
+
+// mongoBoletoAmendmentStore ...
+type mongoBoletoAmendmentStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoBoletoAmendmentStore returns a store shared by many instances.
+// The collection should be indexed by authenticationCode and createdAt.
+func NewMongoBoletoAmendmentStore(collection *mongo.Collection) BoletoAmendmentStore {
+	return &mongoBoletoAmendmentStore{collection: collection}
+}
+
+func (m *mongoBoletoAmendmentStore) Create(ctx context.Context, amendment *BoletoAmendment) error {
+	_, err := m.collection.InsertOne(ctx, amendment)
+	return err
+}
+
+func (m *mongoBoletoAmendmentStore) ListByAuthenticationCode(ctx context.Context, authenticationCode string) ([]*BoletoAmendment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+
+	cursor, err := m.collection.Find(ctx, bson.M{"authenticationCode": authenticationCode}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []*BoletoAmendment{}
+	if err := cursor.All(ctx, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,202 @@
+package bankly_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BoletoAmendmentTestSuite struct {
+	suite.Suite
+	assert   *assert.Assertions
+	ctx      context.Context
+	boletos  *bankly.Boletos
+	account  *bankly.Account
+	requests []*http.Request
+	bodies   []map[string]interface{}
+	patch    *http.Response
+}
+
+func TestBoletoAmendmentTestSuite(t *testing.T) {
+	suite.Run(t, new(BoletoAmendmentTestSuite))
+}
+
+func (s *BoletoAmendmentTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.account = &bankly.Account{Branch: "0001", Number: "189162"}
+	s.requests = []*http.Request{}
+	s.bodies = []map[string]interface{}{}
+	s.patch = nil
+
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		s.requests = append(s.requests, req)
+
+		body := map[string]interface{}{}
+		if req.Body != nil {
+			json.NewDecoder(req.Body).Decode(&body)
+		}
+		s.bodies = append(s.bodies, body)
+
+		switch req.Method {
+		case http.MethodGet:
+			return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.BoletoDetailedResponse{
+				DueDate:   date(2026, time.November, 10),
+				Amount:    &bankly.BoletoAmount{Value: 1250.5},
+				Discounts: &bankly.BoletoDiscounts{LimitDate: date(2026, time.November, 1), Value: 2, Type: bankly.FixedAmountDiscountType},
+			})}
+		case http.MethodPatch:
+			if s.patch != nil {
+				return s.patch
+			}
+			return &http.Response{StatusCode: http.StatusAccepted, Body: jsonBody(nil)}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(nil)}
+	})
+
+	s.boletos = bankly.NewBoletos(httpClient, *session)
+	s.boletos.EnableAmendments()
+}
+
+func (s *BoletoAmendmentTestSuite) TestAmendBankslip() {
+	dueDate := date(2026, time.November, 20)
+	amount := 1300.0
+
+	ctx := context.WithValue(s.ctx, "Request-Id", "request-id")
+	amendment, err := s.boletos.AmendBankslip(ctx, &bankly.AmendBoletoRequest{
+		AuthenticationCode: "boleto-code",
+		Account:            s.account,
+		DueDate:            &dueDate,
+		Amount:             &amount,
+		Discount:           &bankly.BoletoDiscounts{LimitDate: dueDate, Value: 10, Type: bankly.FixedAmountDiscountType},
+		Author:             "operator@example.com",
+		Reason:             "renegotiated",
+	})
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.BoletoAmendmentApplied, amendment.Status)
+	s.assert.Equal([]bankly.BoletoAmendmentType{bankly.BoletoAmendDueDate, bankly.BoletoAmendAmount, bankly.BoletoAmendDiscount}, amendment.Types)
+	s.assert.Equal(1250.5, *amendment.Previous.Amount)
+	s.assert.True(date(2026, time.November, 10).Equal(*amendment.Previous.DueDate))
+	s.assert.Equal(2.0, amendment.Previous.Discount.Value)
+
+	s.assert.Len(s.requests, 2)
+	s.assert.Equal(http.MethodPatch, s.requests[1].Method)
+	s.assert.Equal("/bankslip/branch/0001/number/189162/boleto-code", s.requests[1].URL.Path)
+	s.assert.Equal("1.0", s.requests[1].Header.Get("api-version"))
+	s.assert.Equal(1300.0, s.bodies[1]["amount"])
+	s.assert.NotNil(s.bodies[1]["discounts"])
+	s.assert.Nil(s.bodies[1]["discount"])
+	s.assert.Equal("request-id", s.requests[1].Header.Get("x-correlation-id"))
+
+	history, err := s.boletos.ListAmendments(s.ctx, "boleto-code")
+	s.assert.NoError(err)
+	s.assert.Len(history, 1)
+	s.assert.Equal("operator@example.com", history[0].Author)
+	s.assert.Equal("renegotiated", history[0].Reason)
+}
+
+func (s *BoletoAmendmentTestSuite) TestAmendBankslip_APIVersion2() {
+	_, err := s.boletos.AmendBankslip(s.ctx, &bankly.AmendBoletoRequest{
+		APIVersion:         bankly.String("2.0"),
+		AuthenticationCode: "boleto-code",
+		Account:            s.account,
+		Discounts:          &bankly.BoletoDiscounts{LimitDate: date(2026, time.November, 5), Value: 5, Type: bankly.FixedPercentDiscountType},
+	})
+
+	s.assert.NoError(err)
+	s.assert.NotNil(s.bodies[1]["discount"])
+	s.assert.Nil(s.bodies[1]["discounts"])
+}
+
+func (s *BoletoAmendmentTestSuite) TestAmendBankslip_Errors() {
+	_, err := s.boletos.AmendBankslip(s.ctx, &bankly.AmendBoletoRequest{
+		AuthenticationCode: "boleto-code",
+		Account:            s.account,
+	})
+	s.assert.Equal(bankly.ErrBoletoAmendmentEmpty, err)
+	s.assert.Len(s.requests, 0)
+
+	s.patch = &http.Response{StatusCode: http.StatusBadRequest, Body: jsonBody([]bankly.ErrorResponse{{
+		CodeMessageErrorResponse: bankly.CodeMessageErrorResponse{Code: "INVALID_DUE_DATE", Message: "invalid due date"},
+	}})}
+
+	dueDate := date(2026, time.October, 1)
+	_, err = s.boletos.AmendBankslip(s.ctx, &bankly.AmendBoletoRequest{
+		AuthenticationCode: "boleto-code",
+		Account:            s.account,
+		DueDate:            &dueDate,
+	})
+	s.assert.Error(err)
+
+	history, _ := s.boletos.ListAmendments(s.ctx, "boleto-code")
+	s.assert.Len(history, 1)
+	s.assert.Equal(bankly.BoletoAmendmentFailed, history[0].Status)
+	s.assert.NotEmpty(history[0].Error)
+}
+
+func (s *BoletoAmendmentTestSuite) TestAmendBankslip_NotEnabled() {
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		s.requests = append(s.requests, req)
+		return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(nil)}
+	})
+	boletos := bankly.NewBoletos(httpClient, *session)
+
+	dueDate := date(2026, time.November, 20)
+	_, err := boletos.AmendBankslip(s.ctx, &bankly.AmendBoletoRequest{
+		AuthenticationCode: "boleto-code",
+		Account:            s.account,
+		DueDate:            &dueDate,
+	})
+	s.assert.Equal(bankly.ErrBoletoAmendmentNotSupported, err)
+	s.assert.Len(s.requests, 0)
+
+	history, _ := boletos.ListAmendments(s.ctx, "boleto-code")
+	s.assert.Len(history, 1)
+	s.assert.Equal(bankly.BoletoAmendmentRejected, history[0].Status)
+}
+
+func (s *BoletoAmendmentTestSuite) TestAmendBankslip_RouteNotFound() {
+	s.patch = &http.Response{StatusCode: http.StatusNotFound, Body: jsonBody(nil)}
+
+	dueDate := date(2026, time.November, 20)
+	_, err := s.boletos.AmendBankslip(s.ctx, &bankly.AmendBoletoRequest{
+		AuthenticationCode: "boleto-code",
+		Account:            s.account,
+		DueDate:            &dueDate,
+	})
+	s.assert.Equal(bankly.ErrBoletoAmendmentRouteNotFound, err)
+
+	history, _ := s.boletos.ListAmendments(s.ctx, "boleto-code")
+	s.assert.Len(history, 1)
+	s.assert.Equal(bankly.ErrBoletoAmendmentRouteNotFound.Error(), history[0].Error)
+}
+
+func (s *BoletoAmendmentTestSuite) TestInstructBankslip() {
+	amendment, err := s.boletos.InstructBankslip(s.ctx, &bankly.BoletoInstructionRequest{
+		AuthenticationCode: "boleto-code",
+		Account:            s.account,
+		Instruction:        bankly.BoletoAmendWriteOff,
+	})
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.BoletoAmendmentApplied, amendment.Status)
+	s.assert.Equal(http.MethodDelete, s.requests[0].Method)
+
+	_, err = s.boletos.InstructBankslip(s.ctx, &bankly.BoletoInstructionRequest{
+		AuthenticationCode: "boleto-code",
+		Account:            s.account,
+		Instruction:        bankly.BoletoAmendProtest,
+	})
+	s.assert.Equal(bankly.ErrBoletoProtestNotSupported, err)
+	s.assert.Len(s.requests, 1)
+
+	history, _ := s.boletos.ListAmendments(s.ctx, "boleto-code")
+	s.assert.Len(history, 2)
+	s.assert.Equal(bankly.BoletoAmendmentRejected, history[1].Status)
+}
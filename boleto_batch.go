@@ -0,0 +1,653 @@
+package bankly
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/contbank/grok"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBoletoBatchConcurrency ...
+const DefaultBoletoBatchConcurrency = 5
+
+const (
+	boletoPayerNameMaxLength      = 50
+	boletoPayerTradeNameMaxLength = 80
+)
+
+// BoletoBatchFormat ...
+type BoletoBatchFormat string
+
+const (
+	// BoletoBatchCSV has a header line with the json fields of the BoletoRequest,
+	// nested fields joined by dots as payer.address.zipCode
+	BoletoBatchCSV BoletoBatchFormat = "CSV"
+	// BoletoBatchJSONLines has a BoletoRequest json at each line
+	BoletoBatchJSONLines BoletoBatchFormat = "JSONL"
+)
+
+// BoletoBatchItemStatus ...
+type BoletoBatchItemStatus string
+
+const (
+	// BoletoBatchCreated the boleto was accepted by Bankly
+	BoletoBatchCreated BoletoBatchItemStatus = "CREATED"
+	// BoletoBatchInvalid the row was refused by the validation before any boleto was issued
+	BoletoBatchInvalid BoletoBatchItemStatus = "INVALID"
+	// BoletoBatchRejected the boleto was refused by Bankly and may be fixed and sent again
+	BoletoBatchRejected BoletoBatchItemStatus = "REJECTED"
+	// BoletoBatchUnknown the outcome is not known and must be checked by the alias
+	BoletoBatchUnknown BoletoBatchItemStatus = "UNKNOWN"
+	// BoletoBatchSkipped the boleto was not sent because of invalid rows or a canceled context
+	BoletoBatchSkipped BoletoBatchItemStatus = "SKIPPED"
+)
+
+// BoletoBatchRowError is a row of the input refused by the validation
+type BoletoBatchRowError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e *BoletoBatchRowError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s %s", e.Line, e.Field, e.Message)
+}
+
+// BoletoBatchOptions ...
+type BoletoBatchOptions struct {
+	Format BoletoBatchFormat
+	// Comma of the CSV, zero uses ','
+	Comma rune
+	// Account of the rows without one
+	Account *Account
+	// APIVersion of the rows without one
+	APIVersion *string
+	// Concurrency of the boletos, zero uses DefaultBoletoBatchConcurrency
+	Concurrency int
+	// SkipInvalid issues the valid rows when there are invalid ones, by default nothing is issued
+	SkipInvalid bool
+}
+
+// BoletoBatchResult ...
+type BoletoBatchResult struct {
+	// Line of the row at the input, a CSV field with line breaks counts as one line
+	Line               int
+	Request            *BoletoRequest
+	Status             BoletoBatchItemStatus
+	AuthenticationCode string
+	Error              error
+	// DownloadError is the error downloading the pdf of a created boleto at DownloadBatch
+	DownloadError error
+}
+
+// BoletoBatchReport has the results in the same order of the rows
+type BoletoBatchReport struct {
+	Results  []*BoletoBatchResult
+	Created  int
+	Invalid  int
+	Rejected int
+	Unknown  int
+	Skipped  int
+}
+
+// CreateBatch reads the boletos from the reader, validates all of them and creates
+// them with bounded concurrency. A row refused by the validation stops the batch
+// before any boleto is sent, unless SkipInvalid is set. The returned error is kept
+// for inputs that can't be read, the errors of the rows are at the results.
+func (b *Boletos) CreateBatch(ctx context.Context, reader io.Reader, options BoletoBatchOptions) (*BoletoBatchReport, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"format":     options.Format,
+	}
+
+	results, err := readBoletoBatch(reader, options)
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error reading boleto batch")
+		return nil, err
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultBoletoBatchConcurrency
+	}
+
+	if options.APIVersion == nil {
+		options.APIVersion = String(b.session.APIVersion)
+	}
+
+	validateBoletoBatch(results, options)
+
+	report := &BoletoBatchReport{Results: results}
+
+	pending := []int{}
+	invalid := false
+	for i, result := range results {
+		if result.Status == BoletoBatchInvalid {
+			invalid = true
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	if invalid && !options.SkipInvalid {
+		pending = []int{}
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+
+	for w := 0; w < options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// the row stays skipped when the context was canceled while it was queued
+				if ctx.Err() != nil {
+					continue
+				}
+
+				result := results[i]
+				response, err := b.CreateBankslip(ctx, result.Request)
+
+				result.Error = err
+				result.Status = boletoBatchStatus(err)
+				if response != nil {
+					result.AuthenticationCode = response.AuthenticationCode
+				}
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case BoletoBatchCreated:
+			report.Created++
+		case BoletoBatchInvalid:
+			report.Invalid++
+		case BoletoBatchRejected:
+			report.Rejected++
+		case BoletoBatchUnknown:
+			report.Unknown++
+		case BoletoBatchSkipped:
+			report.Skipped++
+		}
+	}
+
+	logrus.WithFields(fields).
+		WithField("created", report.Created).
+		WithField("invalid", report.Invalid).
+		WithField("rejected", report.Rejected).
+		WithField("unknown", report.Unknown).
+		WithField("skipped", report.Skipped).
+		Info("boleto batch finished")
+
+	return report, nil
+}
+
+// DownloadBatch writes a zip with the pdfs of the boletos created by the batch, named
+// by the line and the alias or the authentication code. A pdf not downloaded is left
+// out of the zip with its error at the result.
+func (b *Boletos) DownloadBatch(ctx context.Context, report *BoletoBatchReport, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	for _, result := range report.Results {
+		if result.Status != BoletoBatchCreated {
+			continue
+		}
+
+		var pdf bytes.Buffer
+		result.DownloadError = b.DownloadBankslip(ctx, result.AuthenticationCode, result.Request.APIVersion, &pdf)
+		if result.DownloadError != nil {
+			logrus.WithField("request_id", GetRequestID(ctx)).
+				WithField("authentication_code", result.AuthenticationCode).
+				WithError(result.DownloadError).Error("error downloading boleto of the batch")
+			continue
+		}
+
+		name := result.AuthenticationCode
+		if result.Request.Alias != nil && *result.Request.Alias != "" {
+			name = *result.Request.Alias
+		}
+		name = strings.NewReplacer("/", "-", "\\", "-").Replace(name)
+
+		file, err := archive.Create(fmt.Sprintf("%04d-%s.pdf", result.Line, name))
+		if err != nil {
+			return err
+		}
+
+		if _, err := pdf.WriteTo(file); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// boletoBatchStatus tells apart the errors answered by Bankly from the ones
+// where the boleto may have been created.
+func boletoBatchStatus(err error) BoletoBatchItemStatus {
+	if err == nil {
+		return BoletoBatchCreated
+	}
+
+	// the errors answered by Bankly are mapped by FindError
+	if banklyErr, ok := ParseErr(err); ok && banklyErr.GrokError != nil &&
+		banklyErr.GrokError.Code < http.StatusInternalServerError {
+		return BoletoBatchRejected
+	}
+
+	var grokErr *grok.Error
+	if errors.As(err, &grokErr) && err != ErrDefaultBoletos && grokErr.Code < http.StatusInternalServerError {
+		return BoletoBatchRejected
+	}
+
+	return BoletoBatchUnknown
+}
+
+func readBoletoBatch(reader io.Reader, options BoletoBatchOptions) ([]*BoletoBatchResult, error) {
+	switch options.Format {
+	case BoletoBatchCSV:
+		return readBoletoBatchCSV(reader, options.Comma)
+	case BoletoBatchJSONLines:
+		return readBoletoBatchJSONLines(reader)
+	}
+	return nil, ErrInvalidBoletoBatch
+}
+
+func readBoletoBatchJSONLines(reader io.Reader) ([]*BoletoBatchResult, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	results := []*BoletoBatchResult{}
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		result := &BoletoBatchResult{Line: line, Request: &BoletoRequest{}, Status: BoletoBatchSkipped}
+		if err := json.Unmarshal([]byte(text), result.Request); err != nil {
+			result.Status = BoletoBatchInvalid
+			result.Error = &BoletoBatchRowError{Line: line, Message: "invalid json"}
+		}
+		results = append(results, result)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, ErrInvalidBoletoBatch
+	}
+
+	return results, nil
+}
+
+func readBoletoBatchCSV(reader io.Reader, comma rune) ([]*BoletoBatchResult, error) {
+	records := csv.NewReader(reader)
+	if comma != 0 {
+		records.Comma = comma
+	}
+	records.FieldsPerRecord = -1
+
+	header, err := records.Read()
+	if err != nil {
+		return nil, ErrInvalidBoletoBatch
+	}
+
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		_, ok := boletoBatchColumns[name]
+		if _, value := boletoBatchValueColumns[name]; !ok && !value {
+			return nil, ErrInvalidBoletoBatch
+		}
+		columns[i] = name
+	}
+
+	results := []*BoletoBatchResult{}
+	line := 1
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, ErrInvalidBoletoBatch
+		}
+
+		line++
+
+		result := &BoletoBatchResult{Line: line, Request: &BoletoRequest{}, Status: BoletoBatchSkipped}
+
+		// the values of the charges are read after their types, in any order of the columns
+		for _, fields := range []map[string]func(*BoletoRequest, string) error{boletoBatchColumns, boletoBatchValueColumns} {
+			for i, value := range record {
+				if i >= len(columns) || result.Error != nil {
+					break
+				}
+
+				set, ok := fields[columns[i]]
+				value = strings.TrimSpace(value)
+				if !ok || value == "" {
+					continue
+				}
+
+				if err := set(result.Request, value); err != nil {
+					result.Status = BoletoBatchInvalid
+					result.Error = &BoletoBatchRowError{Line: line, Field: columns[i], Message: err.Error()}
+				}
+			}
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		return nil, ErrInvalidBoletoBatch
+	}
+
+	return results, nil
+}
+
+// boletoBatchColumns sets the fields of the request from the columns of the CSV
+var boletoBatchColumns = map[string]func(request *BoletoRequest, value string) error{
+	"account.branch": func(r *BoletoRequest, v string) error { boletoBatchAccount(r).Branch = v; return nil },
+	"account.number": func(r *BoletoRequest, v string) error { boletoBatchAccount(r).Number = v; return nil },
+	"documentNumber": func(r *BoletoRequest, v string) error { r.Document = v; return nil },
+	"amount":         func(r *BoletoRequest, v string) error { return boletoBatchAmount(&r.Amount, v) },
+	"dueDate":        func(r *BoletoRequest, v string) error { return boletoBatchDate(&r.DueDate, v) },
+	"type":           func(r *BoletoRequest, v string) error { r.Type = BoletoType(v); return nil },
+	"alias":          func(r *BoletoRequest, v string) error { r.Alias = &v; return nil },
+	"closePayment":   func(r *BoletoRequest, v string) error { return boletoBatchDate(&r.ClosePayment, v) },
+
+	"payer.name":      func(r *BoletoRequest, v string) error { boletoBatchPayer(r).Name = v; return nil },
+	"payer.tradeName": func(r *BoletoRequest, v string) error { boletoBatchPayer(r).TradeName = v; return nil },
+	"payer.document":  func(r *BoletoRequest, v string) error { boletoBatchPayer(r).Document = v; return nil },
+	"payer.address.zipCode": func(r *BoletoRequest, v string) error {
+		boletoBatchAddress(r).ZipCode = v
+		return nil
+	},
+	"payer.address.addressLine": func(r *BoletoRequest, v string) error {
+		boletoBatchAddress(r).AddressLine = v
+		return nil
+	},
+	"payer.address.neighborhood": func(r *BoletoRequest, v string) error {
+		boletoBatchAddress(r).Neighborhood = v
+		return nil
+	},
+	"payer.address.city":  func(r *BoletoRequest, v string) error { boletoBatchAddress(r).City = v; return nil },
+	"payer.address.state": func(r *BoletoRequest, v string) error { boletoBatchAddress(r).State = v; return nil },
+
+	"fine.type":      func(r *BoletoRequest, v string) error { boletoBatchFine(r).Type = BoletoFineType(v); return nil },
+	"fine.startDate": func(r *BoletoRequest, v string) error { return boletoBatchDate(&boletoBatchFine(r).StartDate, v) },
+
+	"interest.type": func(r *BoletoRequest, v string) error {
+		boletoBatchInterest(r).Type = BoletoInterestType(v)
+		return nil
+	},
+	"interest.startDate": func(r *BoletoRequest, v string) error {
+		return boletoBatchDate(&boletoBatchInterest(r).StartDate, v)
+	},
+
+	"discount.type": func(r *BoletoRequest, v string) error {
+		boletoBatchDiscount(r).Type = BoletoDiscountsType(v)
+		return nil
+	},
+	"discount.limitDate": func(r *BoletoRequest, v string) error {
+		return boletoBatchDate(&boletoBatchDiscount(r).LimitDate, v)
+	},
+}
+
+// boletoBatchValueColumns sets the values of the charges, amounts in reais or percentages
+// as the types set by boletoBatchColumns
+var boletoBatchValueColumns = map[string]func(request *BoletoRequest, value string) error{
+	"fine.value": func(r *BoletoRequest, v string) error {
+		fine := boletoBatchFine(r)
+		return boletoBatchChargeValue(&fine.Value, v, fine.Type == FixedAmountFineType)
+	},
+	"interest.value": func(r *BoletoRequest, v string) error {
+		interest := boletoBatchInterest(r)
+		return boletoBatchChargeValue(&interest.Value, v, interest.Type == FixedAmountInterestType)
+	},
+	"discount.value": func(r *BoletoRequest, v string) error {
+		discount := boletoBatchDiscount(r)
+		return boletoBatchChargeValue(&discount.Value, v, discount.Type == FixedAmountDiscountType)
+	},
+}
+
+func boletoBatchAccount(r *BoletoRequest) *Account {
+	if r.Account == nil {
+		r.Account = &Account{}
+	}
+	return r.Account
+}
+
+func boletoBatchPayer(r *BoletoRequest) *BoletoPayer {
+	if r.Payer == nil {
+		r.Payer = &BoletoPayer{}
+	}
+	return r.Payer
+}
+
+func boletoBatchAddress(r *BoletoRequest) *BoletoAddress {
+	payer := boletoBatchPayer(r)
+	if payer.Address == nil {
+		payer.Address = &BoletoAddress{}
+	}
+	return payer.Address
+}
+
+func boletoBatchFine(r *BoletoRequest) *BoletoFine {
+	if r.Fine == nil {
+		r.Fine = &BoletoFine{}
+	}
+	return r.Fine
+}
+
+func boletoBatchInterest(r *BoletoRequest) *BoletoInterest {
+	if r.Interest == nil {
+		r.Interest = &BoletoInterest{}
+	}
+	return r.Interest
+}
+
+func boletoBatchDiscount(r *BoletoRequest) *BoletoDiscounts {
+	if r.Discount == nil {
+		r.Discount = &BoletoDiscounts{}
+	}
+	return r.Discount
+}
+
+// boletoBatchAmount parses an amount in reais, 1234.56 or 1.234,56
+func boletoBatchAmount(field *float64, value string) error {
+	money, err := ParseMoney(value)
+	if err != nil {
+		return errors.New("invalid amount")
+	}
+	*field = money.Float64()
+	return nil
+}
+
+// boletoBatchChargeValue parses the value of a fine, an interest or a discount, an amount in
+// reais as the amount column when fixed and a percentage otherwise
+func boletoBatchChargeValue(field *float64, value string, fixed bool) error {
+	if fixed {
+		return boletoBatchAmount(field, value)
+	}
+	return boletoBatchRate(field, value)
+}
+
+// boletoBatchRate parses a percentage with all its decimals: 0.033, 0,033 or 1.234,56 with
+// a decimal comma. The dot followed by three digits, as 1.250, is refused because it reads
+// as thousands in the amounts.
+func boletoBatchRate(field *float64, value string) error {
+	if _, thousands := removeThousands(value); thousands && strings.Contains(value, ".") {
+		return errors.New("ambiguous number, use a decimal comma")
+	}
+
+	if strings.Contains(value, ",") {
+		value = strings.Replace(strings.Replace(value, ".", "", -1), ",", ".", 1)
+	}
+
+	// refuses the signs, the exponents, Inf and NaN accepted by ParseFloat
+	if strings.Trim(value, "0123456789.") != "" {
+		return errors.New("invalid number")
+	}
+
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return errors.New("invalid number")
+	}
+	*field = rate
+	return nil
+}
+
+// boletoBatchDate parses 2006-01-02 or 02/01/2006 at Sao Paulo
+func boletoBatchDate(field *time.Time, value string) error {
+	for _, layout := range []string{"2006-01-02", "02/01/2006"} {
+		if date, err := time.ParseInLocation(layout, value, calendar.Location()); err == nil {
+			*field = date
+			return nil
+		}
+	}
+	return errors.New("invalid date")
+}
+
+// validateBoletoBatch fills the defaults of the rows and marks the invalid ones. The checks
+// repeat the tags of the models, so that the whole input is refused before sending anything.
+func validateBoletoBatch(results []*BoletoBatchResult, options BoletoBatchOptions) {
+	aliases := map[string]int{}
+
+	for _, result := range results {
+		if result.Status == BoletoBatchInvalid {
+			continue
+		}
+
+		request := result.Request
+		if request.Account == nil && options.Account != nil {
+			account := *options.Account
+			request.Account = &account
+		}
+		if request.APIVersion == nil {
+			request.APIVersion = options.APIVersion
+		}
+
+		field, message := validateBoletoBatchRequest(request)
+		if message == "" && request.Alias != nil && *request.Alias != "" {
+			if line, ok := aliases[*request.Alias]; ok {
+				field, message = "alias", fmt.Sprintf("repeated from line %d", line)
+			}
+			aliases[*request.Alias] = result.Line
+		}
+
+		if message == "" {
+			if err := grok.Validator.Struct(request); err != nil {
+				message = grok.FromValidationErros(err).Error()
+			}
+		}
+
+		if message != "" {
+			result.Status = BoletoBatchInvalid
+			result.Error = &BoletoBatchRowError{Line: result.Line, Field: field, Message: message}
+		}
+	}
+}
+
+func validateBoletoBatchRequest(request *BoletoRequest) (string, string) {
+	if request.Account == nil || request.Account.Branch == "" || request.Account.Number == "" {
+		return "account", "is required"
+	}
+
+	if !isValidBoletoDocument(request.Document) {
+		return "documentNumber", "is not a valid cpf or cnpj"
+	}
+
+	if request.Amount <= 0 {
+		return "amount", "must be positive"
+	}
+
+	if request.DueDate.IsZero() {
+		return "dueDate", "is required"
+	}
+
+	if request.Type != Deposit && request.Type != Levy && request.Type != Invoice {
+		return "type", "is invalid"
+	}
+
+	payer := request.Payer
+	if payer == nil {
+		if request.Type == Levy {
+			return "payer", "is required"
+		}
+		return "", ""
+	}
+
+	if payer.Name == "" {
+		return "payer.name", "is required"
+	}
+
+	if utf8.RuneCountInString(payer.Name) > boletoPayerNameMaxLength {
+		return "payer.name", fmt.Sprintf("longer than %d characters", boletoPayerNameMaxLength)
+	}
+
+	if utf8.RuneCountInString(payer.TradeName) > boletoPayerTradeNameMaxLength {
+		return "payer.tradeName", fmt.Sprintf("longer than %d characters", boletoPayerTradeNameMaxLength)
+	}
+
+	if !isValidBoletoDocument(payer.Document) {
+		return "payer.document", "is not a valid cpf or cnpj"
+	}
+
+	address := payer.Address
+	switch {
+	case address == nil:
+		return "payer.address", "is required"
+	case len(OnlyDigits(address.ZipCode)) != 8:
+		return "payer.address.zipCode", "is invalid"
+	case address.AddressLine == "":
+		return "payer.address.addressLine", "is required"
+	case address.Neighborhood == "":
+		return "payer.address.neighborhood", "is required"
+	case address.City == "":
+		return "payer.address.city", "is required"
+	case len(address.State) != 2:
+		return "payer.address.state", "is invalid"
+	}
+
+	return "", ""
+}
+
+func isValidBoletoDocument(document string) bool {
+	digits := OnlyDigits(document)
+	return IsValidCPF(digits) || IsValidCNPJ(digits)
+}
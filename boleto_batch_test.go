@@ -0,0 +1,216 @@
+package bankly_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const boletoBatchCSV = `alias,documentNumber,amount,dueDate,type,payer.name,payer.document,payer.address.zipCode,payer.address.addressLine,payer.address.neighborhood,payer.address.city,payer.address.state,fine.value,fine.type,fine.startDate
+NF-1,16246241620,"1.250,50",2026-11-10,Levy,Maria da Silva,52998224725,01310-100,Av Paulista 1000,Bela Vista,Sao Paulo,SP,"0,033",Percent,11/11/2026
+NF-2,16246241620,99.90,2026-11-10,Levy,Padaria Pao Quente,11222333000181,01310100,Av Paulista 1000,Bela Vista,Sao Paulo,SP,,,
+NF-3,16246241620,10,2026-11-10,Deposit,,,,,,,,,,
+`
+
+type BoletoBatchTestSuite struct {
+	suite.Suite
+	assert  *assert.Assertions
+	ctx     context.Context
+	mutex   sync.Mutex
+	created []*bankly.BoletoRequest
+	answers map[string]*http.Response
+	boletos *bankly.Boletos
+	account *bankly.Account
+}
+
+func TestBoletoBatchTestSuite(t *testing.T) {
+	suite.Run(t, new(BoletoBatchTestSuite))
+}
+
+func (s *BoletoBatchTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.created = []*bankly.BoletoRequest{}
+	s.answers = map[string]*http.Response{}
+	s.account = &bankly.Account{Branch: "0001", Number: "189162"}
+
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		if req.Method == http.MethodGet {
+			if strings.Contains(req.URL.Path, "missing") {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: jsonBody(nil)}
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("%PDF " + req.URL.Path))}
+		}
+
+		var model bankly.BoletoRequest
+		json.NewDecoder(req.Body).Decode(&model)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		s.created = append(s.created, &model)
+
+		if answer, ok := s.answers[*model.Alias]; ok {
+			return answer
+		}
+
+		return &http.Response{StatusCode: http.StatusAccepted, Body: jsonBody(bankly.BoletoResponse{
+			AuthenticationCode: "code-" + *model.Alias,
+		})}
+	})
+
+	s.boletos = bankly.NewBoletos(httpClient, *session)
+}
+
+func (s *BoletoBatchTestSuite) TestCreateBatch_CSV() {
+	s.answers["NF-2"] = &http.Response{StatusCode: http.StatusBadRequest, Body: jsonBody([]bankly.ErrorResponse{{
+		CodeMessageErrorResponse: bankly.CodeMessageErrorResponse{Code: "INVALID_DUE_DATE", Message: "invalid due date"},
+	}})}
+	s.answers["NF-3"] = &http.Response{StatusCode: http.StatusBadGateway, Body: jsonBody(nil)}
+
+	report, err := s.boletos.CreateBatch(s.ctx, strings.NewReader(boletoBatchCSV), bankly.BoletoBatchOptions{
+		Format:  bankly.BoletoBatchCSV,
+		Account: s.account,
+	})
+
+	s.assert.NoError(err)
+	s.assert.Len(report.Results, 3)
+	s.assert.Equal(1, report.Created)
+	s.assert.Equal(1, report.Rejected)
+	s.assert.Equal(1, report.Unknown)
+
+	first := report.Results[0]
+	s.assert.Equal(2, first.Line)
+	s.assert.Equal(bankly.BoletoBatchCreated, first.Status)
+	s.assert.Equal("code-NF-1", first.AuthenticationCode)
+	s.assert.Equal(1250.5, first.Request.Amount)
+	s.assert.Equal(0.033, first.Request.Fine.Value)
+	s.assert.Equal(date(2026, time.November, 11), first.Request.Fine.StartDate)
+	s.assert.Equal("189162", first.Request.Account.Number)
+	s.assert.Nil(report.Results[1].Request.Fine)
+
+	s.assert.Equal(bankly.BoletoBatchRejected, report.Results[1].Status)
+	s.assert.Error(report.Results[1].Error)
+
+	// a deposit doesn't need a payer, the gateway error may have created it
+	s.assert.Equal(bankly.BoletoBatchUnknown, report.Results[2].Status)
+	s.assert.Len(s.created, 3)
+}
+
+func (s *BoletoBatchTestSuite) TestCreateBatch_InvalidRows() {
+	input := strings.Join([]string{
+		`{"alias":"A-1","documentNumber":"16246241620","amount":10,"dueDate":"2026-11-10T00:00:00-03:00","type":"Deposit"}`,
+		`{"alias":"A-2","documentNumber":"16246241620","amount":10,"dueDate":"2026-11-10T00:00:00-03:00","type":"Levy",` +
+			`"payer":{"name":"` + strings.Repeat("a", 51) + `","document":"52998224725"}}`,
+		``,
+		`{"alias":"A-1","documentNumber":"16246241621","amount":10}`,
+		`{"alias":`,
+		`{"alias":"A-1","documentNumber":"16246241620","amount":10,"dueDate":"2026-11-10T00:00:00-03:00","type":"Deposit"}`,
+	}, "\n")
+
+	report, err := s.boletos.CreateBatch(s.ctx, strings.NewReader(input), bankly.BoletoBatchOptions{
+		Format:  bankly.BoletoBatchJSONLines,
+		Account: s.account,
+	})
+
+	s.assert.NoError(err)
+	s.assert.Len(s.created, 0)
+	s.assert.Equal(1, report.Skipped)
+	s.assert.Equal(4, report.Invalid)
+
+	messages := []string{}
+	for _, result := range report.Results[1:] {
+		messages = append(messages, result.Error.Error())
+	}
+	s.assert.Equal([]string{
+		"line 2: payer.name longer than 50 characters",
+		"line 4: documentNumber is not a valid cpf or cnpj",
+		"line 5: invalid json",
+		"line 6: alias repeated from line 1",
+	}, messages)
+
+	report, err = s.boletos.CreateBatch(s.ctx, strings.NewReader(input), bankly.BoletoBatchOptions{
+		Format:      bankly.BoletoBatchJSONLines,
+		Account:     s.account,
+		SkipInvalid: true,
+	})
+
+	s.assert.NoError(err)
+	s.assert.Equal(1, report.Created)
+	s.assert.Len(s.created, 1)
+}
+
+func (s *BoletoBatchTestSuite) TestCreateBatch_ChargeValues() {
+	input := strings.Join([]string{
+		"alias,documentNumber,amount,dueDate,type,interest.value,interest.type,interest.startDate,fine.type,fine.value,fine.startDate",
+		`NF-1,16246241620,10,2026-11-10,Deposit,1.250,FixedAmount,2026-11-11,Percent,"2,5",2026-11-11`,
+		`NF-2,16246241620,10,2026-11-10,Deposit,"1.250,5",FixedAmount,2026-11-11,Percent,0.033,2026-11-11`,
+		`NF-3,16246241620,10,2026-11-10,Deposit,1.250,Percent,2026-11-11,,,`,
+	}, "\n")
+
+	report, err := s.boletos.CreateBatch(s.ctx, strings.NewReader(input), bankly.BoletoBatchOptions{
+		Format:  bankly.BoletoBatchCSV,
+		Account: s.account,
+	})
+
+	s.assert.NoError(err)
+	s.assert.Len(s.created, 0)
+	s.assert.Equal(1250.0, report.Results[0].Request.Interest.Value)
+	s.assert.Equal(2.5, report.Results[0].Request.Fine.Value)
+	s.assert.Equal(1250.5, report.Results[1].Request.Interest.Value)
+	s.assert.Equal(0.033, report.Results[1].Request.Fine.Value)
+
+	// 1.250 is one thousand as an amount and would be 1.25 as a percentage
+	s.assert.Equal(bankly.BoletoBatchInvalid, report.Results[2].Status)
+	s.assert.EqualError(report.Results[2].Error, "line 4: interest.value ambiguous number, use a decimal comma")
+}
+
+func (s *BoletoBatchTestSuite) TestCreateBatch_InvalidInput() {
+	_, err := s.boletos.CreateBatch(s.ctx, strings.NewReader("alias,unknown\nNF-1,1\n"), bankly.BoletoBatchOptions{
+		Format: bankly.BoletoBatchCSV,
+	})
+	s.assert.Equal(bankly.ErrInvalidBoletoBatch, err)
+
+	_, err = s.boletos.CreateBatch(s.ctx, strings.NewReader(""), bankly.BoletoBatchOptions{
+		Format: bankly.BoletoBatchJSONLines,
+	})
+	s.assert.Equal(bankly.ErrInvalidBoletoBatch, err)
+}
+
+func (s *BoletoBatchTestSuite) TestDownloadBatch() {
+	report := &bankly.BoletoBatchReport{Results: []*bankly.BoletoBatchResult{
+		{Line: 2, Status: bankly.BoletoBatchCreated, AuthenticationCode: "code-1", Request: &bankly.BoletoRequest{Alias: bankly.String("NF/1")}},
+		{Line: 3, Status: bankly.BoletoBatchRejected, Request: &bankly.BoletoRequest{}},
+		{Line: 4, Status: bankly.BoletoBatchCreated, AuthenticationCode: "missing", Request: &bankly.BoletoRequest{}},
+		{Line: 5, Status: bankly.BoletoBatchCreated, AuthenticationCode: "code-3", Request: &bankly.BoletoRequest{}},
+	}}
+
+	var buffer bytes.Buffer
+	s.assert.NoError(s.boletos.DownloadBatch(s.ctx, report, &buffer))
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	s.assert.NoError(err)
+
+	names := []string{}
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	s.assert.Equal([]string{"0002-NF-1.pdf", "0005-code-3.pdf"}, names)
+
+	content, _ := archive.File[0].Open()
+	pdf, _ := io.ReadAll(content)
+	s.assert.Equal("%PDF /bankslip/code-1/pdf", string(pdf))
+
+	s.assert.Equal(bankly.ErrEntryNotFound, report.Results[2].DownloadError)
+}
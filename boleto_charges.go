@@ -0,0 +1,84 @@
+package bankly
+
+import (
+	"math"
+	"time"
+
+	"github.com/contbank/bankly-sdk/boleto"
+)
+
+// discountTypes maps the discounts of Bankly to the charge types of the calculator
+var discountTypes = map[BoletoDiscountsType]boleto.ChargeType{
+	FixedAmountDiscountType:  boleto.FixedAmount,
+	FixedPercentDiscountType: boleto.Percent,
+}
+
+// Terms returns the amount and the charges of the boleto to calculate its amount due offline
+func (b *BoletoDetailedResponse) Terms() boleto.Terms {
+	terms := boleto.Terms{DueDate: b.DueDate}
+
+	if b.Amount != nil {
+		terms.Amount = b.Amount.Money().Cents
+	}
+
+	if b.ClosePayment != nil {
+		terms.ClosePayment = *b.ClosePayment
+	}
+
+	if b.Fine != nil {
+		terms.Fine = &boleto.Charge{
+			Type:  boleto.ChargeType(b.Fine.Type),
+			Date:  b.Fine.StartDate,
+			Value: chargeValue(boleto.ChargeType(b.Fine.Type), b.Fine.Value),
+		}
+	}
+
+	if b.Interest != nil {
+		terms.Interest = &boleto.Charge{
+			Type:  boleto.ChargeType(b.Interest.Type),
+			Date:  b.Interest.StartDate,
+			Value: chargeValue(boleto.ChargeType(b.Interest.Type), b.Interest.Value),
+		}
+	}
+
+	discount := b.Discount
+	if discount == nil {
+		discount = b.Discounts
+	}
+	if discount != nil {
+		chargeType, ok := discountTypes[discount.Type]
+		if !ok {
+			chargeType = boleto.ChargeType(discount.Type)
+		}
+		terms.Discount = &boleto.Charge{
+			Type:  chargeType,
+			Date:  discount.LimitDate,
+			Value: chargeValue(chargeType, discount.Value),
+		}
+	}
+
+	return terms
+}
+
+// chargeValue converts the value of Bankly to centavos at the fixed amounts and to
+// millionths of percent at the rates, so a rate as 0.033 isn't rounded to centavos
+func chargeValue(chargeType boleto.ChargeType, value float64) int64 {
+	if chargeType == boleto.Percent {
+		return int64(math.Round(value * float64(boleto.PercentScale)))
+	}
+	return MoneyFromFloat(value).Cents
+}
+
+// AmountDue calculates offline the amount due of the boleto at the payment date
+func (b *BoletoDetailedResponse) AmountDue(paymentDate time.Time) (*boleto.Due, error) {
+	return boleto.AmountDue(b.Terms(), paymentDate)
+}
+
+// Cents returns the charges calculated by Bankly in centavos, to check them with Due.Check
+func (c Charges) Cents() boleto.Charges {
+	return boleto.Charges{
+		Fine:     MoneyFromFloat(c.FineAmountCalculated).Cents,
+		Interest: MoneyFromFloat(c.InterestAmountCalculated).Cents,
+		Discount: MoneyFromFloat(c.DiscountAmount).Cents,
+	}
+}
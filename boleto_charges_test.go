@@ -0,0 +1,78 @@
+package bankly_test
+
+import (
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/boleto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BoletoChargesTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func TestBoletoChargesTestSuite(t *testing.T) {
+	suite.Run(t, new(BoletoChargesTestSuite))
+}
+
+func (s *BoletoChargesTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+}
+
+func (s *BoletoChargesTestSuite) TestAmountDue() {
+	dueDate := time.Date(2026, time.November, 10, 0, 0, 0, 0, time.UTC)
+	closePayment := dueDate.AddDate(0, 0, 30)
+
+	detail := &bankly.BoletoDetailedResponse{
+		DueDate:      dueDate,
+		ClosePayment: &closePayment,
+		Amount:       &bankly.BoletoAmount{Value: 1250.5},
+		Fine:         &bankly.BoletoFine{StartDate: dueDate.AddDate(0, 0, 1), Value: 2, Type: bankly.PercentFineType},
+		Interest:     &bankly.BoletoInterest{StartDate: dueDate.AddDate(0, 0, 1), Value: 0.41, Type: bankly.FixedAmountInterestType},
+		Discounts:    &bankly.BoletoDiscounts{LimitDate: dueDate, Value: 10, Type: bankly.FixedPercentDiscountType},
+	}
+
+	terms := detail.Terms()
+	s.assert.Equal(int64(125050), terms.Amount)
+	s.assert.Equal(&boleto.Charge{Type: boleto.Percent, Date: dueDate, Value: 10 * boleto.PercentScale}, terms.Discount)
+	s.assert.Equal(&boleto.Charge{Type: boleto.FixedAmount, Date: dueDate.AddDate(0, 0, 1), Value: 41}, terms.Interest)
+
+	due, err := detail.AmountDue(dueDate.AddDate(0, 0, -1))
+	s.assert.NoError(err)
+	s.assert.Equal(int64(112545), due.Total)
+
+	due, err = detail.AmountDue(dueDate.AddDate(0, 0, 3))
+	s.assert.NoError(err)
+	s.assert.Equal(boleto.Charges{Fine: 2501, Interest: 123}, due.Charges)
+	s.assert.Equal(int64(127674), due.Total)
+
+	charges := bankly.Charges{FineAmountCalculated: 25.01, InterestAmountCalculated: 1.23}
+	s.assert.NoError(due.Check(charges.Cents()))
+
+	charges.InterestAmountCalculated = 2.46
+	s.assert.Error(due.Check(charges.Cents()))
+}
+
+func (s *BoletoChargesTestSuite) TestAmountDue_FractionalRate() {
+	dueDate := time.Date(2026, time.November, 10, 0, 0, 0, 0, time.UTC)
+
+	detail := &bankly.BoletoDetailedResponse{
+		DueDate:  dueDate,
+		Amount:   &bankly.BoletoAmount{Value: 1000},
+		Interest: &bankly.BoletoInterest{StartDate: dueDate.AddDate(0, 0, 1), Value: 0.033, Type: bankly.PercentInterestType},
+	}
+
+	s.assert.Equal(int64(33000), detail.Terms().Interest.Value)
+
+	// 0.033% a month for 30 days
+	due, err := detail.AmountDue(dueDate.AddDate(0, 0, 30))
+	s.assert.NoError(err)
+	s.assert.Equal(boleto.Charges{Interest: 33}, due.Charges)
+
+	charges := bankly.Charges{InterestAmountCalculated: 0.33}
+	s.assert.NoError(due.Check(charges.Cents()))
+}
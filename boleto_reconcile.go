@@ -0,0 +1,335 @@
+package bankly
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/contbank/bankly-sdk/boleto"
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/sirupsen/logrus"
+)
+
+// BoletoUpdatesReader returns the pages of the boletos updated at a date, Boletos implements it
+type BoletoUpdatesReader interface {
+	FilterBankslipByUpdateAtPage(ctx context.Context, date time.Time, pageToken string) (*FilterBoletoResponse, error)
+}
+
+// BoletoSettlementStatus ...
+type BoletoSettlementStatus string
+
+const (
+	// BoletoSettlementOpen is a boleto not paid and not expired
+	BoletoSettlementOpen BoletoSettlementStatus = "OPEN"
+	// BoletoSettlementPaid ...
+	BoletoSettlementPaid BoletoSettlementStatus = "PAID"
+	// BoletoSettlementPartiallyPaid ...
+	BoletoSettlementPartiallyPaid BoletoSettlementStatus = "PARTIALLY_PAID"
+	// BoletoSettlementOverpaid ...
+	BoletoSettlementOverpaid BoletoSettlementStatus = "OVERPAID"
+	// BoletoSettlementPaidLate is a boleto paid after its due date, moved to the next business day
+	BoletoSettlementPaidLate BoletoSettlementStatus = "PAID_LATE"
+	// BoletoSettlementCancelled ...
+	BoletoSettlementCancelled BoletoSettlementStatus = "CANCELLED"
+	// BoletoSettlementExpiredUnpaid ...
+	BoletoSettlementExpiredUnpaid BoletoSettlementStatus = "EXPIRED_UNPAID"
+)
+
+// IssuedBoleto is a boleto of the portfolio, matched to the Bankly updates by the
+// authentication code or, without it, by the alias. Status is the last known one,
+// an event is emitted when the reconciliation changes it. With Terms, the amount
+// expected at the payment has the fine, the interest and the discount of the boleto.
+type IssuedBoleto struct {
+	AuthenticationCode string
+	Alias              string
+	Reference          string
+	Amount             Money
+	DueDate            time.Time
+	Terms              *boleto.Terms
+	Status             BoletoSettlementStatus
+}
+
+// BoletoSettlement is the reconciliation of an issued boleto
+type BoletoSettlement struct {
+	Boleto         IssuedBoleto
+	Status         BoletoSettlementStatus
+	BanklyStatus   string
+	Expected       Money
+	Paid           Money
+	Difference     Money
+	Payments       []*BoletoPayment
+	PaidOutDate    *time.Time
+	PaymentChannel PaymentChannel
+	UpdatedAt      *time.Time
+}
+
+// BoletoSettlementEvent is a change of the status of an issued boleto
+type BoletoSettlementEvent struct {
+	Boleto     IssuedBoleto
+	Previous   BoletoSettlementStatus
+	Status     BoletoSettlementStatus
+	OccurredAt time.Time
+	Settlement *BoletoSettlement
+}
+
+// BoletoSettlementReport ...
+type BoletoSettlementReport struct {
+	Start       time.Time
+	End         time.Time
+	Settlements []*BoletoSettlement
+	Events      []*BoletoSettlementEvent
+	// Unmatched are the updates of boletos out of the portfolio
+	Unmatched []FilterBoletoData
+}
+
+// Count returns the number of boletos at the status
+func (r *BoletoSettlementReport) Count(status BoletoSettlementStatus) int {
+	count := 0
+	for _, settlement := range r.Settlements {
+		if settlement.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// BoletoReconcilerConfig ...
+type BoletoReconcilerConfig struct {
+	// Calendar of business days moving the due dates, nil uses the national calendar
+	Calendar calendar.Calendar
+}
+
+// BoletoReconciler matches the settlements of Bankly back to the issued boletos
+type BoletoReconciler struct {
+	updates BoletoUpdatesReader
+	config  BoletoReconcilerConfig
+	changed []func(ctx context.Context, event *BoletoSettlementEvent)
+}
+
+// NewBoletoReconciler ...
+func NewBoletoReconciler(updates BoletoUpdatesReader, config BoletoReconcilerConfig) *BoletoReconciler {
+	if config.Calendar == nil {
+		config.Calendar = calendar.NewNational()
+	}
+
+	return &BoletoReconciler{updates: updates, config: config}
+}
+
+// OnStatusChanged registers a hook called for each event of the reconciliations.
+func (r *BoletoReconciler) OnStatusChanged(hook func(ctx context.Context, event *BoletoSettlementEvent)) {
+	r.changed = append(r.changed, hook)
+}
+
+// Reconcile walks all the pages of the updates of each day from start to end and classifies the
+// issued boletos. The latest update of a boleto wins, and the boletos not paid after their due
+// date are expired at the end of the range. A boleto without updates at the range keeps its
+// known status when it is not open, so a paid boleto isn't expired by a later range.
+func (r *BoletoReconciler) Reconcile(ctx context.Context, issued []IssuedBoleto, start, end time.Time) (*BoletoSettlementReport, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"start":      start,
+		"end":        end,
+	}
+
+	byCode := map[string]int{}
+	byAlias := map[string]int{}
+	for i, b := range issued {
+		if b.AuthenticationCode != "" {
+			byCode[b.AuthenticationCode] = i
+		}
+		if b.Alias != "" {
+			byAlias[b.Alias] = i
+		}
+	}
+
+	report := &BoletoSettlementReport{
+		Start:     startOfDay(start),
+		End:       startOfDay(end),
+		Unmatched: []FilterBoletoData{},
+	}
+
+	updates := make([]*FilterBoletoData, len(issued))
+	updatedAt := make([]time.Time, len(issued))
+
+	for day := report.Start; !day.After(report.End); day = day.AddDate(0, 0, 1) {
+		pages, err := r.filter(ctx, day)
+		if err != nil {
+			logrus.WithFields(fields).WithField("date", day).
+				WithError(err).Error("error filtering boletos to reconcile")
+			return nil, err
+		}
+
+		for _, page := range pages {
+			for i := range page.Data {
+				data := page.Data[i]
+
+				index, ok := byCode[data.AuthenticationCode]
+				if !ok && data.Alias != nil {
+					index, ok = byAlias[*data.Alias]
+				}
+				if !ok {
+					report.Unmatched = append(report.Unmatched, data)
+					continue
+				}
+
+				updates[index] = &data
+				updatedAt[index] = day
+			}
+		}
+	}
+
+	for i, b := range issued {
+		settlement, err := r.settle(b, updates[i], updatedAt[i], report.End)
+		if err != nil {
+			logrus.WithFields(fields).WithField("authentication_code", b.AuthenticationCode).
+				WithError(err).Error("error reconciling boleto")
+			return nil, err
+		}
+		report.Settlements = append(report.Settlements, settlement)
+
+		if settlement.Status == b.Status {
+			continue
+		}
+
+		event := &BoletoSettlementEvent{
+			Boleto:     b,
+			Previous:   b.Status,
+			Status:     settlement.Status,
+			OccurredAt: report.End,
+			Settlement: settlement,
+		}
+		if settlement.PaidOutDate != nil {
+			event.OccurredAt = *settlement.PaidOutDate
+		} else if settlement.UpdatedAt != nil {
+			event.OccurredAt = *settlement.UpdatedAt
+		}
+
+		report.Events = append(report.Events, event)
+		for _, hook := range r.changed {
+			hook(ctx, event)
+		}
+	}
+
+	logrus.WithFields(fields).
+		WithField("boletos", len(issued)).
+		WithField("events", len(report.Events)).
+		WithField("unmatched", len(report.Unmatched)).
+		Info("boletos reconciled")
+
+	return report, nil
+}
+
+// filter reads all the pages of the updates of the day, none when Bankly finds no boleto
+func (r *BoletoReconciler) filter(ctx context.Context, day time.Time) ([]*FilterBoletoResponse, error) {
+	pages := []*FilterBoletoResponse{}
+	seen := map[string]bool{}
+
+	pageToken := ""
+	for {
+		page, err := r.updates.FilterBankslipByUpdateAtPage(ctx, day, pageToken)
+		if err == ErrBoletoNotFound {
+			return pages, nil
+		} else if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+
+		if page.NextPageToken == "" {
+			return pages, nil
+		}
+		if seen[page.NextPageToken] {
+			return nil, ErrBoletoReconcilePaging
+		}
+		seen[page.NextPageToken] = true
+		pageToken = page.NextPageToken
+	}
+}
+
+func (r *BoletoReconciler) settle(issued IssuedBoleto, data *FilterBoletoData, updatedAt time.Time, end time.Time) (*BoletoSettlement, error) {
+	settlement := &BoletoSettlement{
+		Boleto:   issued,
+		Status:   BoletoSettlementOpen,
+		Expected: issued.Amount,
+		Paid:     NewMoney(0),
+		Payments: []*BoletoPayment{},
+	}
+
+	// without updates at the range, the status known before is kept
+	if data == nil && issued.Status != "" && issued.Status != BoletoSettlementOpen {
+		settlement.Status = issued.Status
+		return settlement, nil
+	}
+
+	dueDate := issued.DueDate
+	if data != nil {
+		settlement.BanklyStatus = data.Status
+		settlement.Payments = data.Payments
+		settlement.UpdatedAt = &updatedAt
+		if dueDate.IsZero() {
+			dueDate = data.DueDate
+		}
+		if issued.Amount.IsZero() && data.Amount != nil {
+			settlement.Expected = data.Amount.Money()
+		}
+	}
+
+	for _, payment := range settlement.Payments {
+		paid, err := settlement.Paid.Add(MoneyFromFloat(payment.Amount))
+		if err != nil {
+			return nil, err
+		}
+		settlement.Paid = paid
+
+		if settlement.PaidOutDate == nil || payment.PaidOutDate.After(*settlement.PaidOutDate) {
+			paidOutDate := payment.PaidOutDate
+			settlement.PaidOutDate = &paidOutDate
+			settlement.PaymentChannel = payment.PaymentChannel
+		}
+	}
+
+	// the due date on a holiday or a weekend is paid without charges at the next business day
+	late := false
+	if !dueDate.IsZero() {
+		effective := calendar.NextBusinessDay(r.config.Calendar, startOfDay(dueDate))
+		if settlement.PaidOutDate != nil {
+			late = startOfDay(*settlement.PaidOutDate).After(effective)
+		} else {
+			late = end.After(effective)
+		}
+	}
+
+	if settlement.PaidOutDate != nil && issued.Terms != nil {
+		due, err := boleto.AmountDue(*issued.Terms, *settlement.PaidOutDate)
+		if err != nil && err != boleto.ErrPaymentClosed {
+			return nil, err
+		}
+		if due != nil {
+			settlement.Expected = NewMoney(due.Total)
+		}
+	}
+
+	difference, err := settlement.Paid.Sub(settlement.Expected)
+	if err != nil {
+		return nil, err
+	}
+	settlement.Difference = difference
+
+	switch {
+	case settlement.Paid.IsZero() && data != nil && strings.HasPrefix(strings.ToLower(data.Status), "cancel"):
+		settlement.Status = BoletoSettlementCancelled
+	case settlement.Paid.IsZero() && late:
+		settlement.Status = BoletoSettlementExpiredUnpaid
+	case settlement.Paid.IsZero():
+		settlement.Status = BoletoSettlementOpen
+	case difference.IsNegative():
+		settlement.Status = BoletoSettlementPartiallyPaid
+	case late:
+		settlement.Status = BoletoSettlementPaidLate
+	case !difference.IsZero():
+		settlement.Status = BoletoSettlementOverpaid
+	default:
+		settlement.Status = BoletoSettlementPaid
+	}
+
+	return settlement, nil
+}
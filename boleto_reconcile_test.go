@@ -0,0 +1,215 @@
+package bankly_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/boleto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// boletoUpdates are the updates of each date, the next pages are keyed by
+// date/token, the tokens grow by one x
+type boletoUpdates map[string][]bankly.FilterBoletoData
+
+func (u boletoUpdates) FilterBankslipByUpdateAtPage(ctx context.Context, date time.Time, pageToken string) (*bankly.FilterBoletoResponse, error) {
+	key := date.Format("2006-01-02")
+	if pageToken != "" {
+		key += "/" + pageToken
+	}
+
+	data, ok := u[key]
+	if !ok {
+		return nil, bankly.ErrBoletoNotFound
+	}
+
+	response := &bankly.FilterBoletoResponse{Data: data}
+	if _, ok := u[date.Format("2006-01-02")+"/"+pageToken+"x"]; ok {
+		response.NextPageToken = pageToken + "x"
+	}
+	return response, nil
+}
+
+type BoletoReconcileTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	ctx    context.Context
+}
+
+func TestBoletoReconcileTestSuite(t *testing.T) {
+	suite.Run(t, new(BoletoReconcileTestSuite))
+}
+
+func (s *BoletoReconcileTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+}
+
+func (s *BoletoReconcileTestSuite) TestReconcile() {
+	dueDate := date(2026, time.November, 10)
+	alias := "NF-1002"
+	unknown := "NF-9999"
+
+	payment := func(amount float64, paidOutDate time.Time) []*bankly.BoletoPayment {
+		return []*bankly.BoletoPayment{{
+			Amount:         amount,
+			PaymentChannel: bankly.InternetBankingPaymentChannel,
+			PaidOutDate:    paidOutDate,
+		}}
+	}
+
+	updates := boletoUpdates{
+		"2026-11-10": {
+			{AuthenticationCode: "paid", Status: "Paid", Payments: payment(100, dueDate)},
+			{Alias: &alias, Status: "Paid", Payments: payment(50, dueDate)},
+			{AuthenticationCode: "over", Status: "Paid", Payments: payment(120, dueDate)},
+			{Alias: &unknown, Status: "Paid"},
+		},
+		"2026-11-12": {
+			{AuthenticationCode: "late", Status: "Paid", Payments: payment(102.03, dueDate.AddDate(0, 0, 2))},
+			{AuthenticationCode: "cancelled", Status: "Cancelled"},
+			{AuthenticationCode: "charged", Status: "Paid", Payments: payment(102.02, dueDate.AddDate(0, 0, 2))},
+		},
+	}
+
+	issue := func(code, alias string, status bankly.BoletoSettlementStatus) bankly.IssuedBoleto {
+		return bankly.IssuedBoleto{
+			AuthenticationCode: code,
+			Alias:              alias,
+			Amount:             bankly.NewMoney(10000),
+			DueDate:            dueDate,
+			Status:             status,
+		}
+	}
+
+	charged := issue("charged", "", bankly.BoletoSettlementOpen)
+	charged.Terms = &boleto.Terms{
+		Amount:   10000,
+		DueDate:  dueDate,
+		Fine:     &boleto.Charge{Type: boleto.Percent, Value: 2 * boleto.PercentScale},
+		Interest: &boleto.Charge{Type: boleto.FixedAmount, Value: 1},
+	}
+
+	issued := []bankly.IssuedBoleto{
+		issue("paid", "", bankly.BoletoSettlementOpen),
+		issue("partial", alias, bankly.BoletoSettlementOpen),
+		issue("over", "", bankly.BoletoSettlementOpen),
+		issue("late", "", bankly.BoletoSettlementOpen),
+		issue("cancelled", "", bankly.BoletoSettlementOpen),
+		issue("expired", "", bankly.BoletoSettlementExpiredUnpaid),
+		charged,
+	}
+
+	reconciler := bankly.NewBoletoReconciler(updates, bankly.BoletoReconcilerConfig{})
+
+	hooked := []*bankly.BoletoSettlementEvent{}
+	reconciler.OnStatusChanged(func(ctx context.Context, event *bankly.BoletoSettlementEvent) {
+		hooked = append(hooked, event)
+	})
+
+	report, err := reconciler.Reconcile(s.ctx, issued, date(2026, time.November, 9), date(2026, time.November, 13))
+	s.assert.NoError(err)
+
+	statuses := []bankly.BoletoSettlementStatus{}
+	for _, settlement := range report.Settlements {
+		statuses = append(statuses, settlement.Status)
+	}
+	s.assert.Equal([]bankly.BoletoSettlementStatus{
+		bankly.BoletoSettlementPaid,
+		bankly.BoletoSettlementPartiallyPaid,
+		bankly.BoletoSettlementOverpaid,
+		bankly.BoletoSettlementPaidLate,
+		bankly.BoletoSettlementCancelled,
+		bankly.BoletoSettlementExpiredUnpaid,
+		bankly.BoletoSettlementPaidLate,
+	}, statuses)
+
+	s.assert.Equal(int64(-5000), report.Settlements[1].Difference.Cents)
+	s.assert.Equal(bankly.InternetBankingPaymentChannel, report.Settlements[3].PaymentChannel)
+	s.assert.Equal(int64(10202), report.Settlements[6].Expected.Cents)
+	s.assert.True(report.Settlements[6].Difference.IsZero())
+	s.assert.Equal(2, report.Count(bankly.BoletoSettlementPaidLate))
+
+	s.assert.Len(report.Unmatched, 1)
+
+	// the expired boleto was already known as expired
+	s.assert.Len(report.Events, 6)
+	s.assert.Equal(report.Events, hooked)
+	s.assert.Equal(bankly.BoletoSettlementOpen, report.Events[0].Previous)
+	s.assert.Equal(dueDate, report.Events[0].OccurredAt)
+	s.assert.Equal(date(2026, time.November, 12), report.Events[4].OccurredAt)
+}
+
+func (s *BoletoReconcileTestSuite) TestReconcile_HolidayDueDate() {
+	// 15/11/2026 is a holiday on a sunday, the payment on monday is on time
+	dueDate := date(2026, time.November, 15)
+
+	updates := boletoUpdates{
+		"2026-11-16": {{AuthenticationCode: "code", Status: "Paid", Payments: []*bankly.BoletoPayment{{
+			Amount: 100, PaidOutDate: date(2026, time.November, 16),
+		}}}},
+	}
+
+	report, err := bankly.NewBoletoReconciler(updates, bankly.BoletoReconcilerConfig{}).Reconcile(s.ctx, []bankly.IssuedBoleto{{
+		AuthenticationCode: "code",
+		Amount:             bankly.NewMoney(10000),
+		DueDate:            dueDate,
+	}}, dueDate, date(2026, time.November, 17))
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.BoletoSettlementPaid, report.Settlements[0].Status)
+}
+
+func (s *BoletoReconcileTestSuite) TestReconcile_Pages() {
+	dueDate := date(2026, time.November, 10)
+	paid := []*bankly.BoletoPayment{{Amount: 100, PaidOutDate: dueDate}}
+
+	updates := boletoUpdates{
+		"2026-11-10":   {{AuthenticationCode: "first", Status: "Paid", Payments: paid}},
+		"2026-11-10/x": {{AuthenticationCode: "second", Status: "Paid", Payments: paid}},
+	}
+
+	issued := []bankly.IssuedBoleto{
+		{AuthenticationCode: "first", Amount: bankly.NewMoney(10000), DueDate: dueDate},
+		{AuthenticationCode: "second", Amount: bankly.NewMoney(10000), DueDate: dueDate},
+	}
+
+	report, err := bankly.NewBoletoReconciler(updates, bankly.BoletoReconcilerConfig{}).
+		Reconcile(s.ctx, issued, dueDate, date(2026, time.November, 12))
+
+	s.assert.NoError(err)
+	s.assert.Equal(2, report.Count(bankly.BoletoSettlementPaid))
+}
+
+func (s *BoletoReconcileTestSuite) TestReconcile_ConsecutiveRanges() {
+	dueDate := date(2026, time.November, 10)
+
+	updates := boletoUpdates{
+		"2026-11-10": {{AuthenticationCode: "code", Status: "Paid", Payments: []*bankly.BoletoPayment{{
+			Amount: 100, PaidOutDate: dueDate,
+		}}}},
+	}
+
+	reconciler := bankly.NewBoletoReconciler(updates, bankly.BoletoReconcilerConfig{})
+	issued := bankly.IssuedBoleto{
+		AuthenticationCode: "code",
+		Amount:             bankly.NewMoney(10000),
+		DueDate:            dueDate,
+		Status:             bankly.BoletoSettlementOpen,
+	}
+
+	report, err := reconciler.Reconcile(s.ctx, []bankly.IssuedBoleto{issued}, dueDate, dueDate)
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.BoletoSettlementPaid, report.Settlements[0].Status)
+	s.assert.Len(report.Events, 1)
+
+	// the next range has no update of the paid boleto
+	issued.Status = report.Settlements[0].Status
+	report, err = reconciler.Reconcile(s.ctx, []bankly.IssuedBoleto{issued}, date(2026, time.November, 11), date(2026, time.November, 20))
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.BoletoSettlementPaid, report.Settlements[0].Status)
+	s.assert.Len(report.Events, 0)
+}
@@ -0,0 +1,668 @@
+package bankly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// BoletoIssuer creates, finds and cancels boletos, Boletos implements it
+type BoletoIssuer interface {
+	CreateBankslip(ctx context.Context, model *BoletoRequest) (*BoletoResponse, error)
+	FindBankslip(ctx context.Context, model *FindBoletoRequest) (*BoletoDetailedResponse, error)
+	CancelBankslip(ctx context.Context, model *CancelBoletoRequest) error
+}
+
+// BoletoSubscriptionFrequency ...
+type BoletoSubscriptionFrequency string
+
+const (
+	// BoletoSubscriptionMonthly due every month at the day of the start date
+	BoletoSubscriptionMonthly BoletoSubscriptionFrequency = "MONTHLY"
+	// BoletoSubscriptionInterval due every IntervalDays days
+	BoletoSubscriptionInterval BoletoSubscriptionFrequency = "INTERVAL"
+)
+
+// BoletoSubscriptionStatus ...
+type BoletoSubscriptionStatus string
+
+const (
+	// BoletoSubscriptionActive waiting the next run
+	BoletoSubscriptionActive BoletoSubscriptionStatus = "ACTIVE"
+	// BoletoSubscriptionFinished all the boletos were issued and checked
+	BoletoSubscriptionFinished BoletoSubscriptionStatus = "FINISHED"
+	// BoletoSubscriptionCanceled ...
+	BoletoSubscriptionCanceled BoletoSubscriptionStatus = "CANCELED"
+)
+
+// BoletoSubscriptionIssueStatus ...
+type BoletoSubscriptionIssueStatus string
+
+const (
+	// BoletoSubscriptionIssuePending the boleto is being created at Bankly, or was created without an answer
+	BoletoSubscriptionIssuePending BoletoSubscriptionIssueStatus = "PENDING"
+	// BoletoSubscriptionIssued the boleto was created at Bankly
+	BoletoSubscriptionIssued BoletoSubscriptionIssueStatus = "ISSUED"
+	// BoletoSubscriptionIssueFailed the boleto was not created after all the attempts
+	BoletoSubscriptionIssueFailed BoletoSubscriptionIssueStatus = "FAILED"
+	// BoletoSubscriptionIssueSkipped the due date passed before the boleto was issued
+	BoletoSubscriptionIssueSkipped BoletoSubscriptionIssueStatus = "SKIPPED"
+	// BoletoSubscriptionIssuePaid the boleto was paid before the auto cancel
+	BoletoSubscriptionIssuePaid BoletoSubscriptionIssueStatus = "PAID"
+	// BoletoSubscriptionIssueCanceled the boleto was canceled unpaid after its due date
+	BoletoSubscriptionIssueCanceled BoletoSubscriptionIssueStatus = "CANCELED"
+)
+
+// BoletoSubscription issues a boleto from the template at each occurrence. The due date,
+// the alias and the dates of the fine, the interest, the discount and the close payment
+// of the template are replaced at each boleto.
+type BoletoSubscription struct {
+	ID            string                      `bson:"_id" json:"id"`
+	AccountNumber string                      `bson:"accountNumber" json:"accountNumber"`
+	Reference     string                      `bson:"reference,omitempty" json:"reference,omitempty"`
+	Template      BoletoRequest               `bson:"template" json:"template"`
+	Frequency     BoletoSubscriptionFrequency `bson:"frequency" json:"frequency"`
+	IntervalDays  int                         `bson:"intervalDays,omitempty" json:"intervalDays,omitempty"`
+	// StartDate is the due date of the first boleto
+	StartDate      time.Time  `bson:"startDate" json:"startDate"`
+	EndDate        *time.Time `bson:"endDate,omitempty" json:"endDate,omitempty"`
+	MaxOccurrences int        `bson:"maxOccurrences,omitempty" json:"maxOccurrences,omitempty"`
+	// IssueDaysBefore the due date the boleto is issued, zero issues it 10 days before
+	IssueDaysBefore int `bson:"issueDaysBefore" json:"issueDaysBefore"`
+	// DiscountDays before the due date is the limit date of the discount
+	DiscountDays int `bson:"discountDays,omitempty" json:"discountDays,omitempty"`
+	// CloseDays after the due date the boleto can't be paid, zero keeps the close payment of Bankly
+	CloseDays int `bson:"closeDays,omitempty" json:"closeDays,omitempty"`
+	// AutoCancelAfterDays after the due date an unpaid boleto is canceled, zero disables it
+	AutoCancelAfterDays int                       `bson:"autoCancelAfterDays,omitempty" json:"autoCancelAfterDays,omitempty"`
+	Status              BoletoSubscriptionStatus  `bson:"status" json:"status"`
+	Occurrence          int                       `bson:"occurrence" json:"occurrence"`
+	DueDate             time.Time                 `bson:"dueDate" json:"dueDate"`
+	NextRun             time.Time                 `bson:"nextRun" json:"nextRun"`
+	Attempts            int                       `bson:"attempts" json:"attempts"`
+	Issues              []BoletoSubscriptionIssue `bson:"issues" json:"issues"`
+	CreatedAt           time.Time                 `bson:"createdAt" json:"createdAt"`
+	UpdatedAt           time.Time                 `bson:"updatedAt" json:"updatedAt"`
+
+	ScheduleLease `bson:",inline" json:"-"`
+}
+
+// BoletoSubscriptionIssue is the boleto of one occurrence
+type BoletoSubscriptionIssue struct {
+	Occurrence         int                           `bson:"occurrence" json:"occurrence"`
+	Alias              string                        `bson:"alias" json:"alias"`
+	DueDate            time.Time                     `bson:"dueDate" json:"dueDate"`
+	Status             BoletoSubscriptionIssueStatus `bson:"status" json:"status"`
+	AuthenticationCode string                        `bson:"authenticationCode,omitempty" json:"authenticationCode,omitempty"`
+	IssuedAt           time.Time                     `bson:"issuedAt" json:"issuedAt"`
+	// CancelAt is when the boleto is checked to be canceled, zero when auto cancel is disabled
+	CancelAt  time.Time `bson:"cancelAt,omitempty" json:"cancelAt,omitempty"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+	Error     string    `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// BoletoSubscriptionAlias returns the alias of the boleto of an occurrence, built from
+// the reference of the subscription or, without it, from its id.
+func BoletoSubscriptionAlias(subscription *BoletoSubscription, occurrence int) string {
+	key := subscription.Reference
+	if key == "" {
+		key = subscription.ID
+	}
+	return fmt.Sprintf("%s-%03d", key, occurrence)
+}
+
+// BoletoSubscriptionSchedulerConfig ...
+type BoletoSubscriptionSchedulerConfig struct {
+	// Owner identifies the worker instance at the leases, empty generates one
+	Owner string
+	// Lease is how long a worker holds a subscription while running it
+	Lease time.Duration
+	// MaxAttempts of each boleto with transient errors
+	MaxAttempts int
+	// RetryDelay between the attempts
+	RetryDelay time.Duration
+	// BatchSize of subscriptions read from the store at each run
+	BatchSize int
+	// Calendar of business days moving the due dates, nil uses the national calendar
+	Calendar calendar.Calendar
+	// Reconcile finds the boleto of an issue created without an answer by its alias, as with
+	// FilterBankslipByUpdateAt. It returns the authentication code, or ErrBoletoSubscriptionNotIssued
+	// when Bankly never created it. When nil, the occurrence fails with ErrBoletoSubscriptionUnconfirmed
+	// and is never created again.
+	Reconcile func(ctx context.Context, subscription *BoletoSubscription, issue BoletoSubscriptionIssue) (string, error)
+}
+
+// BoletoSubscriptionScheduler issues the boletos of the subscriptions stored at the BoletoSubscriptionStore.
+// Many instances can run with the same store, each subscription is run by one instance at a time.
+type BoletoSubscriptionScheduler struct {
+	boletos      BoletoIssuer
+	store        BoletoSubscriptionStore
+	config       BoletoSubscriptionSchedulerConfig
+	issued       []func(ctx context.Context, subscription *BoletoSubscription, issue BoletoSubscriptionIssue)
+	failed       []func(ctx context.Context, subscription *BoletoSubscription, issue BoletoSubscriptionIssue)
+	autoCanceled []func(ctx context.Context, subscription *BoletoSubscription, issue BoletoSubscriptionIssue)
+	canceled     []func(ctx context.Context, subscription *BoletoSubscription)
+}
+
+// NewBoletoSubscriptionScheduler ...
+func NewBoletoSubscriptionScheduler(boletos BoletoIssuer, store BoletoSubscriptionStore,
+	config BoletoSubscriptionSchedulerConfig) *BoletoSubscriptionScheduler {
+
+	scheduleDefaults(&config.Owner, &config.Lease, &config.MaxAttempts, &config.RetryDelay, &config.BatchSize)
+
+	if config.Calendar == nil {
+		config.Calendar = calendar.NewNational()
+	}
+
+	return &BoletoSubscriptionScheduler{
+		boletos: boletos,
+		store:   store,
+		config:  config,
+	}
+}
+
+// OnIssued registers a hook called after each boleto created at Bankly.
+func (s *BoletoSubscriptionScheduler) OnIssued(hook func(ctx context.Context, subscription *BoletoSubscription, issue BoletoSubscriptionIssue)) {
+	s.issued = append(s.issued, hook)
+}
+
+// OnFailed registers a hook called when a boleto is not issued after all the attempts or its due date passed.
+func (s *BoletoSubscriptionScheduler) OnFailed(hook func(ctx context.Context, subscription *BoletoSubscription, issue BoletoSubscriptionIssue)) {
+	s.failed = append(s.failed, hook)
+}
+
+// OnAutoCanceled registers a hook called when an unpaid boleto is canceled after its due date.
+func (s *BoletoSubscriptionScheduler) OnAutoCanceled(hook func(ctx context.Context, subscription *BoletoSubscription, issue BoletoSubscriptionIssue)) {
+	s.autoCanceled = append(s.autoCanceled, hook)
+}
+
+// OnCanceled registers a hook called when a subscription is canceled.
+func (s *BoletoSubscriptionScheduler) OnCanceled(hook func(ctx context.Context, subscription *BoletoSubscription)) {
+	s.canceled = append(s.canceled, hook)
+}
+
+// Subscribe validates and stores a new subscription.
+func (s *BoletoSubscriptionScheduler) Subscribe(ctx context.Context, subscription *BoletoSubscription) (*BoletoSubscription, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"object":     subscription,
+	}
+
+	template := subscription.Template
+	if template.Account == nil || template.Document == "" || template.Amount <= 0 || subscription.StartDate.IsZero() {
+		logrus.WithFields(fields).WithError(ErrInvalidBoletoSubscription).Error("invalid boleto subscription")
+		return nil, ErrInvalidBoletoSubscription
+	}
+
+	if subscription.Frequency == "" {
+		subscription.Frequency = BoletoSubscriptionMonthly
+	}
+
+	if (subscription.Frequency != BoletoSubscriptionMonthly && subscription.Frequency != BoletoSubscriptionInterval) ||
+		(subscription.Frequency == BoletoSubscriptionInterval && subscription.IntervalDays <= 0) {
+		logrus.WithFields(fields).WithError(ErrInvalidBoletoSubscription).Error("invalid boleto subscription frequency")
+		return nil, ErrInvalidBoletoSubscription
+	}
+
+	if subscription.EndDate != nil && subscription.EndDate.Before(subscription.StartDate) {
+		logrus.WithFields(fields).WithError(ErrInvalidBoletoSubscription).Error("invalid boleto subscription end date")
+		return nil, ErrInvalidBoletoSubscription
+	}
+
+	if subscription.IssueDaysBefore <= 0 {
+		subscription.IssueDaysBefore = 10
+	}
+
+	subscription.StartDate = startOfDay(subscription.StartDate)
+	if subscription.EndDate != nil {
+		endDate := startOfDay(*subscription.EndDate)
+		subscription.EndDate = &endDate
+	}
+
+	now := time.Now().UTC()
+
+	subscription.ID = uuid.New().String()
+	subscription.AccountNumber = template.Account.Number
+	subscription.Status = BoletoSubscriptionActive
+	subscription.Occurrence = 1
+	subscription.DueDate = s.dueDate(subscription, 1)
+	subscription.Issues = []BoletoSubscriptionIssue{}
+	subscription.CreatedAt = now
+	subscription.UpdatedAt = now
+	s.schedule(subscription)
+
+	if err := s.store.Create(ctx, subscription); err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error creating boleto subscription")
+		return nil, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("next_run", subscription.NextRun).
+		Info("boleto subscription created")
+
+	return subscription, nil
+}
+
+// Cancel stops the next boletos of the subscription, the boletos already issued are kept.
+func (s *BoletoSubscriptionScheduler) Cancel(ctx context.Context, id string) (*BoletoSubscription, error) {
+	subscription, err := s.store.Cancel(ctx, id, time.Now().UTC())
+	if err != nil {
+		logrus.WithField("request_id", GetRequestID(ctx)).
+			WithField("subscription_id", id).
+			WithError(err).Error("error canceling boleto subscription")
+		return nil, err
+	}
+
+	for _, hook := range s.canceled {
+		hook(ctx, subscription)
+	}
+
+	return subscription, nil
+}
+
+// Start runs the due subscriptions at each interval until the context is done.
+func (s *BoletoSubscriptionScheduler) Start(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, s.config.Owner, "error running boleto subscriptions", s.RunOnce)
+}
+
+// RunOnce runs the subscriptions due at now and returns how many were processed by this instance.
+func (s *BoletoSubscriptionScheduler) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.store.Due(ctx, now, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	items := make([]leasedItem, len(due))
+	for i, item := range due {
+		id := item.ID
+
+		var subscription *BoletoSubscription
+		items[i] = leasedItem{
+			ID: id,
+			Acquire: func(at time.Time) (bool, error) {
+				var err error
+				subscription, err = s.store.Acquire(ctx, id, s.config.Owner, at, s.config.Lease)
+				return subscription != nil, err
+			},
+			Run: func(started time.Time) error {
+				return s.run(GenerateNewRequestID(ctx), subscription, now, started)
+			},
+			Release: func() error {
+				return s.store.Release(ctx, subscription, s.config.Owner)
+			},
+		}
+	}
+
+	// a subscription left to the lease created no boleto
+	return runLeased(leasedRun{
+		Field:    "subscription_id",
+		Kept:     "error keeping boleto subscription before the boleto",
+		Released: "error releasing boleto subscription",
+	}, now, items)
+}
+
+// run issues the due boletos of the subscription. It returns an error only when the pending
+// issue could not be kept, the boleto is not created and the subscription must not be released.
+func (s *BoletoSubscriptionScheduler) run(ctx context.Context, subscription *BoletoSubscription, now time.Time,
+	started time.Time) error {
+
+	subscription.UpdatedAt = now
+
+	for i := range subscription.Issues {
+		issue := &subscription.Issues[i]
+		if issue.Status == BoletoSubscriptionIssued && !issue.CancelAt.IsZero() && !issue.CancelAt.After(now) {
+			s.autoCancel(ctx, subscription, issue, now)
+		}
+	}
+
+	for !s.exhausted(subscription) && !s.issueDate(subscription.DueDate, subscription).After(now) {
+		done, err := s.issue(ctx, subscription, now, started)
+		if err != nil {
+			return err
+		}
+
+		if !done {
+			// retries or reconciles the occurrence later
+			subscription.NextRun = now.Add(s.config.RetryDelay)
+			return nil
+		}
+	}
+
+	s.schedule(subscription)
+	return nil
+}
+
+// issue creates the boleto of the current occurrence and moves to the next one. It returns
+// false when the occurrence will be retried, and an error when the pending issue was not kept.
+// The pending issue is kept at the store before the boleto is created, so a boleto created by
+// a run that lost its lease is never created again before being reconciled.
+func (s *BoletoSubscriptionScheduler) issue(ctx context.Context, subscription *BoletoSubscription, now time.Time,
+	started time.Time) (bool, error) {
+
+	alias := BoletoSubscriptionAlias(subscription, subscription.Occurrence)
+
+	fields := logrus.Fields{
+		"request_id":      GetRequestID(ctx),
+		"subscription_id": subscription.ID,
+		"occurrence":      subscription.Occurrence,
+		"alias":           alias,
+	}
+
+	// the boleto of the occurrence was already issued, or created without an answer
+	for i, issue := range subscription.Issues {
+		if issue.Alias != alias {
+			continue
+		}
+
+		if issue.Status != BoletoSubscriptionIssuePending {
+			s.advance(subscription)
+			return true, nil
+		}
+
+		create, err := s.reconcile(ctx, subscription, i, now)
+		if err != nil {
+			return false, nil
+		}
+
+		if !create {
+			return true, nil
+		}
+		break
+	}
+
+	issue := BoletoSubscriptionIssue{
+		Occurrence: subscription.Occurrence,
+		Alias:      alias,
+		DueDate:    subscription.DueDate,
+		IssuedAt:   now,
+		UpdatedAt:  now,
+	}
+
+	if subscription.DueDate.Before(startOfDay(now)) {
+		issue.Status = BoletoSubscriptionIssueSkipped
+		logrus.WithFields(fields).Warn("boleto subscription occurrence skipped")
+		s.fail(ctx, subscription, issue)
+		return true, nil
+	}
+
+	subscription.Attempts++
+
+	issue.Status = BoletoSubscriptionIssuePending
+	subscription.Issues = append(subscription.Issues, issue)
+	pending := len(subscription.Issues) - 1
+
+	if err := s.store.Checkpoint(ctx, subscription, s.config.Owner, now.Add(time.Since(started)), s.config.Lease); err != nil {
+		return false, err
+	}
+
+	response, err := s.boletos.CreateBankslip(ctx, s.request(subscription, alias))
+	if err != nil && !isRejectedRequest(err) {
+		logrus.WithFields(fields).
+			WithError(err).Warn("boleto subscription occurrence not confirmed, it will be reconciled")
+		return false, nil
+	}
+
+	subscription.Issues = append(subscription.Issues[:pending], subscription.Issues[pending+1:]...)
+
+	if err != nil && isTransientError(err, true) && subscription.Attempts < s.config.MaxAttempts {
+		logrus.WithFields(fields).
+			WithField("attempts", subscription.Attempts).
+			WithError(err).Warn("boleto subscription will retry")
+		return false, nil
+	}
+
+	if err != nil {
+		issue.Status = BoletoSubscriptionIssueFailed
+		issue.Error = err.Error()
+		logrus.WithFields(fields).WithError(err).Error("boleto subscription occurrence failed")
+		s.fail(ctx, subscription, issue)
+		return true, nil
+	}
+
+	s.confirm(ctx, subscription, issue, response.AuthenticationCode)
+	return true, nil
+}
+
+// reconcile resolves the pending issue at index and reports whether its boleto must be created,
+// when Bankly never created it. It returns an error when the issue is reconciled later.
+func (s *BoletoSubscriptionScheduler) reconcile(ctx context.Context, subscription *BoletoSubscription, index int,
+	now time.Time) (bool, error) {
+
+	issue := subscription.Issues[index]
+	fields := logrus.Fields{
+		"request_id":      GetRequestID(ctx),
+		"subscription_id": subscription.ID,
+		"occurrence":      issue.Occurrence,
+		"alias":           issue.Alias,
+	}
+
+	subscription.Issues = append(subscription.Issues[:index], subscription.Issues[index+1:]...)
+	issue.UpdatedAt = now
+
+	if s.config.Reconcile == nil {
+		issue.Status = BoletoSubscriptionIssueFailed
+		issue.Error = ErrBoletoSubscriptionUnconfirmed.Error()
+		logrus.WithFields(fields).WithError(ErrBoletoSubscriptionUnconfirmed).Error("boleto subscription occurrence failed")
+		s.fail(ctx, subscription, issue)
+		return false, nil
+	}
+
+	authenticationCode, err := s.config.Reconcile(ctx, subscription, issue)
+	if err == ErrBoletoSubscriptionNotIssued {
+		logrus.WithFields(fields).Info("boleto subscription occurrence not created by bankly, it will be created")
+		return true, nil
+	} else if err != nil {
+		subscription.Issues = append(subscription.Issues, issue)
+		logrus.WithFields(fields).WithError(err).Error("error reconciling boleto subscription occurrence")
+		return false, err
+	}
+
+	s.confirm(ctx, subscription, issue, authenticationCode)
+	return false, nil
+}
+
+// confirm records the boleto created at Bankly and moves to the next occurrence.
+func (s *BoletoSubscriptionScheduler) confirm(ctx context.Context, subscription *BoletoSubscription,
+	issue BoletoSubscriptionIssue, authenticationCode string) {
+
+	fields := logrus.Fields{
+		"request_id":      GetRequestID(ctx),
+		"subscription_id": subscription.ID,
+		"occurrence":      issue.Occurrence,
+		"alias":           issue.Alias,
+	}
+
+	issue.Status = BoletoSubscriptionIssued
+	issue.AuthenticationCode = authenticationCode
+	if subscription.AutoCancelAfterDays > 0 {
+		issue.CancelAt = subscription.DueDate.AddDate(0, 0, subscription.AutoCancelAfterDays)
+	}
+
+	logrus.WithFields(fields).
+		WithField("authentication_code", issue.AuthenticationCode).
+		Info("boleto subscription occurrence issued")
+
+	subscription.Issues = append(subscription.Issues, issue)
+	s.advance(subscription)
+
+	for _, hook := range s.issued {
+		hook(ctx, subscription, issue)
+	}
+}
+
+func (s *BoletoSubscriptionScheduler) fail(ctx context.Context, subscription *BoletoSubscription, issue BoletoSubscriptionIssue) {
+	subscription.Issues = append(subscription.Issues, issue)
+	s.advance(subscription)
+
+	for _, hook := range s.failed {
+		hook(ctx, subscription, issue)
+	}
+}
+
+// autoCancel cancels the boleto when it is still unpaid, the errors are retried at the next run.
+func (s *BoletoSubscriptionScheduler) autoCancel(ctx context.Context, subscription *BoletoSubscription,
+	issue *BoletoSubscriptionIssue, now time.Time) {
+
+	fields := logrus.Fields{
+		"request_id":          GetRequestID(ctx),
+		"subscription_id":     subscription.ID,
+		"occurrence":          issue.Occurrence,
+		"authentication_code": issue.AuthenticationCode,
+	}
+
+	issue.UpdatedAt = now
+
+	detail, err := s.boletos.FindBankslip(ctx, &FindBoletoRequest{
+		APIVersion:         subscription.Template.APIVersion,
+		AuthenticationCode: issue.AuthenticationCode,
+		Account:            subscription.Template.Account,
+	})
+	if err != nil {
+		issue.Error = err.Error()
+		issue.CancelAt = now.Add(s.config.RetryDelay)
+		logrus.WithFields(fields).WithError(err).Error("error finding boleto to auto cancel")
+		return
+	}
+
+	status := strings.ToLower(detail.Status)
+	if len(detail.Payments) > 0 || strings.HasPrefix(status, "paid") || strings.HasPrefix(status, "settled") {
+		issue.Status = BoletoSubscriptionIssuePaid
+		issue.Error = ""
+		return
+	}
+
+	if !strings.HasPrefix(status, "cancel") {
+		err = s.boletos.CancelBankslip(ctx, &CancelBoletoRequest{
+			APIVersion:         subscription.Template.APIVersion,
+			AuthenticationCode: issue.AuthenticationCode,
+			Account:            subscription.Template.Account,
+		})
+		if err != nil {
+			issue.Error = err.Error()
+			issue.CancelAt = now.Add(s.config.RetryDelay)
+			logrus.WithFields(fields).WithError(err).Error("error auto canceling boleto")
+			return
+		}
+	}
+
+	issue.Status = BoletoSubscriptionIssueCanceled
+	issue.Error = ""
+
+	logrus.WithFields(fields).Info("unpaid boleto auto canceled")
+
+	for _, hook := range s.autoCanceled {
+		hook(ctx, subscription, *issue)
+	}
+}
+
+// request builds the boleto of the current occurrence from the template.
+func (s *BoletoSubscriptionScheduler) request(subscription *BoletoSubscription, alias string) *BoletoRequest {
+	request := subscription.Template
+	dueDate := subscription.DueDate
+
+	request.DueDate = dueDate
+	request.Alias = &alias
+
+	if request.Fine != nil {
+		fine := *request.Fine
+		fine.StartDate = dueDate.AddDate(0, 0, 1)
+		request.Fine = &fine
+	}
+
+	if request.Interest != nil {
+		interest := *request.Interest
+		interest.StartDate = dueDate.AddDate(0, 0, 1)
+		request.Interest = &interest
+	}
+
+	if request.Discount != nil {
+		discount := *request.Discount
+		discount.LimitDate = dueDate.AddDate(0, 0, -subscription.DiscountDays)
+		request.Discount = &discount
+	}
+
+	if request.Discounts != nil {
+		discounts := *request.Discounts
+		discounts.LimitDate = dueDate.AddDate(0, 0, -subscription.DiscountDays)
+		request.Discounts = &discounts
+	}
+
+	request.ClosePayment = time.Time{}
+	if subscription.CloseDays > 0 {
+		request.ClosePayment = dueDate.AddDate(0, 0, subscription.CloseDays)
+	}
+
+	return &request
+}
+
+// advance moves the subscription to the next occurrence.
+func (s *BoletoSubscriptionScheduler) advance(subscription *BoletoSubscription) {
+	subscription.Attempts = 0
+	subscription.Occurrence++
+	subscription.DueDate = s.dueDate(subscription, subscription.Occurrence)
+}
+
+// exhausted reports whether all the boletos of the subscription were issued.
+func (s *BoletoSubscriptionScheduler) exhausted(subscription *BoletoSubscription) bool {
+	if subscription.MaxOccurrences > 0 && subscription.Occurrence > subscription.MaxOccurrences {
+		return true
+	}
+	return subscription.EndDate != nil && s.nominalDueDate(subscription, subscription.Occurrence).After(*subscription.EndDate)
+}
+
+// schedule sets the next run at the next issue or auto cancel, or finishes the subscription.
+func (s *BoletoSubscriptionScheduler) schedule(subscription *BoletoSubscription) {
+	next := time.Time{}
+	if !s.exhausted(subscription) {
+		next = s.issueDate(subscription.DueDate, subscription)
+	}
+
+	for _, issue := range subscription.Issues {
+		if issue.Status != BoletoSubscriptionIssued || issue.CancelAt.IsZero() {
+			continue
+		}
+		if next.IsZero() || issue.CancelAt.Before(next) {
+			next = issue.CancelAt
+		}
+	}
+
+	if next.IsZero() {
+		subscription.Status = BoletoSubscriptionFinished
+		return
+	}
+
+	subscription.NextRun = next
+}
+
+func (s *BoletoSubscriptionScheduler) issueDate(dueDate time.Time, subscription *BoletoSubscription) time.Time {
+	return dueDate.AddDate(0, 0, -subscription.IssueDaysBefore)
+}
+
+// dueDate returns the due date of the occurrence moved to the next business day.
+func (s *BoletoSubscriptionScheduler) dueDate(subscription *BoletoSubscription, occurrence int) time.Time {
+	return calendar.NextBusinessDay(s.config.Calendar, s.nominalDueDate(subscription, occurrence))
+}
+
+// nominalDueDate keeps the day of the start date at the months without that day (31 becomes 30, 28 or 29).
+func (s *BoletoSubscriptionScheduler) nominalDueDate(subscription *BoletoSubscription, occurrence int) time.Time {
+	start := subscription.StartDate
+
+	if subscription.Frequency == BoletoSubscriptionInterval {
+		return start.AddDate(0, 0, subscription.IntervalDays*(occurrence-1))
+	}
+
+	first := time.Date(start.Year(), start.Month()+time.Month(occurrence-1), 1, 0, 0, 0, 0, start.Location())
+	last := first.AddDate(0, 1, -1).Day()
+
+	day := start.Day()
+	if day > last {
+		day = last
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
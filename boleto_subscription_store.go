@@ -0,0 +1,330 @@
+package bankly
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BoletoSubscriptionStore persists the subscriptions and the leases of the workers
+type BoletoSubscriptionStore interface {
+	Create(ctx context.Context, subscription *BoletoSubscription) error
+	Get(ctx context.Context, id string) (*BoletoSubscription, error)
+	ListByAccount(ctx context.Context, accountNumber string) ([]*BoletoSubscription, error)
+	// Due returns the active subscriptions with the next run until now
+	Due(ctx context.Context, now time.Time, limit int) ([]*BoletoSubscription, error)
+	// Acquire leases the subscription to the owner when it is still due and not leased
+	// by another owner. It returns nil when the lease is not acquired.
+	Acquire(ctx context.Context, id string, owner string, now time.Time, lease time.Duration) (*BoletoSubscription, error)
+	// Checkpoint saves the subscription and renews the lease while the owner still holds it at now,
+	// otherwise it returns ErrBoletoSubscriptionLeaseLost.
+	Checkpoint(ctx context.Context, subscription *BoletoSubscription, owner string, now time.Time, lease time.Duration) error
+	// Release saves the subscription and releases the lease, it returns ErrBoletoSubscriptionLeaseLost
+	// when the owner does not hold the lease anymore.
+	Release(ctx context.Context, subscription *BoletoSubscription, owner string) error
+	// Cancel cancels an active subscription not leased at the moment and without a boleto
+	// waiting for confirmation.
+	Cancel(ctx context.Context, id string, now time.Time) (*BoletoSubscription, error)
+}
+
+// memoryBoletoSubscriptionStore ...
+type memoryBoletoSubscriptionStore struct {
+	mutex         sync.Mutex
+	subscriptions map[string]*BoletoSubscription
+}
+
+// NewMemoryBoletoSubscriptionStore returns a store for a single instance or tests.
+func NewMemoryBoletoSubscriptionStore() BoletoSubscriptionStore {
+	return &memoryBoletoSubscriptionStore{subscriptions: map[string]*BoletoSubscription{}}
+}
+
+func (m *memoryBoletoSubscriptionStore) Create(ctx context.Context, subscription *BoletoSubscription) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.subscriptions[subscription.ID] = copyBoletoSubscription(subscription)
+	return nil
+}
+
+func (m *memoryBoletoSubscriptionStore) Get(ctx context.Context, id string) (*BoletoSubscription, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	subscription, ok := m.subscriptions[id]
+	if !ok {
+		return nil, ErrBoletoSubscriptionNotFound
+	}
+	return copyBoletoSubscription(subscription), nil
+}
+
+func (m *memoryBoletoSubscriptionStore) ListByAccount(ctx context.Context, accountNumber string) ([]*BoletoSubscription, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := []*BoletoSubscription{}
+	for _, subscription := range m.subscriptions {
+		if subscription.AccountNumber == accountNumber {
+			response = append(response, copyBoletoSubscription(subscription))
+		}
+	}
+
+	sort.Slice(response, func(i, j int) bool { return response[i].CreatedAt.Before(response[j].CreatedAt) })
+	return response, nil
+}
+
+func (m *memoryBoletoSubscriptionStore) Due(ctx context.Context, now time.Time, limit int) ([]*BoletoSubscription, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := []*BoletoSubscription{}
+	for _, subscription := range m.subscriptions {
+		if subscription.Status == BoletoSubscriptionActive && !subscription.NextRun.After(now) {
+			response = append(response, copyBoletoSubscription(subscription))
+		}
+	}
+
+	sort.Slice(response, func(i, j int) bool { return response[i].NextRun.Before(response[j].NextRun) })
+	if limit > 0 && len(response) > limit {
+		response = response[:limit]
+	}
+	return response, nil
+}
+
+func (m *memoryBoletoSubscriptionStore) Acquire(ctx context.Context, id string, owner string, now time.Time,
+	lease time.Duration) (*BoletoSubscription, error) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	subscription, ok := m.subscriptions[id]
+	if !ok {
+		return nil, ErrBoletoSubscriptionNotFound
+	}
+
+	if subscription.Status != BoletoSubscriptionActive || subscription.NextRun.After(now) {
+		return nil, nil
+	}
+
+	if subscription.leasedByOther(owner, now) {
+		return nil, nil
+	}
+
+	subscription.lock(owner, now, lease)
+
+	return copyBoletoSubscription(subscription), nil
+}
+
+func (m *memoryBoletoSubscriptionStore) Checkpoint(ctx context.Context, subscription *BoletoSubscription, owner string,
+	now time.Time, lease time.Duration) error {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current, ok := m.subscriptions[subscription.ID]
+	if !ok {
+		return ErrBoletoSubscriptionNotFound
+	}
+
+	if !current.heldBy(owner, now) {
+		return ErrBoletoSubscriptionLeaseLost
+	}
+
+	saved := copyBoletoSubscription(subscription)
+	saved.lock(owner, now, lease)
+	m.subscriptions[subscription.ID] = saved
+
+	return nil
+}
+
+func (m *memoryBoletoSubscriptionStore) Release(ctx context.Context, subscription *BoletoSubscription, owner string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current, ok := m.subscriptions[subscription.ID]
+	if !ok {
+		return ErrBoletoSubscriptionNotFound
+	}
+
+	if current.LockedBy != owner {
+		return ErrBoletoSubscriptionLeaseLost
+	}
+
+	released := copyBoletoSubscription(subscription)
+	released.unlock()
+	m.subscriptions[subscription.ID] = released
+
+	return nil
+}
+
+func (m *memoryBoletoSubscriptionStore) Cancel(ctx context.Context, id string, now time.Time) (*BoletoSubscription, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	subscription, ok := m.subscriptions[id]
+	if !ok {
+		return nil, ErrBoletoSubscriptionNotFound
+	}
+
+	if subscription.Status != BoletoSubscriptionActive {
+		return nil, ErrBoletoSubscriptionNotActive
+	}
+
+	if subscription.leased(now) || hasPendingIssue(subscription) {
+		return nil, ErrBoletoSubscriptionLocked
+	}
+
+	subscription.Status = BoletoSubscriptionCanceled
+	subscription.UpdatedAt = now
+
+	return copyBoletoSubscription(subscription), nil
+}
+
+func hasPendingIssue(subscription *BoletoSubscription) bool {
+	for _, issue := range subscription.Issues {
+		if issue.Status == BoletoSubscriptionIssuePending {
+			return true
+		}
+	}
+	return false
+}
+
+func copyBoletoSubscription(subscription *BoletoSubscription) *BoletoSubscription {
+	response := *subscription
+	response.Issues = append([]BoletoSubscriptionIssue{}, subscription.Issues...)
+	if subscription.EndDate != nil {
+		endDate := *subscription.EndDate
+		response.EndDate = &endDate
+	}
+	return &response
+}
+
+// mongoBoletoSubscriptionStore ...
+type mongoBoletoSubscriptionStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoBoletoSubscriptionStore returns a store shared by many worker instances.
+// The collection should be indexed by status and nextRun.
+func NewMongoBoletoSubscriptionStore(collection *mongo.Collection) BoletoSubscriptionStore {
+	return &mongoBoletoSubscriptionStore{collection: collection}
+}
+
+func (m *mongoBoletoSubscriptionStore) Create(ctx context.Context, subscription *BoletoSubscription) error {
+	_, err := m.collection.InsertOne(ctx, subscription)
+	return err
+}
+
+func (m *mongoBoletoSubscriptionStore) Get(ctx context.Context, id string) (*BoletoSubscription, error) {
+	subscription := new(BoletoSubscription)
+
+	err := m.collection.FindOne(ctx, bson.M{"_id": id}).Decode(subscription)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrBoletoSubscriptionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (m *mongoBoletoSubscriptionStore) ListByAccount(ctx context.Context, accountNumber string) ([]*BoletoSubscription, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	return m.find(ctx, bson.M{"accountNumber": accountNumber}, opts)
+}
+
+func (m *mongoBoletoSubscriptionStore) Due(ctx context.Context, now time.Time, limit int) ([]*BoletoSubscription, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "nextRun", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	filter := bson.M{
+		"status":  BoletoSubscriptionActive,
+		"nextRun": bson.M{"$lte": now},
+	}
+
+	return m.find(ctx, filter, opts)
+}
+
+func (m *mongoBoletoSubscriptionStore) Acquire(ctx context.Context, id string, owner string, now time.Time,
+	lease time.Duration) (*BoletoSubscription, error) {
+
+	filter := bson.M{
+		"_id":     id,
+		"status":  BoletoSubscriptionActive,
+		"nextRun": bson.M{"$lte": now},
+		"$or":     mongoLeaseFree(now, owner),
+	}
+
+	subscription := new(BoletoSubscription)
+
+	found, err := mongoFindOneAndUpdate(ctx, m.collection, filter, mongoLeaseLock(owner, now, lease), subscription)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+func (m *mongoBoletoSubscriptionStore) Checkpoint(ctx context.Context, subscription *BoletoSubscription, owner string,
+	now time.Time, lease time.Duration) error {
+
+	saved := copyBoletoSubscription(subscription)
+	saved.lock(owner, now, lease)
+
+	return mongoReplaceLeased(ctx, m.collection, mongoLeaseHeld(subscription.ID, owner, now), saved, ErrBoletoSubscriptionLeaseLost)
+}
+
+func (m *mongoBoletoSubscriptionStore) Release(ctx context.Context, subscription *BoletoSubscription, owner string) error {
+	released := copyBoletoSubscription(subscription)
+	released.unlock()
+
+	filter := bson.M{"_id": subscription.ID, "lockedBy": owner}
+	return mongoReplaceLeased(ctx, m.collection, filter, released, ErrBoletoSubscriptionLeaseLost)
+}
+
+func (m *mongoBoletoSubscriptionStore) Cancel(ctx context.Context, id string, now time.Time) (*BoletoSubscription, error) {
+	filter := bson.M{
+		"_id":           id,
+		"status":        BoletoSubscriptionActive,
+		"issues.status": bson.M{"$ne": BoletoSubscriptionIssuePending},
+		"$or":           mongoLeaseFree(now),
+	}
+
+	update := bson.M{"$set": bson.M{"status": BoletoSubscriptionCanceled, "updatedAt": now}}
+
+	subscription := new(BoletoSubscription)
+
+	found, err := mongoFindOneAndUpdate(ctx, m.collection, filter, update, subscription)
+	if err != nil {
+		return nil, err
+	} else if found {
+		return subscription, nil
+	}
+
+	// tells apart the reasons the subscription was not canceled
+	current, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Status != BoletoSubscriptionActive {
+		return nil, ErrBoletoSubscriptionNotActive
+	}
+
+	return nil, ErrBoletoSubscriptionLocked
+}
+
+func (m *mongoBoletoSubscriptionStore) find(ctx context.Context, filter interface{}, opts *options.FindOptions) ([]*BoletoSubscription, error) {
+	response := []*BoletoSubscription{}
+	if err := mongoFind(ctx, m.collection, filter, opts, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
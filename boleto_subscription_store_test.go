@@ -0,0 +1,70 @@
+package bankly_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMongoBoletoSubscriptionStore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := context.Background()
+	now := time.Date(2026, time.November, 10, 12, 0, 0, 0, time.UTC)
+
+	mt.Run("acquire", func(mt *mtest.T) {
+		store := bankly.NewMongoBoletoSubscriptionStore(mt.Coll)
+
+		mt.AddMockResponses(mongoValue(bson.D{{Key: "_id", Value: "sub-1"}, {Key: "lockedBy", Value: "worker-1"}}))
+		subscription, err := store.Acquire(ctx, "sub-1", "worker-1", now, time.Minute)
+		assert.NoError(mt, err)
+		assert.Equal(mt, "worker-1", subscription.LockedBy)
+
+		command := mt.GetStartedEvent().Command
+		owners, expired := mongoLeaseQuery(command)
+		assert.Equal(mt, []string{"", "worker-1"}, owners)
+		assert.Equal(mt, now, expired)
+		assert.Equal(mt, string(bankly.BoletoSubscriptionActive), command.Lookup("query", "status").StringValue())
+
+		mt.AddMockResponses(mongoValue(nil))
+		subscription, err = store.Acquire(ctx, "sub-1", "worker-2", now, time.Minute)
+		assert.NoError(mt, err)
+		assert.Nil(mt, subscription)
+	})
+
+	mt.Run("checkpoint and release", func(mt *mtest.T) {
+		store := bankly.NewMongoBoletoSubscriptionStore(mt.Coll)
+		subscription := &bankly.BoletoSubscription{ID: "sub-1", Status: bankly.BoletoSubscriptionActive}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		assert.Equal(mt, bankly.ErrBoletoSubscriptionLeaseLost, store.Checkpoint(ctx, subscription, "worker-1", now, time.Minute))
+		assert.Equal(mt, now, mt.GetStartedEvent().Command.Lookup("updates", "0", "q", "lockedUntil", "$gt").Time().UTC())
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		assert.NoError(mt, store.Release(ctx, subscription, "worker-1"))
+		assert.Equal(mt, "worker-1", mt.GetStartedEvent().Command.Lookup("updates", "0", "q", "lockedBy").StringValue())
+	})
+
+	mt.Run("cancel", func(mt *mtest.T) {
+		store := bankly.NewMongoBoletoSubscriptionStore(mt.Coll)
+
+		// an issue pending at Bankly keeps the subscription
+		mt.AddMockResponses(mongoValue(nil), mongoCursor(mt, bson.D{{Key: "_id", Value: "sub-1"}, {Key: "status", Value: bankly.BoletoSubscriptionActive}}))
+		_, err := store.Cancel(ctx, "sub-1", now)
+		assert.Equal(mt, bankly.ErrBoletoSubscriptionLocked, err)
+
+		command := mt.GetStartedEvent().Command
+		owners, _ := mongoLeaseQuery(command)
+		assert.Equal(mt, []string{""}, owners)
+		assert.Equal(mt, string(bankly.BoletoSubscriptionIssuePending), command.Lookup("query", "issues.status", "$ne").StringValue())
+
+		mt.AddMockResponses(mongoValue(bson.D{{Key: "_id", Value: "sub-1"}, {Key: "status", Value: bankly.BoletoSubscriptionCanceled}}))
+		subscription, err := store.Cancel(ctx, "sub-1", now)
+		assert.NoError(mt, err)
+		assert.Equal(mt, bankly.BoletoSubscriptionCanceled, subscription.Status)
+	})
+}
@@ -0,0 +1,317 @@
+package bankly_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type fakeBoletoIssuer struct {
+	err      error
+	created  []*bankly.BoletoRequest
+	found    map[string]*bankly.BoletoDetailedResponse
+	canceled []string
+}
+
+func (f *fakeBoletoIssuer) CreateBankslip(ctx context.Context, model *bankly.BoletoRequest) (*bankly.BoletoResponse, error) {
+	f.created = append(f.created, model)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &bankly.BoletoResponse{AuthenticationCode: fmt.Sprintf("code-%d", len(f.created))}, nil
+}
+
+func (f *fakeBoletoIssuer) FindBankslip(ctx context.Context, model *bankly.FindBoletoRequest) (*bankly.BoletoDetailedResponse, error) {
+	detail, ok := f.found[model.AuthenticationCode]
+	if !ok {
+		return nil, bankly.ErrBoletoNotFound
+	}
+	return detail, nil
+}
+
+func (f *fakeBoletoIssuer) CancelBankslip(ctx context.Context, model *bankly.CancelBoletoRequest) error {
+	f.canceled = append(f.canceled, model.AuthenticationCode)
+	return nil
+}
+
+type BoletoSubscriptionTestSuite struct {
+	suite.Suite
+	assert    *assert.Assertions
+	ctx       context.Context
+	issuer    *fakeBoletoIssuer
+	store     bankly.BoletoSubscriptionStore
+	scheduler *bankly.BoletoSubscriptionScheduler
+}
+
+func TestBoletoSubscriptionTestSuite(t *testing.T) {
+	suite.Run(t, new(BoletoSubscriptionTestSuite))
+}
+
+func (s *BoletoSubscriptionTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.issuer = &fakeBoletoIssuer{found: map[string]*bankly.BoletoDetailedResponse{}}
+	s.store = bankly.NewMemoryBoletoSubscriptionStore()
+	s.scheduler = bankly.NewBoletoSubscriptionScheduler(s.issuer, s.store, bankly.BoletoSubscriptionSchedulerConfig{
+		Owner: "worker-1",
+	})
+}
+
+func (s *BoletoSubscriptionTestSuite) TestMonthlySubscription() {
+	subscription := buildBoletoSubscription(bankly.BoletoSubscriptionMonthly, date(2026, time.January, 31))
+	subscription.MaxOccurrences = 2
+	subscription.DiscountDays = 5
+
+	subscription, err := s.scheduler.Subscribe(s.ctx, subscription)
+	s.assert.NoError(err)
+	s.assert.Equal("189162", subscription.AccountNumber)
+	s.assert.Equal(date(2026, time.February, 2), subscription.DueDate) // january 31 is saturday
+	s.assert.Equal(date(2026, time.January, 23), subscription.NextRun)
+
+	var issued []bankly.BoletoSubscriptionIssue
+	s.scheduler.OnIssued(func(ctx context.Context, subscription *bankly.BoletoSubscription, issue bankly.BoletoSubscriptionIssue) {
+		issued = append(issued, issue)
+	})
+
+	processed, err := s.scheduler.RunOnce(s.ctx, date(2026, time.January, 23).Add(time.Hour))
+	s.assert.NoError(err)
+	s.assert.Equal(1, processed)
+
+	request := s.issuer.created[0]
+	s.assert.Equal("CT-42-001", *request.Alias)
+	s.assert.Equal(date(2026, time.February, 2), request.DueDate)
+	s.assert.Equal(date(2026, time.February, 3), request.Fine.StartDate)
+	s.assert.Equal(date(2026, time.February, 3), request.Interest.StartDate)
+	s.assert.Equal(date(2026, time.January, 28), request.Discount.LimitDate)
+	s.assert.True(request.ClosePayment.IsZero())
+	s.assert.Equal("code-1", issued[0].AuthenticationCode)
+
+	stored, err := s.store.Get(s.ctx, subscription.ID)
+	s.assert.NoError(err)
+	s.assert.Equal(2, stored.Occurrence)
+	s.assert.Equal(date(2026, time.March, 2), stored.DueDate) // february 28 is saturday
+	s.assert.Equal(date(2026, time.February, 20), stored.NextRun)
+	s.assert.Empty(stored.LockedBy)
+
+	processed, err = s.scheduler.RunOnce(s.ctx, date(2026, time.February, 19))
+	s.assert.NoError(err)
+	s.assert.Equal(0, processed)
+
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.February, 20))
+	s.assert.NoError(err)
+
+	stored, _ = s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(bankly.BoletoSubscriptionFinished, stored.Status)
+	s.assert.Len(stored.Issues, 2)
+	s.assert.Equal("CT-42-002", *s.issuer.created[1].Alias)
+}
+
+func (s *BoletoSubscriptionTestSuite) TestAutoCancel() {
+	subscription := buildBoletoSubscription(bankly.BoletoSubscriptionInterval, date(2026, time.October, 20))
+	subscription.IntervalDays = 15
+	subscription.MaxOccurrences = 2
+	subscription.AutoCancelAfterDays = 5
+	subscription.CloseDays = 3
+
+	subscription, err := s.scheduler.Subscribe(s.ctx, subscription)
+	s.assert.NoError(err)
+
+	var canceled []bankly.BoletoSubscriptionIssue
+	s.scheduler.OnAutoCanceled(func(ctx context.Context, subscription *bankly.BoletoSubscription, issue bankly.BoletoSubscriptionIssue) {
+		canceled = append(canceled, issue)
+	})
+
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 10))
+	s.assert.NoError(err)
+	s.assert.Equal(date(2026, time.October, 23), s.issuer.created[0].ClosePayment)
+
+	stored, _ := s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(date(2026, time.October, 25), stored.Issues[0].CancelAt)
+	s.assert.Equal(date(2026, time.October, 25), stored.NextRun)
+
+	// the first boleto is canceled unpaid and the second is issued at the same run
+	s.issuer.found["code-1"] = &bankly.BoletoDetailedResponse{Status: "Registered"}
+
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 25))
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"code-1"}, s.issuer.canceled)
+	s.assert.Len(canceled, 1)
+	s.assert.Len(s.issuer.created, 2)
+
+	stored, _ = s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(bankly.BoletoSubscriptionIssueCanceled, stored.Issues[0].Status)
+	s.assert.Equal(bankly.BoletoSubscriptionActive, stored.Status)
+	s.assert.Equal(date(2026, time.November, 9), stored.NextRun)
+
+	s.issuer.found["code-2"] = &bankly.BoletoDetailedResponse{Status: "Paid", Payments: []*bankly.BoletoPayment{{Amount: 150}}}
+
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.November, 9))
+	s.assert.NoError(err)
+	s.assert.Len(s.issuer.canceled, 1)
+
+	stored, _ = s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(bankly.BoletoSubscriptionIssuePaid, stored.Issues[1].Status)
+	s.assert.Equal(bankly.BoletoSubscriptionFinished, stored.Status)
+}
+
+func (s *BoletoSubscriptionTestSuite) TestDeduplicatesByAlias() {
+	subscription, err := s.scheduler.Subscribe(s.ctx, buildBoletoSubscription(bankly.BoletoSubscriptionMonthly, date(2026, time.November, 10)))
+	s.assert.NoError(err)
+
+	// the boleto of the first occurrence was issued by a run that lost its lease
+	stored, _ := s.store.Get(s.ctx, subscription.ID)
+	stored.Issues = append(stored.Issues, bankly.BoletoSubscriptionIssue{
+		Occurrence: 1,
+		Alias:      bankly.BoletoSubscriptionAlias(stored, 1),
+		Status:     bankly.BoletoSubscriptionIssued,
+	})
+	s.assert.NoError(s.store.Create(s.ctx, stored))
+
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 31))
+	s.assert.NoError(err)
+	s.assert.Len(s.issuer.created, 0)
+
+	stored, _ = s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(2, stored.Occurrence)
+	s.assert.Len(stored.Issues, 1)
+}
+
+func (s *BoletoSubscriptionTestSuite) TestUnconfirmed_NeverCreatedAgain() {
+	subscription, err := s.scheduler.Subscribe(s.ctx, buildBoletoSubscription(bankly.BoletoSubscriptionMonthly, date(2026, time.November, 10)))
+	s.assert.NoError(err)
+
+	var failed []bankly.BoletoSubscriptionIssue
+	s.scheduler.OnFailed(func(ctx context.Context, subscription *bankly.BoletoSubscription, issue bankly.BoletoSubscriptionIssue) {
+		failed = append(failed, issue)
+	})
+
+	s.issuer.err = bankly.ErrDefaultBoletos
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 31))
+	s.assert.NoError(err)
+
+	stored, _ := s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(bankly.BoletoSubscriptionIssuePending, stored.Issues[0].Status)
+
+	_, err = s.scheduler.Cancel(s.ctx, subscription.ID)
+	s.assert.Equal(bankly.ErrBoletoSubscriptionLocked, err)
+
+	s.issuer.err = nil
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 31).Add(time.Hour))
+	s.assert.NoError(err)
+	s.assert.Len(s.issuer.created, 1)
+	s.assert.Len(failed, 1)
+	s.assert.Equal(bankly.ErrBoletoSubscriptionUnconfirmed.Error(), failed[0].Error)
+
+	stored, _ = s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(2, stored.Occurrence)
+	s.assert.Len(stored.Issues, 1)
+	s.assert.Equal(bankly.BoletoSubscriptionIssueFailed, stored.Issues[0].Status)
+}
+
+func (s *BoletoSubscriptionTestSuite) TestUnconfirmed_Reconciled() {
+	reconciled := map[string]error{"CT-42-001": nil, "CT-42-002": bankly.ErrBoletoSubscriptionNotIssued}
+	s.scheduler = bankly.NewBoletoSubscriptionScheduler(s.issuer, s.store, bankly.BoletoSubscriptionSchedulerConfig{
+		Owner: "worker-1",
+		Reconcile: func(ctx context.Context, subscription *bankly.BoletoSubscription, issue bankly.BoletoSubscriptionIssue) (string, error) {
+			return "reconciled-code", reconciled[issue.Alias]
+		},
+	})
+
+	subscription, err := s.scheduler.Subscribe(s.ctx, buildBoletoSubscription(bankly.BoletoSubscriptionMonthly, date(2026, time.November, 10)))
+	s.assert.NoError(err)
+
+	s.issuer.err = bankly.ErrDefaultBoletos
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 31))
+	s.assert.NoError(err)
+
+	s.issuer.err = nil
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 31).Add(time.Hour))
+	s.assert.NoError(err)
+	s.assert.Len(s.issuer.created, 1)
+
+	stored, _ := s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(bankly.BoletoSubscriptionIssued, stored.Issues[0].Status)
+	s.assert.Equal("reconciled-code", stored.Issues[0].AuthenticationCode)
+
+	// the boleto of the second occurrence was never created by bankly
+	s.issuer.err = bankly.ErrDefaultBoletos
+	_, err = s.scheduler.RunOnce(s.ctx, stored.NextRun)
+	s.assert.NoError(err)
+
+	s.issuer.err = nil
+	_, err = s.scheduler.RunOnce(s.ctx, stored.NextRun.Add(time.Hour))
+	s.assert.NoError(err)
+	s.assert.Len(s.issuer.created, 3)
+
+	stored, _ = s.store.Get(s.ctx, subscription.ID)
+	s.assert.Len(stored.Issues, 2)
+	s.assert.Equal("code-3", stored.Issues[1].AuthenticationCode)
+}
+
+func (s *BoletoSubscriptionTestSuite) TestSkipsPastDueOccurrences() {
+	subscription, err := s.scheduler.Subscribe(s.ctx, buildBoletoSubscription(bankly.BoletoSubscriptionMonthly, date(2026, time.September, 10)))
+	s.assert.NoError(err)
+
+	var failed []bankly.BoletoSubscriptionIssue
+	s.scheduler.OnFailed(func(ctx context.Context, subscription *bankly.BoletoSubscription, issue bankly.BoletoSubscriptionIssue) {
+		failed = append(failed, issue)
+	})
+
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 3))
+	s.assert.NoError(err)
+	s.assert.Len(failed, 1)
+	s.assert.Equal(bankly.BoletoSubscriptionIssueSkipped, failed[0].Status)
+	s.assert.Len(s.issuer.created, 1)
+	s.assert.Equal(date(2026, time.October, 13), s.issuer.created[0].DueDate) // october 12 is a holiday
+
+	stored, _ := s.store.Get(s.ctx, subscription.ID)
+	s.assert.Equal(3, stored.Occurrence)
+}
+
+func (s *BoletoSubscriptionTestSuite) TestSubscribe_Invalid() {
+	subscription := buildBoletoSubscription(bankly.BoletoSubscriptionInterval, date(2026, time.November, 10))
+	_, err := s.scheduler.Subscribe(s.ctx, subscription)
+	s.assert.Equal(bankly.ErrInvalidBoletoSubscription, err)
+
+	subscription = buildBoletoSubscription(bankly.BoletoSubscriptionMonthly, date(2026, time.November, 10))
+	subscription.Template.Amount = 0
+	_, err = s.scheduler.Subscribe(s.ctx, subscription)
+	s.assert.Equal(bankly.ErrInvalidBoletoSubscription, err)
+}
+
+func (s *BoletoSubscriptionTestSuite) TestCancel() {
+	subscription, err := s.scheduler.Subscribe(s.ctx, buildBoletoSubscription(bankly.BoletoSubscriptionMonthly, date(2026, time.November, 10)))
+	s.assert.NoError(err)
+
+	canceled, err := s.scheduler.Cancel(s.ctx, subscription.ID)
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.BoletoSubscriptionCanceled, canceled.Status)
+
+	_, err = s.scheduler.Cancel(s.ctx, subscription.ID)
+	s.assert.Equal(bankly.ErrBoletoSubscriptionNotActive, err)
+
+	_, err = s.scheduler.Cancel(s.ctx, "unknown")
+	s.assert.Equal(bankly.ErrBoletoSubscriptionNotFound, err)
+}
+
+func buildBoletoSubscription(frequency bankly.BoletoSubscriptionFrequency, startDate time.Time) *bankly.BoletoSubscription {
+	return &bankly.BoletoSubscription{
+		Reference: "CT-42",
+		Frequency: frequency,
+		StartDate: startDate,
+		Template: bankly.BoletoRequest{
+			Account:  &bankly.Account{Branch: "0001", Number: "189162"},
+			Document: "16246241620",
+			Amount:   150,
+			Type:     bankly.Levy,
+			Fine:     &bankly.BoletoFine{Value: 2, Type: bankly.PercentFineType},
+			Interest: &bankly.BoletoInterest{Value: 1, Type: bankly.PercentInterestType},
+			Discount: &bankly.BoletoDiscounts{Value: 5, Type: bankly.FixedAmountDiscountType},
+		},
+	}
+}
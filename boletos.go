@@ -20,6 +20,8 @@ type Boletos struct {
 	session        Session
 	httpClient     *http.Client
 	authentication *Authentication
+	amendments     BoletoAmendmentStore
+	amendable      bool
 }
 
 // NewBoletos ...
@@ -28,6 +30,7 @@ func NewBoletos(httpClient *http.Client, session Session) *Boletos {
 		session:        session,
 		httpClient:     httpClient,
 		authentication: NewAuthentication(httpClient, session),
+		amendments:     NewMemoryBoletoAmendmentStore(),
 	}
 }
 
@@ -518,6 +521,12 @@ func (b *Boletos) SandboxSimulateBankslipPayment(ctx *context.Context, model *Sa
 
 // FilterBankslipByUpdateAt ...
 func (b *Boletos) FilterBankslipByUpdateAt(ctx context.Context, date time.Time) (*FilterBoletoResponse, error) {
+	return b.FilterBankslipByUpdateAtPage(ctx, date, "")
+}
+
+// FilterBankslipByUpdateAtPage returns the page of the boletos updated at the date, the first
+// one with an empty page token. The next pages are read with the NextPageToken of the response.
+func (b *Boletos) FilterBankslipByUpdateAtPage(ctx context.Context, date time.Time, pageToken string) (*FilterBoletoResponse, error) {
 
 	// only at 1.0 api version
 	apiVersion := "1.0"
@@ -526,6 +535,7 @@ func (b *Boletos) FilterBankslipByUpdateAt(ctx context.Context, date time.Time)
 		"request_id":  grok.GetRequestID(ctx),
 		"api_version": apiVersion,
 		"object":      date,
+		"page_token":  pageToken,
 	}
 
 	u, err := url.Parse(b.session.APIEndpoint)
@@ -538,6 +548,11 @@ func (b *Boletos) FilterBankslipByUpdateAt(ctx context.Context, date time.Time)
 	u.Path = path.Join(u.Path, BoletosPath)
 	u.Path = path.Join(u.Path, "searchstatus")
 	u.Path = path.Join(u.Path, url.QueryEscape(date.UTC().Format("2006-01-02")))
+	if pageToken != "" {
+		q := u.Query()
+		q.Set("pageToken", pageToken)
+		u.RawQuery = q.Encode()
+	}
 	endpoint := u.String()
 
 	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
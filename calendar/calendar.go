@@ -0,0 +1,160 @@
+package calendar
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	location     *time.Location
+	locationOnce sync.Once
+)
+
+// Location returns America/Sao_Paulo, or a fixed UTC-3 zone when the tz database is not available.
+func Location() *time.Location {
+	locationOnce.Do(func() {
+		loc, err := time.LoadLocation("America/Sao_Paulo")
+		if err != nil {
+			loc = time.FixedZone("BRT", -3*60*60)
+		}
+		location = loc
+	})
+	return location
+}
+
+// Calendar tells whether a day is a business day
+type Calendar interface {
+	IsBusinessDay(date time.Time) bool
+}
+
+// National is the calendar of the brazilian national banking holidays
+type National struct {
+	extra map[string]bool
+}
+
+// NewNational returns the national calendar with extra holidays, like local holidays
+// of the city where the payments are settled.
+func NewNational(extra ...time.Time) *National {
+	holidays := map[string]bool{}
+	for _, date := range extra {
+		holidays[dateKey(date)] = true
+	}
+	return &National{extra: holidays}
+}
+
+// IsBusinessDay ...
+func (n *National) IsBusinessDay(date time.Time) bool {
+	date = date.In(Location())
+
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+
+	return !n.IsHoliday(date)
+}
+
+// IsHoliday ...
+func (n *National) IsHoliday(date time.Time) bool {
+	date = date.In(Location())
+
+	if n.extra[dateKey(date)] {
+		return true
+	}
+
+	for _, holiday := range Holidays(date.Year()) {
+		if holiday.Month() == date.Month() && holiday.Day() == date.Day() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Holidays returns the national banking holidays of the year.
+func Holidays(year int) []time.Time {
+	easter := Easter(year)
+
+	holidays := []time.Time{
+		day(year, time.January, 1),
+		easter.AddDate(0, 0, -48), // carnival monday
+		easter.AddDate(0, 0, -47), // carnival tuesday
+		easter.AddDate(0, 0, -2),  // good friday
+		day(year, time.April, 21),
+		day(year, time.May, 1),
+		easter.AddDate(0, 0, 60), // corpus christi
+		day(year, time.September, 7),
+		day(year, time.October, 12),
+		day(year, time.November, 2),
+		day(year, time.November, 15),
+		day(year, time.December, 25),
+	}
+
+	// black consciousness day is a national holiday since 2024 (Lei 14.759/2023)
+	if year >= 2024 {
+		holidays = append(holidays, day(year, time.November, 20))
+	}
+
+	return holidays
+}
+
+// Easter returns the easter sunday of the year (anonymous gregorian algorithm).
+func Easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	dayOfMonth := (h+l-7*m+114)%31 + 1
+
+	return day(year, time.Month(month), dayOfMonth)
+}
+
+// NextBusinessDay returns the date when it is a business day, otherwise the next one.
+func NextBusinessDay(cal Calendar, date time.Time) time.Time {
+	for !cal.IsBusinessDay(date) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// PreviousBusinessDay returns the date when it is a business day, otherwise the previous one.
+func PreviousBusinessDay(cal Calendar, date time.Time) time.Time {
+	for !cal.IsBusinessDay(date) {
+		date = date.AddDate(0, 0, -1)
+	}
+	return date
+}
+
+// AddBusinessDays ...
+func AddBusinessDays(cal Calendar, date time.Time, days int) time.Time {
+	step := 1
+	if days < 0 {
+		step = -1
+		days = -days
+	}
+
+	for days > 0 {
+		date = date.AddDate(0, 0, step)
+		if cal.IsBusinessDay(date) {
+			days--
+		}
+	}
+
+	return date
+}
+
+func day(year int, month time.Month, dayOfMonth int) time.Time {
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, Location())
+}
+
+func dateKey(date time.Time) string {
+	return date.In(Location()).Format("2006-01-02")
+}
@@ -0,0 +1,45 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEaster(t *testing.T) {
+	assert.Equal(t, "2024-03-31", Easter(2024).Format("2006-01-02"))
+	assert.Equal(t, "2025-04-20", Easter(2025).Format("2006-01-02"))
+	assert.Equal(t, "2026-04-05", Easter(2026).Format("2006-01-02"))
+}
+
+func TestNational_IsBusinessDay(t *testing.T) {
+	national := NewNational()
+
+	assert.True(t, national.IsBusinessDay(date(2026, time.October, 19)))
+	assert.False(t, national.IsBusinessDay(date(2026, time.October, 18)))  // sunday
+	assert.False(t, national.IsBusinessDay(date(2026, time.October, 12)))  // nossa senhora aparecida
+	assert.False(t, national.IsBusinessDay(date(2026, time.February, 17))) // carnival tuesday
+	assert.False(t, national.IsBusinessDay(date(2026, time.April, 3)))     // good friday
+	assert.False(t, national.IsBusinessDay(date(2026, time.June, 4)))      // corpus christi
+	assert.False(t, national.IsBusinessDay(date(2026, time.November, 20))) // black consciousness
+	assert.True(t, national.IsBusinessDay(date(2023, time.November, 20)))
+	assert.False(t, NewNational(date(2026, time.January, 26)).IsBusinessDay(date(2026, time.January, 26)))
+
+	// 02:00 UTC is still the previous day at Sao Paulo
+	assert.False(t, national.IsBusinessDay(time.Date(2026, time.October, 13, 2, 0, 0, 0, time.UTC)))
+}
+
+func TestNextBusinessDay(t *testing.T) {
+	national := NewNational()
+
+	assert.Equal(t, date(2026, time.October, 13), NextBusinessDay(national, date(2026, time.October, 10)))
+	assert.Equal(t, date(2026, time.October, 9), PreviousBusinessDay(national, date(2026, time.October, 12)))
+	assert.Equal(t, date(2026, time.October, 19), NextBusinessDay(national, date(2026, time.October, 19)))
+	assert.Equal(t, date(2026, time.October, 15), AddBusinessDays(national, date(2026, time.October, 9), 3))
+	assert.Equal(t, date(2026, time.October, 8), AddBusinessDays(national, date(2026, time.October, 13), -2))
+}
+
+func date(year int, month time.Month, dayOfMonth int) time.Time {
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, Location())
+}
@@ -0,0 +1,94 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidWindow ...
+var ErrInvalidWindow = errors.New("invalid window, expected HH:MM or HH:MM:SS")
+
+// Window is a period of the business days at Sao Paulo, End is exclusive
+type Window struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+var (
+	// TEDWindow is the period to send TEDs to other banks
+	TEDWindow = Window{Start: 7 * time.Hour, End: 17 * time.Hour}
+	// BillPaymentWindow is the period to pay bills settled at the same day
+	BillPaymentWindow = Window{Start: 7 * time.Hour, End: 20 * time.Hour}
+)
+
+// NewWindow parses the start and end of the window, as the business hours returned by Bankly.
+func NewWindow(start, end string) (Window, error) {
+	startOffset, err := parseClock(start)
+	if err != nil {
+		return Window{}, err
+	}
+
+	endOffset, err := parseClock(end)
+	if err != nil {
+		return Window{}, err
+	}
+
+	if endOffset <= startOffset {
+		return Window{}, ErrInvalidWindow
+	}
+
+	return Window{Start: startOffset, End: endOffset}, nil
+}
+
+// String ...
+func (w Window) String() string {
+	return formatClock(w.Start) + "-" + formatClock(w.End)
+}
+
+// Contains checks only the time of the day, at Sao Paulo.
+func (w Window) Contains(date time.Time) bool {
+	offset := sinceMidnight(date.In(Location()))
+	return offset >= w.Start && offset < w.End
+}
+
+// IsWithinWindow returns true when the date is a business day and within the window.
+func IsWithinWindow(cal Calendar, window Window, date time.Time) bool {
+	return cal.IsBusinessDay(date) && window.Contains(date)
+}
+
+// NextWindowOpening returns the date when it is within the window, otherwise
+// the next time the window opens.
+func NextWindowOpening(cal Calendar, window Window, date time.Time) time.Time {
+	if IsWithinWindow(cal, window, date) {
+		return date
+	}
+
+	local := date.In(Location())
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, Location())
+
+	if !cal.IsBusinessDay(local) || sinceMidnight(local) >= window.End {
+		midnight = NextBusinessDay(cal, midnight.AddDate(0, 0, 1))
+	}
+
+	return midnight.Add(window.Start)
+}
+
+func sinceMidnight(date time.Time) time.Duration {
+	return time.Duration(date.Hour())*time.Hour +
+		time.Duration(date.Minute())*time.Minute +
+		time.Duration(date.Second())*time.Second
+}
+
+func parseClock(value string) (time.Duration, error) {
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if clock, err := time.Parse(layout, value); err == nil {
+			return sinceMidnight(clock), nil
+		}
+	}
+	return 0, ErrInvalidWindow
+}
+
+func formatClock(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset.Hours()), int(offset.Minutes())%60)
+}
@@ -0,0 +1,48 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWindow(t *testing.T) {
+	window, err := NewWindow("07:00:00", "20:30")
+	assert.NoError(t, err)
+	assert.Equal(t, Window{Start: 7 * time.Hour, End: 20*time.Hour + 30*time.Minute}, window)
+	assert.Equal(t, "07:00-20:30", window.String())
+
+	_, err = NewWindow("7h", "20:00")
+	assert.Equal(t, ErrInvalidWindow, err)
+
+	_, err = NewWindow("20:00", "07:00")
+	assert.Equal(t, ErrInvalidWindow, err)
+}
+
+func TestIsWithinWindow(t *testing.T) {
+	national := NewNational()
+
+	assert.True(t, IsWithinWindow(national, TEDWindow, at(2026, time.October, 19, 7, 0)))
+	assert.True(t, IsWithinWindow(national, TEDWindow, at(2026, time.October, 19, 16, 59)))
+	assert.False(t, IsWithinWindow(national, TEDWindow, at(2026, time.October, 19, 17, 0)))
+	assert.False(t, IsWithinWindow(national, TEDWindow, at(2026, time.October, 12, 10, 0)))
+
+	// 12:00 UTC is 09:00 at Sao Paulo
+	assert.True(t, IsWithinWindow(national, TEDWindow, time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestNextWindowOpening(t *testing.T) {
+	national := NewNational()
+
+	within := at(2026, time.October, 19, 10, 0)
+	assert.Equal(t, within, NextWindowOpening(national, TEDWindow, within))
+	assert.Equal(t, at(2026, time.October, 19, 7, 0), NextWindowOpening(national, TEDWindow, at(2026, time.October, 19, 5, 0)))
+	assert.Equal(t, at(2026, time.October, 20, 7, 0), NextWindowOpening(national, TEDWindow, at(2026, time.October, 19, 18, 0)))
+	assert.Equal(t, at(2026, time.October, 13, 7, 0), NextWindowOpening(national, TEDWindow, at(2026, time.October, 9, 17, 30)))
+	assert.Equal(t, at(2026, time.October, 13, 7, 0), NextWindowOpening(national, TEDWindow, at(2026, time.October, 12, 6, 0)))
+}
+
+func at(year int, month time.Month, dayOfMonth, hour, minute int) time.Time {
+	return time.Date(year, month, dayOfMonth, hour, minute, 0, 0, Location())
+}
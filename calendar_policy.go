@@ -0,0 +1,87 @@
+package bankly
+
+import (
+	"context"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/sirupsen/logrus"
+)
+
+// CalendarPolicyMode ...
+type CalendarPolicyMode string
+
+const (
+	// CalendarReject returns ErrOutOfServicePeriod without calling Bankly
+	CalendarReject CalendarPolicyMode = "REJECT"
+	// CalendarDefer calls the Defer hook with the next window opening and returns ErrOperationDeferred
+	CalendarDefer CalendarPolicyMode = "DEFER"
+)
+
+// CalendarPolicy checks the banking window before sending an operation to Bankly
+type CalendarPolicy struct {
+	Mode     CalendarPolicyMode
+	Calendar calendar.Calendar
+	Window   calendar.Window
+	// Defer receives the operation out of the window, like a TransfersRequest or a ConfirmPaymentRequest
+	Defer func(ctx context.Context, next time.Time, operation interface{}) error
+	// Now is used by tests, nil uses time.Now
+	Now func() time.Time
+}
+
+// NewTEDCalendarPolicy ...
+func NewTEDCalendarPolicy(mode CalendarPolicyMode) *CalendarPolicy {
+	return &CalendarPolicy{Mode: mode, Calendar: calendar.NewNational(), Window: calendar.TEDWindow}
+}
+
+// NewBillPaymentCalendarPolicy ...
+func NewBillPaymentCalendarPolicy(mode CalendarPolicyMode) *CalendarPolicy {
+	return &CalendarPolicy{Mode: mode, Calendar: calendar.NewNational(), Window: calendar.BillPaymentWindow}
+}
+
+// Check returns nil when the operation can be sent now.
+func (p *CalendarPolicy) Check(ctx context.Context, operation interface{}) error {
+	now := time.Now()
+	if p.Now != nil {
+		now = p.Now()
+	}
+
+	cal := p.Calendar
+	if cal == nil {
+		cal = calendar.NewNational()
+	}
+
+	if calendar.IsWithinWindow(cal, p.Window, now) {
+		return nil
+	}
+
+	next := calendar.NextWindowOpening(cal, p.Window, now)
+
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"window":     p.Window.String(),
+		"next":       next,
+	}
+
+	if p.Mode != CalendarDefer || p.Defer == nil {
+		logrus.WithFields(fields).Info("operation rejected out of service period")
+		return ErrOutOfServicePeriod
+	}
+
+	if err := p.Defer(ctx, next, operation); err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error deferring operation")
+		return err
+	}
+
+	logrus.WithFields(fields).Info("operation deferred to the next window")
+
+	return ErrOperationDeferred
+}
+
+// BusinessWindow returns the business hours of the payment as a calendar window.
+func (r *ValidatePaymentResponse) BusinessWindow() (calendar.Window, error) {
+	if r.BusinessHours == nil {
+		return calendar.BillPaymentWindow, nil
+	}
+	return calendar.NewWindow(r.BusinessHours.Start, r.BusinessHours.End)
+}
@@ -0,0 +1,83 @@
+package bankly_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CalendarPolicyTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	ctx    context.Context
+	calls  int
+}
+
+func TestCalendarPolicyTestSuite(t *testing.T) {
+	suite.Run(t, new(CalendarPolicyTestSuite))
+}
+
+func (s *CalendarPolicyTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.calls = 0
+}
+
+func (s *CalendarPolicyTestSuite) TestCheck() {
+	now := time.Date(2026, time.October, 19, 10, 0, 0, 0, calendar.Location())
+	policy := bankly.NewTEDCalendarPolicy(bankly.CalendarReject)
+	policy.Now = func() time.Time { return now }
+
+	s.assert.NoError(policy.Check(s.ctx, nil))
+
+	now = time.Date(2026, time.October, 12, 10, 0, 0, 0, calendar.Location())
+	s.assert.Equal(bankly.ErrOutOfServicePeriod, policy.Check(s.ctx, nil))
+
+	var deferred time.Time
+	policy.Mode = bankly.CalendarDefer
+	policy.Defer = func(ctx context.Context, next time.Time, operation interface{}) error {
+		deferred = next
+		return nil
+	}
+
+	s.assert.Equal(bankly.ErrOperationDeferred, policy.Check(s.ctx, nil))
+	s.assert.Equal(time.Date(2026, time.October, 13, 7, 0, 0, 0, calendar.Location()), deferred)
+}
+
+func (s *CalendarPolicyTestSuite) TestTransfers_RejectsWithoutCallingBankly() {
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		s.calls++
+		return &http.Response{StatusCode: http.StatusInternalServerError, Body: jsonBody(nil)}
+	})
+
+	policy := bankly.NewTEDCalendarPolicy(bankly.CalendarReject)
+	policy.Now = func() time.Time { return time.Date(2026, time.October, 19, 18, 0, 0, 0, calendar.Location()) }
+
+	transfers := bankly.NewTransfers(httpClient, *session)
+	transfers.SetCalendarPolicy(policy)
+
+	_, err := transfers.CreateTransfer(s.ctx, "correlation-id", bankly.TransfersRequest{
+		Amount: 100,
+		Sender: bankly.SenderRequest{Branch: "0001", Account: "207802", Document: "52998224725", Name: "Sender"},
+		Recipient: bankly.RecipientRequest{
+			BankCode: "001", Branch: "1234", Account: "123456", Document: "16246241620", Name: "Recipient",
+			TransfersAccountType: bankly.CheckingAccount,
+		},
+	})
+	s.assert.Equal(bankly.ErrOutOfServicePeriod, err)
+	s.assert.Equal(0, s.calls)
+}
+
+func (s *CalendarPolicyTestSuite) TestBusinessWindow() {
+	response := &bankly.ValidatePaymentResponse{BusinessHours: &bankly.BusinessHours{Start: "07:00:00", End: "22:00:00"}}
+
+	window, err := response.BusinessWindow()
+	s.assert.NoError(err)
+	s.assert.Equal(22*time.Hour, window.End)
+}
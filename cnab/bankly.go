@@ -0,0 +1,304 @@
+package cnab
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/grok"
+)
+
+// ErrResultsMismatch ...
+var ErrResultsMismatch = errors.New("the results do not match the payments of the remessa")
+
+// Occurrences of the retorno
+const (
+	OccurrencePaid              = "00"
+	OccurrenceInsufficientFunds = "01"
+	OccurrenceCanceled          = "02"
+	OccurrenceInvalidDate       = "AP"
+	OccurrenceInvalidBranch     = "AM"
+	OccurrenceInvalidAccount    = "AN"
+	OccurrenceInvalidAmount     = "AR"
+	OccurrenceScheduled         = "BD"
+	// OccurrenceRejected is any other refusal, the reason goes at the information field
+	OccurrenceRejected = "ZZ"
+)
+
+// Instruction is a payment of the remessa converted to a Bankly request,
+// only one of Transfer, PixCashOut and BillPayment is set.
+type Instruction struct {
+	Payment    *Payment
+	Transfer   *bankly.TransfersRequest
+	PixCashOut *bankly.PixCashOutRequest
+	// PixKey must be looked up at the DICT to set the endToEndId of the cash out
+	PixKey      *bankly.PixTypeValue
+	BillPayment *bankly.ConfirmPaymentRequest
+	// Barcode must be validated at Bankly to set the id of the bill payment
+	Barcode string
+}
+
+// Result of an instruction, built by TransferResult, PixCashOutResult or BillPaymentResult
+type Result struct {
+	Occurrence         string
+	AuthenticationCode string
+	EffectiveDate      time.Time
+	EffectiveAmount    bankly.Money
+	Message            string
+}
+
+// Instructions converts the payments of the remessa, in the order of the file.
+// The sender is the company of the file header.
+func (f *File) Instructions() ([]Instruction, error) {
+	company := f.Header.Company
+	sender := bankly.SenderRequest{
+		Branch:   branch(company.Branch),
+		Account:  strings.TrimLeft(company.Account, "0") + company.AccountDigit,
+		Document: company.Document,
+		Name:     company.Name,
+	}
+
+	instructions := []Instruction{}
+
+	for _, batch := range f.Batches {
+		for _, payment := range batch.Payments {
+			instruction := Instruction{Payment: payment}
+
+			switch {
+			case payment.Segment == SegmentJ:
+				description := payment.YourNumber
+				instruction.Barcode = payment.Barcode
+				instruction.BillPayment = &bankly.ConfirmPaymentRequest{
+					BankBranch:  sender.Branch,
+					BankAccount: sender.Account,
+					Description: &description,
+				}
+				instruction.BillPayment.SetAmount(payment.Amount)
+
+			case !payment.HasSegmentB:
+				return nil, &LayoutError{Field: "segment", Message: "segment B required to the payment " + payment.YourNumber}
+
+			case payment.IsPix() || batch.Header.Form == FormPixTransfer:
+				instruction.PixCashOut, instruction.PixKey = pixCashOut(sender, payment)
+
+			default:
+				instruction.Transfer = &bankly.TransfersRequest{
+					Amount:      payment.Amount.Cents,
+					Sender:      sender,
+					Recipient:   recipient(payment),
+					Description: description(payment),
+				}
+			}
+
+			instructions = append(instructions, instruction)
+		}
+	}
+
+	return instructions, nil
+}
+
+func recipient(payment *Payment) bankly.RecipientRequest {
+	accountType := bankly.CheckingAccount
+	if payment.AccountPurpose == "PP" {
+		accountType = bankly.SavingsAccount
+	}
+
+	return bankly.RecipientRequest{
+		TransfersAccountType: accountType,
+		BankCode:             payment.BankCode,
+		Branch:               branch(payment.Branch),
+		Account:              strings.TrimLeft(payment.Account, "0") + payment.AccountDigit,
+		Document:             payment.Recipient.Document,
+		Name:                 payment.Name,
+	}
+}
+
+func pixCashOut(sender bankly.SenderRequest, payment *Payment) (*bankly.PixCashOutRequest, *bankly.PixTypeValue) {
+	request := &bankly.PixCashOutRequest{
+		Sender: bankly.PixCashOutSenderRequest{
+			Account:        bankly.PixCashOutAccountRequest{Branch: sender.Branch, Number: sender.Account},
+			Bank:           bankly.PixCashOutBankRequest{Ispb: bankly.BanklyISPB},
+			DocumentNumber: sender.Document,
+			Name:           sender.Name,
+		},
+		Description: payment.PixMessage,
+	}
+	request.SetAmount(payment.Amount)
+
+	if request.Description == "" {
+		request.Description = description(payment)
+	}
+
+	if payment.PixInitiation == PixInitiationAccount {
+		request.InitializationType = bankly.Manual
+		request.Recipient = bankly.PixCashOutRecipientRequest{
+			Account: bankly.PixCashOutAccountRequest{
+				Branch: branch(payment.Branch),
+				Number: strings.TrimLeft(payment.Account, "0") + payment.AccountDigit,
+			},
+			Bank:           bankly.PixCashOutBankRequest{Ispb: payment.ISPB},
+			DocumentNumber: payment.Recipient.Document,
+			Name:           payment.Name,
+		}
+		return request, nil
+	}
+
+	key := &bankly.PixTypeValue{Value: payment.PixKey}
+	switch payment.PixInitiation {
+	case PixInitiationPhone:
+		key.Type = bankly.PixPHONE
+	case PixInitiationEmail:
+		key.Type = bankly.PixEMAIL
+	case PixInitiationDocument:
+		key.Type = bankly.PixCPF
+		if len(bankly.OnlyDigits(payment.PixKey)) == 14 {
+			key.Type = bankly.PixCNPJ
+		}
+	default:
+		key.Type = bankly.PixEVP
+	}
+
+	request.InitializationType = bankly.Key
+	request.Recipient.DocumentNumber = payment.Recipient.Document
+	request.Recipient.Name = payment.Name
+
+	return request, key
+}
+
+func description(payment *Payment) string {
+	if payment.Information != "" {
+		return payment.Information
+	}
+	return payment.YourNumber
+}
+
+// branch returns the branch with four digits, as Bankly expects
+func branch(value string) string {
+	value = strings.TrimLeft(value, "0")
+	if len(value) < 4 {
+		value = strings.Repeat("0", 4-len(value)) + value
+	}
+	return value
+}
+
+// TransferResult ...
+func TransferResult(response *bankly.TransferByCodeResponse, err error) Result {
+	if err != nil {
+		return errorResult(err)
+	}
+
+	result := Result{
+		AuthenticationCode: response.AuthenticationCode,
+		EffectiveAmount:    response.AmountMoney(),
+		Occurrence:         OccurrenceScheduled,
+	}
+
+	switch response.Status {
+	case bankly.TransfersStatusDone:
+		result.Occurrence = OccurrencePaid
+		result.EffectiveDate = response.UpdatedAt
+	case bankly.TransfersStatusCanceled, bankly.TransfersStatusReproved, bankly.TransfersStatusUndone:
+		result.Occurrence = OccurrenceCanceled
+		result.EffectiveAmount = bankly.NewMoney(0)
+	}
+
+	return result
+}
+
+// PixCashOutResult returns the accepted cash out as scheduled, its settlement comes by webhook
+func PixCashOutResult(response *bankly.PixCashOutResponse, err error) Result {
+	if err != nil {
+		return errorResult(err)
+	}
+
+	return Result{
+		Occurrence:         OccurrenceScheduled,
+		AuthenticationCode: response.AuthenticationCode,
+		EffectiveAmount:    bankly.MoneyFromFloat(response.Amount),
+	}
+}
+
+// BillPaymentResult ...
+func BillPaymentResult(response *bankly.ConfirmPaymentResponse, amount bankly.Money, err error) Result {
+	if err != nil {
+		return errorResult(err)
+	}
+
+	return Result{
+		Occurrence:         OccurrencePaid,
+		AuthenticationCode: response.AuthenticationCode,
+		EffectiveDate:      response.SettledDate,
+		EffectiveAmount:    amount,
+	}
+}
+
+func errorResult(err error) Result {
+	occurrence := OccurrenceRejected
+
+	switch err {
+	case bankly.ErrInsufficientBalance, bankly.ErrInsufficientBalancePix:
+		occurrence = OccurrenceInsufficientFunds
+	case bankly.ErrInvalidRecipientBranch:
+		occurrence = OccurrenceInvalidBranch
+	case bankly.ErrInvalidRecipientAccount:
+		occurrence = OccurrenceInvalidAccount
+	case bankly.ErrInvalidAmount:
+		occurrence = OccurrenceInvalidAmount
+	case bankly.ErrOutOfServicePeriod:
+		occurrence = OccurrenceInvalidDate
+	}
+
+	message := err.Error()
+	if grokErr, ok := err.(*grok.Error); ok && len(grokErr.Messages) > 0 {
+		message = strings.Join(grokErr.Messages, ", ")
+	}
+
+	return Result{Occurrence: occurrence, Message: message}
+}
+
+// NewRetorno builds the retorno of the remessa with the results in the order of Instructions.
+// The authentication code, or the reason of the refusal, goes at the information field of the segment A.
+func NewRetorno(remessa *File, results []Result, generatedAt time.Time) (*File, error) {
+	retorno := &File{
+		Header:     remessa.Header,
+		trailerRaw: remessa.trailerRaw,
+	}
+	retorno.Header.Kind = Retorno
+	retorno.Header.GeneratedAt = generatedAt
+
+	i := 0
+	for _, batch := range remessa.Batches {
+		copied := &Batch{Header: batch.Header, trailerRaw: batch.trailerRaw}
+
+		for _, payment := range batch.Payments {
+			if i >= len(results) {
+				return nil, ErrResultsMismatch
+			}
+			result := results[i]
+			i++
+
+			paid := *payment
+			paid.Occurrences = result.Occurrence
+
+			if paid.Segment == SegmentA {
+				paid.EffectiveDate = result.EffectiveDate
+				paid.EffectiveAmount = result.EffectiveAmount
+				paid.Information = result.AuthenticationCode
+				if paid.Information == "" {
+					paid.Information = result.Message
+				}
+			}
+
+			copied.Payments = append(copied.Payments, &paid)
+		}
+
+		retorno.Batches = append(retorno.Batches, copied)
+	}
+
+	if i != len(results) {
+		return nil, ErrResultsMismatch
+	}
+
+	return retorno, nil
+}
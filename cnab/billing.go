@@ -0,0 +1,575 @@
+package cnab
+
+import (
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+)
+
+// BillingLayoutVersion is the default layout of the cobrança batches
+const BillingLayoutVersion = "060"
+
+// Movements of the cobrança remessa
+const (
+	BillingEntry    = "01"
+	BillingWriteOff = "02"
+)
+
+// Occurrences of the cobrança retorno
+const (
+	BillingRegistered = "02"
+	BillingRejected   = "03"
+	BillingSettled    = "06"
+	BillingCancelled  = "09"
+)
+
+// Codes of the interest, discount and fine of the boleto
+const (
+	InterestDaily       = "1"
+	InterestMonthlyRate = "2"
+	InterestExempt      = "3"
+	DiscountFixed       = "1"
+	DiscountPercent     = "2"
+	FineFixed           = "1"
+	FinePercent         = "2"
+)
+
+// Charge is an interest, discount or fine of the boleto. Value is an amount, or
+// a percentage with two decimals, as set by the code.
+type Charge struct {
+	Code  string
+	Date  time.Time
+	Value bankly.Money
+}
+
+// Payer of the boleto, at the segment Q
+type Payer struct {
+	Party
+	Address      string
+	Neighborhood string
+	ZipCode      string
+	City         string
+	State        string
+}
+
+// Boleto is a title of the cobrança: the segments P, Q and R of the remessa, or T
+// and U of the retorno. Movement is the código de movimento of both.
+type Boleto struct {
+	Segment    string
+	Movement   string
+	OurNumber  string
+	Wallet     string
+	YourNumber string
+	CompanyUse string
+	DueDate    time.Time
+	Amount     bankly.Money
+	Kind       string
+	Acceptance string
+	IssueDate  time.Time
+	Interest   Charge
+	Discount   Charge
+	Fine       Charge
+	Rebate     bankly.Money
+	CloseDays  int64
+	Payer      Payer
+	Drawer     Party
+	HasQ       bool
+	HasR       bool
+
+	// segments T and U
+	Reasons         string
+	CollectorBank   string
+	CollectorBranch string
+	Tariff          bankly.Money
+	PaidInterest    bankly.Money
+	PaidDiscount    bankly.Money
+	PaidAmount      bankly.Money
+	CreditedAmount  bankly.Money
+	OccurredAt      time.Time
+	CreditDate      time.Time
+	HasU            bool
+
+	firstRaw  string
+	secondRaw string
+	thirdRaw  string
+}
+
+func parseBillingHeader(l line, header *BatchHeader) error {
+	var err error
+
+	if header.Company, err = parseCompany(l, 1); err != nil {
+		return err
+	}
+
+	header.Message = l.alpha(104, 143)
+
+	if header.Sequence, err = l.number("sequence", 184, 191); err != nil {
+		return err
+	}
+	if header.RecordedAt, err = l.date("recorded date", 192, 199); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func parseBillingSegment(l line, batch *Batch) error {
+	var last *Boleto
+	if len(batch.Boletos) > 0 {
+		last = batch.Boletos[len(batch.Boletos)-1]
+	}
+
+	segment := l.raw(14, 14)
+	switch segment {
+	case SegmentP:
+		boleto, err := parseSegmentP(l)
+		if err != nil {
+			return err
+		}
+		batch.Boletos = append(batch.Boletos, boleto)
+
+	case SegmentQ:
+		if last == nil || last.Segment != SegmentP || last.HasQ {
+			return l.err("segment", "segment Q must follow a segment P")
+		}
+		return parseSegmentQ(l, last)
+
+	case SegmentR:
+		if last == nil || last.Segment != SegmentP || last.HasR {
+			return l.err("segment", "segment R must follow a segment P")
+		}
+		return parseSegmentR(l, last)
+
+	case SegmentT:
+		boleto, err := parseSegmentT(l)
+		if err != nil {
+			return err
+		}
+		batch.Boletos = append(batch.Boletos, boleto)
+
+	case SegmentU:
+		if last == nil || last.Segment != SegmentT || last.HasU {
+			return l.err("segment", "segment U must follow a segment T")
+		}
+		return parseSegmentU(l, last)
+
+	default:
+		return l.err("segment", "unsupported segment "+segment+" at a cobrança batch")
+	}
+
+	return nil
+}
+
+func parseCharge(l line, field string, code, end int) (Charge, error) {
+	var err error
+	charge := Charge{Code: l.alpha(code, code)}
+
+	if charge.Date, err = l.date(field+" date", code+1, code+8); err != nil {
+		return charge, err
+	}
+	if charge.Value, err = l.money(field, code+9, end); err != nil {
+		return charge, err
+	}
+
+	return charge, nil
+}
+
+func parseSegmentP(l line) (*Boleto, error) {
+	var err error
+	boleto := &Boleto{Segment: SegmentP, firstRaw: l.text}
+
+	boleto.Movement = l.raw(16, 17)
+	boleto.OurNumber = l.alpha(38, 57)
+	boleto.Wallet = l.alpha(58, 58)
+	boleto.YourNumber = l.alpha(63, 77)
+
+	if boleto.DueDate, err = l.date("due date", 78, 85); err != nil {
+		return nil, err
+	}
+	if boleto.DueDate.IsZero() {
+		return nil, l.err("due date", "required")
+	}
+	if boleto.Amount, err = l.money("amount", 86, 100); err != nil {
+		return nil, err
+	}
+	if boleto.Amount.Cents == 0 {
+		return nil, l.err("amount", "required")
+	}
+
+	boleto.Kind = l.alpha(107, 108)
+	boleto.Acceptance = l.alpha(109, 109)
+
+	if boleto.IssueDate, err = l.date("issue date", 110, 117); err != nil {
+		return nil, err
+	}
+	if boleto.Interest, err = parseCharge(l, "interest", 118, 141); err != nil {
+		return nil, err
+	}
+	if boleto.Discount, err = parseCharge(l, "discount", 142, 165); err != nil {
+		return nil, err
+	}
+	if boleto.Rebate, err = l.money("rebate", 181, 195); err != nil {
+		return nil, err
+	}
+
+	boleto.CompanyUse = l.alpha(196, 220)
+
+	if l.alpha(225, 227) != "" {
+		if boleto.CloseDays, err = l.number("close days", 225, 227); err != nil {
+			return nil, err
+		}
+	}
+
+	return boleto, nil
+}
+
+func parseSegmentQ(l line, boleto *Boleto) error {
+	boleto.HasQ = true
+	boleto.secondRaw = l.text
+
+	party, err := parseParty(l, "payer", 18, 19, 33, 34, 73)
+	if err != nil {
+		return err
+	}
+
+	zipCode, err := l.digits("payer zip code", 129, 136)
+	if err != nil {
+		return err
+	}
+
+	boleto.Payer = Payer{
+		Party:        party,
+		Address:      l.alpha(74, 113),
+		Neighborhood: l.alpha(114, 128),
+		ZipCode:      zipCode,
+		City:         l.alpha(137, 151),
+		State:        l.alpha(152, 153),
+	}
+
+	if l.alpha(154, 154) != "" && l.raw(154, 154) != "0" {
+		if boleto.Drawer, err = parseParty(l, "drawer", 154, 155, 169, 170, 209); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func parseSegmentR(l line, boleto *Boleto) error {
+	var err error
+
+	boleto.HasR = true
+	boleto.thirdRaw = l.text
+	boleto.Fine, err = parseCharge(l, "fine", 66, 89)
+
+	return err
+}
+
+func parseSegmentT(l line) (*Boleto, error) {
+	var err error
+	boleto := &Boleto{Segment: SegmentT, firstRaw: l.text}
+
+	boleto.Movement = l.raw(16, 17)
+	boleto.OurNumber = l.alpha(38, 57)
+	boleto.Wallet = l.alpha(58, 58)
+	boleto.YourNumber = l.alpha(59, 73)
+
+	if boleto.DueDate, err = l.date("due date", 74, 81); err != nil {
+		return nil, err
+	}
+	if boleto.Amount, err = l.money("amount", 82, 96); err != nil {
+		return nil, err
+	}
+	if boleto.CollectorBank, err = l.digits("collector bank", 97, 99); err != nil {
+		return nil, err
+	}
+	if boleto.CollectorBranch, err = l.digits("collector branch", 100, 104); err != nil {
+		return nil, err
+	}
+
+	boleto.CompanyUse = l.alpha(106, 130)
+
+	payer, err := parseParty(l, "payer", 133, 134, 148, 149, 188)
+	if err != nil {
+		return nil, err
+	}
+	boleto.Payer = Payer{Party: payer}
+
+	if boleto.Tariff, err = l.money("tariff", 199, 213); err != nil {
+		return nil, err
+	}
+
+	boleto.Reasons = l.alpha(214, 223)
+
+	return boleto, nil
+}
+
+func parseSegmentU(l line, boleto *Boleto) error {
+	var err error
+
+	boleto.HasU = true
+	boleto.secondRaw = l.text
+
+	if boleto.PaidInterest, err = l.money("paid interest", 18, 32); err != nil {
+		return err
+	}
+	if boleto.PaidDiscount, err = l.money("paid discount", 33, 47); err != nil {
+		return err
+	}
+	if boleto.Rebate, err = l.money("rebate", 48, 62); err != nil {
+		return err
+	}
+	if boleto.PaidAmount, err = l.money("paid amount", 78, 92); err != nil {
+		return err
+	}
+	if boleto.CreditedAmount, err = l.money("credited amount", 93, 107); err != nil {
+		return err
+	}
+	if boleto.OccurredAt, err = l.date("occurrence date", 138, 145); err != nil {
+		return err
+	}
+	if boleto.CreditDate, err = l.date("credit date", 146, 153); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func writeBillingHeader(r record, header *BatchHeader) error {
+	if err := writeCompany(r, header.Company, 1); err != nil {
+		return err
+	}
+
+	r.alpha(104, 143, header.Message)
+
+	if err := r.number("sequence", 184, 191, header.Sequence); err != nil {
+		return err
+	}
+	r.date(192, 199, header.RecordedAt)
+
+	return nil
+}
+
+// writeBoleto writes the segments of the boleto, the account of the company goes at the segments P and T
+func writeBoleto(next func(raw, segment string) (record, error), company Company, boleto *Boleto) ([]record, error) {
+	records := []record{}
+
+	movement := boleto.Movement
+	if movement == "" && boleto.Segment == SegmentP {
+		movement = BillingEntry
+	}
+
+	add := func(raw, segment string, write func(r record) error) error {
+		r, err := next(raw, segment)
+		if err != nil {
+			return err
+		}
+		r.alpha(16, 17, movement)
+		if err := write(r); err != nil {
+			return err
+		}
+		records = append(records, r)
+		return nil
+	}
+
+	var err error
+	switch boleto.Segment {
+	case SegmentP:
+		err = add(boleto.firstRaw, SegmentP, func(r record) error { return writeSegmentP(r, company, boleto) })
+		if err == nil && boleto.HasQ {
+			err = add(boleto.secondRaw, SegmentQ, func(r record) error { return writeSegmentQ(r, boleto) })
+		}
+		if err == nil && boleto.HasR {
+			err = add(boleto.thirdRaw, SegmentR, func(r record) error { return writeCharge(r, "fine", 66, 89, boleto.Fine) })
+		}
+
+	case SegmentT:
+		err = add(boleto.firstRaw, SegmentT, func(r record) error { return writeSegmentT(r, company, boleto) })
+		if err == nil && boleto.HasU {
+			err = add(boleto.secondRaw, SegmentU, func(r record) error { return writeSegmentU(r, boleto) })
+		}
+
+	default:
+		err = &LayoutError{Field: "segment", Message: "unsupported segment " + boleto.Segment + " at a cobrança batch"}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func writeBillingAccount(r record, company Company) error {
+	if err := r.digits("company branch", 18, 22, company.Branch); err != nil {
+		return err
+	}
+	r.alpha(23, 23, company.BranchDigit)
+	if err := r.digits("company account", 24, 35, company.Account); err != nil {
+		return err
+	}
+	r.alpha(36, 36, company.AccountDigit)
+	return nil
+}
+
+func writeCharge(r record, field string, code, end int, charge Charge) error {
+	value := charge.Code
+	if value == "" {
+		value = "0"
+	}
+	r.alpha(code, code, value)
+	r.date(code+1, code+8, charge.Date)
+	return r.money(field, code+9, end, charge.Value)
+}
+
+func writeSegmentP(r record, company Company, boleto *Boleto) error {
+	if err := writeBillingAccount(r, company); err != nil {
+		return err
+	}
+
+	r.alpha(38, 57, boleto.OurNumber)
+	r.alpha(58, 58, boleto.Wallet)
+	r.alpha(63, 77, boleto.YourNumber)
+	r.date(78, 85, boleto.DueDate)
+
+	if err := r.money("amount", 86, 100, boleto.Amount); err != nil {
+		return err
+	}
+
+	if boleto.firstRaw == "" {
+		// registered, issued and sent by the company, without the collector branch
+		r.alpha(59, 62, "1122")
+		copy(r[100:106], "000000")
+		copy(r[165:180], "000000000000000")
+		r.alpha(221, 224, "3002")
+		copy(r[227:239], "090000000000")
+	}
+
+	kind := boleto.Kind
+	if kind == "" {
+		kind = "02"
+	}
+	r.alpha(107, 108, kind)
+
+	acceptance := boleto.Acceptance
+	if acceptance == "" {
+		acceptance = "N"
+	}
+	r.alpha(109, 109, acceptance)
+	r.date(110, 117, boleto.IssueDate)
+
+	if err := writeCharge(r, "interest", 118, 141, boleto.Interest); err != nil {
+		return err
+	}
+	if err := writeCharge(r, "discount", 142, 165, boleto.Discount); err != nil {
+		return err
+	}
+	if err := r.money("rebate", 181, 195, boleto.Rebate); err != nil {
+		return err
+	}
+
+	r.alpha(196, 220, boleto.CompanyUse)
+
+	if boleto.CloseDays > 0 {
+		r.alpha(224, 224, "1")
+		return r.number("close days", 225, 227, boleto.CloseDays)
+	}
+
+	return nil
+}
+
+func writeParty(r record, field string, start int, size int, party Party) error {
+	if err := r.number(field+" document type", start, start, int64(party.DocumentType)); err != nil {
+		return err
+	}
+	if err := r.digits(field+" document", start+1, start+15, party.Document); err != nil {
+		return err
+	}
+	r.alpha(start+16, start+15+size, party.Name)
+	return nil
+}
+
+func writeSegmentQ(r record, boleto *Boleto) error {
+	if err := writeParty(r, "payer", 18, 40, boleto.Payer.Party); err != nil {
+		return err
+	}
+
+	r.alpha(74, 113, boleto.Payer.Address)
+	r.alpha(114, 128, boleto.Payer.Neighborhood)
+	if err := r.digits("payer zip code", 129, 136, boleto.Payer.ZipCode); err != nil {
+		return err
+	}
+	r.alpha(137, 151, boleto.Payer.City)
+	r.alpha(152, 153, boleto.Payer.State)
+
+	if boleto.Drawer.Document == "" {
+		copy(r[153:169], "0000000000000000")
+		return nil
+	}
+	return writeParty(r, "drawer", 154, 40, boleto.Drawer)
+}
+
+func writeSegmentT(r record, company Company, boleto *Boleto) error {
+	if err := writeBillingAccount(r, company); err != nil {
+		return err
+	}
+
+	r.alpha(38, 57, boleto.OurNumber)
+	r.alpha(58, 58, boleto.Wallet)
+	r.alpha(59, 73, boleto.YourNumber)
+	r.date(74, 81, boleto.DueDate)
+
+	if err := r.money("amount", 82, 96, boleto.Amount); err != nil {
+		return err
+	}
+	if err := r.digits("collector bank", 97, 99, boleto.CollectorBank); err != nil {
+		return err
+	}
+	if err := r.digits("collector branch", 100, 104, boleto.CollectorBranch); err != nil {
+		return err
+	}
+
+	r.alpha(106, 130, boleto.CompanyUse)
+
+	if boleto.firstRaw == "" {
+		r.alpha(131, 132, "09")
+	}
+
+	if err := writeParty(r, "payer", 133, 40, boleto.Payer.Party); err != nil {
+		return err
+	}
+	if err := r.money("tariff", 199, 213, boleto.Tariff); err != nil {
+		return err
+	}
+
+	r.alpha(214, 223, boleto.Reasons)
+
+	return nil
+}
+
+func writeSegmentU(r record, boleto *Boleto) error {
+	amounts := []struct {
+		field  string
+		start  int
+		amount bankly.Money
+	}{
+		{"paid interest", 18, boleto.PaidInterest},
+		{"paid discount", 33, boleto.PaidDiscount},
+		{"rebate", 48, boleto.Rebate},
+		{"iof", 63, bankly.NewMoney(0)},
+		{"paid amount", 78, boleto.PaidAmount},
+		{"credited amount", 93, boleto.CreditedAmount},
+		{"other expenses", 108, bankly.NewMoney(0)},
+		{"other credits", 123, bankly.NewMoney(0)},
+	}
+
+	for _, a := range amounts {
+		if err := r.money(a.field, a.start, a.start+14, a.amount); err != nil {
+			return err
+		}
+	}
+
+	r.date(138, 145, boleto.OccurredAt)
+	r.date(146, 153, boleto.CreditDate)
+
+	return nil
+}
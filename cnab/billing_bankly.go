@@ -0,0 +1,297 @@
+package cnab
+
+import (
+	"strings"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+)
+
+// paymentChannels maps the channels of the Bankly payments to the FEBRABAN
+// liquidation reasons, written at the reasons of the settled boletos
+var paymentChannels = map[bankly.PaymentChannel]string{
+	bankly.AgencyPaymentChannel:               "03",
+	bankly.SelfServiceTerminalPaymentChannel:  "32",
+	bankly.InternetBankingPaymentChannel:      "33",
+	bankly.CorrespondentBankingPaymentChannel: "35",
+	bankly.CallCenterPaymentChannel:           "37",
+	bankly.EletronicFilePaymentChannel:        "06",
+	bankly.DDAPaymentChannel:                  "06",
+}
+
+// BoletoRequests converts the entries of the cobrança batches to Bankly requests.
+// The boletos are issued by the company of the file header to its own account.
+func (f *File) BoletoRequests() ([]*bankly.BoletoRequest, error) {
+	company := f.Header.Company
+	account := &bankly.Account{
+		Branch: branch(company.Branch),
+		Number: strings.TrimLeft(company.Account, "0") + company.AccountDigit,
+	}
+
+	requests := []*bankly.BoletoRequest{}
+
+	for _, batch := range f.Batches {
+		for _, boleto := range batch.Boletos {
+			request, err := boletoRequest(account, company, boleto)
+			if err != nil {
+				return nil, err
+			}
+			requests = append(requests, request)
+		}
+	}
+
+	return requests, nil
+}
+
+func boletoRequest(account *bankly.Account, company Company, boleto *Boleto) (*bankly.BoletoRequest, error) {
+	fail := func(field, message string) error {
+		return &LayoutError{Field: field, Message: message + " at the boleto " + boleto.YourNumber}
+	}
+
+	switch {
+	case boleto.Segment != SegmentP:
+		return nil, fail("segment", "expected the segment P")
+	case boleto.Movement != BillingEntry:
+		return nil, fail("movement", "unsupported movement "+boleto.Movement)
+	case !boleto.HasQ:
+		return nil, fail("segment", "segment Q required")
+	case !boleto.Rebate.IsZero():
+		return nil, fail("rebate", "rebates not supported by Bankly")
+	}
+
+	request := &bankly.BoletoRequest{
+		Account:  account,
+		Document: company.Document,
+		DueDate:  boleto.DueDate,
+		Type:     bankly.Levy,
+		Payer: &bankly.BoletoPayer{
+			Name:     boleto.Payer.Name,
+			Document: boleto.Payer.Document,
+			Address: &bankly.BoletoAddress{
+				ZipCode:      boleto.Payer.ZipCode,
+				AddressLine:  boleto.Payer.Address,
+				Neighborhood: boleto.Payer.Neighborhood,
+				State:        boleto.Payer.State,
+				City:         boleto.Payer.City,
+			},
+		},
+	}
+	request.SetAmount(boleto.Amount)
+
+	if boleto.Payer.Document == company.Document {
+		request.Type = bankly.Deposit
+	}
+
+	if boleto.YourNumber != "" {
+		alias := boleto.YourNumber
+		request.Alias = &alias
+	}
+
+	if boleto.CloseDays > 0 {
+		request.ClosePayment = boleto.DueDate.AddDate(0, 0, int(boleto.CloseDays))
+	}
+
+	// interest and fine start at the day after the due date when the file has no date
+	next := boleto.DueDate.AddDate(0, 0, 1)
+
+	switch boleto.Interest.Code {
+	case InterestDaily, InterestMonthlyRate:
+		request.Interest = &bankly.BoletoInterest{
+			StartDate: chargeDate(boleto.Interest, next),
+			Value:     boleto.Interest.Value.Float64(),
+			Type:      bankly.FixedAmountInterestType,
+		}
+		if boleto.Interest.Code == InterestMonthlyRate {
+			request.Interest.Type = bankly.PercentInterestType
+		}
+	case "", "0", InterestExempt:
+	default:
+		return nil, fail("interest", "unsupported code "+boleto.Interest.Code)
+	}
+
+	switch boleto.Discount.Code {
+	case DiscountFixed, DiscountPercent:
+		request.Discount = &bankly.BoletoDiscounts{
+			LimitDate: chargeDate(boleto.Discount, boleto.DueDate),
+			Value:     boleto.Discount.Value.Float64(),
+			Type:      bankly.FixedAmountDiscountType,
+		}
+		if boleto.Discount.Code == DiscountPercent {
+			request.Discount.Type = bankly.FixedPercentDiscountType
+		}
+	case "", "0":
+	default:
+		return nil, fail("discount", "unsupported code "+boleto.Discount.Code)
+	}
+
+	switch boleto.Fine.Code {
+	case FineFixed, FinePercent:
+		request.Fine = &bankly.BoletoFine{
+			StartDate: chargeDate(boleto.Fine, next),
+			Value:     boleto.Fine.Value.Float64(),
+			Type:      bankly.FixedAmountFineType,
+		}
+		if boleto.Fine.Code == FinePercent {
+			request.Fine.Type = bankly.PercentFineType
+		}
+	case "", "0":
+	default:
+		return nil, fail("fine", "unsupported code "+boleto.Fine.Code)
+	}
+
+	return request, nil
+}
+
+func chargeDate(charge Charge, otherwise time.Time) time.Time {
+	if charge.Date.IsZero() {
+		return otherwise
+	}
+	return charge.Date
+}
+
+// DetailedBoletoEvents returns the events of the boleto to the retorno: a settlement for
+// each payment, the cancellation or the registration, as its status.
+func DetailedBoletoEvents(response *bankly.BoletoDetailedResponse) []*Boleto {
+	occurredAt := response.UpdatedAt
+	if occurredAt.IsZero() && response.EmissionDate != nil {
+		occurredAt = *response.EmissionDate
+	}
+
+	return boletoEvents(boletoState{
+		status:     response.Status,
+		ourNumber:  response.OurNumber,
+		alias:      response.Alias,
+		dueDate:    response.DueDate,
+		amount:     response.Amount,
+		payer:      response.Payer,
+		payments:   response.Payments,
+		occurredAt: occurredAt,
+	})
+}
+
+// FilterBoletoEvents returns the events of the boletos updated at the date, the
+// registrations and cancellations are dated at it.
+func FilterBoletoEvents(response *bankly.FilterBoletoResponse, date time.Time) []*Boleto {
+	events := []*Boleto{}
+
+	for _, data := range response.Data {
+		events = append(events, boletoEvents(boletoState{
+			status:     data.Status,
+			alias:      data.Alias,
+			dueDate:    data.DueDate,
+			amount:     data.Amount,
+			payer:      data.Payer,
+			payments:   data.Payments,
+			occurredAt: date,
+		})...)
+	}
+
+	return events
+}
+
+// boletoState has the fields shared by the detailed and the filtered boletos
+type boletoState struct {
+	status     string
+	ourNumber  string
+	alias      *string
+	dueDate    time.Time
+	amount     *bankly.BoletoAmount
+	payer      *bankly.Payer
+	payments   []*bankly.BoletoPayment
+	occurredAt time.Time
+}
+
+func boletoEvents(state boletoState) []*Boleto {
+	event := func(movement string, occurredAt time.Time) *Boleto {
+		boleto := &Boleto{
+			Segment:       SegmentT,
+			Movement:      movement,
+			OurNumber:     state.ourNumber,
+			DueDate:       state.dueDate,
+			CollectorBank: bankly.InternalBankCode,
+			OccurredAt:    occurredAt,
+			CreditDate:    occurredAt,
+			HasU:          true,
+		}
+		if state.alias != nil {
+			boleto.YourNumber = *state.alias
+		}
+		if state.amount != nil {
+			boleto.Amount = state.amount.Money()
+		}
+		if state.payer != nil {
+			document := bankly.OnlyDigits(state.payer.Document)
+			boleto.Payer.Party = Party{DocumentType: documentType(document), Document: document, Name: state.payer.Name}
+		}
+		return boleto
+	}
+
+	status := strings.ToLower(state.status)
+	events := []*Boleto{}
+
+	switch {
+	case len(state.payments) > 0:
+		for _, payment := range state.payments {
+			settled := event(BillingSettled, payment.PaidOutDate)
+			settled.Reasons = paymentChannels[payment.PaymentChannel]
+			settled.PaidAmount = bankly.MoneyFromFloat(payment.Amount)
+			settled.CreditedAmount = settled.PaidAmount
+
+			// paid out of the nominal amount, with interest and fine or with discount
+			difference, _ := settled.PaidAmount.Sub(settled.Amount)
+			if difference.IsNegative() {
+				settled.PaidDiscount, _ = settled.Amount.Sub(settled.PaidAmount)
+			} else {
+				settled.PaidInterest = difference
+			}
+
+			events = append(events, settled)
+		}
+
+	case status == "settled" || status == "paid":
+		settled := event(BillingSettled, state.occurredAt)
+		settled.PaidAmount = settled.Amount
+		settled.CreditedAmount = settled.Amount
+		events = append(events, settled)
+
+	case strings.HasPrefix(status, "cancel"):
+		events = append(events, event(BillingCancelled, state.occurredAt))
+
+	case status == "registered" || status == "accepted":
+		events = append(events, event(BillingRegistered, state.occurredAt))
+	}
+
+	return events
+}
+
+func documentType(document string) int {
+	if len(document) == 11 {
+		return 1
+	}
+	return 2
+}
+
+// NewBillingRetorno builds the cobrança retorno of the events, written with Marshal
+// as CNAB 240 or with Marshal400 as CNAB 400.
+func NewBillingRetorno(company Company, events []*Boleto, generatedAt time.Time, sequence int64) *File {
+	return &File{
+		Header: FileHeader{
+			BankCode:    bankly.InternalBankCode,
+			Company:     company,
+			BankName:    "BANKLY",
+			Kind:        Retorno,
+			GeneratedAt: generatedAt,
+			Sequence:    sequence,
+		},
+		Batches: []*Batch{{
+			Header: BatchHeader{
+				Operation:   "T",
+				ServiceType: ServiceBilling,
+				Company:     company,
+				Sequence:    sequence,
+				RecordedAt:  generatedAt,
+			},
+			Boletos: events,
+		}},
+	}
+}
@@ -0,0 +1,267 @@
+package cnab
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildBillingRemessa() *File {
+	company := Company{
+		DocumentType: 2,
+		Document:     "12345678000195",
+		Branch:       "00001",
+		Account:      "000000018916",
+		AccountDigit: "2",
+		Name:         "Empresa Cobradora",
+	}
+
+	return &File{
+		Header: FileHeader{
+			BankCode:    bankly.InternalBankCode,
+			Company:     company,
+			Kind:        Remessa,
+			GeneratedAt: date(2026, time.October, 19).Add(9 * time.Hour),
+			Sequence:    7,
+		},
+		Batches: []*Batch{{
+			Header: BatchHeader{ServiceType: ServiceBilling, Company: company, Sequence: 7},
+			Boletos: []*Boleto{{
+				Segment:    SegmentP,
+				YourNumber: "NF-1001",
+				DueDate:    date(2026, time.November, 10),
+				Amount:     bankly.NewMoney(125050),
+				IssueDate:  date(2026, time.October, 19),
+				Interest:   Charge{Code: InterestMonthlyRate, Value: bankly.NewMoney(100)},
+				Discount:   Charge{Code: DiscountFixed, Date: date(2026, time.November, 5), Value: bankly.NewMoney(1000)},
+				Fine:       Charge{Code: FinePercent, Value: bankly.NewMoney(200)},
+				CloseDays:  30,
+				Payer: Payer{
+					Party:        Party{DocumentType: 1, Document: "76385230056", Name: "João da Silva"},
+					Address:      "Rua das Flores 100",
+					Neighborhood: "Centro",
+					ZipCode:      "01001000",
+					City:         "São Paulo",
+					State:        "SP",
+				},
+				HasQ: true,
+				HasR: true,
+			}},
+		}},
+	}
+}
+
+func TestBilling_Marshal(t *testing.T) {
+	data, err := Marshal(buildBillingRemessa())
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+	assert.Len(t, lines, 7)
+	assert.Equal(t, "R01", lines[1][8:11])
+	assert.Equal(t, "012345678000195", lines[1][18:33])
+	assert.Equal(t, "00001P 01", lines[2][8:17])
+	assert.Equal(t, "00002Q 01", lines[3][8:17])
+	assert.Equal(t, "00003R 01", lines[4][8:17])
+	assert.Equal(t, "JOAO DA SILVA", strings.TrimSpace(lines[3][33:73]))
+	assert.Equal(t, "000005", lines[5][17:23])
+
+	file, err := Parse(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	again, err := Marshal(file)
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+}
+
+func TestBilling_BoletoRequests(t *testing.T) {
+	data, err := Marshal(buildBillingRemessa())
+	assert.NoError(t, err)
+
+	file, err := Parse(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	requests, err := file.BoletoRequests()
+	assert.NoError(t, err)
+	assert.Len(t, requests, 1)
+
+	request := requests[0]
+	assert.Equal(t, &bankly.Account{Branch: "0001", Number: "189162"}, request.Account)
+	assert.Equal(t, "12345678000195", request.Document)
+	assert.Equal(t, 1250.5, request.Amount)
+	assert.Equal(t, bankly.Levy, request.Type)
+	assert.Equal(t, "NF-1001", *request.Alias)
+	assert.Equal(t, date(2026, time.December, 10), request.ClosePayment)
+	assert.Equal(t, &bankly.BoletoPayer{
+		Name:     "JOAO DA SILVA",
+		Document: "76385230056",
+		Address: &bankly.BoletoAddress{
+			ZipCode:      "01001000",
+			AddressLine:  "RUA DAS FLORES 100",
+			Neighborhood: "CENTRO",
+			State:        "SP",
+			City:         "SAO PAULO",
+		},
+	}, request.Payer)
+	assert.Equal(t, &bankly.BoletoInterest{
+		StartDate: date(2026, time.November, 11),
+		Value:     1,
+		Type:      bankly.PercentInterestType,
+	}, request.Interest)
+	assert.Equal(t, &bankly.BoletoDiscounts{
+		LimitDate: date(2026, time.November, 5),
+		Value:     10,
+		Type:      bankly.FixedAmountDiscountType,
+	}, request.Discount)
+	assert.Equal(t, &bankly.BoletoFine{
+		StartDate: date(2026, time.November, 11),
+		Value:     2,
+		Type:      bankly.PercentFineType,
+	}, request.Fine)
+}
+
+func TestBilling_BoletoRequests_Unsupported(t *testing.T) {
+	file := buildBillingRemessa()
+	file.Batches[0].Boletos[0].Movement = BillingWriteOff
+
+	_, err := file.BoletoRequests()
+	assert.Equal(t, &LayoutError{Field: "movement", Message: "unsupported movement 02 at the boleto NF-1001"}, err)
+
+	file = buildBillingRemessa()
+	file.Batches[0].Boletos[0].Rebate = bankly.NewMoney(100)
+
+	_, err = file.BoletoRequests()
+	assert.Error(t, err)
+}
+
+func TestBilling_LayoutErrors(t *testing.T) {
+	data, err := Marshal(buildBillingRemessa())
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+	lines[2], lines[3] = lines[3], lines[2]
+	lines[2] = lines[2][:8] + "00001" + lines[2][13:]
+	lines[3] = lines[3][:8] + "00002" + lines[3][13:]
+
+	_, err = Parse(strings.NewReader(strings.Join(lines, "\r\n")))
+	assert.Equal(t, &LayoutError{Line: 3, Field: "segment", Message: "segment Q must follow a segment P"}, err)
+}
+
+func TestBilling_CNAB400(t *testing.T) {
+	remessa := buildBillingRemessa()
+	remessa.Batches[0].Boletos[0].Interest = Charge{Code: InterestDaily, Value: bankly.NewMoney(41)}
+	remessa.Batches[0].Boletos[0].CloseDays = 0
+
+	data, err := Marshal400(remessa)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+	assert.Len(t, lines, 3)
+	for i, l := range lines {
+		assert.Len(t, l, Record400Length)
+		assert.Equal(t, pad(i+1, 6), l[394:])
+	}
+	assert.Equal(t, "01REMESSA01COBRANCA", lines[0][:19])
+	assert.Equal(t, "9000003", lines[2][:7])
+
+	file, err := Parse400(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, "12345678000195", file.Header.Company.Document)
+
+	requests, err := file.BoletoRequests()
+	assert.NoError(t, err)
+	assert.Len(t, requests, 1)
+	assert.Equal(t, &bankly.Account{Branch: "0001", Number: "189162"}, requests[0].Account)
+	assert.Equal(t, 1250.5, requests[0].Amount)
+	assert.Equal(t, bankly.FixedAmountInterestType, requests[0].Interest.Type)
+	assert.Equal(t, 0.41, requests[0].Interest.Value)
+	assert.Equal(t, bankly.PercentFineType, requests[0].Fine.Type)
+	assert.Equal(t, "CENTRO", requests[0].Payer.Address.Neighborhood)
+
+	again, err := Marshal400(file)
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+
+	remessa.Batches[0].Boletos[0].Interest.Code = InterestMonthlyRate
+	_, err = Marshal400(remessa)
+	assert.Equal(t, &LayoutError{Line: 2, Field: "interest", Message: "only daily amounts of interest at the CNAB 400"}, err)
+
+	lines[1] = lines[1][:394] + "000003"
+	_, err = Parse400(strings.NewReader(strings.Join(lines, "\n")))
+	assert.Equal(t, &LayoutError{Line: 2, Field: "sequence", Message: "expected 000002"}, err)
+}
+
+func TestBilling_Retorno(t *testing.T) {
+	alias := "NF-1001"
+	paidAt := date(2026, time.November, 12).Add(14 * time.Hour)
+	updatedAt := date(2026, time.November, 12)
+
+	events := DetailedBoletoEvents(&bankly.BoletoDetailedResponse{
+		Alias:     &alias,
+		Status:    "Paid",
+		OurNumber: "1234567",
+		DueDate:   date(2026, time.November, 10),
+		Amount:    &bankly.BoletoAmount{Value: 1250.5},
+		Payer:     &bankly.Payer{Name: "João da Silva", Document: "763.852.300-56"},
+		Payments: []*bankly.BoletoPayment{{
+			Amount:         1275.51,
+			PaymentChannel: bankly.InternetBankingPaymentChannel,
+			PaidOutDate:    paidAt,
+		}},
+	})
+
+	events = append(events, FilterBoletoEvents(&bankly.FilterBoletoResponse{Data: []bankly.FilterBoletoData{
+		{Status: "Cancelled", DueDate: date(2026, time.November, 15), Amount: &bankly.BoletoAmount{Value: 10}},
+		{Status: "Registered", DueDate: date(2026, time.November, 20), Amount: &bankly.BoletoAmount{Value: 20}},
+		{Status: "Overdue", DueDate: date(2026, time.November, 1), Amount: &bankly.BoletoAmount{Value: 30}},
+	}}, updatedAt)...)
+
+	assert.Len(t, events, 3)
+	assert.Equal(t, BillingSettled, events[0].Movement)
+	assert.Equal(t, "33", events[0].Reasons)
+	assert.Equal(t, int64(127551), events[0].PaidAmount.Cents)
+	assert.Equal(t, int64(2501), events[0].PaidInterest.Cents)
+	assert.Equal(t, BillingCancelled, events[1].Movement)
+	assert.Equal(t, BillingRegistered, events[2].Movement)
+
+	company := buildBillingRemessa().Header.Company
+	retorno := NewBillingRetorno(company, events, updatedAt.Add(20*time.Hour), 8)
+
+	data, err := Marshal(retorno)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+	assert.Len(t, lines, 10)
+	assert.Equal(t, "00000800000300000000000128050", lines[8][17:46])
+
+	parsed, err := Parse(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, Retorno, parsed.Header.Kind)
+
+	settled := parsed.Batches[0].Boletos[0]
+	assert.Equal(t, SegmentT, settled.Segment)
+	assert.Equal(t, BillingSettled, settled.Movement)
+	assert.Equal(t, "NF-1001", settled.YourNumber)
+	assert.Equal(t, "76385230056", settled.Payer.Document)
+	assert.Equal(t, int64(127551), settled.PaidAmount.Cents)
+	assert.Equal(t, date(2026, time.November, 12), settled.OccurredAt)
+
+	data, err = Marshal400(retorno)
+	assert.NoError(t, err)
+
+	parsed, err = Parse400(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, Retorno, parsed.Header.Kind)
+	assert.Len(t, parsed.Batches[0].Boletos, 3)
+
+	settled = parsed.Batches[0].Boletos[0]
+	assert.Equal(t, BillingSettled, settled.Movement)
+	assert.Equal(t, "1234567", settled.OurNumber)
+	assert.Equal(t, "33", settled.Reasons)
+	assert.Equal(t, int64(127551), settled.PaidAmount.Cents)
+	assert.Equal(t, int64(2501), settled.PaidInterest.Cents)
+	assert.Equal(t, BillingCancelled, parsed.Batches[0].Boletos[1].Movement)
+}
@@ -0,0 +1,760 @@
+// Package cnab reads and writes the FEBRABAN CNAB files: the CNAB 240 payment
+// files, with the remessa of TEDs, credits, pix and boletos to pay and the
+// retorno with the result of each payment, and the CNAB 240 and 400 cobrança
+// files, with the boletos to issue and the retorno of their settlements.
+package cnab
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/calendar"
+)
+
+// FileKind ...
+type FileKind string
+
+const (
+	// Remessa is the file sent by the customer
+	Remessa FileKind = "1"
+	// Retorno is the file sent back by the bank
+	Retorno FileKind = "2"
+)
+
+// Services of the batch header
+const (
+	ServiceBilling  = "01"
+	ServicePayments = "20"
+)
+
+// Segments of the payment details
+const (
+	SegmentA = "A"
+	SegmentB = "B"
+	SegmentJ = "J"
+)
+
+// Segments of the cobrança details, P, Q and R at the remessa, T and U at the retorno
+const (
+	SegmentP = "P"
+	SegmentQ = "Q"
+	SegmentR = "R"
+	SegmentT = "T"
+	SegmentU = "U"
+)
+
+// Forms of the batch header, the forma de lançamento
+const (
+	FormCredit          = "01"
+	FormDOCTED          = "03"
+	FormBoletoOwnBank   = "30"
+	FormBoletoOtherBank = "31"
+	FormTEDOtherHolder  = "41"
+	FormTEDSameHolder   = "43"
+	FormPixTransfer     = "45"
+	FormPixQrCode       = "47"
+)
+
+// ChamberPix is the clearing chamber of the pix at the segment A
+const ChamberPix = "009"
+
+// Forms of pix initiation at the segment B
+const (
+	PixInitiationPhone    = "01"
+	PixInitiationEmail    = "02"
+	PixInitiationDocument = "03"
+	PixInitiationEVP      = "04"
+	PixInitiationAccount  = "05"
+)
+
+// Company is the customer at the file and batch headers
+type Company struct {
+	// DocumentType is 1 to CPF and 2 to CNPJ
+	DocumentType int
+	Document     string
+	Agreement    string
+	Branch       string
+	BranchDigit  string
+	Account      string
+	AccountDigit string
+	Name         string
+}
+
+// FileHeader ...
+type FileHeader struct {
+	BankCode      string
+	Company       Company
+	BankName      string
+	Kind          FileKind
+	GeneratedAt   time.Time
+	Sequence      int64
+	LayoutVersion string
+
+	raw string
+}
+
+// BatchHeader ...
+type BatchHeader struct {
+	Operation     string
+	ServiceType   string
+	Form          string
+	LayoutVersion string
+	Company       Company
+	Message       string
+	Occurrences   string
+	// Sequence and RecordedAt are read only from the cobrança batches
+	Sequence   int64
+	RecordedAt time.Time
+
+	raw string
+}
+
+// Party is a person at the segments B and J-52
+type Party struct {
+	DocumentType int
+	Document     string
+	Name         string
+}
+
+// Payment is a segment A with its segment B, or a segment J with its J-52
+type Payment struct {
+	Segment      string
+	MovementType string
+	Instruction  string
+	YourNumber   string
+	OurNumber    string
+	PaymentDate  time.Time
+	Amount       bankly.Money
+	Occurrences  string
+
+	// segment A
+	Chamber         string
+	BankCode        string
+	Branch          string
+	BranchDigit     string
+	Account         string
+	AccountDigit    string
+	Name            string
+	Currency        string
+	EffectiveDate   time.Time
+	EffectiveAmount bankly.Money
+	Information     string
+	TEDPurpose      string
+	AccountPurpose  string
+	Notice          string
+	Recipient       Party
+	ISPB            string
+	PixInitiation   string
+	PixTxID         string
+	PixMessage      string
+	PixKey          string
+	HasSegmentB     bool
+	segmentARaw     string
+	segmentBRaw     string
+
+	// segment J
+	Barcode     string
+	Assignor    string
+	DueDate     time.Time
+	Discount    bankly.Money
+	Interest    bankly.Money
+	Payer       Party
+	Beneficiary Party
+	Drawer      Party
+	HasJ52      bool
+	segmentJRaw string
+	j52Raw      string
+}
+
+// Batch has the payments, or the boletos of a cobrança batch
+type Batch struct {
+	Header   BatchHeader
+	Payments []*Payment
+	Boletos  []*Boleto
+
+	trailerRaw string
+}
+
+// File ...
+type File struct {
+	Header  FileHeader
+	Batches []*Batch
+
+	trailerRaw string
+}
+
+// IsPix returns true for a segment A paid by pix
+func (p *Payment) IsPix() bool {
+	return p.Segment == SegmentA && p.Chamber == ChamberPix
+}
+
+// Parse reads a CNAB 240 file checking the layout: the length of the lines, the
+// order of the records, the sequences and the totals of the trailers.
+func Parse(reader io.Reader) (*File, error) {
+	lines, err := readLines(reader, RecordLength)
+	if err != nil {
+		return nil, err
+	}
+	return parseLines(lines)
+}
+
+func readLines(reader io.Reader, length int) ([]line, error) {
+	scanner := bufio.NewScanner(reader)
+
+	texts := []string{}
+	for scanner.Scan() {
+		texts = append(texts, strings.TrimRight(scanner.Text(), "\r"))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// blank lines are accepted only at the end of the file
+	for len(texts) > 0 && texts[len(texts)-1] == "" {
+		texts = texts[:len(texts)-1]
+	}
+
+	lines := []line{}
+	for i, text := range texts {
+		text = decodeLine(text)
+		if len(text) != length {
+			return nil, &LayoutError{Line: i + 1, Message: fmt.Sprintf("expected %d characters", length)}
+		}
+		lines = append(lines, line{index: i + 1, text: text})
+	}
+
+	return lines, nil
+}
+
+func parseLines(lines []line) (*File, error) {
+	if len(lines) < 2 {
+		return nil, &LayoutError{Line: len(lines) + 1, Message: "expected file header and trailer"}
+	}
+
+	file := &File{}
+
+	first := lines[0]
+	if first.raw(8, 8) != "0" {
+		return nil, first.err("record type", "expected file header")
+	}
+
+	if err := parseFileHeader(first, &file.Header); err != nil {
+		return nil, err
+	}
+
+	records := 1
+	i := 1
+
+	for i < len(lines)-1 {
+		current := lines[i]
+		if err := checkBank(current, file.Header.BankCode); err != nil {
+			return nil, err
+		}
+
+		if current.raw(8, 8) != "1" {
+			return nil, current.err("record type", "expected batch header")
+		}
+
+		batch, next, err := parseBatch(lines, i, len(file.Batches)+1)
+		if err != nil {
+			return nil, err
+		}
+
+		records += next - i
+		file.Batches = append(file.Batches, batch)
+		i = next
+	}
+
+	last := lines[len(lines)-1]
+	if err := checkBank(last, file.Header.BankCode); err != nil {
+		return nil, err
+	}
+
+	if last.raw(4, 8) != "99999" {
+		return nil, last.err("record type", "expected file trailer")
+	}
+
+	batches, err := last.number("batches", 18, 23)
+	if err != nil {
+		return nil, err
+	}
+	if int(batches) != len(file.Batches) {
+		return nil, last.err("batches", "does not match the batches of the file")
+	}
+
+	total, err := last.number("records", 24, 29)
+	if err != nil {
+		return nil, err
+	}
+	if int(total) != records+1 {
+		return nil, last.err("records", "does not match the records of the file")
+	}
+
+	file.trailerRaw = last.text
+
+	return file, nil
+}
+
+func parseFileHeader(l line, header *FileHeader) error {
+	var err error
+
+	header.raw = l.text
+	header.BankCode, err = l.digits("bank code", 1, 3)
+	if err != nil {
+		return err
+	}
+
+	if l.raw(4, 7) != "0000" {
+		return l.err("batch", "expected 0000 at the file header")
+	}
+
+	header.Company, err = parseCompany(l, 0)
+	if err != nil {
+		return err
+	}
+
+	header.BankName = l.alpha(103, 132)
+	header.Kind = FileKind(l.raw(143, 143))
+	if header.Kind != Remessa && header.Kind != Retorno {
+		return l.err("file code", "expected 1 or 2")
+	}
+
+	date, err := l.date("generation date", 144, 151)
+	if err != nil {
+		return err
+	}
+
+	clock, err := l.digits("generation time", 152, 157)
+	if err != nil {
+		return err
+	}
+
+	generatedAt, err := time.ParseInLocation(dateLayout+"150405", date.Format(dateLayout)+clock, calendar.Location())
+	if err != nil {
+		return l.err("generation time", "invalid time "+clock)
+	}
+	header.GeneratedAt = generatedAt
+
+	header.Sequence, err = l.number("sequence", 158, 163)
+	if err != nil {
+		return err
+	}
+
+	header.LayoutVersion = l.raw(164, 166)
+
+	return nil
+}
+
+// parseCompany reads the company fields shared by the file and batch headers.
+// The cobrança batch header has a document of 15 digits, shifting the fields by one.
+func parseCompany(l line, shift int) (Company, error) {
+	company := Company{}
+
+	documentType, err := l.number("company document type", 18, 18)
+	if err != nil {
+		return company, err
+	}
+	if documentType != 1 && documentType != 2 {
+		return company, l.err("company document type", "expected 1 or 2")
+	}
+	company.DocumentType = int(documentType)
+
+	document, err := l.digits("company document", 19, 32+shift)
+	if err != nil {
+		return company, err
+	}
+	company.Document = trimDocument(document, company.DocumentType)
+
+	company.Agreement = l.alpha(33+shift, 52+shift)
+
+	branch, err := l.digits("company branch", 53+shift, 57+shift)
+	if err != nil {
+		return company, err
+	}
+	company.Branch = branch
+	company.BranchDigit = l.alpha(58+shift, 58+shift)
+
+	account, err := l.digits("company account", 59+shift, 70+shift)
+	if err != nil {
+		return company, err
+	}
+	company.Account = account
+	company.AccountDigit = l.alpha(71+shift, 71+shift)
+	company.Name = l.alpha(73+shift, 102+shift)
+
+	return company, nil
+}
+
+func parseBatch(lines []line, start int, expected int) (*Batch, int, error) {
+	header := lines[start]
+	batch := &Batch{}
+
+	number, err := header.number("batch", 4, 7)
+	if err != nil {
+		return nil, 0, err
+	}
+	if int(number) != expected {
+		return nil, 0, header.err("batch", "expected batch "+pad(expected, 4))
+	}
+
+	batch.Header.raw = header.text
+	batch.Header.Operation = header.raw(9, 9)
+	batch.Header.ServiceType = header.raw(10, 11)
+	batch.Header.LayoutVersion = header.raw(14, 16)
+
+	billing := batch.Header.ServiceType == ServiceBilling
+	if billing {
+		if err := parseBillingHeader(header, &batch.Header); err != nil {
+			return nil, 0, err
+		}
+	} else {
+		batch.Header.Form = header.raw(12, 13)
+		batch.Header.Message = header.alpha(103, 142)
+		batch.Header.Occurrences = header.alpha(231, 240)
+
+		batch.Header.Company, err = parseCompany(header, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	sequence := 0
+	total := bankly.NewMoney(0)
+	i := start + 1
+
+	for ; i < len(lines)-1 && lines[i].raw(8, 8) == "3"; i++ {
+		current := lines[i]
+
+		if err := checkBatch(current, number); err != nil {
+			return nil, 0, err
+		}
+
+		sequence++
+		if err := checkSequence(current, sequence); err != nil {
+			return nil, 0, err
+		}
+
+		if billing {
+			if err := parseBillingSegment(current, batch); err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		var last *Payment
+		if len(batch.Payments) > 0 {
+			last = batch.Payments[len(batch.Payments)-1]
+		}
+
+		segment := current.raw(14, 14)
+		switch {
+		case segment == SegmentA:
+			payment, err := parseSegmentA(current)
+			if err != nil {
+				return nil, 0, err
+			}
+			batch.Payments = append(batch.Payments, payment)
+			total, _ = total.Add(payment.Amount)
+
+		case segment == SegmentB:
+			if last == nil || last.Segment != SegmentA || last.HasSegmentB {
+				return nil, 0, current.err("segment", "segment B must follow a segment A")
+			}
+			if err := parseSegmentB(current, last, batch.Header.Form); err != nil {
+				return nil, 0, err
+			}
+
+		case segment == SegmentJ && current.raw(18, 19) == "52":
+			if last == nil || last.Segment != SegmentJ || last.HasJ52 {
+				return nil, 0, current.err("segment", "segment J-52 must follow a segment J")
+			}
+			if err := parseSegmentJ52(current, last); err != nil {
+				return nil, 0, err
+			}
+
+		case segment == SegmentJ:
+			payment, err := parseSegmentJ(current)
+			if err != nil {
+				return nil, 0, err
+			}
+			batch.Payments = append(batch.Payments, payment)
+			total, _ = total.Add(payment.Amount)
+
+		default:
+			return nil, 0, current.err("segment", "unsupported segment "+segment)
+		}
+	}
+
+	if i >= len(lines)-1 || lines[i].raw(8, 8) != "5" {
+		line := lines[len(lines)-1]
+		if i < len(lines) {
+			line = lines[i]
+		}
+		return nil, 0, line.err("record type", "expected batch trailer")
+	}
+
+	trailer := lines[i]
+	if err := checkBatch(trailer, number); err != nil {
+		return nil, 0, err
+	}
+
+	records, err := trailer.number("batch records", 18, 23)
+	if err != nil {
+		return nil, 0, err
+	}
+	if int(records) != sequence+2 {
+		return nil, 0, trailer.err("batch records", "does not match the records of the batch")
+	}
+
+	// the cobrança trailer has no total amount, only the totals of the retorno by wallet
+	if !billing {
+		amount, err := trailer.money("batch amount", 24, 41)
+		if err != nil {
+			return nil, 0, err
+		}
+		if amount.Cents != total.Cents {
+			return nil, 0, trailer.err("batch amount", "does not match the amounts of the batch")
+		}
+	}
+
+	batch.trailerRaw = trailer.text
+
+	return batch, i + 1, nil
+}
+
+func parseSegmentA(l line) (*Payment, error) {
+	var err error
+	payment := &Payment{Segment: SegmentA, segmentARaw: l.text}
+
+	payment.MovementType = l.raw(15, 15)
+	payment.Instruction = l.raw(16, 17)
+
+	if payment.Chamber, err = l.digits("chamber", 18, 20); err != nil {
+		return nil, err
+	}
+	if payment.BankCode, err = l.digits("favored bank", 21, 23); err != nil {
+		return nil, err
+	}
+	if payment.Branch, err = l.digits("favored branch", 24, 28); err != nil {
+		return nil, err
+	}
+	payment.BranchDigit = l.alpha(29, 29)
+	if payment.Account, err = l.digits("favored account", 30, 41); err != nil {
+		return nil, err
+	}
+	payment.AccountDigit = l.alpha(42, 42)
+	payment.Name = l.alpha(44, 73)
+	payment.YourNumber = l.alpha(74, 93)
+
+	if payment.PaymentDate, err = l.date("payment date", 94, 101); err != nil {
+		return nil, err
+	}
+	if payment.PaymentDate.IsZero() {
+		return nil, l.err("payment date", "required")
+	}
+
+	payment.Currency = l.alpha(102, 104)
+	if payment.Amount, err = l.money("amount", 120, 134); err != nil {
+		return nil, err
+	}
+	if payment.Amount.Cents == 0 {
+		return nil, l.err("amount", "required")
+	}
+
+	payment.OurNumber = l.alpha(135, 154)
+	if payment.EffectiveDate, err = l.date("effective date", 155, 162); err != nil {
+		return nil, err
+	}
+	if payment.EffectiveAmount, err = l.money("effective amount", 163, 177); err != nil {
+		return nil, err
+	}
+
+	payment.Information = l.alpha(178, 217)
+	payment.TEDPurpose = l.alpha(220, 224)
+	payment.AccountPurpose = l.alpha(225, 226)
+	payment.Notice = l.alpha(230, 230)
+	payment.Occurrences = l.alpha(231, 240)
+
+	return payment, nil
+}
+
+func parseSegmentB(l line, payment *Payment, form string) error {
+	payment.HasSegmentB = true
+	payment.segmentBRaw = l.text
+
+	documentType, err := l.number("favored document type", 18, 18)
+	if err != nil {
+		return err
+	}
+
+	document, err := l.digits("favored document", 19, 32)
+	if err != nil {
+		return err
+	}
+
+	payment.Recipient = Party{
+		DocumentType: int(documentType),
+		Document:     trimDocument(document, int(documentType)),
+		Name:         payment.Name,
+	}
+
+	payment.ISPB = strings.TrimSpace(l.raw(233, 240))
+	if payment.ISPB != "" && !bankly.IsOnlyDigits(payment.ISPB) {
+		return l.err("ispb", "expected digits")
+	}
+
+	if payment.Chamber != ChamberPix && form != FormPixTransfer && form != FormPixQrCode {
+		return nil
+	}
+
+	payment.PixInitiation = l.alpha(15, 17)
+	if len(payment.PixInitiation) == 3 && payment.PixInitiation[0] == '0' {
+		payment.PixInitiation = payment.PixInitiation[1:]
+	}
+	payment.PixTxID = l.alpha(33, 67)
+	payment.PixMessage = l.alpha(68, 127)
+	payment.PixKey = strings.TrimSpace(l.raw(128, 226))
+
+	switch payment.PixInitiation {
+	case PixInitiationPhone, PixInitiationEmail, PixInitiationDocument, PixInitiationEVP:
+		if payment.PixKey == "" {
+			return l.err("pix key", "required by the initiation "+payment.PixInitiation)
+		}
+	case PixInitiationAccount:
+		if payment.ISPB == "" {
+			return l.err("ispb", "required by the initiation by account")
+		}
+	default:
+		return l.err("pix initiation", "unsupported initiation "+payment.PixInitiation)
+	}
+
+	return nil
+}
+
+func parseSegmentJ(l line) (*Payment, error) {
+	var err error
+	payment := &Payment{Segment: SegmentJ, segmentJRaw: l.text}
+
+	payment.MovementType = l.raw(15, 15)
+	payment.Instruction = l.raw(16, 17)
+
+	if payment.Barcode, err = l.digits("barcode", 18, 61); err != nil {
+		return nil, err
+	}
+
+	payment.Assignor = l.alpha(62, 91)
+
+	if payment.DueDate, err = l.date("due date", 92, 99); err != nil {
+		return nil, err
+	}
+	if payment.Discount, err = l.money("discount", 115, 129); err != nil {
+		return nil, err
+	}
+	if payment.Interest, err = l.money("interest", 130, 144); err != nil {
+		return nil, err
+	}
+	if payment.PaymentDate, err = l.date("payment date", 145, 152); err != nil {
+		return nil, err
+	}
+	if payment.PaymentDate.IsZero() {
+		return nil, l.err("payment date", "required")
+	}
+	if payment.Amount, err = l.money("amount", 153, 167); err != nil {
+		return nil, err
+	}
+	if payment.Amount.Cents == 0 {
+		return nil, l.err("amount", "required")
+	}
+
+	payment.YourNumber = l.alpha(183, 202)
+	payment.OurNumber = l.alpha(203, 222)
+	payment.Occurrences = l.alpha(231, 240)
+
+	return payment, nil
+}
+
+func parseSegmentJ52(l line, payment *Payment) error {
+	payment.HasJ52 = true
+	payment.j52Raw = l.text
+
+	var err error
+	if payment.Payer, err = parseParty(l, "payer", 20, 21, 35, 36, 75); err != nil {
+		return err
+	}
+	if payment.Beneficiary, err = parseParty(l, "beneficiary", 76, 77, 91, 92, 131); err != nil {
+		return err
+	}
+	if payment.Drawer, err = parseParty(l, "drawer", 132, 133, 147, 148, 187); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func parseParty(l line, field string, typePosition, documentStart, documentEnd, nameStart, nameEnd int) (Party, error) {
+	documentType, err := l.number(field+" document type", typePosition, typePosition)
+	if err != nil {
+		return Party{}, err
+	}
+
+	document, err := l.digits(field+" document", documentStart, documentEnd)
+	if err != nil {
+		return Party{}, err
+	}
+
+	return Party{
+		DocumentType: int(documentType),
+		Document:     trimDocument(document, int(documentType)),
+		Name:         l.alpha(nameStart, nameEnd),
+	}, nil
+}
+
+func checkBank(l line, bankCode string) error {
+	if l.raw(1, 3) != bankCode {
+		return l.err("bank code", "expected "+bankCode)
+	}
+	return nil
+}
+
+func checkBatch(l line, number int64) error {
+	batch, err := l.number("batch", 4, 7)
+	if err != nil {
+		return err
+	}
+	if batch != number {
+		return l.err("batch", "expected batch "+pad(int(number), 4))
+	}
+	return nil
+}
+
+func checkSequence(l line, sequence int) error {
+	current, err := l.number("sequence", 9, 13)
+	if err != nil {
+		return err
+	}
+	if int(current) != sequence {
+		return l.err("sequence", "expected "+pad(sequence, 5))
+	}
+	return nil
+}
+
+// trimDocument removes the left zeros out of the CPF and CNPJ lengths
+func trimDocument(document string, documentType int) string {
+	size := 14
+	if documentType == 1 {
+		size = 11
+	}
+	if len(document) > size {
+		document = document[len(document)-size:]
+	}
+	return document
+}
+
+func pad(value int, size int) string {
+	return fmt.Sprintf("%0*d", size, value)
+}
@@ -0,0 +1,597 @@
+package cnab
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	bankly "github.com/contbank/bankly-sdk"
+)
+
+// Parse400 reads a CNAB 400 cobrança file. The CNAB 400 has no batches, its details
+// are read as a single cobrança batch: the remessa as the segments P, Q and R and
+// the retorno as the segments T and U. The positions follow the Santander layout,
+// the one adopted by most banks without a layout of their own.
+func Parse400(reader io.Reader) (*File, error) {
+	lines, err := readLines(reader, Record400Length)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) < 2 {
+		return nil, &LayoutError{Line: len(lines) + 1, Message: "expected file header and trailer"}
+	}
+
+	for i, l := range lines {
+		if err := checkSequence400(l, i+1); err != nil {
+			return nil, err
+		}
+	}
+
+	file := &File{}
+	if err := parseHeader400(lines[0], &file.Header); err != nil {
+		return nil, err
+	}
+
+	batch := &Batch{Header: BatchHeader{
+		ServiceType: ServiceBilling,
+		Company:     file.Header.Company,
+		Sequence:    file.Header.Sequence,
+		RecordedAt:  file.Header.GeneratedAt,
+	}}
+	if file.Header.Kind == Retorno {
+		batch.Header.Operation = "T"
+	}
+
+	total := bankly.NewMoney(0)
+	last := lines[len(lines)-1]
+
+	for _, l := range lines[1 : len(lines)-1] {
+		if l.raw(1, 1) != "1" {
+			return nil, l.err("record type", "expected detail")
+		}
+
+		// the document of the company is only at the details
+		if len(batch.Boletos) == 0 {
+			if err := parseCompany400(l, &file.Header.Company); err != nil {
+				return nil, err
+			}
+			batch.Header.Company = file.Header.Company
+		}
+
+		var boleto *Boleto
+		if file.Header.Kind == Retorno {
+			boleto, err = parseRetorno400(l)
+		} else {
+			boleto, err = parseRemessa400(l)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		batch.Boletos = append(batch.Boletos, boleto)
+		total, _ = total.Add(boleto.Amount)
+	}
+
+	if last.raw(1, 1) != "9" {
+		return nil, last.err("record type", "expected file trailer")
+	}
+
+	records, err := last.number("records", 2, 7)
+	if err != nil {
+		return nil, err
+	}
+	if int(records) != len(lines) {
+		return nil, last.err("records", "does not match the records of the file")
+	}
+
+	amount, err := last.money("amount", 8, 20)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Cents != total.Cents {
+		return nil, last.err("amount", "does not match the amounts of the file")
+	}
+
+	file.Batches = []*Batch{batch}
+
+	return file, nil
+}
+
+// Marshal400 writes the boletos of the cobrança batches as a CNAB 400 file, with CRLF line endings
+func Marshal400(file *File) ([]byte, error) {
+	lines := [][]byte{}
+	add := func(r record, err error) error {
+		if err != nil {
+			if layoutErr, ok := err.(*LayoutError); ok {
+				layoutErr.Line = len(lines) + 1
+			}
+			return err
+		}
+		if err := r.number("sequence", 395, 400, int64(len(lines)+1)); err != nil {
+			return err
+		}
+		lines = append(lines, r)
+		return nil
+	}
+
+	if err := add(writeHeader400(&file.Header)); err != nil {
+		return nil, err
+	}
+
+	total := bankly.NewMoney(0)
+	for _, batch := range file.Batches {
+		if batch.Header.ServiceType != ServiceBilling {
+			return nil, &LayoutError{Field: "service type", Message: "only cobrança batches at the CNAB 400"}
+		}
+
+		for _, boleto := range batch.Boletos {
+			if err := add(writeDetail400(file.Header, boleto)); err != nil {
+				return nil, err
+			}
+
+			var err error
+			if total, err = total.Add(boleto.Amount); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	r := newSizedRecord("", Record400Length)
+	copy(r[0:1], "9")
+	if err := r.number("records", 2, 7, int64(len(lines)+1)); err != nil {
+		return nil, err
+	}
+	copy(r[20:394], strings.Repeat("0", 374))
+	if err := add(r, r.money("amount", 8, 20, total)); err != nil {
+		return nil, err
+	}
+
+	return append(bytes.Join(lines, []byte("\r\n")), '\r', '\n'), nil
+}
+
+func checkSequence400(l line, sequence int) error {
+	current, err := l.number("sequence", 395, 400)
+	if err != nil {
+		return err
+	}
+	if int(current) != sequence {
+		return l.err("sequence", "expected "+pad(sequence, 6))
+	}
+	return nil
+}
+
+func parseHeader400(l line, header *FileHeader) error {
+	var err error
+
+	if l.raw(1, 1) != "0" {
+		return l.err("record type", "expected file header")
+	}
+
+	header.Kind = FileKind(l.raw(2, 2))
+	if header.Kind != Remessa && header.Kind != Retorno {
+		return l.err("file code", "expected 1 or 2")
+	}
+	if l.raw(10, 11) != ServiceBilling {
+		return l.err("service type", "expected "+ServiceBilling)
+	}
+
+	if header.Company.Branch, err = l.digits("company branch", 27, 30); err != nil {
+		return err
+	}
+	if header.Company.Account, err = l.digits("company account", 31, 37); err != nil {
+		return err
+	}
+	header.Company.AccountDigit = l.alpha(38, 38)
+	header.Company.Name = l.alpha(47, 76)
+
+	if header.BankCode, err = l.digits("bank code", 77, 79); err != nil {
+		return err
+	}
+	header.BankName = l.alpha(80, 94)
+
+	if header.GeneratedAt, err = l.date("generation date", 95, 100); err != nil {
+		return err
+	}
+	if header.Sequence, err = l.number("sequence", 101, 107); err != nil {
+		return err
+	}
+
+	header.LayoutVersion = l.alpha(392, 394)
+	header.raw = l.text
+
+	return nil
+}
+
+func parseCompany400(l line, company *Company) error {
+	documentType, err := l.number("company document type", 2, 3)
+	if err != nil {
+		return err
+	}
+
+	document, err := l.digits("company document", 4, 17)
+	if err != nil {
+		return err
+	}
+
+	company.DocumentType = int(documentType)
+	company.Document = trimDocument(document, company.DocumentType)
+
+	return nil
+}
+
+// parseDetail400 reads the fields shared by the details of the remessa and retorno
+func parseDetail400(l line, boleto *Boleto) error {
+	ourNumber, err := l.digits("our number", 63, 70)
+	if err != nil {
+		return err
+	}
+
+	boleto.CompanyUse = l.alpha(38, 62)
+	boleto.OurNumber = strings.TrimLeft(ourNumber, "0")
+	boleto.Wallet = l.alpha(108, 108)
+	boleto.Movement = l.raw(109, 110)
+	boleto.firstRaw = l.text
+
+	return nil
+}
+
+func parseRemessa400(l line) (*Boleto, error) {
+	var err error
+	boleto := &Boleto{Segment: SegmentP, HasQ: true}
+
+	if err := parseDetail400(l, boleto); err != nil {
+		return nil, err
+	}
+
+	if l.raw(78, 78) == "4" {
+		boleto.HasR = true
+		boleto.Fine.Code = FinePercent
+		if boleto.Fine.Value, err = l.money("fine", 79, 82); err != nil {
+			return nil, err
+		}
+		if boleto.Fine.Date, err = l.date("fine date", 102, 107); err != nil {
+			return nil, err
+		}
+	}
+
+	boleto.YourNumber = l.alpha(111, 120)
+
+	if boleto.DueDate, err = l.date("due date", 121, 126); err != nil {
+		return nil, err
+	}
+	if boleto.DueDate.IsZero() {
+		return nil, l.err("due date", "required")
+	}
+	if boleto.Amount, err = l.money("amount", 127, 139); err != nil {
+		return nil, err
+	}
+	if boleto.Amount.Cents == 0 {
+		return nil, l.err("amount", "required")
+	}
+
+	boleto.Kind = l.alpha(148, 149)
+	boleto.Acceptance = l.alpha(150, 150)
+
+	if boleto.IssueDate, err = l.date("issue date", 151, 156); err != nil {
+		return nil, err
+	}
+	if boleto.Interest.Value, err = l.money("interest", 161, 173); err != nil {
+		return nil, err
+	}
+	if !boleto.Interest.Value.IsZero() {
+		boleto.Interest.Code = InterestDaily
+	}
+	if boleto.Discount.Date, err = l.date("discount date", 174, 179); err != nil {
+		return nil, err
+	}
+	if boleto.Discount.Value, err = l.money("discount", 180, 192); err != nil {
+		return nil, err
+	}
+	if !boleto.Discount.Value.IsZero() {
+		boleto.Discount.Code = DiscountFixed
+	}
+	if boleto.Rebate, err = l.money("rebate", 206, 218); err != nil {
+		return nil, err
+	}
+
+	payerType, err := l.number("payer document type", 219, 220)
+	if err != nil {
+		return nil, err
+	}
+	payerDocument, err := l.digits("payer document", 221, 234)
+	if err != nil {
+		return nil, err
+	}
+	zipCode, err := l.digits("payer zip code", 327, 334)
+	if err != nil {
+		return nil, err
+	}
+
+	boleto.Payer = Payer{
+		Party: Party{
+			DocumentType: int(payerType),
+			Document:     trimDocument(payerDocument, int(payerType)),
+			Name:         l.alpha(235, 274),
+		},
+		Address:      l.alpha(275, 314),
+		Neighborhood: l.alpha(315, 326),
+		ZipCode:      zipCode,
+		City:         l.alpha(335, 349),
+		State:        l.alpha(350, 351),
+	}
+
+	return boleto, nil
+}
+
+func parseRetorno400(l line) (*Boleto, error) {
+	var err error
+	boleto := &Boleto{Segment: SegmentT, HasU: true}
+
+	if err := parseDetail400(l, boleto); err != nil {
+		return nil, err
+	}
+
+	if boleto.OccurredAt, err = l.date("occurrence date", 111, 116); err != nil {
+		return nil, err
+	}
+
+	boleto.YourNumber = l.alpha(117, 126)
+	boleto.Reasons = l.alpha(137, 146)
+
+	if boleto.DueDate, err = l.date("due date", 147, 152); err != nil {
+		return nil, err
+	}
+	if boleto.Amount, err = l.money("amount", 153, 165); err != nil {
+		return nil, err
+	}
+	if boleto.CollectorBank, err = l.digits("collector bank", 166, 168); err != nil {
+		return nil, err
+	}
+	if boleto.CollectorBranch, err = l.digits("collector branch", 169, 173); err != nil {
+		return nil, err
+	}
+
+	boleto.Kind = l.alpha(174, 175)
+
+	amounts := []struct {
+		field  string
+		start  int
+		amount *bankly.Money
+	}{
+		{"tariff", 176, &boleto.Tariff},
+		{"rebate", 228, &boleto.Rebate},
+		{"paid discount", 241, &boleto.PaidDiscount},
+		{"paid amount", 254, &boleto.PaidAmount},
+		{"paid interest", 267, &boleto.PaidInterest},
+	}
+
+	for _, a := range amounts {
+		if *a.amount, err = l.money(a.field, a.start, a.start+12); err != nil {
+			return nil, err
+		}
+	}
+
+	if boleto.CreditDate, err = l.date("credit date", 296, 301); err != nil {
+		return nil, err
+	}
+
+	boleto.Payer = Payer{Party: Party{Name: l.alpha(302, 337)}}
+
+	return boleto, nil
+}
+
+func writeHeader400(header *FileHeader) (record, error) {
+	r := newSizedRecord(header.raw, Record400Length)
+
+	kind := header.Kind
+	if kind == "" {
+		kind = Remessa
+	}
+
+	copy(r[0:1], "0")
+	r.alpha(2, 2, string(kind))
+	if kind == Retorno {
+		r.alpha(3, 9, "RETORNO")
+	} else {
+		r.alpha(3, 9, "REMESSA")
+	}
+	r.alpha(10, 11, ServiceBilling)
+	r.alpha(12, 26, "COBRANCA")
+
+	if err := writeAccount400(r, header.Company); err != nil {
+		return nil, err
+	}
+
+	r.alpha(47, 76, header.Company.Name)
+	if err := r.digits("bank code", 77, 79, header.BankCode); err != nil {
+		return nil, err
+	}
+	r.alpha(80, 94, header.BankName)
+	r.date(95, 100, header.GeneratedAt)
+
+	if err := r.number("sequence", 101, 107, header.Sequence); err != nil {
+		return nil, err
+	}
+
+	r.alpha(392, 394, header.LayoutVersion)
+
+	return r, nil
+}
+
+// writeAccount400 writes the branch with 4 digits and the account with 7 digits and its digit
+func writeAccount400(r record, company Company) error {
+	if err := r.digits("company branch", 27, 30, strings.TrimLeft(company.Branch, "0")); err != nil {
+		return err
+	}
+	if err := r.digits("company account", 31, 37, strings.TrimLeft(company.Account, "0")); err != nil {
+		return err
+	}
+	r.alpha(38, 38, company.AccountDigit)
+	copy(r[38:46], "00000000")
+	return nil
+}
+
+func writeDetail400(header FileHeader, boleto *Boleto) (record, error) {
+	r := newSizedRecord(boleto.firstRaw, Record400Length)
+	copy(r[0:1], "1")
+
+	company := header.Company
+	if err := r.number("company document type", 2, 3, int64(company.DocumentType)); err != nil {
+		return nil, err
+	}
+	if err := r.digits("company document", 4, 17, company.Document); err != nil {
+		return nil, err
+	}
+
+	account := newSizedRecord("", Record400Length)
+	if err := writeAccount400(account, company); err != nil {
+		return nil, err
+	}
+	copy(r[17:37], account[26:46])
+
+	r.alpha(38, 62, boleto.CompanyUse)
+	if err := r.digits("our number", 63, 70, strings.TrimLeft(boleto.OurNumber, "0")); err != nil {
+		return nil, err
+	}
+	r.alpha(108, 108, boleto.Wallet)
+
+	movement := boleto.Movement
+	if movement == "" && header.Kind != Retorno {
+		movement = BillingEntry
+	}
+	r.alpha(109, 110, movement)
+
+	if header.Kind == Retorno {
+		if boleto.Segment != SegmentT {
+			return nil, &LayoutError{Field: "segment", Message: "expected the segment T at the retorno"}
+		}
+		return r, writeRetorno400(r, boleto)
+	}
+
+	if boleto.Segment != SegmentP {
+		return nil, &LayoutError{Field: "segment", Message: "expected the segment P at the remessa"}
+	}
+	return r, writeRemessa400(r, boleto)
+}
+
+func writeRemessa400(r record, boleto *Boleto) error {
+	switch {
+	case boleto.Fine.Code == FinePercent:
+		r.alpha(78, 78, "4")
+		if err := r.money("fine", 79, 82, boleto.Fine.Value); err != nil {
+			return err
+		}
+		r.date(102, 107, boleto.Fine.Date)
+	case boleto.Fine.Code == FineFixed:
+		return &LayoutError{Field: "fine", Message: "only percent fines at the CNAB 400"}
+	default:
+		r.alpha(78, 78, "0")
+		copy(r[78:82], "0000")
+		r.date(102, 107, boleto.Fine.Date)
+	}
+
+	r.alpha(111, 120, boleto.YourNumber)
+	r.date(121, 126, boleto.DueDate)
+
+	if err := r.money("amount", 127, 139, boleto.Amount); err != nil {
+		return err
+	}
+
+	kind := boleto.Kind
+	if kind == "" {
+		kind = "02"
+	}
+	r.alpha(148, 149, kind)
+
+	acceptance := boleto.Acceptance
+	if acceptance == "" {
+		acceptance = "N"
+	}
+	r.alpha(150, 150, acceptance)
+	r.date(151, 156, boleto.IssueDate)
+
+	if boleto.Interest.Code == InterestMonthlyRate {
+		return &LayoutError{Field: "interest", Message: "only daily amounts of interest at the CNAB 400"}
+	}
+	if boleto.Discount.Code == DiscountPercent {
+		return &LayoutError{Field: "discount", Message: "only fixed discounts at the CNAB 400"}
+	}
+
+	if err := r.money("interest", 161, 173, boleto.Interest.Value); err != nil {
+		return err
+	}
+	r.date(174, 179, boleto.Discount.Date)
+	if err := r.money("discount", 180, 192, boleto.Discount.Value); err != nil {
+		return err
+	}
+	if err := r.money("iof", 193, 205, bankly.NewMoney(0)); err != nil {
+		return err
+	}
+	if err := r.money("rebate", 206, 218, boleto.Rebate); err != nil {
+		return err
+	}
+
+	if err := r.number("payer document type", 219, 220, int64(boleto.Payer.DocumentType)); err != nil {
+		return err
+	}
+	if err := r.digits("payer document", 221, 234, boleto.Payer.Document); err != nil {
+		return err
+	}
+	r.alpha(235, 274, boleto.Payer.Name)
+	r.alpha(275, 314, boleto.Payer.Address)
+	r.alpha(315, 326, boleto.Payer.Neighborhood)
+	if err := r.digits("payer zip code", 327, 334, boleto.Payer.ZipCode); err != nil {
+		return err
+	}
+	r.alpha(335, 349, boleto.Payer.City)
+	r.alpha(350, 351, boleto.Payer.State)
+
+	return nil
+}
+
+func writeRetorno400(r record, boleto *Boleto) error {
+	r.date(111, 116, boleto.OccurredAt)
+	r.alpha(117, 126, boleto.YourNumber)
+	r.alpha(137, 146, boleto.Reasons)
+	r.date(147, 152, boleto.DueDate)
+
+	if err := r.money("amount", 153, 165, boleto.Amount); err != nil {
+		return err
+	}
+	if err := r.digits("collector bank", 166, 168, boleto.CollectorBank); err != nil {
+		return err
+	}
+	if err := r.digits("collector branch", 169, 173, boleto.CollectorBranch); err != nil {
+		return err
+	}
+
+	kind := boleto.Kind
+	if kind == "" {
+		kind = "02"
+	}
+	r.alpha(174, 175, kind)
+
+	amounts := []struct {
+		field  string
+		start  int
+		amount bankly.Money
+	}{
+		{"tariff", 176, boleto.Tariff},
+		{"rebate", 228, boleto.Rebate},
+		{"paid discount", 241, boleto.PaidDiscount},
+		{"paid amount", 254, boleto.PaidAmount},
+		{"paid interest", 267, boleto.PaidInterest},
+	}
+
+	for _, a := range amounts {
+		if err := r.money(a.field, a.start, a.start+12, a.amount); err != nil {
+			return err
+		}
+	}
+
+	r.date(296, 301, boleto.CreditDate)
+	r.alpha(302, 337, boleto.Payer.Name)
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package cnab
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, calendar.Location())
+}
+
+func buildRemessa() *File {
+	company := Company{
+		DocumentType: 2,
+		Document:     "12345678000195",
+		Branch:       "00001",
+		Account:      "000000018916",
+		AccountDigit: "2",
+		Name:         "Empresa Pagadora",
+	}
+
+	return &File{
+		Header: FileHeader{
+			BankCode:    bankly.InternalBankCode,
+			Company:     company,
+			BankName:    "Bankly",
+			Kind:        Remessa,
+			GeneratedAt: date(2026, time.October, 19).Add(9 * time.Hour),
+			Sequence:    1,
+		},
+		Batches: []*Batch{
+			{
+				Header: BatchHeader{Form: FormTEDOtherHolder, Company: company},
+				Payments: []*Payment{{
+					Segment:        SegmentA,
+					Chamber:        "018",
+					BankCode:       "001",
+					Branch:         "00001",
+					BranchDigit:    "9",
+					Account:        "000000012345",
+					AccountDigit:   "5",
+					Name:           "João da Silva",
+					YourNumber:     "TED-1",
+					PaymentDate:    date(2026, time.October, 19),
+					Amount:         bankly.NewMoney(150000),
+					AccountPurpose: "PP",
+					HasSegmentB:    true,
+					Recipient:      Party{DocumentType: 1, Document: "76385230056"},
+				}},
+			},
+			{
+				Header: BatchHeader{Form: FormPixTransfer, Company: company},
+				Payments: []*Payment{
+					{
+						Segment:       SegmentA,
+						Chamber:       ChamberPix,
+						BankCode:      "341",
+						Branch:        "02545",
+						Account:       "000000002366",
+						AccountDigit:  "1",
+						Name:          "Maria Souza",
+						YourNumber:    "PIX-1",
+						PaymentDate:   date(2026, time.October, 19),
+						Amount:        bankly.NewMoney(1005),
+						HasSegmentB:   true,
+						Recipient:     Party{DocumentType: 1, Document: "16246241620"},
+						PixInitiation: PixInitiationEmail,
+						PixMessage:    "Aluguel",
+						PixKey:        "Maria@Example.com",
+					},
+					{
+						Segment:       SegmentA,
+						Chamber:       ChamberPix,
+						BankCode:      "341",
+						Branch:        "02545",
+						Account:       "000000002366",
+						AccountDigit:  "1",
+						Name:          "Maria Souza",
+						YourNumber:    "PIX-2",
+						PaymentDate:   date(2026, time.October, 19),
+						Amount:        bankly.NewMoney(2000),
+						HasSegmentB:   true,
+						Recipient:     Party{DocumentType: 1, Document: "16246241620"},
+						PixInitiation: PixInitiationAccount,
+						ISPB:          "60701190",
+					},
+				},
+			},
+			{
+				Header: BatchHeader{Form: FormBoletoOtherBank, Company: company},
+				Payments: []*Payment{{
+					Segment:     SegmentJ,
+					Barcode:     "34191090080012345678901234567890123456789012",
+					Assignor:    "Fornecedor",
+					DueDate:     date(2026, time.October, 20),
+					PaymentDate: date(2026, time.October, 19),
+					Amount:      bankly.NewMoney(25990),
+					YourNumber:  "BOLETO-1",
+					HasJ52:      true,
+					Payer:       Party{DocumentType: 2, Document: "12345678000195", Name: "Empresa Pagadora"},
+					Beneficiary: Party{DocumentType: 2, Document: "11222333000181", Name: "Fornecedor"},
+				}},
+			},
+		},
+	}
+}
+
+func marshalRemessa(t *testing.T) []byte {
+	data, err := Marshal(buildRemessa())
+	assert.NoError(t, err)
+	return data
+}
+
+func TestMarshal(t *testing.T) {
+	data := marshalRemessa(t)
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+
+	// header, 3 batches with header and trailer, 5 details of TED and pix, 2 of boleto, trailer
+	assert.Len(t, lines, 1+6+6+2+1)
+	for _, l := range lines {
+		assert.Len(t, l, RecordLength)
+	}
+
+	assert.Equal(t, "33200000", lines[0][:8])
+	assert.Equal(t, "33200013", lines[2][:8])
+	assert.Equal(t, "00002B", lines[3][8:14])
+	assert.Equal(t, "JOAO DA SILVA", strings.TrimSpace(lines[2][43:73]))
+	assert.Equal(t, "Maria@Example.com", strings.TrimSpace(lines[7][127:226]))
+	assert.Equal(t, "33299999", lines[len(lines)-1][:8])
+	assert.Equal(t, "000003000016", lines[len(lines)-1][17:29])
+}
+
+func TestParse(t *testing.T) {
+	file, err := Parse(bytes.NewReader(marshalRemessa(t)))
+
+	assert.NoError(t, err)
+	assert.Equal(t, Remessa, file.Header.Kind)
+	assert.Equal(t, "12345678000195", file.Header.Company.Document)
+	assert.Equal(t, date(2026, time.October, 19).Add(9*time.Hour), file.Header.GeneratedAt)
+	assert.Len(t, file.Batches, 3)
+
+	ted := file.Batches[0].Payments[0]
+	assert.Equal(t, "JOAO DA SILVA", ted.Name)
+	assert.Equal(t, "76385230056", ted.Recipient.Document)
+	assert.Equal(t, int64(150000), ted.Amount.Cents)
+	assert.False(t, ted.IsPix())
+
+	pix := file.Batches[1].Payments[0]
+	assert.True(t, pix.IsPix())
+	assert.Equal(t, PixInitiationEmail, pix.PixInitiation)
+	assert.Equal(t, "Maria@Example.com", pix.PixKey)
+	assert.Equal(t, "60701190", file.Batches[1].Payments[1].ISPB)
+
+	boleto := file.Batches[2].Payments[0]
+	assert.True(t, boleto.HasJ52)
+	assert.Equal(t, "34191090080012345678901234567890123456789012", boleto.Barcode)
+	assert.Equal(t, "11222333000181", boleto.Beneficiary.Document)
+	assert.Equal(t, date(2026, time.October, 20), boleto.DueDate)
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	data := marshalRemessa(t)
+
+	file, err := Parse(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	again, err := Marshal(file)
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), string(again))
+}
+
+func TestParse_Accents(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(string(marshalRemessa(t)), "\r\n"), "\r\n")
+
+	for _, name := range []string{"JOÃO DA SILVA", "JO\xc3O DA SILVA"} {
+		copied := append([]string{}, lines...)
+		copied[2] = copied[2][:43] + name + copied[2][43+len("JOAO DA SILVA"):]
+
+		file, err := Parse(strings.NewReader(strings.Join(copied, "\r\n")))
+		assert.NoError(t, err)
+		assert.Equal(t, "JOAO DA SILVA", file.Batches[0].Payments[0].Name)
+	}
+}
+
+func TestParse_LayoutErrors(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(string(marshalRemessa(t)), "\r\n"), "\r\n")
+
+	parse := func(change func(lines []string) []string) error {
+		copied := append([]string{}, lines...)
+		_, err := Parse(strings.NewReader(strings.Join(change(copied), "\n")))
+		return err
+	}
+
+	err := parse(func(l []string) []string {
+		l[2] = l[2][:239]
+		return l
+	})
+	assert.Equal(t, &LayoutError{Line: 3, Message: "expected 240 characters"}, err)
+
+	err = parse(func(l []string) []string {
+		l[4] = l[4][:23] + "000000000000150001" + l[4][41:]
+		return l
+	})
+	assert.Equal(t, &LayoutError{Line: 5, Field: "batch amount", Message: "does not match the amounts of the batch"}, err)
+
+	err = parse(func(l []string) []string {
+		return append(l[:2], l[3:]...)
+	})
+	var layoutErr *LayoutError
+	assert.True(t, errors.As(err, &layoutErr))
+	assert.Equal(t, 3, layoutErr.Line)
+
+	err = parse(func(l []string) []string {
+		l[3] = l[3][:8] + "00001" + l[3][13:]
+		l[2], l[3] = l[3], l[2]
+		return l
+	})
+	assert.Equal(t, &LayoutError{Line: 3, Field: "segment", Message: "segment B must follow a segment A"}, err)
+
+	err = parse(func(l []string) []string {
+		l[len(l)-1] = l[len(l)-1][:17] + "000004" + l[len(l)-1][23:]
+		return l
+	})
+	assert.Equal(t, &LayoutError{Line: 16, Field: "batches", Message: "does not match the batches of the file"}, err)
+
+	err = parse(func(l []string) []string {
+		l[1] = "341" + l[1][3:]
+		return l
+	})
+	assert.Equal(t, &LayoutError{Line: 2, Field: "bank code", Message: "expected 332"}, err)
+}
+
+func TestInstructions(t *testing.T) {
+	file, err := Parse(bytes.NewReader(marshalRemessa(t)))
+	assert.NoError(t, err)
+
+	instructions, err := file.Instructions()
+	assert.NoError(t, err)
+	assert.Len(t, instructions, 4)
+
+	transfer := instructions[0].Transfer
+	assert.Equal(t, int64(150000), transfer.Amount)
+	assert.Equal(t, bankly.SenderRequest{Branch: "0001", Account: "189162", Document: "12345678000195", Name: "EMPRESA PAGADORA"}, transfer.Sender)
+	assert.Equal(t, bankly.RecipientRequest{
+		TransfersAccountType: bankly.SavingsAccount,
+		BankCode:             "001",
+		Branch:               "0001",
+		Account:              "123455",
+		Document:             "76385230056",
+		Name:                 "JOAO DA SILVA",
+	}, transfer.Recipient)
+	assert.Equal(t, "TED-1", transfer.Description)
+
+	key := instructions[1]
+	assert.Equal(t, bankly.Key, key.PixCashOut.InitializationType)
+	assert.Equal(t, 10.05, key.PixCashOut.Amount)
+	assert.Equal(t, "ALUGUEL", key.PixCashOut.Description)
+	assert.Equal(t, &bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "Maria@Example.com"}, key.PixKey)
+
+	account := instructions[2]
+	assert.Equal(t, bankly.Manual, account.PixCashOut.InitializationType)
+	assert.Nil(t, account.PixKey)
+	assert.Equal(t, "60701190", account.PixCashOut.Recipient.Bank.Ispb)
+	assert.Equal(t, bankly.PixCashOutAccountRequest{Branch: "2545", Number: "23661"}, account.PixCashOut.Recipient.Account)
+
+	boleto := instructions[3]
+	assert.Equal(t, "34191090080012345678901234567890123456789012", boleto.Barcode)
+	assert.Equal(t, 259.9, boleto.BillPayment.Amount)
+	assert.Equal(t, "189162", boleto.BillPayment.BankAccount)
+}
+
+func TestInstructions_WithoutSegmentB(t *testing.T) {
+	file := buildRemessa()
+	file.Batches[0].Payments[0].HasSegmentB = false
+
+	_, err := file.Instructions()
+	assert.Error(t, err)
+}
+
+func TestNewRetorno(t *testing.T) {
+	remessa, err := Parse(bytes.NewReader(marshalRemessa(t)))
+	assert.NoError(t, err)
+
+	settled := date(2026, time.October, 19).Add(11 * time.Hour)
+	results := []Result{
+		TransferResult(&bankly.TransferByCodeResponse{
+			AuthenticationCode: "1d3e4b47-8f0c-4c8f-a5bb-7f1a0e1f6d57",
+			Amount:             1500,
+			Status:             bankly.TransfersStatusDone,
+			UpdatedAt:          settled,
+		}, nil),
+		PixCashOutResult(nil, bankly.ErrInsufficientBalancePix),
+		PixCashOutResult(&bankly.PixCashOutResponse{AuthenticationCode: "pix-code", Amount: 20}, nil),
+		BillPaymentResult(&bankly.ConfirmPaymentResponse{AuthenticationCode: "bill-code", SettledDate: settled}, bankly.NewMoney(25990), nil),
+	}
+
+	_, err = NewRetorno(remessa, results[:3], settled)
+	assert.Equal(t, ErrResultsMismatch, err)
+
+	retorno, err := NewRetorno(remessa, results, settled)
+	assert.NoError(t, err)
+	assert.Equal(t, Remessa, remessa.Header.Kind)
+	assert.Empty(t, remessa.Batches[0].Payments[0].Occurrences)
+
+	data, err := Marshal(retorno)
+	assert.NoError(t, err)
+
+	parsed, err := Parse(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, Retorno, parsed.Header.Kind)
+
+	ted := parsed.Batches[0].Payments[0]
+	assert.Equal(t, OccurrencePaid, ted.Occurrences)
+	assert.Equal(t, "1D3E4B47-8F0C-4C8F-A5BB-7F1A0E1F6D57", ted.Information)
+	assert.Equal(t, date(2026, time.October, 19), ted.EffectiveDate)
+	assert.Equal(t, int64(150000), ted.EffectiveAmount.Cents)
+
+	refused := parsed.Batches[1].Payments[0]
+	assert.Equal(t, OccurrenceInsufficientFunds, refused.Occurrences)
+	assert.Equal(t, "INSUFFICIENT BALANCE", refused.Information)
+	assert.True(t, refused.EffectiveDate.IsZero())
+
+	assert.Equal(t, OccurrenceScheduled, parsed.Batches[1].Payments[1].Occurrences)
+	assert.Equal(t, OccurrencePaid, parsed.Batches[2].Payments[0].Occurrences)
+}
@@ -0,0 +1,218 @@
+package cnab
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/calendar"
+)
+
+// Lengths of the lines of the CNAB 240 and 400
+const (
+	RecordLength    = 240
+	Record400Length = 400
+)
+
+// Layouts of the CNAB dates at Sao Paulo, DDMMAAAA and DDMMAA at the CNAB 400
+const (
+	dateLayout      = "02012006"
+	shortDateLayout = "020106"
+)
+
+// LayoutError is a field out of the layout, Line starts at 1
+type LayoutError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e *LayoutError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("cnab line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("cnab line %d, field %s: %s", e.Line, e.Field, e.Message)
+}
+
+// line reads the fields of a record by their 1-based positions, end inclusive
+type line struct {
+	index int
+	text  string
+}
+
+func (l line) err(field, message string) error {
+	return &LayoutError{Line: l.index, Field: field, Message: message}
+}
+
+func (l line) raw(start, end int) string {
+	return l.text[start-1 : end]
+}
+
+func (l line) alpha(start, end int) string {
+	return strings.TrimSpace(l.raw(start, end))
+}
+
+func (l line) digits(field string, start, end int) (string, error) {
+	value := l.raw(start, end)
+	if !bankly.IsOnlyDigits(value) {
+		return "", l.err(field, "expected digits at "+strconv.Itoa(start)+"-"+strconv.Itoa(end))
+	}
+	return value, nil
+}
+
+func (l line) number(field string, start, end int) (int64, error) {
+	value, err := l.digits(field, start, end)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func (l line) money(field string, start, end int) (bankly.Money, error) {
+	cents, err := l.number(field, start, end)
+	if err != nil {
+		return bankly.Money{}, err
+	}
+	return bankly.NewMoney(cents), nil
+}
+
+// date returns the zero time for 00000000
+func (l line) date(field string, start, end int) (time.Time, error) {
+	layout := dateLayout
+	if end-start+1 == len(shortDateLayout) {
+		layout = shortDateLayout
+	}
+
+	value, err := l.digits(field, start, end)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if strings.Trim(value, "0") == "" {
+		return time.Time{}, nil
+	}
+
+	date, err := time.ParseInLocation(layout, value, calendar.Location())
+	if err != nil {
+		return time.Time{}, l.err(field, "invalid date "+value)
+	}
+	return date, nil
+}
+
+// record writes the fields of a line, starting from the line read when there is one
+type record []byte
+
+func newRecord(raw string) record {
+	return newSizedRecord(raw, RecordLength)
+}
+
+func newSizedRecord(raw string, size int) record {
+	if len(raw) == size {
+		return record(raw)
+	}
+	return record(strings.Repeat(" ", size))
+}
+
+// alpha writes the value aligned to the left, upper case and without accents.
+// Longer values are truncated, as the names of the layout.
+func (r record) alpha(start, end int, value string) {
+	value = normalize(value)
+	size := end - start + 1
+	if len(value) > size {
+		value = value[:size]
+	}
+	copy(r[start-1:end], value+strings.Repeat(" ", size-len(value)))
+}
+
+// digits writes the value aligned to the right with zeros, longer values are an error
+func (r record) digits(field string, start, end int, value string) error {
+	size := end - start + 1
+	if !bankly.IsOnlyDigits(value) {
+		return &LayoutError{Field: field, Message: "expected digits, got " + value}
+	}
+	if len(value) > size {
+		return &LayoutError{Field: field, Message: fmt.Sprintf("value %s longer than %d digits", value, size)}
+	}
+	copy(r[start-1:end], strings.Repeat("0", size-len(value))+value)
+	return nil
+}
+
+func (r record) number(field string, start, end int, value int64) error {
+	if value < 0 {
+		return &LayoutError{Field: field, Message: "negative value"}
+	}
+	return r.digits(field, start, end, strconv.FormatInt(value, 10))
+}
+
+func (r record) money(field string, start, end int, value bankly.Money) error {
+	return r.number(field, start, end, value.Cents)
+}
+
+func (r record) date(start, end int, value time.Time) {
+	layout := dateLayout
+	if end-start+1 == len(shortDateLayout) {
+		layout = shortDateLayout
+	}
+
+	if value.IsZero() {
+		copy(r[start-1:end], strings.Repeat("0", len(layout)))
+		return
+	}
+	copy(r[start-1:end], value.In(calendar.Location()).Format(layout))
+}
+
+var accents = strings.NewReplacer(
+	"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A",
+	"É", "E", "È", "E", "Ê", "E", "Ë", "E",
+	"Í", "I", "Ì", "I", "Î", "I", "Ï", "I",
+	"Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+	"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U",
+	"Ç", "C", "Ñ", "N",
+)
+
+// decodeLine reads the line as UTF-8, or as Latin-1 when it is not valid UTF-8, with
+// the accents removed, so each character takes one position of the layout. The
+// characters out of ASCII without an accent to remove are read as '?'.
+func decodeLine(text string) string {
+	runes := []rune(text)
+	if !utf8.ValidString(text) {
+		runes = make([]rune, len(text))
+		for i := 0; i < len(text); i++ {
+			runes[i] = rune(text[i])
+		}
+	}
+
+	var builder strings.Builder
+	for _, c := range runes {
+		if c > '~' {
+			lower := unicode.IsLower(c)
+			folded := accents.Replace(string(unicode.ToUpper(c)))
+			c = '?'
+			if len(folded) == 1 {
+				c = rune(folded[0])
+			}
+			if lower {
+				c = unicode.ToLower(c)
+			}
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// normalize keeps the printable ASCII characters accepted by the banks
+func normalize(value string) string {
+	value = accents.Replace(strings.ToUpper(value))
+
+	var builder strings.Builder
+	for _, c := range value {
+		if c >= ' ' && c <= '~' {
+			builder.WriteRune(c)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,517 @@
+package cnab
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/calendar"
+)
+
+// Default layout versions of FEBRABAN 10.11
+const (
+	FileLayoutVersion  = "103"
+	BatchLayoutVersion = "046"
+)
+
+// writer renumbers the records while writing them
+type writer struct {
+	lines [][]byte
+}
+
+func (w *writer) add(r record, err error) error {
+	if err != nil {
+		if layoutErr, ok := err.(*LayoutError); ok {
+			layoutErr.Line = len(w.lines) + 1
+		}
+		return err
+	}
+	w.lines = append(w.lines, r)
+	return nil
+}
+
+// Marshal writes the file with CRLF line endings. The batches and sequences are
+// renumbered and the trailers are recomputed. The fields not modeled are kept as
+// read by Parse.
+func Marshal(file *File) ([]byte, error) {
+	w := &writer{}
+
+	if err := w.add(writeFileHeader(&file.Header)); err != nil {
+		return nil, err
+	}
+
+	for i, batch := range file.Batches {
+		number := int64(i + 1)
+		start := len(w.lines)
+
+		if err := w.add(writeBatchHeader(file.Header.BankCode, number, &batch.Header)); err != nil {
+			return nil, err
+		}
+
+		next := detailWriter(file.Header.BankCode, number)
+		total := bankly.NewMoney(0)
+
+		for _, payment := range batch.Payments {
+			records, err := writePayment(next, batch.Header.Form, payment)
+			if err != nil {
+				return nil, w.add(nil, err)
+			}
+			w.lines = append(w.lines, toLines(records)...)
+
+			total, err = total.Add(payment.Amount)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, boleto := range batch.Boletos {
+			records, err := writeBoleto(next, batch.Header.Company, boleto)
+			if err != nil {
+				return nil, w.add(nil, err)
+			}
+			w.lines = append(w.lines, toLines(records)...)
+
+			total, err = total.Add(boleto.Amount)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		records := int64(len(w.lines) - start + 1)
+		if err := w.add(writeBatchTrailer(file.Header.BankCode, number, records, total, batch)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.add(writeFileTrailer(file, int64(len(w.lines)+1))); err != nil {
+		return nil, err
+	}
+
+	return append(bytes.Join(w.lines, []byte("\r\n")), '\r', '\n'), nil
+}
+
+// Write ...
+func Write(out io.Writer, file *File) error {
+	data, err := Marshal(file)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+func toLines(records []record) [][]byte {
+	lines := [][]byte{}
+	for _, r := range records {
+		lines = append(lines, r)
+	}
+	return lines
+}
+
+// detailWriter starts the detail records of the batch, numbering their sequence
+func detailWriter(bankCode string, batch int64) func(raw, segment string) (record, error) {
+	sequence := int64(0)
+
+	return func(raw, segment string) (record, error) {
+		sequence++
+		r := newRecord(raw)
+		if err := writeControl(r, bankCode, batch, "3"); err != nil {
+			return nil, err
+		}
+		if err := r.number("sequence", 9, 13, sequence); err != nil {
+			return nil, err
+		}
+		copy(r[13:14], segment)
+		return r, nil
+	}
+}
+
+func writeControl(r record, bankCode string, batch int64, recordType string) error {
+	if err := r.digits("bank code", 1, 3, bankCode); err != nil {
+		return err
+	}
+	if err := r.number("batch", 4, 7, batch); err != nil {
+		return err
+	}
+	copy(r[7:8], recordType)
+	return nil
+}
+
+func writeCompany(r record, company Company, shift int) error {
+	if err := r.number("company document type", 18, 18, int64(company.DocumentType)); err != nil {
+		return err
+	}
+	if err := r.digits("company document", 19, 32+shift, company.Document); err != nil {
+		return err
+	}
+	r.alpha(33+shift, 52+shift, company.Agreement)
+	if err := r.digits("company branch", 53+shift, 57+shift, company.Branch); err != nil {
+		return err
+	}
+	r.alpha(58+shift, 58+shift, company.BranchDigit)
+	if err := r.digits("company account", 59+shift, 70+shift, company.Account); err != nil {
+		return err
+	}
+	r.alpha(71+shift, 71+shift, company.AccountDigit)
+	r.alpha(73+shift, 102+shift, company.Name)
+	return nil
+}
+
+func writeFileHeader(header *FileHeader) (record, error) {
+	r := newRecord(header.raw)
+
+	if err := writeControl(r, header.BankCode, 0, "0"); err != nil {
+		return nil, err
+	}
+	if err := writeCompany(r, header.Company, 0); err != nil {
+		return nil, err
+	}
+
+	r.alpha(103, 132, header.BankName)
+	r.alpha(143, 143, string(header.Kind))
+	r.date(144, 151, header.GeneratedAt)
+	copy(r[151:157], header.GeneratedAt.In(calendar.Location()).Format("150405"))
+
+	if err := r.number("sequence", 158, 163, header.Sequence); err != nil {
+		return nil, err
+	}
+
+	version := header.LayoutVersion
+	if version == "" {
+		version = FileLayoutVersion
+	}
+	r.alpha(164, 166, version)
+
+	if header.raw == "" {
+		r.alpha(167, 171, "01600")
+	}
+
+	return r, nil
+}
+
+func writeBatchHeader(bankCode string, number int64, header *BatchHeader) (record, error) {
+	r := newRecord(header.raw)
+
+	if err := writeControl(r, bankCode, number, "1"); err != nil {
+		return nil, err
+	}
+
+	serviceType := header.ServiceType
+	if serviceType == "" {
+		serviceType = ServicePayments
+	}
+	if err := r.digits("service type", 10, 11, serviceType); err != nil {
+		return nil, err
+	}
+
+	operation := header.Operation
+	version := header.LayoutVersion
+
+	if serviceType == ServiceBilling {
+		if operation == "" {
+			operation = "R"
+		}
+		if version == "" {
+			version = BillingLayoutVersion
+		}
+		r.alpha(9, 9, operation)
+		r.alpha(14, 16, version)
+
+		if err := writeBillingHeader(r, header); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+
+	if operation == "" {
+		operation = "C"
+	}
+	if version == "" {
+		version = BatchLayoutVersion
+	}
+	r.alpha(9, 9, operation)
+	r.alpha(14, 16, version)
+
+	if err := r.digits("form", 12, 13, header.Form); err != nil {
+		return nil, err
+	}
+	if err := writeCompany(r, header.Company, 0); err != nil {
+		return nil, err
+	}
+
+	r.alpha(103, 142, header.Message)
+	r.alpha(231, 240, header.Occurrences)
+
+	return r, nil
+}
+
+func writePayment(next func(raw, segment string) (record, error), form string, payment *Payment) ([]record, error) {
+	records := []record{}
+
+	switch payment.Segment {
+	case SegmentA:
+		r, err := next(payment.segmentARaw, SegmentA)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeSegmentA(r, payment); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+
+		if payment.HasSegmentB {
+			r, err := next(payment.segmentBRaw, SegmentB)
+			if err != nil {
+				return nil, err
+			}
+			pix := payment.Chamber == ChamberPix || form == FormPixTransfer || form == FormPixQrCode
+			if err := writeSegmentB(r, payment, pix); err != nil {
+				return nil, err
+			}
+			records = append(records, r)
+		}
+
+	case SegmentJ:
+		r, err := next(payment.segmentJRaw, SegmentJ)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeSegmentJ(r, payment); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+
+		if payment.HasJ52 {
+			r, err := next(payment.j52Raw, SegmentJ)
+			if err != nil {
+				return nil, err
+			}
+			if err := writeSegmentJ52(r, payment); err != nil {
+				return nil, err
+			}
+			records = append(records, r)
+		}
+
+	default:
+		return nil, &LayoutError{Field: "segment", Message: "unsupported segment " + payment.Segment}
+	}
+
+	return records, nil
+}
+
+func movement(r record, payment *Payment) {
+	movementType := payment.MovementType
+	if movementType == "" {
+		movementType = "0"
+	}
+	instruction := payment.Instruction
+	if instruction == "" {
+		instruction = "00"
+	}
+	r.alpha(15, 15, movementType)
+	r.alpha(16, 17, instruction)
+}
+
+func writeSegmentA(r record, payment *Payment) error {
+	movement(r, payment)
+
+	if err := r.digits("chamber", 18, 20, payment.Chamber); err != nil {
+		return err
+	}
+	if err := r.digits("favored bank", 21, 23, payment.BankCode); err != nil {
+		return err
+	}
+	if err := r.digits("favored branch", 24, 28, payment.Branch); err != nil {
+		return err
+	}
+	r.alpha(29, 29, payment.BranchDigit)
+	if err := r.digits("favored account", 30, 41, payment.Account); err != nil {
+		return err
+	}
+	r.alpha(42, 42, payment.AccountDigit)
+	r.alpha(44, 73, payment.Name)
+	r.alpha(74, 93, payment.YourNumber)
+	r.date(94, 101, payment.PaymentDate)
+
+	currency := payment.Currency
+	if currency == "" {
+		currency = bankly.CurrencyBRL
+	}
+	r.alpha(102, 104, currency)
+
+	if payment.segmentARaw == "" {
+		copy(r[104:119], "000000000000000")
+	}
+
+	if err := r.money("amount", 120, 134, payment.Amount); err != nil {
+		return err
+	}
+
+	r.alpha(135, 154, payment.OurNumber)
+	r.date(155, 162, payment.EffectiveDate)
+
+	if err := r.money("effective amount", 163, 177, payment.EffectiveAmount); err != nil {
+		return err
+	}
+
+	r.alpha(178, 217, payment.Information)
+	r.alpha(220, 224, payment.TEDPurpose)
+	r.alpha(225, 226, payment.AccountPurpose)
+	r.alpha(230, 230, payment.Notice)
+	r.alpha(231, 240, payment.Occurrences)
+
+	return nil
+}
+
+func writeSegmentB(r record, payment *Payment, pix bool) error {
+	if err := r.number("favored document type", 18, 18, int64(payment.Recipient.DocumentType)); err != nil {
+		return err
+	}
+	if err := r.digits("favored document", 19, 32, payment.Recipient.Document); err != nil {
+		return err
+	}
+
+	if pix {
+		r.alpha(15, 17, payment.PixInitiation)
+		r.alpha(33, 67, payment.PixTxID)
+		r.alpha(68, 127, payment.PixMessage)
+		// the keys are case sensitive, as the random keys and e-mails
+		if len(payment.PixKey) > 99 {
+			return &LayoutError{Field: "pix key", Message: "longer than 99 characters"}
+		}
+		copy(r[127:226], payment.PixKey+strings.Repeat(" ", 99-len(payment.PixKey)))
+	}
+
+	if payment.ISPB != "" {
+		if err := r.digits("ispb", 233, 240, payment.ISPB); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeSegmentJ(r record, payment *Payment) error {
+	movement(r, payment)
+
+	if len(payment.Barcode) != 44 {
+		return &LayoutError{Field: "barcode", Message: "expected 44 digits"}
+	}
+	if err := r.digits("barcode", 18, 61, payment.Barcode); err != nil {
+		return err
+	}
+
+	r.alpha(62, 91, payment.Assignor)
+	r.date(92, 99, payment.DueDate)
+
+	if payment.segmentJRaw == "" {
+		if err := r.money("nominal amount", 100, 114, payment.Amount); err != nil {
+			return err
+		}
+		copy(r[167:182], "000000000000000")
+		copy(r[222:224], "09")
+	}
+
+	if err := r.money("discount", 115, 129, payment.Discount); err != nil {
+		return err
+	}
+	if err := r.money("interest", 130, 144, payment.Interest); err != nil {
+		return err
+	}
+
+	r.date(145, 152, payment.PaymentDate)
+
+	if err := r.money("amount", 153, 167, payment.Amount); err != nil {
+		return err
+	}
+
+	r.alpha(183, 202, payment.YourNumber)
+	r.alpha(203, 222, payment.OurNumber)
+	r.alpha(231, 240, payment.Occurrences)
+
+	return nil
+}
+
+func writeSegmentJ52(r record, payment *Payment) error {
+	r.alpha(15, 15, "")
+	movement := payment.Instruction
+	if movement == "" {
+		movement = "00"
+	}
+	r.alpha(16, 17, movement)
+	copy(r[17:19], "52")
+
+	parties := []struct {
+		field string
+		party Party
+		start int
+	}{
+		{"payer", payment.Payer, 20},
+		{"beneficiary", payment.Beneficiary, 76},
+		{"drawer", payment.Drawer, 132},
+	}
+
+	for _, p := range parties {
+		if err := r.number(p.field+" document type", p.start, p.start, int64(p.party.DocumentType)); err != nil {
+			return err
+		}
+		if err := r.digits(p.field+" document", p.start+1, p.start+15, p.party.Document); err != nil {
+			return err
+		}
+		r.alpha(p.start+16, p.start+55, p.party.Name)
+	}
+
+	return nil
+}
+
+// writeBatchTrailer writes the total of the payments, or the total of the boletos
+// as cobrança simples when it is a retorno of cobrança
+func writeBatchTrailer(bankCode string, number int64, records int64, total bankly.Money, batch *Batch) (record, error) {
+	r := newRecord(batch.trailerRaw)
+
+	if err := writeControl(r, bankCode, number, "5"); err != nil {
+		return nil, err
+	}
+	if err := r.number("batch records", 18, 23, records); err != nil {
+		return nil, err
+	}
+
+	if batch.Header.ServiceType == ServiceBilling {
+		if batch.Header.Operation != "T" {
+			return r, nil
+		}
+		if err := r.number("boletos", 24, 29, int64(len(batch.Boletos))); err != nil {
+			return nil, err
+		}
+		return r, r.money("boletos amount", 30, 46, total)
+	}
+
+	if err := r.money("batch amount", 24, 41, total); err != nil {
+		return nil, err
+	}
+	if batch.trailerRaw == "" {
+		copy(r[41:65], "000000000000000000000000")
+	}
+
+	return r, nil
+}
+
+func writeFileTrailer(file *File, records int64) (record, error) {
+	r := newRecord(file.trailerRaw)
+
+	if err := writeControl(r, file.Header.BankCode, 9999, "9"); err != nil {
+		return nil, err
+	}
+	if err := r.number("batches", 18, 23, int64(len(file.Batches))); err != nil {
+		return nil, err
+	}
+	if err := r.number("records", 24, 29, records); err != nil {
+		return nil, err
+	}
+	if file.trailerRaw == "" {
+		copy(r[29:35], "000000")
+	}
+
+	return r, nil
+}
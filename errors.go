@@ -1,7 +1,9 @@
 package bankly
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 
@@ -33,12 +35,18 @@ var (
 	ErrInvalidAmount = grok.NewError(http.StatusBadRequest, "INVALID_AMOUNT", "invalid amount")
 	// ErrInsufficientBalance ...
 	ErrInsufficientBalance = grok.NewError(http.StatusBadRequest, "INSUFFICIENT_BALANCE", "insufficient balance")
+	// ErrMoneyCurrencyMismatch ...
+	ErrMoneyCurrencyMismatch = grok.NewError(http.StatusBadRequest, "MONEY_CURRENCY_MISMATCH", "amounts with different currencies")
+	// ErrMoneyOverflow ...
+	ErrMoneyOverflow = grok.NewError(http.StatusBadRequest, "MONEY_OVERFLOW", "amount out of range")
 	// ErrInvalidAuthenticationCodeOrAccount ...
 	ErrInvalidAuthenticationCodeOrAccount = grok.NewError(http.StatusBadRequest, "INVALID_AUTHENTICATION_CODE_OR_ACCOUNT_NUMBER", "invalid authentication code or account number")
 	// ErrInvalidAccountNumber ...
 	ErrInvalidAccountNumber = grok.NewError(http.StatusBadRequest, "INVALID_ACCOUNT_NUMBER", "invalid account number")
 	// ErrOutOfServicePeriod ...
 	ErrOutOfServicePeriod = grok.NewError(http.StatusBadRequest, "OUT_SERVICE_PERIOD", "out of service period")
+	// ErrOperationDeferred ...
+	ErrOperationDeferred = grok.NewError(http.StatusAccepted, "OPERATION_DEFERRED", "operation deferred to the next service period")
 	// ErrCashoutLimitNotEnough ...
 	ErrCashoutLimitNotEnough = grok.NewError(http.StatusBadRequest, "CASHOUT_LIMIT_NOT_ENOUGH", "cashout limit not enough")
 	// ErrInvalidParameter ...
@@ -73,12 +81,68 @@ var (
 	ErrInvalidCardProxy = grok.NewError(http.StatusBadRequest, "INVALID_CARD_PROXY", "invalid card proxy")
 	// ErrBarcodeNotFound ...
 	ErrBarcodeNotFound = grok.NewError(http.StatusNotFound, "BARCODE_NOT_FOUND", "bar code not found")
+	// ErrBoletoAmendmentEmpty ...
+	ErrBoletoAmendmentEmpty = grok.NewError(http.StatusBadRequest, "BOLETO_AMENDMENT_EMPTY", "error boleto amendment without changes")
+	// ErrBoletoProtestNotSupported ...
+	ErrBoletoProtestNotSupported = grok.NewError(http.StatusMethodNotAllowed, "BOLETO_PROTEST_NOT_SUPPORTED", "boleto protest not supported by bankly")
+	// ErrBoletoAmendmentNotSupported ...
+	ErrBoletoAmendmentNotSupported = grok.NewError(http.StatusNotImplemented, "BOLETO_AMENDMENT_NOT_SUPPORTED", "boleto amendment not enabled, its bankly route is not confirmed")
+	// ErrBoletoAmendmentRouteNotFound ...
+	ErrBoletoAmendmentRouteNotFound = grok.NewError(http.StatusBadGateway, "BOLETO_AMENDMENT_ROUTE_NOT_FOUND", "boleto amendment route not found at bankly")
+	// ErrInvalidBoletoBatch ...
+	ErrInvalidBoletoBatch = grok.NewError(http.StatusUnprocessableEntity, "INVALID_BOLETO_BATCH", "invalid boleto batch")
+	// ErrInvalidBoletoSubscription ...
+	ErrInvalidBoletoSubscription = grok.NewError(http.StatusUnprocessableEntity, "INVALID_BOLETO_SUBSCRIPTION", "invalid boleto subscription")
+	// ErrBoletoSubscriptionNotFound ...
+	ErrBoletoSubscriptionNotFound = grok.NewError(http.StatusNotFound, "BOLETO_SUBSCRIPTION_NOT_FOUND", "boleto subscription not found")
+	// ErrBoletoSubscriptionNotActive ...
+	ErrBoletoSubscriptionNotActive = grok.NewError(http.StatusConflict, "BOLETO_SUBSCRIPTION_NOT_ACTIVE", "boleto subscription is not active")
+	// ErrBoletoSubscriptionLocked ...
+	ErrBoletoSubscriptionLocked = grok.NewError(http.StatusConflict, "BOLETO_SUBSCRIPTION_LOCKED", "boleto subscription is being executed")
+	// ErrBoletoSubscriptionLeaseLost ...
+	ErrBoletoSubscriptionLeaseLost = grok.NewError(http.StatusConflict, "BOLETO_SUBSCRIPTION_LEASE_LOST", "boleto subscription lease held by another worker")
+	// ErrBoletoReconcilePaging ...
+	ErrBoletoReconcilePaging = grok.NewError(http.StatusBadGateway, "BOLETO_RECONCILE_PAGING", "boleto updates repeated a page token")
+	// ErrBoletoSubscriptionNotIssued ...
+	ErrBoletoSubscriptionNotIssued = grok.NewError(http.StatusNotFound, "BOLETO_SUBSCRIPTION_NOT_ISSUED", "boleto subscription boleto not created by bankly")
+	// ErrBoletoSubscriptionUnconfirmed ...
+	ErrBoletoSubscriptionUnconfirmed = grok.NewError(http.StatusConflict, "BOLETO_SUBSCRIPTION_UNCONFIRMED", "boleto subscription boleto created without confirmation")
+	// ErrInvalidBarcode ...
+	ErrInvalidBarcode = grok.NewError(http.StatusBadRequest, "INVALID_BARCODE", "error invalid bar code")
+	// ErrPaymentCancelRouteNotFound ...
+	ErrPaymentCancelRouteNotFound = grok.NewError(http.StatusBadGateway, "PAYMENT_CANCEL_ROUTE_NOT_FOUND", "payment cancel route not found at bankly")
 	// ErrPaymentInvalidStatus ...
 	ErrPaymentInvalidStatus = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PAYMENT_STATUS", "payment was in an invalid status")
 	// ErrDefaultTransfers ...
 	ErrDefaultTransfers = grok.NewError(http.StatusConflict, "TRANSFERS_ERROR", "error transfers")
+	// ErrInvalidRouteRecipient ...
+	ErrInvalidRouteRecipient = grok.NewError(http.StatusUnprocessableEntity, "INVALID_ROUTE_RECIPIENT", "recipient without pix key or account data")
+	// ErrNoRouteAvailable ...
+	ErrNoRouteAvailable = grok.NewError(http.StatusUnprocessableEntity, "NO_ROUTE_AVAILABLE", "no payment rail available to the recipient")
+	// ErrInvalidTransferBatch ...
+	ErrInvalidTransferBatch = grok.NewError(http.StatusUnprocessableEntity, "INVALID_TRANSFER_BATCH", "invalid transfer batch")
 	// ErrDefaultFindTransfers ...
 	ErrDefaultFindTransfers = grok.NewError(http.StatusConflict, "FIND_TRANSFERS_ERROR", "error find transfers")
+	// ErrPaymentAmountChangeNotAllowed ...
+	ErrPaymentAmountChangeNotAllowed = grok.NewError(http.StatusUnprocessableEntity, "PAYMENT_AMOUNT_CHANGE_NOT_ALLOWED", "bill payment amount can't be changed")
+	// ErrPaymentAmountOutOfRange ...
+	ErrPaymentAmountOutOfRange = grok.NewError(http.StatusUnprocessableEntity, "PAYMENT_AMOUNT_OUT_OF_RANGE", "bill payment amount out of the allowed range")
+	// ErrInvalidPaymentBatch ...
+	ErrInvalidPaymentBatch = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PAYMENT_BATCH", "invalid payment batch")
+	// ErrScheduledPaymentNotFound ...
+	ErrScheduledPaymentNotFound = grok.NewError(http.StatusNotFound, "SCHEDULED_PAYMENT_NOT_FOUND", "scheduled payment not found")
+	// ErrInvalidScheduledPayment ...
+	ErrInvalidScheduledPayment = grok.NewError(http.StatusUnprocessableEntity, "INVALID_SCHEDULED_PAYMENT", "invalid scheduled payment")
+	// ErrScheduledPaymentNotPending ...
+	ErrScheduledPaymentNotPending = grok.NewError(http.StatusConflict, "SCHEDULED_PAYMENT_NOT_PENDING", "scheduled payment is not pending")
+	// ErrScheduledPaymentLocked ...
+	ErrScheduledPaymentLocked = grok.NewError(http.StatusConflict, "SCHEDULED_PAYMENT_LOCKED", "scheduled payment is being executed")
+	// ErrScheduledPaymentLeaseLost ...
+	ErrScheduledPaymentLeaseLost = grok.NewError(http.StatusConflict, "SCHEDULED_PAYMENT_LEASE_LOST", "scheduled payment lease held by another worker")
+	// ErrScheduledPaymentNotConfirmed ...
+	ErrScheduledPaymentNotConfirmed = grok.NewError(http.StatusNotFound, "SCHEDULED_PAYMENT_NOT_CONFIRMED", "scheduled payment not confirmed by bankly")
+	// ErrScheduledPaymentUnconfirmed ...
+	ErrScheduledPaymentUnconfirmed = grok.NewError(http.StatusConflict, "SCHEDULED_PAYMENT_UNCONFIRMED", "scheduled payment confirmed without an answer")
 	// ErrDefaultPayment ...
 	ErrDefaultPayment = grok.NewError(http.StatusInternalServerError, "PAYMENT_ERROR", "error payment")
 	// ErrDefaultBusinessAccounts ...
@@ -119,6 +183,46 @@ var (
 	ErrInvalidQrCodePayload = grok.NewError(http.StatusConflict, "INVALID_QRCODE_PAYLOAD", "invalid qrcode payload")
 	// ErrInvalidKeyType ...
 	ErrInvalidKeyType = grok.NewError(http.StatusUnprocessableEntity, "INVALID_KEY_TYPE", "invalid key type")
+	// ErrInvalidPixKey ...
+	ErrInvalidPixKey = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PIX_KEY", "invalid pix key")
+	// ErrPixClaimNotFound ...
+	ErrPixClaimNotFound = grok.NewError(http.StatusNotFound, "PIX_CLAIM_NOT_FOUND", "pix claim not found")
+	// ErrInvalidPixClaimTransition ...
+	ErrInvalidPixClaimTransition = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PIX_CLAIM_TRANSITION", "pix claim status does not allow this operation")
+	// ErrPixClaimOperationNotAllowed ...
+	ErrPixClaimOperationNotAllowed = grok.NewError(http.StatusForbidden, "PIX_CLAIM_OPERATION_NOT_ALLOWED", "operation not allowed for this side of the pix claim")
+	// ErrInvalidEndToEndID ...
+	ErrInvalidEndToEndID = grok.NewError(http.StatusUnprocessableEntity, "INVALID_END_TO_END_ID", "invalid end to end id")
+	// ErrInvalidTxID ...
+	ErrInvalidTxID = grok.NewError(http.StatusUnprocessableEntity, "INVALID_TXID", "invalid txid")
+	// ErrPixLookupLimitExceeded ...
+	ErrPixLookupLimitExceeded = grok.NewError(http.StatusTooManyRequests, "PIX_LOOKUP_LIMIT_EXCEEDED", "pix key lookup limit exceeded")
+	// ErrInvalidPixCharge ...
+	ErrInvalidPixCharge = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PIX_CHARGE", "invalid pix charge")
+	// ErrInvalidPixChargeDueDate ...
+	ErrInvalidPixChargeDueDate = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PIX_CHARGE_DUE_DATE", "invalid pix charge due date")
+	// ErrInvalidPixChargeDiscount ...
+	ErrInvalidPixChargeDiscount = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PIX_CHARGE_DISCOUNT", "invalid pix charge discount")
+	// ErrInvalidPixChargePayer ...
+	ErrInvalidPixChargePayer = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PIX_CHARGE_PAYER", "invalid pix charge payer")
+	// ErrPixDueDateChargeNotSupported ...
+	ErrPixDueDateChargeNotSupported = grok.NewError(http.StatusNotImplemented, "PIX_DUE_DATE_CHARGE_NOT_SUPPORTED", "pix due date charges not enabled, their bankly routes are not confirmed")
+	// ErrPixChargeNotFound ...
+	ErrPixChargeNotFound = grok.NewError(http.StatusNotFound, "PIX_CHARGE_NOT_FOUND", "pix charge not found")
+	// ErrInvalidPixSchedule ...
+	ErrInvalidPixSchedule = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PIX_SCHEDULE", "invalid pix schedule")
+	// ErrPixScheduleNotFound ...
+	ErrPixScheduleNotFound = grok.NewError(http.StatusNotFound, "PIX_SCHEDULE_NOT_FOUND", "pix schedule not found")
+	// ErrPixScheduleNotActive ...
+	ErrPixScheduleNotActive = grok.NewError(http.StatusConflict, "PIX_SCHEDULE_NOT_ACTIVE", "pix schedule is not active")
+	// ErrPixScheduleLocked ...
+	ErrPixScheduleLocked = grok.NewError(http.StatusConflict, "PIX_SCHEDULE_LOCKED", "pix schedule is being executed")
+	// ErrPixScheduleLeaseLost ...
+	ErrPixScheduleLeaseLost = grok.NewError(http.StatusConflict, "PIX_SCHEDULE_LEASE_LOST", "pix schedule lease held by another worker")
+	// ErrPixScheduleNotSent ...
+	ErrPixScheduleNotSent = grok.NewError(http.StatusNotFound, "PIX_SCHEDULE_NOT_SENT", "pix schedule cash out not received by bankly")
+	// ErrPixScheduleUnconfirmed ...
+	ErrPixScheduleUnconfirmed = grok.NewError(http.StatusConflict, "PIX_SCHEDULE_UNCONFIRMED", "pix schedule cash out sent without confirmation")
 	// ErrInvalidParameterPix ...
 	ErrInvalidParameterPix = grok.NewError(http.StatusUnprocessableEntity, "INVALID_PARAMENTER", "invalid parameter")
 	// ErrInsufficientBalancePix ...
@@ -520,6 +624,10 @@ var errorPixList = []Error{
 		ErrorKey:  "INVALID_KEY_TYPE",
 		GrokError: ErrInvalidKeyType,
 	},
+	{
+		ErrorKey:  "CHARGE_NOT_FOUND",
+		GrokError: ErrPixChargeNotFound,
+	},
 	{
 		ErrorKey:  "INVALID_PARAMETER_PIX",
 		GrokError: ErrInvalidParameterPix,
@@ -641,6 +749,47 @@ func ParseErr(err error) (*Error, bool) {
 	return banklyErr, ok
 }
 
+// isRejectedRequest reports an operation refused by Bankly, which surely did not execute it.
+// Server errors and errors without an answer leave the operation unknown.
+func isRejectedRequest(err error) bool {
+	grokErr, ok := asGrokError(err)
+	return ok && grokErr.Code >= http.StatusBadRequest && grokErr.Code < http.StatusInternalServerError &&
+		grokErr.Code != http.StatusRequestTimeout
+}
+
+// isTransientError reports the errors of Bankly worth a new attempt. Once the operation
+// was sent only rate limit errors are retried, since a server error does not guarantee
+// the operation was rejected.
+func isTransientError(err error, sent bool) bool {
+	if grokErr, ok := asGrokError(err); ok {
+		if sent {
+			return grokErr.Code == http.StatusTooManyRequests
+		}
+		return grokErr.Code >= http.StatusInternalServerError || grokErr.Code == http.StatusTooManyRequests
+	}
+
+	if sent {
+		return false
+	}
+
+	if netErr, ok := err.(net.Error); ok {
+		return netErr.Timeout()
+	}
+
+	return err == context.DeadlineExceeded
+}
+
+// asGrokError returns the grok error of the errors of Bankly, found by FindError or not
+func asGrokError(err error) (*grok.Error, bool) {
+	switch e := err.(type) {
+	case *grok.Error:
+		return e, true
+	case *Error:
+		return e.GrokError, e.GrokError != nil
+	}
+	return nil, false
+}
+
 // FindTransferError ..
 func FindTransferError(transferErrorResponse TransferErrorResponse) *grok.Error {
 	// get the error code if errors list is null
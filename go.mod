@@ -6,8 +6,10 @@ require (
 	github.com/aws/aws-sdk-go v1.34.28
 	github.com/contbank/grok v0.0.90
 	github.com/google/uuid v1.1.2
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/sirupsen/logrus v1.7.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.8.1
 	github.com/thoas/go-funk v0.9.2
 	github.com/tidwall/gjson v1.12.1
@@ -86,6 +86,26 @@ const (
 	DefaultOperation CancelReason = "DEFAULT_OPERATION"
 )
 
+// ConfirmReason reason informed by the donor to give the key away in a portability or ownership claim
+type ConfirmReason string
+
+const (
+	// ConfirmDonorRequest the donor agreed to give the key away
+	ConfirmDonorRequest ConfirmReason = "DONOR_REQUEST"
+	// ConfirmAccountClosure the account of the donor is being closed
+	ConfirmAccountClosure ConfirmReason = "ACCOUNT_CLOSURE"
+)
+
+// PixClaimRole side of the claim played by the account
+type PixClaimRole string
+
+const (
+	// PixClaimRoleClaimer the account is requesting the key
+	PixClaimRoleClaimer PixClaimRole = "CLAIMER"
+	// PixClaimRoleDonor the account currently owns the key
+	PixClaimRoleDonor PixClaimRole = "DONOR"
+)
+
 const (
 	// InternalBankCode ...
 	InternalBankCode string = "332"
@@ -1362,6 +1382,26 @@ type FilterPaymentsRequest struct {
 	PageToken   *string
 }
 
+// PaymentStatus is the status of a bill payment, read by PaymentResponse.PaymentStatus.
+// The values are not in the public docs of Bankly, they are compared ignoring the case
+// and the other values are kept as read, see PaymentStatus.IsKnown.
+type PaymentStatus string
+
+const (
+	// PaymentStatusCreated the payment was received and not confirmed yet
+	PaymentStatusCreated PaymentStatus = "CREATED"
+	// PaymentStatusScheduled the payment will be settled at the settle date
+	PaymentStatusScheduled PaymentStatus = "SCHEDULED"
+	// PaymentStatusConfirmed the payment was confirmed and waits the settlement
+	PaymentStatusConfirmed PaymentStatus = "CONFIRMED"
+	// PaymentStatusSettled the payment was settled
+	PaymentStatusSettled PaymentStatus = "SETTLED"
+	// PaymentStatusCanceled the payment was canceled before the settlement
+	PaymentStatusCanceled PaymentStatus = "CANCELED"
+	// PaymentStatusReversed the payment was refused by the recipient bank and the amount returned
+	PaymentStatusReversed PaymentStatus = "REVERSED"
+)
+
 // PaymentResponse ...
 type PaymentResponse struct {
 	AuthenticationCode string     `json:"authenticationCode,omitempty"`
@@ -1397,6 +1437,13 @@ type DetailPaymentRequest struct {
 	AuthenticationCode string `validate:"required"`
 }
 
+// CancelPaymentRequest ...
+type CancelPaymentRequest struct {
+	BankBranch         string `validate:"required" json:"bankBranch,omitempty"`
+	BankAccount        string `validate:"required" json:"bankAccount,omitempty"`
+	AuthenticationCode string `validate:"required" json:"authenticationCode,omitempty"`
+}
+
 // CreateTicketRequest ...
 type CreateTicketRequest struct {
 	GroupID     int      `json:"group_id"`
@@ -1713,8 +1760,9 @@ type PixCashOutRequest struct {
 }
 
 type PixCashOutAccountRequest struct {
-	Branch string `json:"branch"`
-	Number string `json:"number"`
+	Branch string               `json:"branch"`
+	Number string               `json:"number"`
+	Type   TransfersAccountType `json:"type,omitempty"`
 }
 
 type PixCashOutBankRequest struct {
@@ -1823,6 +1871,133 @@ type PixQrCodeResponse struct {
 	EncodedValue string `json:"encodedValue"`
 }
 
+// PixChargeStatus ...
+type PixChargeStatus string
+
+const (
+	// PixChargeActive charge waiting the payment
+	PixChargeActive PixChargeStatus = "ACTIVE"
+	// PixChargeCompleted charge paid
+	PixChargeCompleted PixChargeStatus = "COMPLETED"
+	// PixChargeCanceled charge canceled by the receiver
+	PixChargeCanceled PixChargeStatus = "CANCELED"
+	// PixChargeExpired charge not paid until the due date plus the days valid after due
+	PixChargeExpired PixChargeStatus = "EXPIRED"
+)
+
+// PixChargeValueType ...
+type PixChargeValueType string
+
+const (
+	// PixChargeFixedAmount value in reais
+	PixChargeFixedAmount PixChargeValueType = "FixedAmount"
+	// PixChargePercent percent of the charge amount
+	PixChargePercent PixChargeValueType = "Percent"
+)
+
+// PixChargeDiscountType ...
+type PixChargeDiscountType string
+
+const (
+	// PixChargeFixedAmountDiscount fixed amount until the limit date
+	PixChargeFixedAmountDiscount PixChargeDiscountType = "FixedAmountUntilLimitDate"
+	// PixChargeFixedPercentDiscount percent of the amount until the limit date
+	PixChargeFixedPercentDiscount PixChargeDiscountType = "FixedPercentUntilLimitDate"
+)
+
+// PixChargeFine applied once when paid after the due date
+type PixChargeFine struct {
+	Type  PixChargeValueType `validate:"required" json:"type"`
+	Value float64            `validate:"required" json:"value"`
+}
+
+// PixChargeInterest applied per month after the due date
+type PixChargeInterest struct {
+	Type  PixChargeValueType `validate:"required" json:"type"`
+	Value float64            `validate:"required" json:"value"`
+}
+
+// PixChargeDiscount ...
+type PixChargeDiscount struct {
+	Type      PixChargeDiscountType `validate:"required" json:"type"`
+	LimitDate string                `validate:"required" json:"limitDate"`
+	Value     float64               `validate:"required" json:"value"`
+}
+
+// PixChargeAbatement ...
+type PixChargeAbatement struct {
+	Type  PixChargeValueType `validate:"required" json:"type"`
+	Value float64            `validate:"required" json:"value"`
+}
+
+// PixDueDateChargeRequest ...
+type PixDueDateChargeRequest struct {
+	AddressingKey         PixTypeValue             `validate:"required" json:"addressingKey"`
+	ConciliationID        string                   `json:"conciliationId"`
+	RecipientName         string                   `validate:"required" json:"recipientName"`
+	Location              PixQrCodeLocation        `validate:"required" json:"location"`
+	Amount                float64                  `validate:"required" json:"amount"`
+	DueDate               string                   `validate:"required" json:"dueDate"`
+	DaysValidAfterDueDate int                      `json:"daysValidAfterDueDate"`
+	Payer                 PixPayer                 `validate:"required" json:"payer"`
+	Fine                  *PixChargeFine           `json:"fine,omitempty"`
+	Interest              *PixChargeInterest       `json:"interest,omitempty"`
+	Discounts             []PixChargeDiscount      `json:"discounts,omitempty"`
+	Abatement             *PixChargeAbatement      `json:"abatement,omitempty"`
+	PayerRequestText      string                   `json:"payerRequestText,omitempty"`
+	AdditionalData        []PixAdditionalDataValue `json:"additionalData,omitempty"`
+}
+
+// PixDueDateChargeUpdateRequest only the fields not nil are changed
+type PixDueDateChargeUpdateRequest struct {
+	Amount                *float64            `json:"amount,omitempty"`
+	DueDate               *string             `json:"dueDate,omitempty"`
+	DaysValidAfterDueDate *int                `json:"daysValidAfterDueDate,omitempty"`
+	Payer                 *PixPayer           `json:"payer,omitempty"`
+	Fine                  *PixChargeFine      `json:"fine,omitempty"`
+	Interest              *PixChargeInterest  `json:"interest,omitempty"`
+	Discounts             []PixChargeDiscount `json:"discounts,omitempty"`
+	Abatement             *PixChargeAbatement `json:"abatement,omitempty"`
+	PayerRequestText      *string             `json:"payerRequestText,omitempty"`
+}
+
+// PixDueDateChargeResponse ...
+type PixDueDateChargeResponse struct {
+	ConciliationID        string                   `json:"conciliationId"`
+	EncodedValue          string                   `json:"encodedValue"`
+	Status                PixChargeStatus          `json:"status"`
+	AddressingKey         PixTypeValue             `json:"addressingKey"`
+	RecipientName         string                   `json:"recipientName"`
+	Amount                float64                  `json:"amount"`
+	DueDate               string                   `json:"dueDate"`
+	DaysValidAfterDueDate int                      `json:"daysValidAfterDueDate"`
+	Payer                 PixPayer                 `json:"payer"`
+	Fine                  *PixChargeFine           `json:"fine,omitempty"`
+	Interest              *PixChargeInterest       `json:"interest,omitempty"`
+	Discounts             []PixChargeDiscount      `json:"discounts,omitempty"`
+	Abatement             *PixChargeAbatement      `json:"abatement,omitempty"`
+	PayerRequestText      string                   `json:"payerRequestText,omitempty"`
+	AdditionalData        []PixAdditionalDataValue `json:"additionalData,omitempty"`
+	EndToEndID            string                   `json:"endToEndId,omitempty"`
+	PaidAmount            float64                  `json:"paidAmount,omitempty"`
+	CreatedAt             string                   `json:"createdAt"`
+	UpdatedAt             string                   `json:"updatedAt"`
+}
+
+// PixDueDateChargeFilter ...
+type PixDueDateChargeFilter struct {
+	ConciliationID string
+	Status         PixChargeStatus
+	PageSize       int
+	NextPage       string
+}
+
+// PixDueDateChargeListResponse ...
+type PixDueDateChargeListResponse struct {
+	Data     []*PixDueDateChargeResponse `json:"data"`
+	NextPage string                      `json:"nextPage,omitempty"`
+}
+
 type PixQrCodeDecodeRequest struct {
 	EncodedValue string `json:"encodedValue"`
 }
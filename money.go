@@ -0,0 +1,605 @@
+package bankly
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CurrencyBRL ...
+const CurrencyBRL = "BRL"
+
+// Money is an exact amount in centavos. The empty currency is BRL.
+// It is marshaled as a JSON number in reais, MoneyCents is marshaled in centavos. The models
+// keep their own amount fields, in reais or in centavos as each endpoint, with accessors
+// to convert from and to Money.
+type Money struct {
+	Cents    int64
+	Currency string
+}
+
+// NewMoney returns an amount in BRL
+func NewMoney(cents int64) Money {
+	return Money{Cents: cents, Currency: CurrencyBRL}
+}
+
+// MoneyFromFloat converts an amount in reais returned by Bankly, rounding half away from zero.
+// The amounts out of range are kept at the limits of Money, NewMoneyFromFloat returns their error.
+func MoneyFromFloat(value float64) Money {
+	money, err := NewMoneyFromFloat(value)
+	switch {
+	case err == ErrMoneyOverflow && value < 0:
+		return NewMoney(math.MinInt64)
+	case err == ErrMoneyOverflow:
+		return NewMoney(math.MaxInt64)
+	case err != nil:
+		return NewMoney(0)
+	}
+	return money
+}
+
+// NewMoneyFromFloat converts an amount in reais, rounding half away from zero. It returns
+// ErrInvalidAmount to NaN and infinities and ErrMoneyOverflow to the amounts out of range.
+func NewMoneyFromFloat(value float64) (Money, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	// the shortest representation is the decimal sent by Bankly, without binary noise
+	return parseDecimalMoney(strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// ParseMoney parses amounts typed by people, as 1234.56, 1.234,56, 1.234 or R$ 1.234,56,
+// rounding half away from zero after the centavos. A dot followed by groups of exactly three
+// digits separates thousands, so 1.234 is one thousand. Mixed or repeated separators that
+// can't be told apart, as 1,234.56 or 1.23.45, are rejected with ErrInvalidAmount.
+func ParseMoney(value string) (Money, error) {
+	value, negative := trimMoneySign(value)
+	value = strings.TrimSpace(strings.TrimPrefix(value, "R$"))
+
+	integer, fraction := value, ""
+	if i := strings.Index(value, ","); i >= 0 {
+		integer, fraction = value[:i], value[i+1:]
+
+		// a dot or another comma after the decimal comma
+		if strings.ContainsAny(fraction, ".,") {
+			return Money{}, ErrInvalidAmount
+		}
+
+		if strings.Contains(integer, ".") {
+			grouped, ok := removeThousands(integer)
+			if !ok {
+				return Money{}, ErrInvalidAmount
+			}
+			integer = grouped
+		}
+	} else if strings.Contains(value, ".") {
+		if grouped, ok := removeThousands(value); ok {
+			integer = grouped
+		} else if i := strings.Index(value, "."); strings.Count(value, ".") == 1 {
+			integer, fraction = value[:i], value[i+1:]
+		} else {
+			return Money{}, ErrInvalidAmount
+		}
+	}
+
+	return newMoneyFromParts(integer, fraction, negative)
+}
+
+// parseDecimalMoney parses amounts in reais with a decimal point, as sent by Bankly
+func parseDecimalMoney(value string) (Money, error) {
+	value, negative := trimMoneySign(value)
+
+	integer, fraction := value, ""
+	if i := strings.Index(value, "."); i >= 0 {
+		integer, fraction = value[:i], value[i+1:]
+	}
+
+	return newMoneyFromParts(integer, fraction, negative)
+}
+
+func trimMoneySign(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+
+	negative := false
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		negative = value[0] == '-'
+		value = value[1:]
+	}
+
+	return strings.TrimSpace(value), negative
+}
+
+// removeThousands removes the dots of 1.234.567, the first group has up to three digits
+// without a leading zero and the others exactly three digits.
+func removeThousands(value string) (string, bool) {
+	groups := strings.Split(value, ".")
+	if len(groups[0]) == 0 || len(groups[0]) > 3 || groups[0][0] == '0' {
+		return "", false
+	}
+
+	for _, group := range groups[1:] {
+		if len(group) != 3 {
+			return "", false
+		}
+	}
+
+	return strings.Join(groups, ""), true
+}
+
+func newMoneyFromParts(integer, fraction string, negative bool) (Money, error) {
+	if integer == "" && fraction == "" || !IsOnlyDigits(integer) || !IsOnlyDigits(fraction) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	if integer == "" {
+		integer = "0"
+	}
+
+	reais, err := strconv.ParseInt(integer, 10, 64)
+	if err != nil || reais > math.MaxInt64/100-1 {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	fraction += "000"
+	cents := reais*100 + int64(fraction[0]-'0')*10 + int64(fraction[1]-'0')
+	if fraction[2] >= '5' {
+		cents++
+	}
+
+	if negative {
+		cents = -cents
+	}
+
+	return NewMoney(cents), nil
+}
+
+// Float64 returns the nearest float in reais, for the models with float fields
+func (m Money) Float64() float64 {
+	return float64(m.Cents) / 100
+}
+
+// Decimal returns the amount in reais as 1234.56
+func (m Money) Decimal() string {
+	sign, cents := m.abs()
+	return sign + strconv.FormatUint(cents/100, 10) + "." + twoDigits(cents%100)
+}
+
+// String returns the amount in pt-BR as R$ 1.234,56
+func (m Money) String() string {
+	symbol := m.currency()
+	if symbol == CurrencyBRL {
+		symbol = "R$"
+	}
+
+	sign, cents := m.abs()
+
+	integer := strconv.FormatUint(cents/100, 10)
+	var groups []string
+	for len(integer) > 3 {
+		groups = append([]string{integer[len(integer)-3:]}, groups...)
+		integer = integer[:len(integer)-3]
+	}
+	groups = append([]string{integer}, groups...)
+
+	return sign + symbol + " " + strings.Join(groups, ".") + "," + twoDigits(cents%100)
+}
+
+// IsZero ...
+func (m Money) IsZero() bool {
+	return m.Cents == 0
+}
+
+// IsNegative ...
+func (m Money) IsNegative() bool {
+	return m.Cents < 0
+}
+
+// Equal returns true for the same amount and currency
+func (m Money) Equal(other Money) bool {
+	return m.Cents == other.Cents && m.currency() == other.currency()
+}
+
+// Cmp returns -1, 0 or 1 comparing m with other, the currencies must match
+func (m Money) Cmp(other Money) (int, error) {
+	if m.currency() != other.currency() {
+		return 0, ErrMoneyCurrencyMismatch
+	}
+
+	switch {
+	case m.Cents < other.Cents:
+		return -1, nil
+	case m.Cents > other.Cents:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Add ...
+func (m Money) Add(other Money) (Money, error) {
+	if m.currency() != other.currency() {
+		return Money{}, ErrMoneyCurrencyMismatch
+	}
+
+	sum := m.Cents + other.Cents
+	if (other.Cents > 0 && sum < m.Cents) || (other.Cents < 0 && sum > m.Cents) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{Cents: sum, Currency: m.currency()}, nil
+}
+
+// Sub ...
+func (m Money) Sub(other Money) (Money, error) {
+	if other.Cents == math.MinInt64 {
+		return Money{}, ErrMoneyOverflow
+	}
+	return m.Add(Money{Cents: -other.Cents, Currency: other.Currency})
+}
+
+// Mul multiplies the amount by a quantity
+func (m Money) Mul(quantity int64) (Money, error) {
+	if m.Cents == 0 || quantity == 0 {
+		return Money{Currency: m.currency()}, nil
+	}
+
+	// the division of math.MinInt64 by -1 overflows back to math.MinInt64
+	if (m.Cents == -1 && quantity == math.MinInt64) || (m.Cents == math.MinInt64 && quantity == -1) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	product := m.Cents * quantity
+	if product/quantity != m.Cents {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{Cents: product, Currency: m.currency()}, nil
+}
+
+// Split divides the amount in n parts, the first parts take the remaining centavos
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	part := m.Cents / int64(n)
+	remainder := m.Cents % int64(n)
+
+	response := make([]Money, n)
+	for i := range response {
+		response[i] = Money{Cents: part, Currency: m.currency()}
+		if remainder > 0 {
+			response[i].Cents++
+			remainder--
+		} else if remainder < 0 {
+			response[i].Cents--
+			remainder++
+		}
+	}
+
+	return response, nil
+}
+
+// MarshalJSON writes the amount in reais as a number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.Decimal()), nil
+}
+
+// UnmarshalJSON reads the amount in reais from a number or a string
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	if value == "null" {
+		return nil
+	}
+
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	money, err := parseDecimalMoney(value)
+	if err != nil {
+		return err
+	}
+
+	*m = money
+	return nil
+}
+
+// MoneyCents is a Money marshaled as a JSON integer in centavos, the format of the
+// endpoints in centavos as the transfers
+type MoneyCents Money
+
+// Money ...
+func (m MoneyCents) Money() Money {
+	return Money(m)
+}
+
+// MarshalJSON writes the amount in centavos as an integer
+func (m MoneyCents) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatInt(m.Cents, 10)), nil
+}
+
+// UnmarshalJSON reads the amount in centavos from an integer or a string
+func (m *MoneyCents) UnmarshalJSON(data []byte) error {
+	value := string(data)
+	if value == "null" {
+		return nil
+	}
+
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+
+	cents, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return ErrMoneyOverflow
+		}
+		return ErrInvalidAmount
+	}
+
+	*m = MoneyCents(NewMoney(cents))
+	return nil
+}
+
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return CurrencyBRL
+	}
+	return m.Currency
+}
+
+func (m Money) abs() (string, uint64) {
+	if m.Cents < 0 {
+		return "-", uint64(-(m.Cents + 1)) + 1
+	}
+	return "", uint64(m.Cents)
+}
+
+func twoDigits(value uint64) string {
+	if value < 10 {
+		return "0" + strconv.FormatUint(value, 10)
+	}
+	return strconv.FormatUint(value, 10)
+}
+
+// AmountMoney returns the amount in centavos of the transfer request
+func (r TransfersRequest) AmountMoney() Money {
+	return NewMoney(r.Amount)
+}
+
+// SetAmount sets the amount in centavos of the transfer request
+func (r *TransfersRequest) SetAmount(amount Money) {
+	r.Amount = amount.Cents
+}
+
+// AmountMoney ...
+func (r TransferByCodeResponse) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// AmountMoney ...
+func (r PixCashOutRequest) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// SetAmount ...
+func (r *PixCashOutRequest) SetAmount(amount Money) {
+	r.Amount = amount.Float64()
+}
+
+// AmountMoney ...
+func (r BoletoRequest) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// SetAmount ...
+func (r *BoletoRequest) SetAmount(amount Money) {
+	r.Amount = amount.Float64()
+}
+
+// AmountMoney ...
+func (r ConfirmPaymentRequest) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// SetAmount ...
+func (r *ConfirmPaymentRequest) SetAmount(amount Money) {
+	r.Amount = amount.Float64()
+}
+
+// Money ...
+func (b BalanceValue) Money() Money {
+	money := MoneyFromFloat(b.Amount)
+	if b.Currency != "" {
+		money.Currency = b.Currency
+	}
+	return money
+}
+
+// Money ...
+func (b BoletoAmount) Money() Money {
+	money := MoneyFromFloat(b.Value)
+	if b.Currency != "" {
+		money.Currency = b.Currency
+	}
+	return money
+}
+
+// NewBoletoAmount ...
+func NewBoletoAmount(amount Money) BoletoAmount {
+	return BoletoAmount{Value: amount.Float64(), Currency: amount.currency()}
+}
+
+// Money ...
+func (a AssertedIncome) Money() Money {
+	money := MoneyFromFloat(a.Value)
+	if a.Currency != "" {
+		money.Currency = a.Currency
+	}
+	return money
+}
+
+// Money ...
+func (a IncomeReportAmount) Money() Money {
+	money := MoneyFromFloat(a.Value)
+	if a.Currency != "" {
+		money.Currency = a.Currency
+	}
+	return money
+}
+
+// AmountMoney ...
+func (p BoletoPayment) AmountMoney() Money {
+	return MoneyFromFloat(p.Amount)
+}
+
+// AmountMoney ...
+func (r TransferRequest) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// SetAmount ...
+func (r *TransferRequest) SetAmount(amount Money) {
+	r.Amount = amount.Float64()
+}
+
+// AmountMoney ...
+func (s Statement) AmountMoney() Money {
+	return MoneyFromFloat(s.Amount)
+}
+
+// InterestAmountCalculatedMoney ...
+func (c Charges) InterestAmountCalculatedMoney() Money {
+	return MoneyFromFloat(c.InterestAmountCalculated)
+}
+
+// FineAmountCalculatedMoney ...
+func (c Charges) FineAmountCalculatedMoney() Money {
+	return MoneyFromFloat(c.FineAmountCalculated)
+}
+
+// DiscountAmountMoney ...
+func (c Charges) DiscountAmountMoney() Money {
+	return MoneyFromFloat(c.DiscountAmount)
+}
+
+// AmountMoney ...
+func (r ValidatePaymentResponse) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// OriginalAmountMoney ...
+func (r ValidatePaymentResponse) OriginalAmountMoney() Money {
+	return MoneyFromFloat(r.OriginalAmount)
+}
+
+// MinAmountMoney ...
+func (r ValidatePaymentResponse) MinAmountMoney() Money {
+	return MoneyFromFloat(r.MinAmount)
+}
+
+// MaxAmountMoney ...
+func (r ValidatePaymentResponse) MaxAmountMoney() Money {
+	return MoneyFromFloat(r.MaxAmount)
+}
+
+// AmountMoney ...
+func (r PaymentResponse) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// OriginalAmountMoney ...
+func (r PaymentResponse) OriginalAmountMoney() Money {
+	return MoneyFromFloat(r.OriginalAmount)
+}
+
+// AmountMoney ...
+func (r PixCashOutResponse) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// AmountMoney ...
+func (r PixCashOutByAuthenticationCodeResponse) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// AmountMoney ...
+func (r PixQrCodeStaticRequest) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// SetAmount ...
+func (r *PixQrCodeStaticRequest) SetAmount(amount Money) {
+	r.Amount = amount.Float64()
+}
+
+// AmountMoney ...
+func (r PixQrCodeDynamicRequest) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// SetAmount ...
+func (r *PixQrCodeDynamicRequest) SetAmount(amount Money) {
+	r.Amount = amount.Float64()
+}
+
+// AmountMoney ...
+func (r PixDueDateChargeRequest) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// SetAmount ...
+func (r *PixDueDateChargeRequest) SetAmount(amount Money) {
+	r.Amount = amount.Float64()
+}
+
+// AmountMoney ...
+func (r PixDueDateChargeResponse) AmountMoney() Money {
+	return MoneyFromFloat(r.Amount)
+}
+
+// PaidAmountMoney ...
+func (r PixDueDateChargeResponse) PaidAmountMoney() Money {
+	return MoneyFromFloat(r.PaidAmount)
+}
+
+// BaseMoney ...
+func (r PixQrCodePaymentResponse) BaseMoney() Money {
+	return MoneyFromFloat(r.BaseValue)
+}
+
+// InterestMoney ...
+func (r PixQrCodePaymentResponse) InterestMoney() Money {
+	return MoneyFromFloat(r.InterestValue)
+}
+
+// PenaltyMoney ...
+func (r PixQrCodePaymentResponse) PenaltyMoney() Money {
+	return MoneyFromFloat(r.PenaltyValue)
+}
+
+// DiscountMoney ...
+func (r PixQrCodePaymentResponse) DiscountMoney() Money {
+	return MoneyFromFloat(r.DiscountValue)
+}
+
+// ReductionMoney ...
+func (r PixQrCodePaymentResponse) ReductionMoney() Money {
+	return MoneyFromFloat(r.ReductionValue)
+}
+
+// TotalMoney ...
+func (r PixQrCodePaymentResponse) TotalMoney() Money {
+	return MoneyFromFloat(r.TotalValue)
+}
+
+// ChangeMoney ...
+func (r PixQrCodePaymentResponse) ChangeMoney() Money {
+	return MoneyFromFloat(r.ChangeValue)
+}
+
+// WithdrawalMoney ...
+func (r PixQrCodePaymentResponse) WithdrawalMoney() Money {
+	return MoneyFromFloat(r.WithdrawalValue)
+}
@@ -0,0 +1,189 @@
+package bankly_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MoneyTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func TestMoneyTestSuite(t *testing.T) {
+	suite.Run(t, new(MoneyTestSuite))
+}
+
+func (s *MoneyTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+}
+
+func (s *MoneyTestSuite) TestParseMoney() {
+	cases := []struct {
+		value string
+		cents int64
+	}{
+		{"1234.56", 123456},
+		{"1.234,56", 123456},
+		{"R$ 1.234,56", 123456},
+		{"1.234.567,89", 123456789},
+		{"-R$ 0,5", -50},
+		{"10", 1000},
+		{".99", 99},
+		{"1,005", 101},
+		{"-1,005", -101},
+		{"0.004", 0},
+		{"0.125", 13},
+		{"92233720368.5", 9223372036850},
+		// a dot followed by groups of three digits separates thousands
+		{"1.234", 123400},
+		{"R$ 1.234", 123400},
+		{"1.234.567", 123456700},
+		{"12.50", 1250},
+	}
+
+	for _, c := range cases {
+		money, err := bankly.ParseMoney(c.value)
+		s.assert.NoError(err, c.value)
+		s.assert.Equal(c.cents, money.Cents, c.value)
+	}
+
+	for _, value := range []string{
+		"", "abc", "1,2,3", "1.2.3", "R$", "1,234.56", "1.23,45", "1.23.45", "1.234.56", "0.123,45", "1,2.3",
+	} {
+		_, err := bankly.ParseMoney(value)
+		s.assert.Equal(bankly.ErrInvalidAmount, err, value)
+	}
+
+	_, err := bankly.ParseMoney("999999999999999999999")
+	s.assert.Equal(bankly.ErrMoneyOverflow, err)
+}
+
+func (s *MoneyTestSuite) TestMoneyFromFloat() {
+	s.assert.Equal(int64(1005), bankly.MoneyFromFloat(10.05).Cents)
+	s.assert.Equal(int64(101), bankly.MoneyFromFloat(1.005).Cents)
+	s.assert.Equal(int64(30), bankly.MoneyFromFloat(0.1+0.2).Cents)
+	s.assert.Equal(int64(-1999), bankly.MoneyFromFloat(-19.99).Cents)
+	s.assert.Equal(10.05, bankly.NewMoney(1005).Float64())
+
+	_, err := bankly.NewMoneyFromFloat(1e300)
+	s.assert.Equal(bankly.ErrMoneyOverflow, err)
+	_, err = bankly.NewMoneyFromFloat(math.NaN())
+	s.assert.Equal(bankly.ErrInvalidAmount, err)
+	s.assert.Equal(int64(math.MaxInt64), bankly.MoneyFromFloat(1e300).Cents)
+	s.assert.Equal(int64(math.MinInt64), bankly.MoneyFromFloat(-1e300).Cents)
+	s.assert.Equal(int64(0), bankly.MoneyFromFloat(math.Inf(1)).Cents)
+}
+
+func (s *MoneyTestSuite) TestFormat() {
+	s.assert.Equal("R$ 1.234.567,89", bankly.NewMoney(123456789).String())
+	s.assert.Equal("-R$ 0,05", bankly.NewMoney(-5).String())
+	s.assert.Equal("USD 10,00", bankly.Money{Cents: 1000, Currency: "USD"}.String())
+	s.assert.Equal("-0.05", bankly.NewMoney(-5).Decimal())
+	s.assert.Equal("-92233720368547758.08", bankly.NewMoney(math.MinInt64).Decimal())
+}
+
+func (s *MoneyTestSuite) TestArithmetic() {
+	sum, err := bankly.NewMoney(10).Add(bankly.Money{Cents: 5})
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.NewMoney(15), sum)
+
+	_, err = bankly.NewMoney(10).Add(bankly.Money{Cents: 5, Currency: "USD"})
+	s.assert.Equal(bankly.ErrMoneyCurrencyMismatch, err)
+
+	_, err = bankly.NewMoney(math.MaxInt64).Add(bankly.NewMoney(1))
+	s.assert.Equal(bankly.ErrMoneyOverflow, err)
+
+	_, err = bankly.NewMoney(0).Sub(bankly.NewMoney(math.MinInt64))
+	s.assert.Equal(bankly.ErrMoneyOverflow, err)
+
+	_, err = bankly.NewMoney(math.MaxInt64 / 2).Mul(3)
+	s.assert.Equal(bankly.ErrMoneyOverflow, err)
+
+	_, err = bankly.NewMoney(math.MinInt64).Mul(-1)
+	s.assert.Equal(bankly.ErrMoneyOverflow, err)
+
+	_, err = bankly.NewMoney(-1).Mul(math.MinInt64)
+	s.assert.Equal(bankly.ErrMoneyOverflow, err)
+
+	product, err := bankly.NewMoney(-5).Mul(-3)
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.NewMoney(15), product)
+
+	parts, err := bankly.NewMoney(100).Split(3)
+	s.assert.NoError(err)
+	s.assert.Equal([]bankly.Money{bankly.NewMoney(34), bankly.NewMoney(33), bankly.NewMoney(33)}, parts)
+
+	cmp, err := bankly.NewMoney(1).Cmp(bankly.NewMoney(2))
+	s.assert.NoError(err)
+	s.assert.Equal(-1, cmp)
+}
+
+func (s *MoneyTestSuite) TestJSON() {
+	var body struct {
+		Amount bankly.Money `json:"amount"`
+		Fee    bankly.Money `json:"fee"`
+	}
+
+	s.assert.NoError(json.Unmarshal([]byte(`{"amount": 1234.5, "fee": "1.005"}`), &body))
+	s.assert.Equal(int64(123450), body.Amount.Cents)
+	s.assert.Equal(int64(101), body.Fee.Cents)
+
+	data, err := json.Marshal(body)
+	s.assert.NoError(err)
+	s.assert.JSONEq(`{"amount": 1234.50, "fee": 1.01}`, string(data))
+
+	var transfer struct {
+		Amount bankly.MoneyCents `json:"amount"`
+	}
+
+	s.assert.NoError(json.Unmarshal([]byte(`{"amount": 1999}`), &transfer))
+	s.assert.Equal(bankly.NewMoney(1999), transfer.Amount.Money())
+
+	data, err = json.Marshal(transfer)
+	s.assert.NoError(err)
+	s.assert.JSONEq(`{"amount": 1999}`, string(data))
+
+	s.assert.Equal(bankly.ErrInvalidAmount, json.Unmarshal([]byte(`{"amount": 19.99}`), &transfer))
+	s.assert.Equal(bankly.ErrMoneyOverflow, json.Unmarshal([]byte(`{"amount": 99999999999999999999}`), &transfer))
+}
+
+func (s *MoneyTestSuite) TestModels() {
+	request := bankly.TransfersRequest{}
+	request.SetAmount(bankly.NewMoney(1999))
+	s.assert.Equal(int64(1999), request.Amount)
+	s.assert.Equal(bankly.NewMoney(1999), request.AmountMoney())
+
+	cashOut := bankly.PixCashOutRequest{}
+	cashOut.SetAmount(bankly.NewMoney(1005))
+	s.assert.Equal(10.05, cashOut.Amount)
+
+	s.assert.Equal(bankly.Money{Cents: 70, Currency: "BRL"}, bankly.BalanceValue{Amount: 0.7, Currency: "BRL"}.Money())
+	s.assert.Equal(bankly.BoletoAmount{Value: 0.7, Currency: "BRL"}, bankly.NewBoletoAmount(bankly.NewMoney(70)))
+
+	transfer := bankly.TransferRequest{}
+	transfer.SetAmount(bankly.NewMoney(1999))
+	s.assert.Equal(19.99, transfer.Amount)
+	s.assert.Equal(bankly.NewMoney(1999), transfer.AmountMoney())
+
+	qrCode := bankly.PixQrCodeDynamicRequest{}
+	qrCode.SetAmount(bankly.NewMoney(30))
+	s.assert.Equal(0.3, qrCode.Amount)
+
+	validated := bankly.ValidatePaymentResponse{Amount: 0.1 + 0.2, MinAmount: 1.005, MaxAmount: 1234.56}
+	s.assert.Equal(bankly.NewMoney(30), validated.AmountMoney())
+	s.assert.Equal(bankly.NewMoney(101), validated.MinAmountMoney())
+	s.assert.Equal(bankly.NewMoney(123456), validated.MaxAmountMoney())
+
+	paid := bankly.PixQrCodePaymentResponse{BaseValue: 100, InterestValue: 0.33, TotalValue: 100.33}
+	s.assert.Equal(bankly.NewMoney(10033), paid.TotalMoney())
+	s.assert.Equal(bankly.NewMoney(33), paid.InterestMoney())
+
+	s.assert.Equal(bankly.Money{Cents: 1000, Currency: "USD"}, bankly.IncomeReportAmount{Value: 10, Currency: "USD"}.Money())
+	s.assert.Equal(bankly.NewMoney(250), bankly.Charges{FineAmountCalculated: 2.5}.FineAmountCalculatedMoney())
+}
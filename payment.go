@@ -10,6 +10,7 @@ import (
 	"path"
 	"strconv"
 
+	"github.com/contbank/bankly-sdk/boleto"
 	"github.com/contbank/grok"
 	"github.com/sirupsen/logrus"
 )
@@ -19,6 +20,8 @@ type Payment struct {
 	session        Session
 	httpClient     *http.Client
 	authentication *Authentication
+	calendarPolicy *CalendarPolicy
+	payConfig      PaymentPayConfig
 }
 
 //NewPayment ...
@@ -30,6 +33,13 @@ func NewPayment(httpClient *http.Client, session Session) *Payment {
 	}
 }
 
+// SetCalendarPolicy checks the bill payment window before confirming payments with
+// ConfirmPayment. Pay and the PaymentScheduler check the business hours of each bill and
+// defer with PaymentPayConfig.Schedules instead.
+func (p *Payment) SetCalendarPolicy(policy *CalendarPolicy) {
+	p.calendarPolicy = policy
+}
+
 // ValidatePayment ...
 func (p *Payment) ValidatePayment(ctx context.Context, correlationID string, model *ValidatePaymentRequest) (*ValidatePaymentResponse, error) {
 	fields := logrus.Fields{
@@ -40,6 +50,15 @@ func (p *Payment) ValidatePayment(ctx context.Context, correlationID string, mod
 		return nil, grok.FromValidationErros(err)
 	}
 
+	// the check digits are validated offline, saving the call to Bankly
+	if err := boleto.Validate(model.Code); err != nil {
+		logrus.
+			WithFields(fields).
+			WithError(err).
+			Error("error validating bar code")
+		return nil, ErrInvalidBarcode
+	}
+
 	u, err := url.Parse(p.session.APIEndpoint)
 
 	if err != nil {
@@ -145,6 +164,22 @@ func (p *Payment) ValidatePayment(ctx context.Context, correlationID string, mod
 
 // ConfirmPayment ...
 func (p *Payment) ConfirmPayment(ctx context.Context, correlationID string, model *ConfirmPaymentRequest) (*ConfirmPaymentResponse, error) {
+	if err := grok.Validator.Struct(model); err != nil {
+		return nil, grok.FromValidationErros(err)
+	}
+
+	if p.calendarPolicy != nil {
+		if err := p.calendarPolicy.Check(ctx, model); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.confirmPayment(ctx, correlationID, model)
+}
+
+// confirmPayment sends the confirmation without the calendar policy, Pay checks the business
+// hours of the bill itself.
+func (p *Payment) confirmPayment(ctx context.Context, correlationID string, model *ConfirmPaymentRequest) (*ConfirmPaymentResponse, error) {
 
 	fields := logrus.Fields{
 		"request_id": correlationID,
@@ -488,3 +523,112 @@ func (p *Payment) DetailPayment(ctx context.Context, correlationID string, model
 
 	return nil, ErrDefaultPayment
 }
+
+// CancelPayment cancels a payment not settled yet, as the payments scheduled to a future settle date.
+// The cancel path is not in the public docs of Bankly, CancelPending reports its 404 for a payment
+// just found as ErrPaymentCancelRouteNotFound.
+func (p *Payment) CancelPayment(ctx context.Context, correlationID string, model *CancelPaymentRequest) error {
+
+	fields := logrus.Fields{
+		"request_id": correlationID,
+	}
+
+	if err := grok.Validator.Struct(model); err != nil {
+		return grok.FromValidationErros(err)
+	}
+
+	u, err := url.Parse(p.session.APIEndpoint)
+
+	if err != nil {
+		logrus.
+			WithFields(fields).
+			WithError(err).
+			Error("error parsing api endpoint")
+		return err
+	}
+
+	u.Path = path.Join(u.Path, PaymentPath)
+	u.Path = path.Join(u.Path, "cancel")
+	endpoint := u.String()
+
+	reqbyte, err := json.Marshal(model)
+
+	if err != nil {
+		logrus.
+			WithFields(fields).
+			WithError(err).
+			Error("error encoding model to json")
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(reqbyte))
+
+	if err != nil {
+		logrus.
+			WithFields(fields).
+			WithError(err).
+			Error("error creating request")
+		return err
+	}
+
+	token, err := p.authentication.Token(ctx)
+
+	if err != nil {
+		logrus.
+			WithFields(fields).
+			WithError(err).
+			Error("error in authentication request")
+		return err
+	}
+
+	req.Header.Add("Authorization", token)
+	req.Header.Add("Content-type", "application/json")
+	req.Header.Add("api-version", p.session.APIVersion)
+	req.Header.Add("x-correlation-id", correlationID)
+
+	resp, err := p.httpClient.Do(req)
+
+	if err != nil {
+		logrus.
+			WithFields(fields).
+			WithError(err).
+			Error("error performing the request")
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	respBody, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusAccepted ||
+		resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrEntryNotFound
+	}
+
+	var bodyErr *ErrorResponse
+
+	err = json.Unmarshal(respBody, &bodyErr)
+	if err != nil {
+		logrus.
+			WithFields(fields).
+			WithError(err).
+			Error("error decoding json response")
+		return ErrDefaultPayment
+	}
+
+	if bodyErr.Code != "" {
+		err = FindError(bodyErr.Code, bodyErr.Message)
+		logrus.
+			WithField("bankly_error", bodyErr).
+			WithFields(fields).
+			WithError(err).
+			Error("bankly cancel payment error")
+		return err
+	}
+
+	return ErrDefaultPayment
+}
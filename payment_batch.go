@@ -0,0 +1,545 @@
+package bankly
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/contbank/bankly-sdk/boleto"
+	"github.com/contbank/grok"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPaymentBatchConcurrency ...
+const DefaultPaymentBatchConcurrency = 5
+
+// PaymentBatchItemStatus ...
+type PaymentBatchItemStatus string
+
+const (
+	// PaymentBatchReady the bill was validated and waits the approval of the batch
+	PaymentBatchReady PaymentBatchItemStatus = "READY"
+	// PaymentBatchInvalid the row was refused before calling Bankly
+	PaymentBatchInvalid PaymentBatchItemStatus = "INVALID"
+	// PaymentBatchRejected the bill was refused by Bankly or by its amount rules and may be fixed and sent again
+	PaymentBatchRejected PaymentBatchItemStatus = "REJECTED"
+	// PaymentBatchConfirmed the payment was confirmed at Bankly
+	PaymentBatchConfirmed PaymentBatchItemStatus = "CONFIRMED"
+	// PaymentBatchScheduled the payment was kept to the next business hours of the bill
+	PaymentBatchScheduled PaymentBatchItemStatus = "SCHEDULED"
+	// PaymentBatchUnknown the outcome is not known and must be checked at FilterPayments before paying again
+	PaymentBatchUnknown PaymentBatchItemStatus = "UNKNOWN"
+	// PaymentBatchSkipped the bill was not sent because the context was canceled
+	PaymentBatchSkipped PaymentBatchItemStatus = "SKIPPED"
+)
+
+// PaymentBatchAnomaly ...
+type PaymentBatchAnomaly string
+
+const (
+	// PaymentBatchExpired the due date of the bill passed, the amount may have fine and interest
+	PaymentBatchExpired PaymentBatchAnomaly = "EXPIRED"
+	// PaymentBatchAmountMismatch the amount of the row differs from the amount of the validation
+	PaymentBatchAmountMismatch PaymentBatchAnomaly = "AMOUNT_MISMATCH"
+	// PaymentBatchDuplicate the bill repeats an earlier row of the batch and is not paid
+	PaymentBatchDuplicate PaymentBatchAnomaly = "DUPLICATE"
+)
+
+// PaymentBatchRowError is a row of the input refused before calling Bankly
+type PaymentBatchRowError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e *PaymentBatchRowError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s %s", e.Line, e.Field, e.Message)
+}
+
+// PaymentBatchOptions ...
+type PaymentBatchOptions struct {
+	// Comma of the CSV, zero uses ','
+	Comma rune
+	// Account paying the bills
+	Account *Account
+	// Concurrency of the calls to Bankly, zero uses DefaultPaymentBatchConcurrency
+	Concurrency int
+}
+
+// PaymentBatchItem ...
+type PaymentBatchItem struct {
+	// Line of the row at the input, a CSV field with line breaks counts as one line
+	Line int
+	Code string
+	// Amount of the row, zero pays the amount of the validation
+	Amount      Money
+	Description *string
+	Status      PaymentBatchItemStatus
+	Anomalies   []PaymentBatchAnomaly
+	Validation  *ValidatePaymentResponse
+	// PayAmount is the amount approved to pay, after the amount rules of the bill
+	PayAmount Money
+	DueDate   *time.Time
+	Receipt   *PaymentReceipt
+	Error     error
+
+	barcode       string
+	correlationID string
+}
+
+// HasAnomaly ...
+func (i *PaymentBatchItem) HasAnomaly(anomaly PaymentBatchAnomaly) bool {
+	for _, current := range i.Anomalies {
+		if current == anomaly {
+			return true
+		}
+	}
+	return false
+}
+
+// PaymentBatchPreview is the validated batch waiting the approval, the items are
+// in the same order of the rows
+type PaymentBatchPreview struct {
+	Items    []*PaymentBatchItem
+	Ready    int
+	Invalid  int
+	Rejected int
+	Skipped  int
+	// Total is the amount of the ready items
+	Total Money
+	// Anomalies counts the items with each anomaly
+	Anomalies map[PaymentBatchAnomaly]int
+
+	options   PaymentBatchOptions
+	mutex     sync.Mutex
+	confirmed bool
+}
+
+// PaymentBatchReport is the consolidated result of ConfirmBatch, the items are in the same order of the rows
+type PaymentBatchReport struct {
+	Items     []*PaymentBatchItem
+	Confirmed int
+	Scheduled int
+	Invalid   int
+	Rejected  int
+	Unknown   int
+	Skipped   int
+	// ConfirmedTotal is the amount of the confirmed items
+	ConfirmedTotal Money
+	// ScheduledTotal is the amount of the scheduled items
+	ScheduledTotal Money
+}
+
+// Receipts returns the receipts of the confirmed and scheduled items
+func (r *PaymentBatchReport) Receipts() []*PaymentReceipt {
+	response := []*PaymentReceipt{}
+	for _, item := range r.Items {
+		if item.Receipt != nil {
+			response = append(response, item.Receipt)
+		}
+	}
+	return response
+}
+
+// PayBatch reads the bills to pay from a CSV with the columns code, amount and description,
+// validates the barcodes offline and each bill at Bankly with bounded concurrency. Nothing is
+// paid, the returned preview has the totals and the anomalies to be approved at ConfirmBatch.
+// The returned error is kept for inputs that can't be read, the errors of the rows are at the items.
+func (p *Payment) PayBatch(ctx context.Context, reader io.Reader, options PaymentBatchOptions) (*PaymentBatchPreview, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+	}
+
+	if options.Account == nil || options.Account.Branch == "" || options.Account.Number == "" {
+		return nil, ErrInvalidAccountNumber
+	}
+
+	items, err := readPaymentBatch(reader, options.Comma)
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error reading payment batch")
+		return nil, err
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultPaymentBatchConcurrency
+	}
+
+	now := p.now()
+	checkPaymentBatch(items, now)
+
+	pending := []int{}
+	for i, item := range items {
+		if item.Status == PaymentBatchSkipped {
+			pending = append(pending, i)
+		}
+	}
+
+	runPaymentBatch(ctx, options.Concurrency, pending, func(i int) {
+		p.validatePaymentBatchItem(ctx, items[i], now)
+	})
+
+	preview := &PaymentBatchPreview{
+		Items:     items,
+		Total:     NewMoney(0),
+		Anomalies: map[PaymentBatchAnomaly]int{},
+		options:   options,
+	}
+
+	for _, item := range items {
+		switch item.Status {
+		case PaymentBatchReady:
+			preview.Ready++
+			if err := addPaymentBatchTotal(&preview.Total, item.PayAmount); err != nil {
+				logrus.WithFields(fields).WithError(err).Error("error adding the payment batch total")
+				return nil, err
+			}
+		case PaymentBatchInvalid:
+			preview.Invalid++
+		case PaymentBatchRejected:
+			preview.Rejected++
+		case PaymentBatchSkipped:
+			preview.Skipped++
+		}
+
+		for _, anomaly := range item.Anomalies {
+			preview.Anomalies[anomaly]++
+		}
+	}
+
+	logrus.WithFields(fields).
+		WithField("ready", preview.Ready).
+		WithField("invalid", preview.Invalid).
+		WithField("rejected", preview.Rejected).
+		WithField("total", preview.Total.String()).
+		Info("payment batch validated")
+
+	return preview, nil
+}
+
+// ConfirmBatch pays the ready items of an approved preview with bounded concurrency. Each bill
+// is paid with the approved amount, out of its business hours it is scheduled as at Pay. A preview
+// is confirmed once, a second call returns ErrInvalidPaymentBatch. The totals are added after the
+// payments, when they are out of range the report is returned with the error.
+func (p *Payment) ConfirmBatch(ctx context.Context, preview *PaymentBatchPreview) (*PaymentBatchReport, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+	}
+
+	if preview == nil || !preview.confirm() {
+		return nil, ErrInvalidPaymentBatch
+	}
+
+	account := preview.options.Account
+
+	report := &PaymentBatchReport{
+		Items:          make([]*PaymentBatchItem, len(preview.Items)),
+		ConfirmedTotal: NewMoney(0),
+		ScheduledTotal: NewMoney(0),
+	}
+
+	pending := []int{}
+	for i, item := range preview.Items {
+		copied := *item
+		copied.Anomalies = append([]PaymentBatchAnomaly{}, item.Anomalies...)
+		report.Items[i] = &copied
+
+		if item.Status == PaymentBatchReady {
+			copied.Status = PaymentBatchSkipped
+			pending = append(pending, i)
+		}
+	}
+
+	runPaymentBatch(ctx, preview.options.Concurrency, pending, func(i int) {
+		item := report.Items[i]
+		request := payRequest{
+			code:        item.Code,
+			amount:      item.PayAmount,
+			branch:      account.Branch,
+			account:     account.Number,
+			description: item.Description,
+		}
+
+		receipt, err := p.payValidated(ctx, request, item.Validation, item.PayAmount, item.correlationID)
+
+		item.Receipt = receipt
+		item.Error = err
+		switch {
+		case err != nil:
+			item.Status = paymentBatchStatus(err)
+		case receipt.Status == PaymentReceiptScheduled:
+			item.Status = PaymentBatchScheduled
+		default:
+			item.Status = PaymentBatchConfirmed
+		}
+	})
+
+	var err error
+	for _, item := range report.Items {
+		switch item.Status {
+		case PaymentBatchConfirmed:
+			report.Confirmed++
+			if total := addPaymentBatchTotal(&report.ConfirmedTotal, item.PayAmount); total != nil {
+				err = total
+			}
+		case PaymentBatchScheduled:
+			report.Scheduled++
+			if total := addPaymentBatchTotal(&report.ScheduledTotal, item.PayAmount); total != nil {
+				err = total
+			}
+		case PaymentBatchInvalid:
+			report.Invalid++
+		case PaymentBatchRejected:
+			report.Rejected++
+		case PaymentBatchUnknown:
+			report.Unknown++
+		case PaymentBatchSkipped:
+			report.Skipped++
+		}
+	}
+
+	logrus.WithFields(fields).
+		WithField("confirmed", report.Confirmed).
+		WithField("scheduled", report.Scheduled).
+		WithField("rejected", report.Rejected).
+		WithField("unknown", report.Unknown).
+		WithField("skipped", report.Skipped).
+		WithField("confirmed_total", report.ConfirmedTotal.String()).
+		Info("payment batch finished")
+
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error adding the payment batch totals")
+		return report, err
+	}
+
+	return report, nil
+}
+
+// confirm marks the preview as confirmed, reporting false when it was confirmed before
+func (p *PaymentBatchPreview) confirm() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.confirmed {
+		return false
+	}
+	p.confirmed = true
+	return true
+}
+
+// addPaymentBatchTotal adds the amount to the total, the total is kept when the sum is out of range
+func addPaymentBatchTotal(total *Money, amount Money) error {
+	sum, err := total.Add(amount)
+	if err != nil {
+		return err
+	}
+	*total = sum
+	return nil
+}
+
+// runPaymentBatch runs the pending items with bounded concurrency, the items queued
+// when the context is canceled are left as they are.
+func runPaymentBatch(ctx context.Context, concurrency int, pending []int, run func(i int)) {
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				run(i)
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+}
+
+func (p *Payment) validatePaymentBatchItem(ctx context.Context, item *PaymentBatchItem, now time.Time) {
+	correlationID := uuid.New().String()
+
+	validation, err := p.ValidatePayment(ctx, correlationID, &ValidatePaymentRequest{Code: item.Code})
+	if err == nil {
+		item.Validation = validation
+		item.correlationID = correlationID
+
+		if dueDate := parsePaymentDate(validation.DueDate); dueDate != nil {
+			item.DueDate = dueDate
+		}
+
+		if !item.Amount.IsZero() && !item.Amount.Equal(MoneyFromFloat(validation.Amount)) {
+			item.Anomalies = append(item.Anomalies, PaymentBatchAmountMismatch)
+		}
+
+		item.PayAmount, err = validation.CheckAmount(item.Amount)
+	}
+
+	if item.DueDate != nil && item.DueDate.Before(startOfDay(now)) {
+		item.Anomalies = append(item.Anomalies, PaymentBatchExpired)
+	}
+
+	if err != nil {
+		item.Status = PaymentBatchRejected
+		item.Error = err
+		return
+	}
+
+	item.Status = PaymentBatchReady
+}
+
+// paymentBatchStatus tells apart the errors answered by Bankly from the ones
+// where the payment may have been confirmed.
+func paymentBatchStatus(err error) PaymentBatchItemStatus {
+	if err == ErrOutOfServicePeriod {
+		return PaymentBatchRejected
+	}
+
+	// the errors answered by Bankly are mapped by FindError
+	if banklyErr, ok := ParseErr(err); ok && banklyErr.GrokError != nil &&
+		banklyErr.GrokError.Code < http.StatusInternalServerError {
+		return PaymentBatchRejected
+	}
+
+	var grokErr *grok.Error
+	if errors.As(err, &grokErr) && err != ErrDefaultPayment && grokErr.Code < http.StatusInternalServerError {
+		return PaymentBatchRejected
+	}
+
+	return PaymentBatchUnknown
+}
+
+// checkPaymentBatch validates the barcodes offline and marks the bills repeated in the batch
+func checkPaymentBatch(items []*PaymentBatchItem, now time.Time) {
+	barcodes := map[string]int{}
+
+	for _, item := range items {
+		if item.Status == PaymentBatchInvalid {
+			continue
+		}
+
+		if item.Code == "" {
+			item.Status = PaymentBatchInvalid
+			item.Error = &PaymentBatchRowError{Line: item.Line, Field: "code", Message: "is required"}
+			continue
+		}
+
+		parsed, err := boleto.ParseAt(item.Code, now)
+		if err != nil {
+			item.Status = PaymentBatchInvalid
+			item.Error = &PaymentBatchRowError{Line: item.Line, Field: "code", Message: "is not a valid barcode"}
+			continue
+		}
+
+		item.barcode = parsed.Barcode
+		if !parsed.DueDate.IsZero() {
+			dueDate := parsed.DueDate
+			item.DueDate = &dueDate
+		}
+
+		if line, ok := barcodes[item.barcode]; ok {
+			item.Status = PaymentBatchInvalid
+			item.Anomalies = append(item.Anomalies, PaymentBatchDuplicate)
+			item.Error = &PaymentBatchRowError{Line: item.Line, Field: "code", Message: fmt.Sprintf("repeated from line %d", line)}
+			continue
+		}
+		barcodes[item.barcode] = item.Line
+	}
+}
+
+func readPaymentBatch(reader io.Reader, comma rune) ([]*PaymentBatchItem, error) {
+	records := csv.NewReader(reader)
+	if comma != 0 {
+		records.Comma = comma
+	}
+	records.FieldsPerRecord = -1
+
+	header, err := records.Read()
+	if err != nil {
+		return nil, ErrInvalidPaymentBatch
+	}
+
+	columns := make([]string, len(header))
+	hasCode := false
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		switch name {
+		case "code":
+			hasCode = true
+		case "amount", "description":
+		default:
+			return nil, ErrInvalidPaymentBatch
+		}
+		columns[i] = name
+	}
+
+	if !hasCode {
+		return nil, ErrInvalidPaymentBatch
+	}
+
+	items := []*PaymentBatchItem{}
+	line := 1
+	for {
+		record, err := records.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, ErrInvalidPaymentBatch
+		}
+
+		line++
+
+		item := &PaymentBatchItem{Line: line, Status: PaymentBatchSkipped}
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+
+			switch columns[i] {
+			case "code":
+				item.Code = value
+			case "description":
+				item.Description = String(value)
+			case "amount":
+				amount, err := ParseMoney(value)
+				if err != nil || amount.IsNegative() {
+					item.Status = PaymentBatchInvalid
+					item.Error = &PaymentBatchRowError{Line: line, Field: "amount", Message: "is invalid"}
+				}
+				item.Amount = amount
+			}
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return nil, ErrInvalidPaymentBatch
+	}
+
+	return items, nil
+}
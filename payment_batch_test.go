@@ -0,0 +1,262 @@
+package bankly_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/boleto"
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	paymentBatchBankBarcode   = "00193373700000001000500940144816060680935031"
+	paymentBatchBankDigitable = "00190.50095 40144.816069 06809.350314 3 37370000000100"
+	paymentBatchCollection    = "836200000021355100403185234319172032100181841691"
+)
+
+type PaymentBatchTestSuite struct {
+	suite.Suite
+	assert      *assert.Assertions
+	ctx         context.Context
+	mutex       sync.Mutex
+	now         time.Time
+	validations map[string]bankly.ValidatePaymentResponse
+	answers     map[string]*http.Response
+	confirms    []*bankly.ConfirmPaymentRequest
+	payment     *bankly.Payment
+	store       bankly.PaymentScheduleStore
+	account     *bankly.Account
+	energy      string
+	water       string
+	phone       string
+}
+
+func TestPaymentBatchTestSuite(t *testing.T) {
+	suite.Run(t, new(PaymentBatchTestSuite))
+}
+
+func (s *PaymentBatchTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.now = time.Date(2026, time.October, 19, 10, 0, 0, 0, calendar.Location())
+	s.answers = map[string]*http.Response{}
+	s.confirms = []*bankly.ConfirmPaymentRequest{}
+	s.store = bankly.NewMemoryPaymentScheduleStore()
+	s.account = &bankly.Account{Branch: "0001", Number: "189162"}
+
+	s.energy, _ = boleto.NewBarcode("341", date(2026, time.October, 25), 15000, "1234567890123456789012345")
+	s.water, _ = boleto.NewBarcode("237", date(2026, time.October, 10), 8000, "1234567890123456789012345")
+	s.phone, _ = boleto.NewBarcode("033", date(2026, time.October, 30), 5000, "1234567890123456789012345")
+
+	s.validations = map[string]bankly.ValidatePaymentResponse{
+		s.energy:                {ID: "val-energy", Amount: 150, DueDate: "2026-10-25"},
+		s.water:                 {ID: "val-water", Amount: 82.4, OriginalAmount: 80, DueDate: "2026-10-10"},
+		paymentBatchBankBarcode: {ID: "val-rent", Amount: 1},
+		paymentBatchCollection:  {ID: "val-tax", Amount: 213.55},
+		s.phone:                 {ID: "val-phone", Amount: 50, AllowChangeAmount: true, MaxAmount: 100},
+	}
+
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		if strings.HasSuffix(req.URL.Path, "validate") {
+			var model bankly.ValidatePaymentRequest
+			json.NewDecoder(req.Body).Decode(&model)
+
+			validation := s.validations[model.Code]
+			validation.Code = model.Code
+			return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(validation)}
+		}
+
+		var model bankly.ConfirmPaymentRequest
+		json.NewDecoder(req.Body).Decode(&model)
+		s.confirms = append(s.confirms, &model)
+
+		if answer, ok := s.answers[model.ID]; ok {
+			return answer
+		}
+
+		return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.ConfirmPaymentResponse{
+			AuthenticationCode: "auth-" + model.ID,
+		})}
+	})
+
+	s.payment = bankly.NewPayment(httpClient, *session)
+	s.payment.SetPayConfig(bankly.PaymentPayConfig{
+		Schedules: s.store,
+		Now:       func() time.Time { return s.now },
+	})
+}
+
+func (s *PaymentBatchTestSuite) input() string {
+	return strings.Join([]string{
+		"code,amount,description",
+		s.energy + ",150.00,Energia",
+		s.water + ",,Agua",
+		paymentBatchBankBarcode + `,"1,00",Aluguel`,
+		paymentBatchBankDigitable + ",,Aluguel de novo",
+		"123,10,Desconhecido",
+		paymentBatchCollection + ",5,Imposto",
+		s.phone + ",60,Telefone",
+	}, "\n")
+}
+
+func (s *PaymentBatchTestSuite) TestPayBatch_Preview() {
+	preview, err := s.payment.PayBatch(s.ctx, strings.NewReader(s.input()), bankly.PaymentBatchOptions{Account: s.account})
+
+	s.assert.NoError(err)
+	s.assert.Len(preview.Items, 7)
+	s.assert.Len(s.confirms, 0)
+
+	s.assert.Equal(4, preview.Ready)
+	s.assert.Equal(2, preview.Invalid)
+	s.assert.Equal(1, preview.Rejected)
+	s.assert.Equal(int64(29340), preview.Total.Cents)
+	s.assert.Equal(map[bankly.PaymentBatchAnomaly]int{
+		bankly.PaymentBatchExpired:        1,
+		bankly.PaymentBatchDuplicate:      1,
+		bankly.PaymentBatchAmountMismatch: 2,
+	}, preview.Anomalies)
+
+	water := preview.Items[1]
+	s.assert.Equal(bankly.PaymentBatchReady, water.Status)
+	s.assert.True(water.HasAnomaly(bankly.PaymentBatchExpired))
+	s.assert.Equal(int64(8240), water.PayAmount.Cents)
+
+	duplicate := preview.Items[3]
+	s.assert.Equal(bankly.PaymentBatchInvalid, duplicate.Status)
+	s.assert.True(duplicate.HasAnomaly(bankly.PaymentBatchDuplicate))
+	s.assert.EqualError(duplicate.Error, "line 5: code repeated from line 4")
+
+	s.assert.EqualError(preview.Items[4].Error, "line 6: code is not a valid barcode")
+
+	tax := preview.Items[5]
+	s.assert.Equal(bankly.PaymentBatchRejected, tax.Status)
+	s.assert.True(tax.HasAnomaly(bankly.PaymentBatchAmountMismatch))
+	s.assert.Equal(bankly.ErrPaymentAmountChangeNotAllowed, tax.Error)
+
+	phone := preview.Items[6]
+	s.assert.Equal(bankly.PaymentBatchReady, phone.Status)
+	s.assert.True(phone.HasAnomaly(bankly.PaymentBatchAmountMismatch))
+	s.assert.Equal(int64(6000), phone.PayAmount.Cents)
+}
+
+func (s *PaymentBatchTestSuite) TestConfirmBatch() {
+	s.answers["val-rent"] = &http.Response{StatusCode: http.StatusBadGateway, Body: jsonBody(nil)}
+	s.answers["val-phone"] = &http.Response{StatusCode: http.StatusBadRequest, Body: jsonBody(bankly.ErrorResponse{
+		CodeMessageErrorResponse: bankly.CodeMessageErrorResponse{Code: "INSUFFICIENT_BALANCE", Message: "insufficient balance"},
+	})}
+
+	preview, err := s.payment.PayBatch(s.ctx, strings.NewReader(s.input()), bankly.PaymentBatchOptions{Account: s.account})
+	s.assert.NoError(err)
+
+	report, err := s.payment.ConfirmBatch(s.ctx, preview)
+
+	s.assert.NoError(err)
+	s.assert.Len(s.confirms, 4)
+	s.assert.Equal(2, report.Confirmed)
+	s.assert.Equal(1, report.Unknown)
+	s.assert.Equal(2, report.Rejected)
+	s.assert.Equal(2, report.Invalid)
+	s.assert.Equal(int64(23240), report.ConfirmedTotal.Cents)
+
+	receipts := report.Receipts()
+	s.assert.Len(receipts, 2)
+	s.assert.Equal("auth-val-energy", receipts[0].AuthenticationCode)
+	s.assert.Equal("Energia", *receipts[0].Description)
+	s.assert.Equal("auth-val-water", receipts[1].AuthenticationCode)
+
+	s.assert.Equal(bankly.PaymentBatchUnknown, report.Items[2].Status)
+	s.assert.Equal(bankly.PaymentBatchRejected, report.Items[6].Status)
+
+	// the preview keeps the items as approved
+	s.assert.Equal(bankly.PaymentBatchReady, preview.Items[0].Status)
+
+	_, err = s.payment.ConfirmBatch(s.ctx, preview)
+	s.assert.Equal(bankly.ErrInvalidPaymentBatch, err)
+	s.assert.Len(s.confirms, 4)
+}
+
+func (s *PaymentBatchTestSuite) TestPayBatch_Amounts() {
+	input := strings.Join([]string{
+		"code;amount",
+		s.energy + ";R$ 1.234,56",
+		s.water + ";1,234.56",
+		s.phone + ";1.234",
+	}, "\n")
+
+	preview, err := s.payment.PayBatch(s.ctx, strings.NewReader(input), bankly.PaymentBatchOptions{
+		Account: s.account,
+		Comma:   ';',
+	})
+	s.assert.NoError(err)
+
+	s.assert.Equal(int64(123456), preview.Items[0].Amount.Cents)
+
+	s.assert.Equal(bankly.PaymentBatchInvalid, preview.Items[1].Status)
+	s.assert.EqualError(preview.Items[1].Error, "line 3: amount is invalid")
+
+	s.assert.Equal(int64(123400), preview.Items[2].Amount.Cents)
+}
+
+func (s *PaymentBatchTestSuite) TestConfirmBatch_Concurrent() {
+	preview, err := s.payment.PayBatch(s.ctx, strings.NewReader(s.input()), bankly.PaymentBatchOptions{Account: s.account})
+	s.assert.NoError(err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.payment.ConfirmBatch(s.ctx, preview)
+		}(i)
+	}
+	wg.Wait()
+
+	s.assert.ElementsMatch([]error{nil, bankly.ErrInvalidPaymentBatch}, errs)
+	s.assert.Len(s.confirms, 4)
+}
+
+func (s *PaymentBatchTestSuite) TestConfirmBatch_OutOfBusinessHours() {
+	s.now = time.Date(2026, time.October, 17, 10, 0, 0, 0, calendar.Location())
+
+	input := "code;amount\n" + s.energy + ";150\n" + s.phone + ";60\n"
+	preview, err := s.payment.PayBatch(s.ctx, strings.NewReader(input), bankly.PaymentBatchOptions{
+		Account: s.account,
+		Comma:   ';',
+	})
+	s.assert.NoError(err)
+	s.assert.Equal(2, preview.Ready)
+
+	report, err := s.payment.ConfirmBatch(s.ctx, preview)
+
+	s.assert.NoError(err)
+	s.assert.Len(s.confirms, 0)
+	s.assert.Equal(2, report.Scheduled)
+	s.assert.Equal(int64(21000), report.ScheduledTotal.Cents)
+
+	scheduled, err := s.store.ListByAccount(s.ctx, s.account.Number)
+	s.assert.NoError(err)
+	s.assert.Len(scheduled, 2)
+}
+
+func (s *PaymentBatchTestSuite) TestPayBatch_InvalidInput() {
+	_, err := s.payment.PayBatch(s.ctx, strings.NewReader("amount,unknown\n1,2\n"), bankly.PaymentBatchOptions{Account: s.account})
+	s.assert.Equal(bankly.ErrInvalidPaymentBatch, err)
+
+	_, err = s.payment.PayBatch(s.ctx, strings.NewReader("code,amount\n"), bankly.PaymentBatchOptions{Account: s.account})
+	s.assert.Equal(bankly.ErrInvalidPaymentBatch, err)
+
+	_, err = s.payment.PayBatch(s.ctx, strings.NewReader(s.input()), bankly.PaymentBatchOptions{})
+	s.assert.Equal(bankly.ErrInvalidAccountNumber, err)
+}
@@ -0,0 +1,355 @@
+package bankly
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// PendingPaymentSource ...
+type PendingPaymentSource string
+
+const (
+	// PendingPaymentBankly the payment was confirmed at Bankly to a future settle date
+	PendingPaymentBankly PendingPaymentSource = "BANKLY"
+	// PendingPaymentScheduled the payment is kept at the PaymentScheduleStore to the next business hours
+	PendingPaymentScheduled PendingPaymentSource = "SCHEDULED"
+)
+
+// PendingPayment is a bill payment not settled yet
+type PendingPayment struct {
+	// ID is the authentication code of the payments at Bankly or the id of the ScheduledPayment
+	ID     string
+	Source PendingPaymentSource
+	// Status of the payment, the statuses of Bankly out of the known ones are kept as read in upper case
+	Status PaymentStatus
+	// Amount of the payment, zero for the scheduled payments of the amount of the validation
+	Amount      Money
+	Digitable   string
+	Assignor    string
+	Description *string
+	BankBranch  string
+	BankAccount string
+	// SettleDate is the settle date at Bankly or the next run of the scheduled payment
+	SettleDate time.Time
+}
+
+// IsKnown reports one of the statuses of the constants, the ones IsPending is sure about.
+func (s PaymentStatus) IsKnown() bool {
+	switch s {
+	case PaymentStatusCreated, PaymentStatusScheduled, PaymentStatusConfirmed,
+		PaymentStatusSettled, PaymentStatusCanceled, PaymentStatusReversed:
+		return true
+	}
+	return false
+}
+
+// PaymentStatus is the status of the payment in upper case.
+func (r *PaymentResponse) PaymentStatus() PaymentStatus {
+	return PaymentStatus(strings.ToUpper(strings.TrimSpace(r.Status)))
+}
+
+// IsPending reports a payment not settled yet, that can be canceled at Bankly. A status out of
+// the known ones is pending until its settle date, so a spelling not expected isn't dropped and
+// Bankly decides about its cancel.
+func (r *PaymentResponse) IsPending(now time.Time) bool {
+	today := startOfDay(now.In(calendar.Location()))
+	settle := startOfDay(r.SettleDate.In(calendar.Location()))
+
+	switch status := r.PaymentStatus(); {
+	case status == PaymentStatusCreated, status == PaymentStatusScheduled:
+		return true
+	case status == PaymentStatusConfirmed:
+		return settle.After(today)
+	case !status.IsKnown():
+		return r.SettleDate.IsZero() || !settle.Before(today)
+	}
+	return false
+}
+
+// PendingPayments lists the payments of the account not settled yet, the ones confirmed at
+// Bankly to a future settle date and the ones kept at the PaymentScheduleStore, by settle date.
+func (p *Payment) PendingPayments(ctx context.Context, account *Account) ([]*PendingPayment, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+	}
+
+	if account == nil || account.Branch == "" || account.Number == "" {
+		return nil, ErrInvalidAccountNumber
+	}
+
+	now := p.now()
+	response := []*PendingPayment{}
+
+	var pageToken *string
+	for {
+		page, err := p.FilterPayments(ctx, uuid.New().String(), &FilterPaymentsRequest{
+			BankBranch:  account.Branch,
+			BankAccount: account.Number,
+			PageSize:    100,
+			PageToken:   pageToken,
+		})
+		if err == ErrEntryNotFound {
+			break
+		} else if err != nil {
+			logrus.WithFields(fields).WithError(err).Error("error listing bill payments")
+			return nil, err
+		}
+
+		for _, payment := range page.Data {
+			if !payment.PaymentStatus().IsKnown() {
+				logrus.WithFields(fields).
+					WithField("authentication_code", payment.AuthenticationCode).
+					WithField("status", payment.Status).
+					Warn("bill payment with an unknown status")
+			}
+			if payment.IsPending(now) {
+				response = append(response, newBanklyPendingPayment(payment))
+			}
+		}
+
+		if page.NextPageToken == "" {
+			break
+		}
+		pageToken = String(page.NextPageToken)
+	}
+
+	if p.payConfig.Schedules != nil {
+		scheduled, err := p.payConfig.Schedules.ListByAccount(ctx, account.Number)
+		if err != nil {
+			logrus.WithFields(fields).WithError(err).Error("error listing scheduled payments")
+			return nil, err
+		}
+
+		for _, payment := range scheduled {
+			if payment.Status == ScheduledPaymentPending && payment.BankBranch == account.Branch {
+				response = append(response, newScheduledPendingPayment(payment))
+			}
+		}
+	}
+
+	sort.SliceStable(response, func(i, j int) bool { return response[i].SettleDate.Before(response[j].SettleDate) })
+
+	return response, nil
+}
+
+// CancelPending cancels a payment not settled yet, by the id of a ScheduledPayment or the
+// authentication code of a payment at Bankly. It returns ErrPaymentInvalidStatus for the
+// payments of Bankly already settled.
+func (p *Payment) CancelPending(ctx context.Context, account *Account, id string) (*PendingPayment, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"id":         id,
+	}
+
+	if account == nil || account.Branch == "" || account.Number == "" {
+		return nil, ErrInvalidAccountNumber
+	}
+
+	now := p.now()
+
+	scheduled, err := p.findScheduledPayment(ctx, account, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheduled != nil {
+		canceled, err := p.payConfig.Schedules.Cancel(ctx, id, now.UTC())
+		if err != nil {
+			logrus.WithFields(fields).WithError(err).Error("error canceling scheduled payment")
+			return nil, err
+		}
+
+		logrus.WithFields(fields).Info("scheduled payment canceled")
+		return newScheduledPendingPayment(canceled), nil
+	}
+
+	payment, err := p.cancelBanklyPayment(ctx, account, id, now)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := newBanklyPendingPayment(payment)
+	pending.Status = PaymentStatusCanceled
+
+	return pending, nil
+}
+
+// ReschedulePending moves a payment not settled yet to another date, which is confirmed at the
+// business hours of the bill from then on. A payment at Bankly is canceled there and kept at the
+// PaymentScheduleStore with the same amount, so rescheduling needs the store.
+func (p *Payment) ReschedulePending(ctx context.Context, account *Account, id string, at time.Time) (*PendingPayment, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"id":         id,
+		"at":         at,
+	}
+
+	if account == nil || account.Branch == "" || account.Number == "" {
+		return nil, ErrInvalidAccountNumber
+	}
+
+	now := p.now()
+
+	if p.payConfig.Schedules == nil || !at.After(now) {
+		return nil, ErrInvalidScheduledPayment
+	}
+
+	scheduled, err := p.findScheduledPayment(ctx, account, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheduled != nil {
+		rescheduled, err := p.payConfig.Schedules.Reschedule(ctx, id, at, now.UTC())
+		if err != nil {
+			logrus.WithFields(fields).WithError(err).Error("error rescheduling scheduled payment")
+			return nil, err
+		}
+
+		logrus.WithFields(fields).Info("scheduled payment rescheduled")
+		return newScheduledPendingPayment(rescheduled), nil
+	}
+
+	payment, err := p.cancelBanklyPayment(ctx, account, id, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// the payment is canceled before being kept, a failure here never pays the bill twice
+	scheduled = &ScheduledPayment{
+		ID:          uuid.New().String(),
+		Code:        payment.Digitable,
+		Amount:      payment.Amount,
+		Description: payment.Description,
+		BankBranch:  account.Branch,
+		BankAccount: account.Number,
+		Digitable:   payment.Digitable,
+		Assignor:    payment.Assignor,
+		Status:      ScheduledPaymentPending,
+		NextRun:     at,
+		CreatedAt:   now.UTC(),
+		UpdatedAt:   now.UTC(),
+	}
+
+	if err := p.payConfig.Schedules.Create(ctx, scheduled); err != nil {
+		logrus.WithFields(fields).
+			WithField("digitable", payment.Digitable).
+			WithError(err).Error("error keeping the bill payment canceled at bankly to reschedule")
+		return nil, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("schedule_id", scheduled.ID).
+		Info("bill payment rescheduled")
+
+	return newScheduledPendingPayment(scheduled), nil
+}
+
+// findScheduledPayment returns the scheduled payment of the account with the id, or nil
+// when the id is not of a scheduled payment.
+func (p *Payment) findScheduledPayment(ctx context.Context, account *Account, id string) (*ScheduledPayment, error) {
+	if p.payConfig.Schedules == nil {
+		return nil, nil
+	}
+
+	scheduled, err := p.payConfig.Schedules.Get(ctx, id)
+	if err == ErrScheduledPaymentNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if scheduled.BankBranch != account.Branch || scheduled.BankAccount != account.Number {
+		return nil, ErrScheduledPaymentNotFound
+	}
+
+	return scheduled, nil
+}
+
+// cancelBanklyPayment cancels the payment at Bankly when it is not settled yet.
+func (p *Payment) cancelBanklyPayment(ctx context.Context, account *Account, authenticationCode string,
+	now time.Time) (*PaymentResponse, error) {
+
+	fields := logrus.Fields{
+		"request_id":          GetRequestID(ctx),
+		"authentication_code": authenticationCode,
+	}
+
+	correlationID := uuid.New().String()
+
+	payment, err := p.DetailPayment(ctx, correlationID, &DetailPaymentRequest{
+		BankBranch:         account.Branch,
+		BankAccount:        account.Number,
+		AuthenticationCode: authenticationCode,
+	})
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error getting bill payment to cancel")
+		return nil, err
+	}
+
+	if !payment.IsPending(now) {
+		logrus.WithFields(fields).
+			WithField("status", payment.Status).
+			Info("bill payment can't be canceled")
+		return nil, ErrPaymentInvalidStatus
+	}
+
+	err = p.CancelPayment(ctx, correlationID, &CancelPaymentRequest{
+		BankBranch:         account.Branch,
+		BankAccount:        account.Number,
+		AuthenticationCode: authenticationCode,
+	})
+	if err == ErrEntryNotFound {
+		// the payment was just found, the cancel route is the one missing
+		err = ErrPaymentCancelRouteNotFound
+	}
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error canceling bill payment")
+		return nil, err
+	}
+
+	logrus.WithFields(fields).Info("bill payment canceled")
+
+	return payment, nil
+}
+
+func newBanklyPendingPayment(payment *PaymentResponse) *PendingPayment {
+	return &PendingPayment{
+		ID:          payment.AuthenticationCode,
+		Source:      PendingPaymentBankly,
+		Status:      payment.PaymentStatus(),
+		Amount:      MoneyFromFloat(payment.Amount),
+		Digitable:   payment.Digitable,
+		Assignor:    payment.Assignor,
+		Description: payment.Description,
+		BankBranch:  payment.BankBranch,
+		BankAccount: payment.BankAccount,
+		SettleDate:  payment.SettleDate,
+	}
+}
+
+func newScheduledPendingPayment(scheduled *ScheduledPayment) *PendingPayment {
+	status := PaymentStatusScheduled
+	if scheduled.Status == ScheduledPaymentCanceled {
+		status = PaymentStatusCanceled
+	}
+
+	return &PendingPayment{
+		ID:          scheduled.ID,
+		Source:      PendingPaymentScheduled,
+		Status:      status,
+		Amount:      MoneyFromFloat(scheduled.Amount),
+		Digitable:   scheduled.Digitable,
+		Assignor:    scheduled.Assignor,
+		Description: scheduled.Description,
+		BankBranch:  scheduled.BankBranch,
+		BankAccount: scheduled.BankAccount,
+		SettleDate:  scheduled.NextRun,
+	}
+}
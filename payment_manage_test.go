@@ -0,0 +1,228 @@
+package bankly_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PaymentManageTestSuite struct {
+	suite.Suite
+	assert   *assert.Assertions
+	ctx      context.Context
+	now      time.Time
+	filters  int
+	payments map[string]*bankly.PaymentResponse
+	canceled []*bankly.CancelPaymentRequest
+	// cancelMissing answers the cancel with a 404, as a missing route
+	cancelMissing bool
+	payment       *bankly.Payment
+	store         bankly.PaymentScheduleStore
+	account       *bankly.Account
+}
+
+func TestPaymentManageTestSuite(t *testing.T) {
+	suite.Run(t, new(PaymentManageTestSuite))
+}
+
+func (s *PaymentManageTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.now = time.Date(2026, time.October, 19, 10, 0, 0, 0, calendar.Location())
+	s.filters = 0
+	s.cancelMissing = false
+	s.canceled = []*bankly.CancelPaymentRequest{}
+	s.store = bankly.NewMemoryPaymentScheduleStore()
+	s.account = &bankly.Account{Branch: "0001", Number: "189162"}
+
+	s.payments = map[string]*bankly.PaymentResponse{}
+	for _, payment := range []*bankly.PaymentResponse{
+		{AuthenticationCode: "auth-created", Status: "created", SettleDate: s.settle(20)},
+		{AuthenticationCode: "auth-settled", Status: string(bankly.PaymentStatusConfirmed), SettleDate: s.settle(19)},
+		{AuthenticationCode: "auth-future", Status: string(bankly.PaymentStatusConfirmed), SettleDate: s.settle(22), Amount: 100},
+		{AuthenticationCode: "auth-canceled", Status: string(bankly.PaymentStatusCanceled), SettleDate: s.settle(23)},
+		{AuthenticationCode: "auth-agendado", Status: "Agendado", SettleDate: s.settle(24)},
+		{AuthenticationCode: "auth-liquidado", Status: "Liquidado", SettleDate: s.settle(16)},
+	} {
+		payment.Digitable = "00190.50095 40144.816069 06809.350314 3 37370000000100"
+		payment.Assignor = "Banco do Brasil"
+		payment.BankBranch = s.account.Branch
+		payment.BankAccount = s.account.Number
+		s.payments[payment.AuthenticationCode] = payment
+	}
+
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "detail"):
+			payment, ok := s.payments[req.URL.Query().Get("authenticationCode")]
+			if !ok {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: jsonBody(nil)}
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(payment)}
+		case strings.HasSuffix(req.URL.Path, "cancel"):
+			if s.cancelMissing {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: jsonBody(nil)}
+			}
+			var model bankly.CancelPaymentRequest
+			json.NewDecoder(req.Body).Decode(&model)
+			s.canceled = append(s.canceled, &model)
+			return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(nil)}
+		}
+
+		s.filters++
+		if req.URL.Query().Get("pageToken") == "" {
+			return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.FilterPaymentsResponse{
+				NextPageToken: "page-2",
+				Data:          []*bankly.PaymentResponse{s.payments["auth-created"], s.payments["auth-settled"]},
+			})}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.FilterPaymentsResponse{
+			Data: []*bankly.PaymentResponse{
+				s.payments["auth-future"], s.payments["auth-canceled"], s.payments["auth-agendado"], s.payments["auth-liquidado"],
+			},
+		})}
+	})
+
+	s.payment = bankly.NewPayment(httpClient, *session)
+	s.payment.SetPayConfig(bankly.PaymentPayConfig{
+		Schedules: s.store,
+		Now:       func() time.Time { return s.now },
+	})
+
+	s.store.Create(s.ctx, &bankly.ScheduledPayment{
+		ID: "schedule-1", Code: "00193373700000001000500940144816060680935031", Amount: 50,
+		BankBranch: s.account.Branch, BankAccount: s.account.Number,
+		Status: bankly.ScheduledPaymentPending, NextRun: s.settle(21),
+	})
+	s.store.Create(s.ctx, &bankly.ScheduledPayment{
+		ID: "schedule-failed", BankBranch: s.account.Branch, BankAccount: s.account.Number,
+		Status: bankly.ScheduledPaymentFailed, NextRun: s.settle(18),
+	})
+	s.store.Create(s.ctx, &bankly.ScheduledPayment{
+		ID: "schedule-other", BankBranch: s.account.Branch, BankAccount: "999999",
+		Status: bankly.ScheduledPaymentPending, NextRun: s.settle(21),
+	})
+}
+
+func (s *PaymentManageTestSuite) settle(day int) time.Time {
+	return time.Date(2026, time.October, day, 7, 0, 0, 0, calendar.Location())
+}
+
+func (s *PaymentManageTestSuite) TestPendingPayments() {
+	pending, err := s.payment.PendingPayments(s.ctx, s.account)
+
+	s.assert.NoError(err)
+	s.assert.Equal(2, s.filters)
+
+	ids := []string{}
+	for _, payment := range pending {
+		ids = append(ids, payment.ID)
+	}
+	s.assert.Equal([]string{"auth-created", "schedule-1", "auth-future", "auth-agendado"}, ids)
+
+	s.assert.Equal(bankly.PendingPaymentBankly, pending[0].Source)
+	s.assert.Equal(bankly.PaymentStatusCreated, pending[0].Status)
+	s.assert.Equal(bankly.PendingPaymentScheduled, pending[1].Source)
+	s.assert.Equal(bankly.PaymentStatusScheduled, pending[1].Status)
+	s.assert.Equal(int64(5000), pending[1].Amount.Cents)
+
+	// the unknown status is kept as read
+	s.assert.Equal(bankly.PaymentStatus("AGENDADO"), pending[3].Status)
+	s.assert.False(pending[3].Status.IsKnown())
+}
+
+func (s *PaymentManageTestSuite) TestCancelPending_Scheduled() {
+	pending, err := s.payment.CancelPending(s.ctx, s.account, "schedule-1")
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PaymentStatusCanceled, pending.Status)
+	s.assert.Len(s.canceled, 0)
+
+	scheduled, _ := s.store.Get(s.ctx, "schedule-1")
+	s.assert.Equal(bankly.ScheduledPaymentCanceled, scheduled.Status)
+
+	_, err = s.payment.CancelPending(s.ctx, s.account, "schedule-1")
+	s.assert.Equal(bankly.ErrScheduledPaymentNotPending, err)
+
+	_, err = s.payment.CancelPending(s.ctx, s.account, "schedule-other")
+	s.assert.Equal(bankly.ErrScheduledPaymentNotFound, err)
+}
+
+func (s *PaymentManageTestSuite) TestCancelPending_Locked() {
+	_, err := s.store.Acquire(s.ctx, "schedule-1", "worker", s.settle(21), time.Minute)
+	s.assert.NoError(err)
+
+	s.now = s.settle(21)
+	_, err = s.payment.CancelPending(s.ctx, s.account, "schedule-1")
+	s.assert.Equal(bankly.ErrScheduledPaymentLocked, err)
+}
+
+func (s *PaymentManageTestSuite) TestCancelPending_Bankly() {
+	pending, err := s.payment.CancelPending(s.ctx, s.account, "auth-future")
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PendingPaymentBankly, pending.Source)
+	s.assert.Equal(bankly.PaymentStatusCanceled, pending.Status)
+	s.assert.Len(s.canceled, 1)
+	s.assert.Equal("auth-future", s.canceled[0].AuthenticationCode)
+	s.assert.Equal("189162", s.canceled[0].BankAccount)
+
+	_, err = s.payment.CancelPending(s.ctx, s.account, "auth-settled")
+	s.assert.Equal(bankly.ErrPaymentInvalidStatus, err)
+
+	_, err = s.payment.CancelPending(s.ctx, s.account, "auth-unknown")
+	s.assert.Equal(bankly.ErrEntryNotFound, err)
+
+	s.assert.Len(s.canceled, 1)
+}
+
+func (s *PaymentManageTestSuite) TestCancelPending_UnknownStatus() {
+	pending, err := s.payment.CancelPending(s.ctx, s.account, "auth-agendado")
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PaymentStatusCanceled, pending.Status)
+
+	_, err = s.payment.CancelPending(s.ctx, s.account, "auth-liquidado")
+	s.assert.Equal(bankly.ErrPaymentInvalidStatus, err)
+	s.assert.Len(s.canceled, 1)
+}
+
+func (s *PaymentManageTestSuite) TestCancelPending_RouteNotFound() {
+	s.cancelMissing = true
+
+	_, err := s.payment.CancelPending(s.ctx, s.account, "auth-future")
+	s.assert.Equal(bankly.ErrPaymentCancelRouteNotFound, err)
+}
+
+func (s *PaymentManageTestSuite) TestReschedulePending() {
+	pending, err := s.payment.ReschedulePending(s.ctx, s.account, "schedule-1", s.settle(26))
+
+	s.assert.NoError(err)
+	s.assert.Equal(s.settle(26), pending.SettleDate)
+
+	scheduled, _ := s.store.Get(s.ctx, "schedule-1")
+	s.assert.Equal(s.settle(26), scheduled.NextRun)
+
+	pending, err = s.payment.ReschedulePending(s.ctx, s.account, "auth-future", s.settle(27))
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PendingPaymentScheduled, pending.Source)
+	s.assert.Len(s.canceled, 1)
+
+	scheduled, err = s.store.Get(s.ctx, pending.ID)
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.ScheduledPaymentPending, scheduled.Status)
+	s.assert.Equal(100.0, scheduled.Amount)
+	s.assert.Equal(s.settle(27), scheduled.NextRun)
+	s.assert.Equal(s.payments["auth-future"].Digitable, scheduled.Code)
+
+	_, err = s.payment.ReschedulePending(s.ctx, s.account, "schedule-1", s.now.Add(-time.Hour))
+	s.assert.Equal(bankly.ErrInvalidScheduledPayment, err)
+}
@@ -0,0 +1,338 @@
+package bankly
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// PaymentReceiptStatus ...
+type PaymentReceiptStatus string
+
+const (
+	// PaymentReceiptConfirmed the payment was confirmed at Bankly
+	PaymentReceiptConfirmed PaymentReceiptStatus = "CONFIRMED"
+	// PaymentReceiptScheduled the payment was out of the business hours and will be confirmed at the next window
+	PaymentReceiptScheduled PaymentReceiptStatus = "SCHEDULED"
+)
+
+// PaymentReceipt is the result of Pay, for the payments confirmed now or scheduled
+type PaymentReceipt struct {
+	Status             PaymentReceiptStatus
+	CorrelationID      string
+	AuthenticationCode string
+	// ScheduleID is the id of the ScheduledPayment of a scheduled payment
+	ScheduleID     string
+	ScheduledTo    *time.Time
+	Code           string
+	Digitable      string
+	Assignor       string
+	Payer          *PaymentPayer
+	Recipient      *PaymentPayer
+	Amount         Money
+	OriginalAmount Money
+	Charges        *Charges
+	DueDate        *time.Time
+	SettleDate     *time.Time
+	NextSettle     bool
+	BankBranch     string
+	BankAccount    string
+	Description    *string
+	ConfirmedAt    *time.Time
+}
+
+// PaymentPayConfig ...
+type PaymentPayConfig struct {
+	// Schedules keeps the payments out of the business hours to the PaymentScheduler,
+	// nil rejects them with ErrOutOfServicePeriod
+	Schedules PaymentScheduleStore
+	// Calendar of business days, nil uses the national calendar
+	Calendar calendar.Calendar
+	// Now is used by tests, nil uses time.Now
+	Now func() time.Time
+}
+
+// payRequest is a payment asked to Pay or stored at a ScheduledPayment
+type payRequest struct {
+	code        string
+	amount      Money
+	branch      string
+	account     string
+	description *string
+}
+
+// SetPayConfig ...
+func (p *Payment) SetPayConfig(config PaymentPayConfig) {
+	if config.Calendar == nil {
+		config.Calendar = calendar.NewNational()
+	}
+	p.payConfig = config
+}
+
+// CheckAmount returns the amount to pay, the amount of the validation when amount is zero.
+// It enforces the range of the validation when the amount can be changed.
+func (r *ValidatePaymentResponse) CheckAmount(amount Money) (Money, error) {
+	validated := MoneyFromFloat(r.Amount)
+
+	if amount.IsNegative() {
+		return Money{}, ErrInvalidAmount
+	}
+
+	if amount.IsZero() {
+		if validated.IsZero() || validated.IsNegative() {
+			return Money{}, ErrInvalidAmount
+		}
+		return validated, nil
+	}
+
+	if !r.AllowChangeAmount {
+		if !amount.Equal(validated) {
+			return Money{}, ErrPaymentAmountChangeNotAllowed
+		}
+		return amount, nil
+	}
+
+	if r.MinAmount > 0 && amount.Cents < MoneyFromFloat(r.MinAmount).Cents {
+		return Money{}, ErrPaymentAmountOutOfRange
+	}
+
+	if r.MaxAmount > 0 && amount.Cents > MoneyFromFloat(r.MaxAmount).Cents {
+		return Money{}, ErrPaymentAmountOutOfRange
+	}
+
+	return amount, nil
+}
+
+// Pay validates the bill and confirms its payment from the account. A zero amount pays the
+// amount of the validation, other amounts must respect the amount rules of the bill. Out of
+// the business hours of the bill the payment is stored to be confirmed at the next window
+// by the PaymentScheduler, and the receipt is returned as scheduled.
+func (p *Payment) Pay(ctx context.Context, code string, amount Money, account *Account) (*PaymentReceipt, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"code":       code,
+		"amount":     amount.String(),
+	}
+
+	if account == nil || account.Branch == "" || account.Number == "" {
+		return nil, ErrInvalidAccountNumber
+	}
+
+	request := payRequest{code: code, amount: amount, branch: account.Branch, account: account.Number}
+
+	validation, validated, correlationID, err := p.validatePay(ctx, request, "")
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error validating bill payment")
+		return nil, err
+	}
+
+	return p.payValidated(ctx, request, validation, validated, correlationID)
+}
+
+// payValidated confirms a validated payment, or schedules it out of the business hours of the bill.
+func (p *Payment) payValidated(ctx context.Context, request payRequest, validation *ValidatePaymentResponse,
+	amount Money, correlationID string) (*PaymentReceipt, error) {
+
+	window, err := validation.BusinessWindow()
+	if err != nil {
+		logrus.WithField("request_id", GetRequestID(ctx)).
+			WithField("code", request.code).
+			WithError(err).Error("error parsing bill payment business hours")
+		return nil, err
+	}
+
+	now := p.now()
+	if !calendar.IsWithinWindow(p.calendar(), window, now) {
+		return p.schedulePay(ctx, request, validation, amount, calendar.NextWindowOpening(p.calendar(), window, now))
+	}
+
+	return p.confirmPay(ctx, request, validation, amount, correlationID)
+}
+
+func (p *Payment) schedulePay(ctx context.Context, request payRequest, validation *ValidatePaymentResponse,
+	amount Money, next time.Time) (*PaymentReceipt, error) {
+
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"code":       request.code,
+		"next":       next,
+	}
+
+	if p.payConfig.Schedules == nil {
+		logrus.WithFields(fields).Info("bill payment rejected out of service period")
+		return nil, ErrOutOfServicePeriod
+	}
+
+	now := p.now().UTC()
+
+	scheduled := &ScheduledPayment{
+		ID:          uuid.New().String(),
+		Code:        request.code,
+		Amount:      request.amount.Float64(),
+		Description: request.description,
+		BankBranch:  request.branch,
+		BankAccount: request.account,
+		Digitable:   validation.Digitable,
+		Assignor:    validation.Assignor,
+		Status:      ScheduledPaymentPending,
+		NextRun:     next,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := p.payConfig.Schedules.Create(ctx, scheduled); err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error scheduling bill payment")
+		return nil, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("schedule_id", scheduled.ID).
+		Info("bill payment scheduled to the next window")
+
+	receipt := newPaymentReceipt(PaymentReceiptScheduled, request, validation, amount)
+	receipt.ScheduleID = scheduled.ID
+	receipt.ScheduledTo = &next
+
+	return receipt, nil
+}
+
+// validatePay validates the bill and checks the amount of the request against it. An empty
+// correlation id generates one, the validation and its confirmation are sent with the same id.
+func (p *Payment) validatePay(ctx context.Context, request payRequest, correlationID string) (*ValidatePaymentResponse, Money, string, error) {
+	if correlationID == "" {
+		correlationID = uuid.New().String()
+	}
+
+	validation, err := p.ValidatePayment(ctx, correlationID, &ValidatePaymentRequest{Code: request.code})
+	if err != nil {
+		return nil, Money{}, "", err
+	}
+
+	amount, err := validation.CheckAmount(request.amount)
+	if err != nil {
+		return nil, Money{}, "", err
+	}
+
+	return validation, amount, correlationID, nil
+}
+
+// confirmPay confirms a validated payment. A validation expired before the confirmation
+// is done again once, checking the amount against the new validation.
+func (p *Payment) confirmPay(ctx context.Context, request payRequest, validation *ValidatePaymentResponse,
+	amount Money, correlationID string) (*PaymentReceipt, error) {
+
+	fields := logrus.Fields{
+		"request_id":     GetRequestID(ctx),
+		"correlation_id": correlationID,
+		"code":           request.code,
+	}
+
+	confirm := func() (*ConfirmPaymentResponse, error) {
+		return p.confirmPayment(ctx, correlationID, &ConfirmPaymentRequest{
+			ID:          validation.ID,
+			Amount:      amount.Float64(),
+			Description: request.description,
+			BankBranch:  request.branch,
+			BankAccount: request.account,
+		})
+	}
+
+	response, err := confirm()
+	if isExpiredPaymentValidation(err) {
+		logrus.WithFields(fields).WithError(err).Warn("bill payment validation expired, validating again")
+
+		// the new validation reuses the correlation id, kept by the receipt to reconcile the payment
+		validation, amount, _, err = p.validatePay(ctx, request, correlationID)
+		if err != nil {
+			logrus.WithFields(fields).WithError(err).Error("error validating bill payment again")
+			return nil, err
+		}
+
+		response, err = confirm()
+	}
+
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error confirming bill payment")
+		return nil, err
+	}
+
+	receipt := newPaymentReceipt(PaymentReceiptConfirmed, request, validation, amount)
+	receipt.CorrelationID = correlationID
+	receipt.AuthenticationCode = response.AuthenticationCode
+
+	confirmedAt := p.now()
+	receipt.ConfirmedAt = &confirmedAt
+	if !response.SettledDate.IsZero() {
+		settleDate := response.SettledDate
+		receipt.SettleDate = &settleDate
+	}
+
+	logrus.WithFields(fields).
+		WithField("authentication_code", receipt.AuthenticationCode).
+		Info("bill payment confirmed")
+
+	return receipt, nil
+}
+
+func newPaymentReceipt(status PaymentReceiptStatus, request payRequest, validation *ValidatePaymentResponse, amount Money) *PaymentReceipt {
+	return &PaymentReceipt{
+		Status:         status,
+		Code:           validation.Code,
+		Digitable:      validation.Digitable,
+		Assignor:       validation.Assignor,
+		Payer:          validation.Payer,
+		Recipient:      validation.Recipient,
+		Amount:         amount,
+		OriginalAmount: MoneyFromFloat(validation.OriginalAmount),
+		Charges:        validation.Charges,
+		DueDate:        parsePaymentDate(validation.DueDate),
+		SettleDate:     parsePaymentDate(validation.SettleDate),
+		NextSettle:     validation.NextSettle,
+		BankBranch:     request.branch,
+		BankAccount:    request.account,
+		Description:    request.description,
+	}
+}
+
+func (p *Payment) now() time.Time {
+	if p.payConfig.Now != nil {
+		return p.payConfig.Now()
+	}
+	return time.Now()
+}
+
+func (p *Payment) calendar() calendar.Calendar {
+	if p.payConfig.Calendar == nil {
+		return calendar.NewNational()
+	}
+	return p.payConfig.Calendar
+}
+
+// parsePaymentDate parses the dates of the validation, returned as text by Bankly
+func parsePaymentDate(value string) *time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if date, err := time.ParseInLocation(layout, value, calendar.Location()); err == nil {
+			return &date
+		}
+	}
+	return nil
+}
+
+// isExpiredPaymentValidation reports a confirmation refused because the validation id
+// expired or is no longer known by Bankly.
+func isExpiredPaymentValidation(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if err == ErrEntryNotFound {
+		return true
+	}
+
+	banklyErr, ok := ParseErr(err)
+	return ok && strings.Contains(banklyErr.ErrorKey, "EXPIRED")
+}
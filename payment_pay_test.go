@@ -0,0 +1,303 @@
+package bankly_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PaymentPayTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	ctx        context.Context
+	mutex      sync.Mutex
+	now        time.Time
+	validation bankly.ValidatePaymentResponse
+	validates  int
+	confirms   []*bankly.ConfirmPaymentRequest
+	// correlations are the x-correlation-id headers of the validations and the confirmations
+	correlations []string
+	answers      []*http.Response
+	payment      *bankly.Payment
+	store        bankly.PaymentScheduleStore
+	account      *bankly.Account
+}
+
+func TestPaymentPayTestSuite(t *testing.T) {
+	suite.Run(t, new(PaymentPayTestSuite))
+}
+
+func (s *PaymentPayTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.now = time.Date(2026, time.October, 19, 10, 0, 0, 0, calendar.Location())
+	s.validation = bankly.ValidatePaymentResponse{
+		ID:        "validation-1",
+		Code:      "00193373700000001000500940144816060680935031",
+		Digitable: "00190.50095 40144.816069 06809.350314 3 37370000000100",
+		Assignor:  "Banco do Brasil",
+		Amount:    100,
+		DueDate:   "2026-10-20T00:00:00",
+	}
+	s.validates = 0
+	s.confirms = []*bankly.ConfirmPaymentRequest{}
+	s.correlations = []string{}
+	s.answers = []*http.Response{}
+	s.store = bankly.NewMemoryPaymentScheduleStore()
+	s.account = &bankly.Account{Branch: "0001", Number: "189162"}
+
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		s.correlations = append(s.correlations, req.Header.Get("x-correlation-id"))
+
+		if strings.HasSuffix(req.URL.Path, "validate") {
+			s.validates++
+			validation := s.validation
+			validation.ID = validation.ID + strings.Repeat("'", s.validates-1)
+			return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(validation)}
+		}
+
+		var model bankly.ConfirmPaymentRequest
+		json.NewDecoder(req.Body).Decode(&model)
+		s.confirms = append(s.confirms, &model)
+
+		if len(s.answers) > 0 {
+			answer := s.answers[0]
+			s.answers = s.answers[1:]
+			return answer
+		}
+
+		return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.ConfirmPaymentResponse{
+			AuthenticationCode: "auth-" + model.ID,
+		})}
+	})
+
+	s.payment = bankly.NewPayment(httpClient, *session)
+	s.payment.SetPayConfig(bankly.PaymentPayConfig{
+		Schedules: s.store,
+		Now:       func() time.Time { return s.now },
+	})
+}
+
+func (s *PaymentPayTestSuite) TestCheckAmount() {
+	validation := &bankly.ValidatePaymentResponse{Amount: 100}
+
+	amount, err := validation.CheckAmount(bankly.Money{})
+	s.assert.NoError(err)
+	s.assert.Equal(int64(10000), amount.Cents)
+
+	_, err = validation.CheckAmount(bankly.MoneyFromFloat(90))
+	s.assert.Equal(bankly.ErrPaymentAmountChangeNotAllowed, err)
+
+	_, err = validation.CheckAmount(bankly.MoneyFromFloat(-1))
+	s.assert.Equal(bankly.ErrInvalidAmount, err)
+
+	validation = &bankly.ValidatePaymentResponse{Amount: 100, AllowChangeAmount: true, MinAmount: 50, MaxAmount: 150}
+
+	amount, err = validation.CheckAmount(bankly.MoneyFromFloat(50))
+	s.assert.NoError(err)
+	s.assert.Equal(int64(5000), amount.Cents)
+
+	_, err = validation.CheckAmount(bankly.MoneyFromFloat(49.99))
+	s.assert.Equal(bankly.ErrPaymentAmountOutOfRange, err)
+
+	_, err = validation.CheckAmount(bankly.MoneyFromFloat(150.01))
+	s.assert.Equal(bankly.ErrPaymentAmountOutOfRange, err)
+}
+
+func (s *PaymentPayTestSuite) TestPay_Confirmed() {
+	receipt, err := s.payment.Pay(s.ctx, s.validation.Code, bankly.Money{}, s.account)
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PaymentReceiptConfirmed, receipt.Status)
+	s.assert.Equal("auth-validation-1", receipt.AuthenticationCode)
+	s.assert.Equal(int64(10000), receipt.Amount.Cents)
+	s.assert.Equal("Banco do Brasil", receipt.Assignor)
+	s.assert.Equal(date(2026, time.October, 20), *receipt.DueDate)
+	s.assert.Equal(s.now, *receipt.ConfirmedAt)
+
+	s.assert.Len(s.confirms, 1)
+	s.assert.Equal(100.0, s.confirms[0].Amount)
+	s.assert.Equal("189162", s.confirms[0].BankAccount)
+}
+
+func (s *PaymentPayTestSuite) TestPay_AmountRules() {
+	_, err := s.payment.Pay(s.ctx, s.validation.Code, bankly.MoneyFromFloat(90), s.account)
+	s.assert.Equal(bankly.ErrPaymentAmountChangeNotAllowed, err)
+
+	_, err = s.payment.Pay(s.ctx, s.validation.Code, bankly.Money{}, nil)
+	s.assert.Equal(bankly.ErrInvalidAccountNumber, err)
+
+	s.assert.Len(s.confirms, 0)
+}
+
+func (s *PaymentPayTestSuite) TestPay_ExpiredValidation() {
+	s.answers = append(s.answers, &http.Response{StatusCode: http.StatusNotFound, Body: jsonBody(bankly.ErrorResponse{
+		CodeMessageErrorResponse: bankly.CodeMessageErrorResponse{Code: "PAYMENT_VALIDATION_EXPIRED", Message: "expired"},
+	})})
+
+	receipt, err := s.payment.Pay(s.ctx, s.validation.Code, bankly.Money{}, s.account)
+
+	s.assert.NoError(err)
+	s.assert.Equal(2, s.validates)
+	s.assert.Len(s.confirms, 2)
+	s.assert.Equal("validation-1'", s.confirms[1].ID)
+	s.assert.Equal("auth-validation-1'", receipt.AuthenticationCode)
+
+	// validate, confirm, validate again and confirm again with the same correlation id
+	s.assert.Len(s.correlations, 4)
+	s.assert.NotEmpty(receipt.CorrelationID)
+	for _, correlationID := range s.correlations {
+		s.assert.Equal(receipt.CorrelationID, correlationID)
+	}
+}
+
+func (s *PaymentPayTestSuite) TestPay_ScheduledOutOfBusinessHours() {
+	s.now = time.Date(2026, time.October, 17, 10, 0, 0, 0, calendar.Location())
+
+	receipt, err := s.payment.Pay(s.ctx, s.validation.Code, bankly.Money{}, s.account)
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PaymentReceiptScheduled, receipt.Status)
+	s.assert.Equal(time.Date(2026, time.October, 19, 7, 0, 0, 0, calendar.Location()), *receipt.ScheduledTo)
+	s.assert.Len(s.confirms, 0)
+
+	scheduled, err := s.store.Get(s.ctx, receipt.ScheduleID)
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.ScheduledPaymentPending, scheduled.Status)
+
+	confirmed := []*bankly.PaymentReceipt{}
+	scheduler := bankly.NewPaymentScheduler(s.payment, s.store, bankly.PaymentSchedulerConfig{})
+	scheduler.OnConfirmed(func(ctx context.Context, scheduled *bankly.ScheduledPayment, receipt *bankly.PaymentReceipt) {
+		confirmed = append(confirmed, receipt)
+	})
+
+	processed, err := scheduler.RunOnce(s.ctx, s.now.Add(time.Hour))
+	s.assert.NoError(err)
+	s.assert.Equal(0, processed)
+
+	s.now = time.Date(2026, time.October, 19, 7, 30, 0, 0, calendar.Location())
+	processed, err = scheduler.RunOnce(s.ctx, s.now)
+	s.assert.NoError(err)
+	s.assert.Equal(1, processed)
+	s.assert.Len(confirmed, 1)
+	s.assert.Equal(receipt.ScheduleID, confirmed[0].ScheduleID)
+
+	scheduled, _ = s.store.Get(s.ctx, receipt.ScheduleID)
+	s.assert.Equal(bankly.ScheduledPaymentConfirmed, scheduled.Status)
+	s.assert.Equal(confirmed[0].AuthenticationCode, scheduled.AuthenticationCode)
+	s.assert.Equal(1, scheduled.Attempts)
+}
+
+func (s *PaymentPayTestSuite) TestPay_OutOfBusinessHoursWithoutStore() {
+	s.now = time.Date(2026, time.October, 19, 21, 0, 0, 0, calendar.Location())
+	s.payment.SetPayConfig(bankly.PaymentPayConfig{Now: func() time.Time { return s.now }})
+
+	_, err := s.payment.Pay(s.ctx, s.validation.Code, bankly.Money{}, s.account)
+
+	s.assert.Equal(bankly.ErrOutOfServicePeriod, err)
+	s.assert.Len(s.confirms, 0)
+}
+
+func (s *PaymentPayTestSuite) TestPay_IgnoresCalendarPolicy() {
+	s.now = time.Date(2026, time.October, 19, 21, 0, 0, 0, calendar.Location())
+	s.validation.BusinessHours = &bankly.BusinessHours{Start: "07:00", End: "22:00"}
+
+	policy := bankly.NewBillPaymentCalendarPolicy(bankly.CalendarDefer)
+	policy.Now = func() time.Time { return s.now }
+	policy.Defer = func(ctx context.Context, next time.Time, operation interface{}) error { return nil }
+	s.payment.SetCalendarPolicy(policy)
+
+	receipt, err := s.payment.Pay(s.ctx, s.validation.Code, bankly.Money{}, s.account)
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PaymentReceiptConfirmed, receipt.Status)
+	s.assert.Len(s.confirms, 1)
+}
+
+func (s *PaymentPayTestSuite) TestScheduler_Unconfirmed_NeverConfirmedAgain() {
+	scheduled := s.schedule()
+
+	failed := []*bankly.ScheduledPayment{}
+	scheduler := bankly.NewPaymentScheduler(s.payment, s.store, bankly.PaymentSchedulerConfig{})
+	scheduler.OnFailed(func(ctx context.Context, scheduled *bankly.ScheduledPayment) {
+		failed = append(failed, scheduled)
+	})
+
+	s.answers = []*http.Response{{StatusCode: http.StatusBadGateway, Body: jsonBody(map[string]string{})}}
+	_, err := scheduler.RunOnce(s.ctx, s.now)
+	s.assert.NoError(err)
+
+	stored, _ := s.store.Get(s.ctx, scheduled.ID)
+	s.assert.Equal(bankly.ScheduledPaymentPending, stored.Status)
+	s.assert.NotNil(stored.Confirming)
+
+	_, err = s.payment.CancelPending(s.ctx, s.account, scheduled.ID)
+	s.assert.Equal(bankly.ErrScheduledPaymentLocked, err)
+
+	_, err = scheduler.RunOnce(s.ctx, s.now.Add(time.Hour))
+	s.assert.NoError(err)
+	s.assert.Len(s.confirms, 1)
+	s.assert.Len(failed, 1)
+
+	stored, _ = s.store.Get(s.ctx, scheduled.ID)
+	s.assert.Equal(bankly.ScheduledPaymentFailed, stored.Status)
+	s.assert.Equal(bankly.ErrScheduledPaymentUnconfirmed.Error(), stored.Error)
+	s.assert.Nil(stored.Confirming)
+}
+
+func (s *PaymentPayTestSuite) TestScheduler_Reconciled() {
+	scheduled := s.schedule()
+
+	var reconcile error = bankly.ErrScheduledPaymentNotConfirmed
+	scheduler := bankly.NewPaymentScheduler(s.payment, s.store, bankly.PaymentSchedulerConfig{
+		Reconcile: func(ctx context.Context, scheduled *bankly.ScheduledPayment) (string, error) {
+			return "auth-reconciled", reconcile
+		},
+	})
+
+	// bankly never received the first confirmation, it is confirmed again
+	s.answers = []*http.Response{{StatusCode: http.StatusBadGateway, Body: jsonBody(map[string]string{})}}
+	_, err := scheduler.RunOnce(s.ctx, s.now)
+	s.assert.NoError(err)
+
+	s.answers = []*http.Response{{StatusCode: http.StatusBadGateway, Body: jsonBody(map[string]string{})}}
+	_, err = scheduler.RunOnce(s.ctx, s.now.Add(time.Hour))
+	s.assert.NoError(err)
+	s.assert.Len(s.confirms, 2)
+
+	// the second confirmation was received
+	reconcile = nil
+	_, err = scheduler.RunOnce(s.ctx, s.now.Add(2*time.Hour))
+	s.assert.NoError(err)
+	s.assert.Len(s.confirms, 2)
+
+	stored, _ := s.store.Get(s.ctx, scheduled.ID)
+	s.assert.Equal(bankly.ScheduledPaymentConfirmed, stored.Status)
+	s.assert.Equal("auth-reconciled", stored.AuthenticationCode)
+}
+
+// schedule pays out of the business hours and moves now to the next window
+func (s *PaymentPayTestSuite) schedule() *bankly.ScheduledPayment {
+	s.now = time.Date(2026, time.October, 17, 10, 0, 0, 0, calendar.Location())
+
+	receipt, err := s.payment.Pay(s.ctx, s.validation.Code, bankly.Money{}, s.account)
+	s.assert.NoError(err)
+
+	scheduled, err := s.store.Get(s.ctx, receipt.ScheduleID)
+	s.assert.NoError(err)
+
+	s.now = time.Date(2026, time.October, 19, 7, 30, 0, 0, calendar.Location())
+	return scheduled
+}
@@ -0,0 +1,301 @@
+package bankly
+
+import (
+	"context"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/sirupsen/logrus"
+)
+
+// ScheduledPaymentStatus ...
+type ScheduledPaymentStatus string
+
+const (
+	// ScheduledPaymentPending waiting the business hours of the bill
+	ScheduledPaymentPending ScheduledPaymentStatus = "PENDING"
+	// ScheduledPaymentConfirmed the payment was confirmed at Bankly
+	ScheduledPaymentConfirmed ScheduledPaymentStatus = "CONFIRMED"
+	// ScheduledPaymentFailed the payment was refused or failed after all the attempts
+	ScheduledPaymentFailed ScheduledPaymentStatus = "FAILED"
+	// ScheduledPaymentCanceled the payment was canceled before the confirmation
+	ScheduledPaymentCanceled ScheduledPaymentStatus = "CANCELED"
+)
+
+// ScheduledPayment is a bill payment deferred by Pay to the next business hours of the bill
+type ScheduledPayment struct {
+	ID   string `bson:"_id" json:"id"`
+	Code string `bson:"code" json:"code"`
+	// Amount to pay, zero pays the amount of the validation at the confirmation
+	Amount             float64                `bson:"amount" json:"amount"`
+	Description        *string                `bson:"description,omitempty" json:"description,omitempty"`
+	BankBranch         string                 `bson:"bankBranch" json:"bankBranch"`
+	BankAccount        string                 `bson:"bankAccount" json:"bankAccount"`
+	Digitable          string                 `bson:"digitable,omitempty" json:"digitable,omitempty"`
+	Assignor           string                 `bson:"assignor,omitempty" json:"assignor,omitempty"`
+	Status             ScheduledPaymentStatus `bson:"status" json:"status"`
+	NextRun            time.Time              `bson:"nextRun" json:"nextRun"`
+	Attempts           int                    `bson:"attempts" json:"attempts"`
+	AuthenticationCode string                 `bson:"authenticationCode,omitempty" json:"authenticationCode,omitempty"`
+	Error              string                 `bson:"error,omitempty" json:"error,omitempty"`
+	// Confirming is kept while the confirmation is sent to Bankly, see ScheduledPaymentConfirming
+	Confirming *ScheduledPaymentConfirming `bson:"confirming,omitempty" json:"confirming,omitempty"`
+	CreatedAt  time.Time                   `bson:"createdAt" json:"createdAt"`
+	UpdatedAt  time.Time                   `bson:"updatedAt" json:"updatedAt"`
+
+	ScheduleLease `bson:",inline" json:"-"`
+}
+
+// ScheduledPaymentConfirming is kept at the store before the confirmation is sent to Bankly. A payment
+// found with it was confirmed without an answer, so it is reconciled instead of confirmed again, and
+// it can't be canceled or rescheduled meanwhile.
+type ScheduledPaymentConfirming struct {
+	CorrelationID string    `bson:"correlationId" json:"correlationId"`
+	StartedAt     time.Time `bson:"startedAt" json:"startedAt"`
+}
+
+func (s *ScheduledPayment) payRequest() payRequest {
+	return payRequest{
+		code:        s.Code,
+		amount:      MoneyFromFloat(s.Amount),
+		branch:      s.BankBranch,
+		account:     s.BankAccount,
+		description: s.Description,
+	}
+}
+
+// PaymentSchedulerConfig ...
+type PaymentSchedulerConfig struct {
+	// Owner identifies the worker instance at the leases, empty generates one
+	Owner string
+	// Lease is how long a worker holds a payment while confirming it
+	Lease time.Duration
+	// MaxAttempts of each payment with transient errors
+	MaxAttempts int
+	// RetryDelay between the attempts
+	RetryDelay time.Duration
+	// BatchSize of payments read from the store at each run
+	BatchSize int
+	// Reconcile finds the payment confirmed without an answer by the correlation id (x-correlation-id)
+	// of ScheduledPayment.Confirming, as with FilterPayments. It returns the authentication code, or
+	// ErrScheduledPaymentNotConfirmed when Bankly never confirmed it. When nil, the payment fails with
+	// ErrScheduledPaymentUnconfirmed and is never confirmed again.
+	Reconcile func(ctx context.Context, scheduled *ScheduledPayment) (string, error)
+}
+
+// PaymentScheduler confirms the bill payments deferred by Pay, stored at the PaymentScheduleStore
+// of the PaymentPayConfig. Many instances can run with the same store, each payment is confirmed
+// by one instance at a time.
+type PaymentScheduler struct {
+	payment   *Payment
+	store     PaymentScheduleStore
+	config    PaymentSchedulerConfig
+	confirmed []func(ctx context.Context, scheduled *ScheduledPayment, receipt *PaymentReceipt)
+	failed    []func(ctx context.Context, scheduled *ScheduledPayment)
+}
+
+// NewPaymentScheduler ...
+func NewPaymentScheduler(payment *Payment, store PaymentScheduleStore, config PaymentSchedulerConfig) *PaymentScheduler {
+	scheduleDefaults(&config.Owner, &config.Lease, &config.MaxAttempts, &config.RetryDelay, &config.BatchSize)
+
+	return &PaymentScheduler{
+		payment: payment,
+		store:   store,
+		config:  config,
+	}
+}
+
+// OnConfirmed registers a hook called after each payment confirmed at Bankly.
+func (s *PaymentScheduler) OnConfirmed(hook func(ctx context.Context, scheduled *ScheduledPayment, receipt *PaymentReceipt)) {
+	s.confirmed = append(s.confirmed, hook)
+}
+
+// OnFailed registers a hook called when a payment fails after all the attempts.
+func (s *PaymentScheduler) OnFailed(hook func(ctx context.Context, scheduled *ScheduledPayment)) {
+	s.failed = append(s.failed, hook)
+}
+
+// Start runs the due payments at each interval until the context is done.
+func (s *PaymentScheduler) Start(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, s.config.Owner, "error running scheduled payments", s.RunOnce)
+}
+
+// RunOnce confirms the payments due at now and returns how many were processed by this instance.
+func (s *PaymentScheduler) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.store.Due(ctx, now, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	items := make([]leasedItem, len(due))
+	for i, item := range due {
+		id := item.ID
+
+		var scheduled *ScheduledPayment
+		items[i] = leasedItem{
+			ID: id,
+			Acquire: func(at time.Time) (bool, error) {
+				var err error
+				scheduled, err = s.store.Acquire(ctx, id, s.config.Owner, at, s.config.Lease)
+				return scheduled != nil, err
+			},
+			Run: func(started time.Time) error {
+				return s.execute(GenerateNewRequestID(ctx), scheduled, now, started)
+			},
+			Release: func() error {
+				return s.store.Release(ctx, scheduled, s.config.Owner)
+			},
+		}
+	}
+
+	// a payment left to the lease confirmed nothing at Bankly
+	return runLeased(leasedRun{
+		Field:    "schedule_id",
+		Kept:     "error keeping scheduled payment before the confirmation",
+		Released: "error releasing scheduled payment",
+	}, now, items)
+}
+
+// execute confirms the payment. It returns an error only when the CONFIRMING state could not
+// be kept, the payment is not confirmed and must not be released.
+func (s *PaymentScheduler) execute(ctx context.Context, scheduled *ScheduledPayment, now time.Time, started time.Time) error {
+	fields := logrus.Fields{
+		"request_id":  GetRequestID(ctx),
+		"schedule_id": scheduled.ID,
+	}
+
+	scheduled.UpdatedAt = now
+
+	// a confirmation sent without an answer is never sent again before being reconciled
+	if scheduled.Confirming != nil && !s.reconcile(ctx, scheduled, now) {
+		return nil
+	}
+
+	request := scheduled.payRequest()
+
+	// the bill is validated again, the amount and the business hours may have changed
+	validation, amount, correlationID, err := s.payment.validatePay(ctx, request, "")
+	if err == nil {
+		window, windowErr := validation.BusinessWindow()
+		if windowErr != nil {
+			err = windowErr
+		} else if cal := s.payment.calendar(); !calendar.IsWithinWindow(cal, window, now) {
+			scheduled.NextRun = calendar.NextWindowOpening(cal, window, now)
+			logrus.WithFields(fields).
+				WithField("next_run", scheduled.NextRun).
+				Info("scheduled payment out of the business hours")
+			return nil
+		}
+	}
+
+	scheduled.Attempts++
+
+	var receipt *PaymentReceipt
+	sent := false
+	if err == nil {
+		scheduled.Confirming = &ScheduledPaymentConfirming{CorrelationID: correlationID, StartedAt: now}
+
+		if err := s.store.Checkpoint(ctx, scheduled, s.config.Owner, now.Add(time.Since(started)), s.config.Lease); err != nil {
+			return err
+		}
+
+		sent = true
+		receipt, err = s.payment.confirmPay(ctx, request, validation, amount, correlationID)
+	}
+
+	if err != nil && sent && !isRejectedRequest(err) {
+		logrus.WithFields(fields).
+			WithField("correlation_id", correlationID).
+			WithError(err).Warn("scheduled payment not confirmed, it will be reconciled")
+		scheduled.NextRun = now.Add(s.config.RetryDelay)
+		return nil
+	}
+
+	scheduled.Confirming = nil
+
+	if err != nil && isTransientError(err, sent) && scheduled.Attempts < s.config.MaxAttempts {
+		logrus.WithFields(fields).
+			WithField("attempts", scheduled.Attempts).
+			WithError(err).Warn("scheduled payment will retry")
+		scheduled.NextRun = now.Add(s.config.RetryDelay)
+		return nil
+	}
+
+	s.finish(ctx, scheduled, receipt, err)
+	return nil
+}
+
+// reconcile resolves a confirmation sent without an answer and reports whether the payment
+// must be confirmed, when Bankly never confirmed it.
+func (s *PaymentScheduler) reconcile(ctx context.Context, scheduled *ScheduledPayment, now time.Time) bool {
+	fields := logrus.Fields{
+		"request_id":     GetRequestID(ctx),
+		"schedule_id":    scheduled.ID,
+		"correlation_id": scheduled.Confirming.CorrelationID,
+	}
+
+	if s.config.Reconcile == nil {
+		scheduled.Confirming = nil
+		s.finish(ctx, scheduled, nil, ErrScheduledPaymentUnconfirmed)
+		return false
+	}
+
+	authenticationCode, err := s.config.Reconcile(ctx, scheduled)
+	if err == ErrScheduledPaymentNotConfirmed {
+		logrus.WithFields(fields).Info("scheduled payment not confirmed by bankly, it will be confirmed")
+		scheduled.Confirming = nil
+		return true
+	} else if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error reconciling scheduled payment")
+		scheduled.NextRun = now.Add(s.config.RetryDelay)
+		return false
+	}
+
+	receipt := &PaymentReceipt{
+		Status:             PaymentReceiptConfirmed,
+		CorrelationID:      scheduled.Confirming.CorrelationID,
+		AuthenticationCode: authenticationCode,
+		Code:               scheduled.Code,
+		Digitable:          scheduled.Digitable,
+		Assignor:           scheduled.Assignor,
+		Amount:             MoneyFromFloat(scheduled.Amount),
+		BankBranch:         scheduled.BankBranch,
+		BankAccount:        scheduled.BankAccount,
+		Description:        scheduled.Description,
+	}
+
+	scheduled.Confirming = nil
+	s.finish(ctx, scheduled, receipt, nil)
+	return false
+}
+
+// finish records the confirmation or the failure of the payment.
+func (s *PaymentScheduler) finish(ctx context.Context, scheduled *ScheduledPayment, receipt *PaymentReceipt, err error) {
+	fields := logrus.Fields{
+		"request_id":  GetRequestID(ctx),
+		"schedule_id": scheduled.ID,
+	}
+
+	if err != nil {
+		scheduled.Status = ScheduledPaymentFailed
+		scheduled.Error = err.Error()
+		logrus.WithFields(fields).WithError(err).Error("scheduled payment failed")
+		for _, hook := range s.failed {
+			hook(ctx, scheduled)
+		}
+		return
+	}
+
+	scheduled.Status = ScheduledPaymentConfirmed
+	scheduled.AuthenticationCode = receipt.AuthenticationCode
+	scheduled.Error = ""
+	receipt.ScheduleID = scheduled.ID
+
+	logrus.WithFields(fields).
+		WithField("authentication_code", receipt.AuthenticationCode).
+		Info("scheduled payment confirmed")
+
+	for _, hook := range s.confirmed {
+		hook(ctx, scheduled, receipt)
+	}
+}
@@ -0,0 +1,351 @@
+package bankly
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PaymentScheduleStore persists the bill payments deferred to the next window and the leases of the workers
+type PaymentScheduleStore interface {
+	Create(ctx context.Context, payment *ScheduledPayment) error
+	Get(ctx context.Context, id string) (*ScheduledPayment, error)
+	ListByAccount(ctx context.Context, bankAccount string) ([]*ScheduledPayment, error)
+	// Due returns the pending payments with the next run until now
+	Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledPayment, error)
+	// Acquire leases the payment to the owner when it is still due and not leased
+	// by another owner. It returns nil when the lease is not acquired.
+	Acquire(ctx context.Context, id string, owner string, now time.Time, lease time.Duration) (*ScheduledPayment, error)
+	// Checkpoint saves the payment and renews the lease while the owner still holds it at now,
+	// otherwise it returns ErrScheduledPaymentLeaseLost.
+	Checkpoint(ctx context.Context, payment *ScheduledPayment, owner string, now time.Time, lease time.Duration) error
+	// Release saves the payment and releases the lease, it returns ErrScheduledPaymentLeaseLost
+	// when the owner does not hold the lease anymore.
+	Release(ctx context.Context, payment *ScheduledPayment, owner string) error
+	// Cancel cancels a pending payment not leased at the moment and without a confirmation
+	// waiting for an answer.
+	Cancel(ctx context.Context, id string, now time.Time) (*ScheduledPayment, error)
+	// Reschedule moves the next run of a pending payment not leased at the moment and without
+	// a confirmation waiting for an answer.
+	Reschedule(ctx context.Context, id string, nextRun time.Time, now time.Time) (*ScheduledPayment, error)
+}
+
+// memoryPaymentScheduleStore ...
+type memoryPaymentScheduleStore struct {
+	mutex    sync.Mutex
+	payments map[string]*ScheduledPayment
+}
+
+// NewMemoryPaymentScheduleStore returns a store for a single instance or tests.
+func NewMemoryPaymentScheduleStore() PaymentScheduleStore {
+	return &memoryPaymentScheduleStore{payments: map[string]*ScheduledPayment{}}
+}
+
+func (m *memoryPaymentScheduleStore) Create(ctx context.Context, payment *ScheduledPayment) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.payments[payment.ID] = copyScheduledPayment(payment)
+	return nil
+}
+
+func (m *memoryPaymentScheduleStore) Get(ctx context.Context, id string) (*ScheduledPayment, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	payment, ok := m.payments[id]
+	if !ok {
+		return nil, ErrScheduledPaymentNotFound
+	}
+	return copyScheduledPayment(payment), nil
+}
+
+func (m *memoryPaymentScheduleStore) ListByAccount(ctx context.Context, bankAccount string) ([]*ScheduledPayment, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := []*ScheduledPayment{}
+	for _, payment := range m.payments {
+		if payment.BankAccount == bankAccount {
+			response = append(response, copyScheduledPayment(payment))
+		}
+	}
+
+	sort.Slice(response, func(i, j int) bool { return response[i].CreatedAt.Before(response[j].CreatedAt) })
+	return response, nil
+}
+
+func (m *memoryPaymentScheduleStore) Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledPayment, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := []*ScheduledPayment{}
+	for _, payment := range m.payments {
+		if payment.Status == ScheduledPaymentPending && !payment.NextRun.After(now) {
+			response = append(response, copyScheduledPayment(payment))
+		}
+	}
+
+	sort.Slice(response, func(i, j int) bool { return response[i].NextRun.Before(response[j].NextRun) })
+	if limit > 0 && len(response) > limit {
+		response = response[:limit]
+	}
+	return response, nil
+}
+
+func (m *memoryPaymentScheduleStore) Acquire(ctx context.Context, id string, owner string, now time.Time,
+	lease time.Duration) (*ScheduledPayment, error) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	payment, ok := m.payments[id]
+	if !ok {
+		return nil, ErrScheduledPaymentNotFound
+	}
+
+	if payment.Status != ScheduledPaymentPending || payment.NextRun.After(now) {
+		return nil, nil
+	}
+
+	if payment.leasedByOther(owner, now) {
+		return nil, nil
+	}
+
+	payment.lock(owner, now, lease)
+
+	return copyScheduledPayment(payment), nil
+}
+
+func (m *memoryPaymentScheduleStore) Checkpoint(ctx context.Context, payment *ScheduledPayment, owner string,
+	now time.Time, lease time.Duration) error {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current, ok := m.payments[payment.ID]
+	if !ok {
+		return ErrScheduledPaymentNotFound
+	}
+
+	if !current.heldBy(owner, now) {
+		return ErrScheduledPaymentLeaseLost
+	}
+
+	saved := copyScheduledPayment(payment)
+	saved.lock(owner, now, lease)
+	m.payments[payment.ID] = saved
+
+	return nil
+}
+
+func (m *memoryPaymentScheduleStore) Release(ctx context.Context, payment *ScheduledPayment, owner string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current, ok := m.payments[payment.ID]
+	if !ok {
+		return ErrScheduledPaymentNotFound
+	}
+
+	if current.LockedBy != owner {
+		return ErrScheduledPaymentLeaseLost
+	}
+
+	released := copyScheduledPayment(payment)
+	released.unlock()
+	m.payments[payment.ID] = released
+
+	return nil
+}
+
+func (m *memoryPaymentScheduleStore) Cancel(ctx context.Context, id string, now time.Time) (*ScheduledPayment, error) {
+	return m.update(id, now, func(payment *ScheduledPayment) {
+		payment.Status = ScheduledPaymentCanceled
+	})
+}
+
+func (m *memoryPaymentScheduleStore) Reschedule(ctx context.Context, id string, nextRun time.Time,
+	now time.Time) (*ScheduledPayment, error) {
+
+	return m.update(id, now, func(payment *ScheduledPayment) {
+		payment.NextRun = nextRun
+	})
+}
+
+// update changes a pending payment not leased at the moment nor being confirmed
+func (m *memoryPaymentScheduleStore) update(id string, now time.Time, change func(payment *ScheduledPayment)) (*ScheduledPayment, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	payment, ok := m.payments[id]
+	if !ok {
+		return nil, ErrScheduledPaymentNotFound
+	}
+
+	if payment.Status != ScheduledPaymentPending {
+		return nil, ErrScheduledPaymentNotPending
+	}
+
+	if payment.leased(now) || payment.Confirming != nil {
+		return nil, ErrScheduledPaymentLocked
+	}
+
+	change(payment)
+	payment.UpdatedAt = now
+
+	return copyScheduledPayment(payment), nil
+}
+
+func copyScheduledPayment(payment *ScheduledPayment) *ScheduledPayment {
+	response := *payment
+	if payment.Description != nil {
+		description := *payment.Description
+		response.Description = &description
+	}
+	if payment.Confirming != nil {
+		confirming := *payment.Confirming
+		response.Confirming = &confirming
+	}
+	return &response
+}
+
+// mongoPaymentScheduleStore ...
+type mongoPaymentScheduleStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPaymentScheduleStore returns a store shared by many worker instances.
+// The collection should be indexed by status and nextRun.
+func NewMongoPaymentScheduleStore(collection *mongo.Collection) PaymentScheduleStore {
+	return &mongoPaymentScheduleStore{collection: collection}
+}
+
+func (m *mongoPaymentScheduleStore) Create(ctx context.Context, payment *ScheduledPayment) error {
+	_, err := m.collection.InsertOne(ctx, payment)
+	return err
+}
+
+func (m *mongoPaymentScheduleStore) Get(ctx context.Context, id string) (*ScheduledPayment, error) {
+	payment := new(ScheduledPayment)
+
+	err := m.collection.FindOne(ctx, bson.M{"_id": id}).Decode(payment)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrScheduledPaymentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (m *mongoPaymentScheduleStore) ListByAccount(ctx context.Context, bankAccount string) ([]*ScheduledPayment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	return m.find(ctx, bson.M{"bankAccount": bankAccount}, opts)
+}
+
+func (m *mongoPaymentScheduleStore) Due(ctx context.Context, now time.Time, limit int) ([]*ScheduledPayment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "nextRun", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	filter := bson.M{
+		"status":  ScheduledPaymentPending,
+		"nextRun": bson.M{"$lte": now},
+	}
+
+	return m.find(ctx, filter, opts)
+}
+
+func (m *mongoPaymentScheduleStore) Acquire(ctx context.Context, id string, owner string, now time.Time,
+	lease time.Duration) (*ScheduledPayment, error) {
+
+	filter := bson.M{
+		"_id":     id,
+		"status":  ScheduledPaymentPending,
+		"nextRun": bson.M{"$lte": now},
+		"$or":     mongoLeaseFree(now, owner),
+	}
+
+	payment := new(ScheduledPayment)
+
+	found, err := mongoFindOneAndUpdate(ctx, m.collection, filter, mongoLeaseLock(owner, now, lease), payment)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (m *mongoPaymentScheduleStore) Checkpoint(ctx context.Context, payment *ScheduledPayment, owner string,
+	now time.Time, lease time.Duration) error {
+
+	saved := copyScheduledPayment(payment)
+	saved.lock(owner, now, lease)
+
+	return mongoReplaceLeased(ctx, m.collection, mongoLeaseHeld(payment.ID, owner, now), saved, ErrScheduledPaymentLeaseLost)
+}
+
+func (m *mongoPaymentScheduleStore) Release(ctx context.Context, payment *ScheduledPayment, owner string) error {
+	released := copyScheduledPayment(payment)
+	released.unlock()
+
+	filter := bson.M{"_id": payment.ID, "lockedBy": owner}
+	return mongoReplaceLeased(ctx, m.collection, filter, released, ErrScheduledPaymentLeaseLost)
+}
+
+func (m *mongoPaymentScheduleStore) Cancel(ctx context.Context, id string, now time.Time) (*ScheduledPayment, error) {
+	return m.update(ctx, id, now, bson.M{"status": ScheduledPaymentCanceled, "updatedAt": now})
+}
+
+func (m *mongoPaymentScheduleStore) Reschedule(ctx context.Context, id string, nextRun time.Time,
+	now time.Time) (*ScheduledPayment, error) {
+
+	return m.update(ctx, id, now, bson.M{"nextRun": nextRun, "updatedAt": now})
+}
+
+// update sets the fields of a pending payment not leased at the moment nor being confirmed
+func (m *mongoPaymentScheduleStore) update(ctx context.Context, id string, now time.Time, set bson.M) (*ScheduledPayment, error) {
+	filter := bson.M{
+		"_id":        id,
+		"status":     ScheduledPaymentPending,
+		"confirming": nil,
+		"$or":        mongoLeaseFree(now),
+	}
+
+	payment := new(ScheduledPayment)
+
+	found, err := mongoFindOneAndUpdate(ctx, m.collection, filter, bson.M{"$set": set}, payment)
+	if err != nil {
+		return nil, err
+	} else if found {
+		return payment, nil
+	}
+
+	// tells apart the reasons the payment was not updated
+	current, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Status != ScheduledPaymentPending {
+		return nil, ErrScheduledPaymentNotPending
+	}
+
+	return nil, ErrScheduledPaymentLocked
+}
+
+func (m *mongoPaymentScheduleStore) find(ctx context.Context, filter interface{}, opts *options.FindOptions) ([]*ScheduledPayment, error) {
+	response := []*ScheduledPayment{}
+	if err := mongoFind(ctx, m.collection, filter, opts, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
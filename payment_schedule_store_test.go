@@ -0,0 +1,74 @@
+package bankly_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMongoPaymentScheduleStore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := context.Background()
+	now := time.Date(2026, time.November, 10, 12, 0, 0, 0, time.UTC)
+
+	mt.Run("acquire", func(mt *mtest.T) {
+		store := bankly.NewMongoPaymentScheduleStore(mt.Coll)
+
+		mt.AddMockResponses(mongoValue(bson.D{{Key: "_id", Value: "p-1"}, {Key: "lockedBy", Value: "worker-1"}}))
+		scheduled, err := store.Acquire(ctx, "p-1", "worker-1", now, time.Minute)
+		assert.NoError(mt, err)
+		assert.Equal(mt, "worker-1", scheduled.LockedBy)
+
+		command := mt.GetStartedEvent().Command
+		owners, expired := mongoLeaseQuery(command)
+		assert.Equal(mt, []string{"", "worker-1"}, owners)
+		assert.Equal(mt, now, expired)
+		assert.Equal(mt, string(bankly.ScheduledPaymentPending), command.Lookup("query", "status").StringValue())
+
+		mt.AddMockResponses(mongoValue(nil))
+		scheduled, err = store.Acquire(ctx, "p-1", "worker-2", now, time.Minute)
+		assert.NoError(mt, err)
+		assert.Nil(mt, scheduled)
+	})
+
+	mt.Run("checkpoint and release", func(mt *mtest.T) {
+		store := bankly.NewMongoPaymentScheduleStore(mt.Coll)
+		scheduled := &bankly.ScheduledPayment{ID: "p-1", Status: bankly.ScheduledPaymentPending}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		assert.Equal(mt, bankly.ErrScheduledPaymentLeaseLost, store.Checkpoint(ctx, scheduled, "worker-1", now, time.Minute))
+		assert.Equal(mt, now, mt.GetStartedEvent().Command.Lookup("updates", "0", "q", "lockedUntil", "$gt").Time().UTC())
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		assert.Equal(mt, bankly.ErrScheduledPaymentLeaseLost, store.Release(ctx, scheduled, "worker-1"))
+	})
+
+	mt.Run("reschedule", func(mt *mtest.T) {
+		store := bankly.NewMongoPaymentScheduleStore(mt.Coll)
+		nextRun := now.AddDate(0, 0, 1)
+
+		mt.AddMockResponses(mongoValue(bson.D{{Key: "_id", Value: "p-1"}, {Key: "nextRun", Value: nextRun}}))
+		scheduled, err := store.Reschedule(ctx, "p-1", nextRun, now)
+		assert.NoError(mt, err)
+		assert.Equal(mt, nextRun, scheduled.NextRun.UTC())
+
+		// only a payment without a lease at now nor a confirmation waiting for an answer
+		command := mt.GetStartedEvent().Command
+		owners, _ := mongoLeaseQuery(command)
+		assert.Equal(mt, []string{""}, owners)
+		assert.Equal(mt, bson.TypeNull, command.Lookup("query", "confirming").Type)
+
+		mt.AddMockResponses(mongoValue(nil), mongoCursor(mt, bson.D{{Key: "_id", Value: "p-1"}, {Key: "status", Value: bankly.ScheduledPaymentPending}}))
+		_, err = store.Cancel(ctx, "p-1", now)
+		assert.Equal(mt, bankly.ErrScheduledPaymentLocked, err)
+
+		mt.AddMockResponses(mongoValue(nil), mongoCursor(mt, bson.D{{Key: "_id", Value: "p-1"}, {Key: "status", Value: bankly.ScheduledPaymentCanceled}}))
+		_, err = store.Cancel(ctx, "p-1", now)
+		assert.Equal(mt, bankly.ErrScheduledPaymentNotPending, err)
+	})
+}
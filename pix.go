@@ -14,13 +14,14 @@ import (
 )
 
 type Pix struct {
-	httpClient BanklyHttpClient
+	httpClient     BanklyHttpClient
+	dueDateCharges bool
 }
 
 // NewPix ...
 func NewPix(newHttpClient BanklyHttpClient) *Pix {
 	newHttpClient.SetErrorHandler(PixErrorHandler)
-	return &Pix{newHttpClient}
+	return &Pix{httpClient: newHttpClient}
 }
 
 // GetAddressKeysByAccount ...
@@ -76,7 +77,7 @@ func (p *Pix) GetAddressKey(ctx context.Context, key string, currentIdentity str
 		"key":        key,
 	}
 
-	url := "pix/entries/" + key
+	url := "pix/entries/" + PixLookupKey(key)
 
 	header := http.Header{}
 	header.Add("x-bkly-pix-user-id", currentIdentity)
@@ -348,6 +349,16 @@ func (p *Pix) GetCashOutByAuthenticationCode(ctx context.Context, accountNumber
 
 // CreateAddressKey ...
 func (p *Pix) CreateAddressKey(ctx context.Context, pix *PixAddressKeyCreateRequest) (*PixAddressKeyCreateResponse, error) {
+	return p.createAddressKey(ctx, pix, "")
+}
+
+// CreateAddressKeyWithTOTP creates a PHONE or EMAIL key with the transactional hash
+// returned by TransactionalHashTOTP.TransactionalHashValidate
+func (p *Pix) CreateAddressKeyWithTOTP(ctx context.Context, pix *PixAddressKeyCreateRequest, hash string) (*PixAddressKeyCreateResponse, error) {
+	return p.createAddressKey(ctx, pix, hash)
+}
+
+func (p *Pix) createAddressKey(ctx context.Context, pix *PixAddressKeyCreateRequest, hash string) (*PixAddressKeyCreateResponse, error) {
 
 	requestID, _ := ctx.Value("Request-Id").(string)
 	if requestID == "" {
@@ -363,10 +374,27 @@ func (p *Pix) CreateAddressKey(ctx context.Context, pix *PixAddressKeyCreateRequ
 		"object":     pix,
 	}
 
+	// evp keys are generated by bankly, so the value is only checked when informed.
+	// The key is normalized at a copy, the request of the caller is kept as informed.
+	if pix.AddressingKey.Type != PixEVP || pix.AddressingKey.Value != "" {
+		value, err := NormalizePixKey(pix.AddressingKey.Type, pix.AddressingKey.Value)
+		if err != nil {
+			logrus.WithFields(fields).WithError(err).Error("invalid pix key")
+			return nil, err
+		}
+
+		normalized := *pix
+		normalized.AddressingKey.Value = value
+		pix = &normalized
+	}
+
 	url := "/pix/entries"
 
 	header := http.Header{}
 	header.Add("x-correlation-id", requestID)
+	if hash != "" {
+		header.Add("x-bkly-totp", hash)
+	}
 
 	resp, err := p.httpClient.Post(ctx, url, pix, &header)
 	if err != nil {
@@ -0,0 +1,418 @@
+package bankly
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PixChargeDateLayout is the layout of the due date and discount limit dates
+	PixChargeDateLayout = "2006-01-02"
+	// pixChargeMaxDiscounts is the maximum of discount dates accepted by DICT
+	pixChargeMaxDiscounts = 3
+)
+
+// EnableDueDateCharges allows the calls of the due date charges. The pix/qrcodes/dynamic/duedate
+// endpoints are not in the public docs of Bankly yet, the paths follow the ones of the dynamic
+// qrcode and must be confirmed at the sandbox before enabling them. Without it, the due date
+// charge calls return ErrPixDueDateChargeNotSupported without calling Bankly.
+func (p *Pix) EnableDueDateCharges() {
+	p.dueDateCharges = true
+}
+
+// CreateDueDateCharge creates a dynamic qrcode with due date (cobrança com vencimento), see
+// EnableDueDateCharges. The key and the generated txid are set at a copy sent to Bankly, the
+// request of the caller is kept as informed and the txid is returned at the response.
+func (p *Pix) CreateDueDateCharge(ctx context.Context, data *PixDueDateChargeRequest,
+	currentIdentity string) (*PixDueDateChargeResponse, error) {
+
+	requestID := GetRequestID(ctx)
+
+	fields := logrus.Fields{
+		"request_id": requestID,
+		"object":     data,
+	}
+
+	if !p.dueDateCharges {
+		return nil, ErrPixDueDateChargeNotSupported
+	}
+
+	if err := ValidateDueDateCharge(data); err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("invalid pix due date charge")
+		return nil, err
+	}
+
+	normalized := *data
+
+	if err := ensureDynamicTxID(&normalized.ConciliationID); err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("invalid pix due date charge conciliation id")
+		return nil, err
+	}
+
+	key, err := NormalizePixKey(normalized.AddressingKey.Type, normalized.AddressingKey.Value)
+	if err != nil {
+		return nil, err
+	}
+	normalized.AddressingKey.Value = key
+
+	header := http.Header{}
+	header.Add("x-bkly-pix-user-id", currentIdentity)
+	header.Add("x-correlation-id", requestID)
+
+	resp, err := p.httpClient.Post(ctx, "pix/qrcodes/dynamic/duedate", &normalized, &header)
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error(err.Error())
+		return nil, err
+	}
+
+	response := new(PixDueDateChargeResponse)
+	if err := decodePixChargeResponse(resp, fields, response); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("response", response).
+		Info("pix create due date charge. bankly response success")
+
+	return response, nil
+}
+
+// GetDueDateCharge ...
+func (p *Pix) GetDueDateCharge(ctx context.Context, conciliationID string,
+	currentIdentity string) (*PixDueDateChargeResponse, error) {
+
+	requestID := GetRequestID(ctx)
+
+	if !p.dueDateCharges {
+		return nil, ErrPixDueDateChargeNotSupported
+	}
+
+	fields := logrus.Fields{
+		"request_id":      requestID,
+		"conciliation_id": conciliationID,
+	}
+
+	header := http.Header{}
+	header.Add("x-bkly-pix-user-id", currentIdentity)
+	header.Add("x-correlation-id", requestID)
+
+	resp, err := p.httpClient.Get(ctx, "pix/qrcodes/dynamic/duedate/"+conciliationID, nil, &header)
+	if err != nil {
+		if err == ErrEntryNotFound {
+			err = ErrPixChargeNotFound
+		}
+		logrus.WithFields(fields).
+			WithError(err).Error(err.Error())
+		return nil, err
+	}
+
+	response := new(PixDueDateChargeResponse)
+	if err := decodePixChargeResponse(resp, fields, response); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("response", response).
+		Info("pix get due date charge. bankly response success")
+
+	return response, nil
+}
+
+// UpdateDueDateCharge changes an active charge. Only the fields not nil are sent.
+func (p *Pix) UpdateDueDateCharge(ctx context.Context, conciliationID string, data *PixDueDateChargeUpdateRequest,
+	currentIdentity string) (*PixDueDateChargeResponse, error) {
+
+	requestID := GetRequestID(ctx)
+
+	if !p.dueDateCharges {
+		return nil, ErrPixDueDateChargeNotSupported
+	}
+
+	fields := logrus.Fields{
+		"request_id":      requestID,
+		"conciliation_id": conciliationID,
+		"object":          data,
+	}
+
+	if err := validateDueDateChargeUpdate(data); err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("invalid pix due date charge update")
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Add("x-bkly-pix-user-id", currentIdentity)
+	header.Add("x-correlation-id", requestID)
+
+	resp, err := p.httpClient.Patch(ctx, "pix/qrcodes/dynamic/duedate/"+conciliationID, data, nil, &header)
+	if err != nil {
+		if err == ErrEntryNotFound {
+			err = ErrPixChargeNotFound
+		}
+		logrus.WithFields(fields).
+			WithError(err).Error(err.Error())
+		return nil, err
+	}
+
+	response := new(PixDueDateChargeResponse)
+	if err := decodePixChargeResponse(resp, fields, response); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("response", response).
+		Info("pix update due date charge. bankly response success")
+
+	return response, nil
+}
+
+// CancelDueDateCharge ...
+func (p *Pix) CancelDueDateCharge(ctx context.Context, conciliationID string, currentIdentity string) error {
+
+	requestID := GetRequestID(ctx)
+
+	if !p.dueDateCharges {
+		return ErrPixDueDateChargeNotSupported
+	}
+
+	fields := logrus.Fields{
+		"request_id":      requestID,
+		"conciliation_id": conciliationID,
+	}
+
+	header := http.Header{}
+	header.Add("x-bkly-pix-user-id", currentIdentity)
+	header.Add("x-correlation-id", requestID)
+
+	resp, err := p.httpClient.Delete(ctx, "pix/qrcodes/dynamic/duedate/"+conciliationID, nil, &header)
+	if err != nil {
+		if err == ErrEntryNotFound {
+			err = ErrPixChargeNotFound
+		}
+		logrus.WithFields(fields).
+			WithError(err).Error(err.Error())
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	logrus.WithFields(fields).
+		Info("pix cancel due date charge. bankly response success")
+
+	return nil
+}
+
+// FilterDueDateCharges returns the charges by conciliation id and status.
+func (p *Pix) FilterDueDateCharges(ctx context.Context, filter PixDueDateChargeFilter,
+	currentIdentity string) (*PixDueDateChargeListResponse, error) {
+
+	requestID := GetRequestID(ctx)
+
+	if !p.dueDateCharges {
+		return nil, ErrPixDueDateChargeNotSupported
+	}
+
+	fields := logrus.Fields{
+		"request_id": requestID,
+		"object":     filter,
+	}
+
+	query := map[string]string{}
+	if filter.ConciliationID != "" {
+		query["conciliationId"] = filter.ConciliationID
+	}
+	if filter.Status != "" {
+		query["status"] = string(filter.Status)
+	}
+	if filter.PageSize > 0 {
+		query["pageSize"] = strconv.Itoa(filter.PageSize)
+	}
+	if filter.NextPage != "" {
+		query["nextPage"] = filter.NextPage
+	}
+
+	header := http.Header{}
+	header.Add("x-bkly-pix-user-id", currentIdentity)
+	header.Add("x-correlation-id", requestID)
+
+	resp, err := p.httpClient.Get(ctx, "pix/qrcodes/dynamic/duedate", query, &header)
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error(err.Error())
+		return nil, err
+	}
+
+	response := new(PixDueDateChargeListResponse)
+	if err := decodePixChargeResponse(resp, fields, response); err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("total", len(response.Data)).
+		Info("pix filter due date charges. bankly response success")
+
+	return response, nil
+}
+
+// ValidateDueDateCharge checks the charge before sending it to Bankly.
+func ValidateDueDateCharge(data *PixDueDateChargeRequest) error {
+	if data == nil || data.Amount <= 0 || data.DaysValidAfterDueDate < 0 {
+		return ErrInvalidPixCharge
+	}
+
+	if err := ValidatePixKey(data.AddressingKey.Type, data.AddressingKey.Value); err != nil {
+		return err
+	}
+
+	dueDate, err := time.Parse(PixChargeDateLayout, data.DueDate)
+	if err != nil {
+		return ErrInvalidPixChargeDueDate
+	}
+
+	if err := validatePixChargePayer(&data.Payer); err != nil {
+		return err
+	}
+
+	return validatePixChargeValues(data.Amount, dueDate, data.Fine, data.Interest, data.Discounts, data.Abatement)
+}
+
+func validateDueDateChargeUpdate(data *PixDueDateChargeUpdateRequest) error {
+	if data == nil {
+		return ErrInvalidPixCharge
+	}
+
+	if data.Amount != nil && *data.Amount <= 0 {
+		return ErrInvalidPixCharge
+	}
+
+	if data.DaysValidAfterDueDate != nil && *data.DaysValidAfterDueDate < 0 {
+		return ErrInvalidPixCharge
+	}
+
+	if data.Payer != nil {
+		if err := validatePixChargePayer(data.Payer); err != nil {
+			return err
+		}
+	}
+
+	// limit dates and abatement can only be checked against the values being changed
+	var dueDate time.Time
+	if data.DueDate != nil {
+		date, err := time.Parse(PixChargeDateLayout, *data.DueDate)
+		if err != nil {
+			return ErrInvalidPixChargeDueDate
+		}
+		dueDate = date
+	}
+
+	var amount float64
+	if data.Amount != nil {
+		amount = *data.Amount
+	}
+
+	return validatePixChargeValues(amount, dueDate, data.Fine, data.Interest, data.Discounts, data.Abatement)
+}
+
+func validatePixChargePayer(payer *PixPayer) error {
+	if payer.Name == "" {
+		return ErrInvalidPixChargePayer
+	}
+
+	document := OnlyDigits(payer.DocumentNumber)
+	if !IsValidCPF(document) && !IsValidCNPJ(document) {
+		return ErrInvalidPixChargePayer
+	}
+
+	return nil
+}
+
+// validatePixChargeValues checks fine, interest, discounts and abatement.
+// A zero amount or due date skips the checks that depend on them.
+func validatePixChargeValues(amount float64, dueDate time.Time, fine *PixChargeFine, interest *PixChargeInterest,
+	discounts []PixChargeDiscount, abatement *PixChargeAbatement) error {
+
+	if fine != nil && !validPixChargeValue(fine.Type, fine.Value, amount) {
+		return ErrInvalidPixCharge
+	}
+
+	if interest != nil && !validPixChargeValue(interest.Type, interest.Value, amount) {
+		return ErrInvalidPixCharge
+	}
+
+	if abatement != nil && !validPixChargeValue(abatement.Type, abatement.Value, amount) {
+		return ErrInvalidPixCharge
+	}
+
+	if len(discounts) > pixChargeMaxDiscounts {
+		return ErrInvalidPixChargeDiscount
+	}
+
+	var previous time.Time
+	for _, discount := range discounts {
+		limitDate, err := time.Parse(PixChargeDateLayout, discount.LimitDate)
+		if err != nil || !limitDate.After(previous) {
+			return ErrInvalidPixChargeDiscount
+		}
+		if !dueDate.IsZero() && limitDate.After(dueDate) {
+			return ErrInvalidPixChargeDiscount
+		}
+
+		valueType := PixChargeFixedAmount
+		if discount.Type == PixChargeFixedPercentDiscount {
+			valueType = PixChargePercent
+		} else if discount.Type != PixChargeFixedAmountDiscount {
+			return ErrInvalidPixChargeDiscount
+		}
+		if !validPixChargeValue(valueType, discount.Value, amount) {
+			return ErrInvalidPixChargeDiscount
+		}
+
+		previous = limitDate
+	}
+
+	return nil
+}
+
+func validPixChargeValue(valueType PixChargeValueType, value float64, amount float64) bool {
+	if value <= 0 {
+		return false
+	}
+
+	switch valueType {
+	case PixChargePercent:
+		return value < 100
+	case PixChargeFixedAmount:
+		return amount == 0 || value < amount
+	}
+
+	return false
+}
+
+func decodePixChargeResponse(resp *http.Response, fields logrus.Fields, response interface{}) error {
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error decoding body response")
+		return err
+	}
+
+	err = json.Unmarshal(respBody, response)
+	if err != nil {
+		logrus.WithFields(fields).
+			WithError(err).Error("error decoding json response")
+		return ErrDefaultPix
+	}
+
+	return nil
+}
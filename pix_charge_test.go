@@ -0,0 +1,165 @@
+package bankly_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PixChargeTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	ctx        context.Context
+	httpClient *mocks.BanklyHttpClient
+	pix        *bankly.Pix
+}
+
+func TestPixChargeTestSuite(t *testing.T) {
+	suite.Run(t, new(PixChargeTestSuite))
+}
+
+func (s *PixChargeTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.httpClient = mocks.NewBanklyHttpClient(s.T())
+	s.httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+	s.pix = bankly.NewPix(s.httpClient)
+	s.pix.EnableDueDateCharges()
+}
+
+func (s *PixChargeTestSuite) TestCreateDueDateCharge() {
+	request := buildDueDateCharge()
+
+	sent := *request
+	sent.AddressingKey.Value = "new@example.com"
+
+	s.httpClient.EXPECT().Post(mock.Anything, "pix/qrcodes/dynamic/duedate", &sent, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusCreated, Body: jsonBody(bankly.PixDueDateChargeResponse{
+			ConciliationID: request.ConciliationID,
+			EncodedValue:   "00020101021226",
+			Status:         bankly.PixChargeActive,
+			Amount:         request.Amount,
+			DueDate:        request.DueDate,
+		})}, nil)
+
+	response, err := s.pix.CreateDueDateCharge(s.ctx, request, "16246241620")
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PixChargeActive, response.Status)
+	s.assert.Equal("00020101021226", response.EncodedValue)
+	// the key is normalized at the copy sent, the request is kept as informed
+	s.assert.Equal("New@Example.com", request.AddressingKey.Value)
+}
+
+func (s *PixChargeTestSuite) TestDueDateCharges_NotEnabled() {
+	httpClient := mocks.NewBanklyHttpClient(s.T())
+	httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+	pix := bankly.NewPix(httpClient)
+
+	_, err := pix.CreateDueDateCharge(s.ctx, buildDueDateCharge(), "16246241620")
+	s.assert.Equal(bankly.ErrPixDueDateChargeNotSupported, err)
+
+	_, err = pix.GetDueDateCharge(s.ctx, "charge-1", "16246241620")
+	s.assert.Equal(bankly.ErrPixDueDateChargeNotSupported, err)
+
+	s.assert.Equal(bankly.ErrPixDueDateChargeNotSupported, pix.CancelDueDateCharge(s.ctx, "charge-1", "16246241620"))
+}
+
+func (s *PixChargeTestSuite) TestCreateDueDateCharge_Invalid() {
+	request := buildDueDateCharge()
+	request.DueDate = "10/12/2026"
+	_, err := s.pix.CreateDueDateCharge(s.ctx, request, "16246241620")
+	s.assert.Equal(bankly.ErrInvalidPixChargeDueDate, err)
+
+	request = buildDueDateCharge()
+	request.Payer.DocumentNumber = "11111111111"
+	_, err = s.pix.CreateDueDateCharge(s.ctx, request, "16246241620")
+	s.assert.Equal(bankly.ErrInvalidPixChargePayer, err)
+
+	request = buildDueDateCharge()
+	request.Discounts[0].LimitDate = "2026-12-20"
+	_, err = s.pix.CreateDueDateCharge(s.ctx, request, "16246241620")
+	s.assert.Equal(bankly.ErrInvalidPixChargeDiscount, err)
+
+	request = buildDueDateCharge()
+	request.Abatement = &bankly.PixChargeAbatement{Type: bankly.PixChargeFixedAmount, Value: 150}
+	_, err = s.pix.CreateDueDateCharge(s.ctx, request, "16246241620")
+	s.assert.Equal(bankly.ErrInvalidPixCharge, err)
+}
+
+func (s *PixChargeTestSuite) TestGetDueDateCharge_NotFound() {
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/qrcodes/dynamic/duedate/charge-1", mock.Anything, mock.Anything).
+		Return(nil, bankly.ErrEntryNotFound)
+
+	_, err := s.pix.GetDueDateCharge(s.ctx, "charge-1", "16246241620")
+	s.assert.Equal(bankly.ErrPixChargeNotFound, err)
+}
+
+func (s *PixChargeTestSuite) TestUpdateDueDateCharge() {
+	amount := 120.0
+	dueDate := "2027-01-10"
+	update := &bankly.PixDueDateChargeUpdateRequest{Amount: &amount, DueDate: &dueDate}
+
+	s.httpClient.EXPECT().Patch(mock.Anything, "pix/qrcodes/dynamic/duedate/charge-1", update, mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixDueDateChargeResponse{
+			ConciliationID: "charge-1",
+			Amount:         amount,
+			DueDate:        dueDate,
+		})}, nil)
+
+	response, err := s.pix.UpdateDueDateCharge(s.ctx, "charge-1", update, "16246241620")
+	s.assert.NoError(err)
+	s.assert.Equal(amount, response.Amount)
+	s.assert.Equal(dueDate, response.DueDate)
+}
+
+func (s *PixChargeTestSuite) TestCancelDueDateCharge() {
+	s.httpClient.EXPECT().Delete(mock.Anything, "pix/qrcodes/dynamic/duedate/charge-1", nil, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusNoContent, Body: jsonBody(nil)}, nil)
+
+	s.assert.NoError(s.pix.CancelDueDateCharge(s.ctx, "charge-1", "16246241620"))
+}
+
+func (s *PixChargeTestSuite) TestFilterDueDateCharges() {
+	query := map[string]string{"conciliationId": "charge-1", "status": "ACTIVE"}
+
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/qrcodes/dynamic/duedate", query, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixDueDateChargeListResponse{
+			Data: []*bankly.PixDueDateChargeResponse{{ConciliationID: "charge-1", Status: bankly.PixChargeActive}},
+		})}, nil)
+
+	response, err := s.pix.FilterDueDateCharges(s.ctx, bankly.PixDueDateChargeFilter{
+		ConciliationID: "charge-1",
+		Status:         bankly.PixChargeActive,
+	}, "16246241620")
+	s.assert.NoError(err)
+	s.assert.Len(response.Data, 1)
+}
+
+func buildDueDateCharge() *bankly.PixDueDateChargeRequest {
+	return &bankly.PixDueDateChargeRequest{
+		AddressingKey:         bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "New@Example.com"},
+		ConciliationID:        "charge0000000000000000000001",
+		RecipientName:         "Contbank",
+		Location:              bankly.PixQrCodeLocation{City: "Sao Paulo", ZipCode: "01310100"},
+		Amount:                100,
+		DueDate:               "2026-12-10",
+		DaysValidAfterDueDate: 30,
+		Payer: bankly.PixPayer{
+			Name:           "Pagador",
+			DocumentNumber: "529.982.247-25",
+			Type:           "CUSTOMER",
+		},
+		Fine:     &bankly.PixChargeFine{Type: bankly.PixChargePercent, Value: 2},
+		Interest: &bankly.PixChargeInterest{Type: bankly.PixChargePercent, Value: 1},
+		Discounts: []bankly.PixChargeDiscount{
+			{Type: bankly.PixChargeFixedAmountDiscount, LimitDate: "2026-12-01", Value: 10},
+			{Type: bankly.PixChargeFixedAmountDiscount, LimitDate: "2026-12-05", Value: 5},
+		},
+	}
+}
@@ -0,0 +1,431 @@
+package bankly
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/contbank/grok"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// PixClaimResolutionPeriod time the donor has to confirm or cancel a claim,
+	// used when bankly does not inform the resolution limit date
+	PixClaimResolutionPeriod = 7 * 24 * time.Hour
+	// PixClaimConclusionPeriod time the claimer has to complete a claim after it was opened,
+	// used when bankly does not inform the conclusion limit date
+	PixClaimConclusionPeriod = 14 * 24 * time.Hour
+)
+
+// pixClaimTransitions status allowed after each claim status
+var pixClaimTransitions = map[StatusClaim][]StatusClaim{
+	Open:              {WaitingResolution, CanceledClaim},
+	WaitingResolution: {Confirmed, CanceledClaim},
+	Confirmed:         {CompletedClaim, CanceledClaim},
+}
+
+// CanTransitionTo reports whether next is allowed right after the current status.
+func (s StatusClaim) CanTransitionTo(next StatusClaim) bool {
+	for _, status := range pixClaimTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFinal ...
+func (s StatusClaim) IsFinal() bool {
+	return s == CompletedClaim || s == CanceledClaim
+}
+
+// canReach reports whether next can be reached from the current status
+// through one or more transitions, as updates may skip intermediate status.
+func (s StatusClaim) canReach(next StatusClaim) bool {
+	for _, status := range pixClaimTransitions[s] {
+		if status == next || status.canReach(next) {
+			return true
+		}
+	}
+	return false
+}
+
+// PixClaimDeadlines regulatory limits of a claim
+type PixClaimDeadlines struct {
+	// Resolution limit for the donor to confirm or cancel the claim
+	Resolution time.Time
+	// Conclusion limit for the claimer to complete the claim
+	Conclusion time.Time
+}
+
+// PixClaimDonorPolicy actions taken automatically when a claim is received by the account
+type PixClaimDonorPolicy struct {
+	AutoConfirm   bool
+	ConfirmReason ConfirmReason
+	AutoCancel    bool
+	CancelReason  CancelReason
+}
+
+// PixClaim claim tracked by the PixClaimManager
+type PixClaim struct {
+	PixClaimResponse
+	Account        string
+	DocumentNumber string
+	Role           PixClaimRole
+	Deadlines      PixClaimDeadlines
+}
+
+// PendingDeadline returns the deadline of the next action expected from the account side,
+// or nil when the account has nothing to do on the claim.
+func (c *PixClaim) PendingDeadline() *time.Time {
+	switch {
+	case c.Role == PixClaimRoleDonor && (c.Status == Open || c.Status == WaitingResolution):
+		return &c.Deadlines.Resolution
+	case c.Role == PixClaimRoleClaimer && c.Status == Confirmed:
+		return &c.Deadlines.Conclusion
+	}
+	return nil
+}
+
+// PixClaimEvent ...
+type PixClaimEvent struct {
+	Claim          PixClaim
+	PreviousStatus StatusClaim
+}
+
+// PixClaimManager tracks the lifecycle of pix claims, rejecting operations not
+// allowed for the current status and applying the donor policies of each account.
+type PixClaimManager struct {
+	pix      *Pix
+	mu       sync.Mutex
+	claims   map[string]*PixClaim
+	policies map[string]PixClaimDonorPolicy
+	handlers []func(ctx context.Context, event PixClaimEvent)
+}
+
+// NewPixClaimManager ...
+func NewPixClaimManager(pix *Pix) *PixClaimManager {
+	return &PixClaimManager{
+		pix:      pix,
+		claims:   map[string]*PixClaim{},
+		policies: map[string]PixClaimDonorPolicy{},
+	}
+}
+
+// SetDonorPolicy sets the policy applied to claims received by the account.
+func (m *PixClaimManager) SetDonorPolicy(account string, policy PixClaimDonorPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policies[grok.OnlyDigits(account)] = policy
+}
+
+// OnChange registers a handler called every time a claim changes its status.
+func (m *PixClaimManager) OnChange(handler func(ctx context.Context, event PixClaimEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Claim returns a copy of the tracked claim.
+func (m *PixClaimManager) Claim(claimID string) (*PixClaim, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	claim, ok := m.claims[claimID]
+	if !ok {
+		return nil, ErrPixClaimNotFound
+	}
+
+	c := *claim
+	return &c, nil
+}
+
+// Pending returns the open claims whose pending deadline ends until now plus within,
+// sorted by the deadline.
+func (m *PixClaimManager) Pending(now time.Time, within time.Duration) []*PixClaim {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	response := []*PixClaim{}
+	for _, claim := range m.claims {
+		deadline := claim.PendingDeadline()
+		if deadline != nil && !deadline.After(now.Add(within)) {
+			c := *claim
+			response = append(response, &c)
+		}
+	}
+
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].PendingDeadline().Before(*response[j].PendingDeadline())
+	})
+
+	return response
+}
+
+// Create opens a claim for the claimer account and starts tracking it.
+func (m *PixClaimManager) Create(ctx context.Context, model *PixClaimRequest, documentNumber string) (*PixClaim, error) {
+	response, err := m.pix.CreatePixClaim(ctx, model, documentNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.Status == "" {
+		response.Status = Open
+	}
+
+	claim := newPixClaim(*response, model.Claimer.Number, documentNumber, PixClaimRoleClaimer)
+
+	m.mu.Lock()
+	m.claims[claim.ClaimId] = claim
+	m.mu.Unlock()
+
+	m.notify(ctx, *claim, "")
+
+	c := *claim
+	return &c, nil
+}
+
+// Confirm confirms a claim received by the donor account.
+func (m *PixClaimManager) Confirm(ctx context.Context, claimID string, reason ConfirmReason) (*PixClaim, error) {
+	claim, err := m.checkTransition(claimID, PixClaimRoleDonor, Confirmed)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.pix.ConfirmPixClaim(ctx, claim.DocumentNumber, claimID,
+		&PixClaimConfirmReason{Reason: string(reason)})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, claimID, response.PixClaimResponse, Confirmed)
+}
+
+// Complete completes a confirmed claim of the claimer account.
+func (m *PixClaimManager) Complete(ctx context.Context, claimID string) (*PixClaim, error) {
+	claim, err := m.checkTransition(claimID, PixClaimRoleClaimer, CompletedClaim)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.pix.CompletePixClaim(ctx, claim.DocumentNumber, claimID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, claimID, response.PixClaimResponse, CompletedClaim)
+}
+
+// Cancel cancels a claim. Both sides are allowed to cancel a claim.
+func (m *PixClaimManager) Cancel(ctx context.Context, claimID string, reason CancelReason) (*PixClaim, error) {
+	claim, err := m.checkTransition(claimID, "", CanceledClaim)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.pix.CancelPixClaim(ctx, claim.DocumentNumber, claimID,
+		&PixClaimCancelReason{Reason: string(reason)})
+	if err != nil {
+		return nil, err
+	}
+
+	return m.apply(ctx, claimID, response.PixClaimResponse, CanceledClaim)
+}
+
+// Poll fetches the claims of the account and applies the updates.
+func (m *PixClaimManager) Poll(ctx context.Context, account string, documentNumber string) error {
+	claims, err := m.pix.GetPixClaim(ctx, account, documentNumber, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, claim := range claims {
+		if err := m.Consume(ctx, account, documentNumber, claim); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Watch polls the claims of a single account on every interval until the context is done.
+// Each account is watched by its own call, or the claims are received by webhook and
+// applied with Consume.
+func (m *PixClaimManager) Watch(ctx context.Context, account string, documentNumber string, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.Poll(ctx, account, documentNumber); err != nil {
+			logrus.WithField("request_id", GetRequestID(ctx)).
+				WithField("account", account).
+				WithError(err).Error("error polling pix claims")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Consume applies a claim update received from bankly, by polling or webhook.
+// Stale updates are ignored and the donor policy of the account is applied
+// to claims waiting for resolution.
+func (m *PixClaimManager) Consume(ctx context.Context, account string, documentNumber string, update *PixClaimResponse) error {
+	account = grok.OnlyDigits(account)
+
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"account":    account,
+		"claim_id":   update.ClaimId,
+		"status":     update.Status,
+	}
+
+	m.mu.Lock()
+	claim, tracked := m.claims[update.ClaimId]
+	var previous StatusClaim
+	if !tracked {
+		role := PixClaimRoleDonor
+		if grok.OnlyDigits(update.Claimer.Number) == account {
+			role = PixClaimRoleClaimer
+		}
+		claim = newPixClaim(*update, account, documentNumber, role)
+		m.claims[claim.ClaimId] = claim
+	} else {
+		previous = claim.Status
+		if previous == update.Status || !previous.canReach(update.Status) {
+			m.mu.Unlock()
+			logrus.WithFields(fields).WithField("previous_status", previous).
+				Info("pix claim update ignored")
+			return nil
+		}
+		claim.PixClaimResponse = *update
+		claim.Deadlines = pixClaimDeadlines(*update)
+	}
+	policy, hasPolicy := m.policies[claim.Account]
+	c := *claim
+	m.mu.Unlock()
+
+	m.notify(ctx, c, previous)
+
+	if !hasPolicy || c.Role != PixClaimRoleDonor || c.Status != WaitingResolution {
+		return nil
+	}
+
+	var err error
+	switch {
+	case policy.AutoConfirm:
+		reason := policy.ConfirmReason
+		if reason == "" {
+			reason = ConfirmDonorRequest
+		}
+		_, err = m.Confirm(ctx, c.ClaimId, reason)
+	case policy.AutoCancel:
+		reason := policy.CancelReason
+		if reason == "" {
+			reason = DonorRequest
+		}
+		_, err = m.Cancel(ctx, c.ClaimId, reason)
+	}
+
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error applying pix claim donor policy")
+	}
+
+	return err
+}
+
+// checkTransition validates that the claim is tracked, belongs to the role and can move to the status.
+func (m *PixClaimManager) checkTransition(claimID string, role PixClaimRole, next StatusClaim) (*PixClaim, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	claim, ok := m.claims[claimID]
+	if !ok {
+		return nil, ErrPixClaimNotFound
+	}
+
+	if role != "" && claim.Role != role {
+		return nil, ErrPixClaimOperationNotAllowed
+	}
+
+	if !claim.Status.CanTransitionTo(next) {
+		return nil, ErrInvalidPixClaimTransition
+	}
+
+	c := *claim
+	return &c, nil
+}
+
+// apply updates the tracked claim with the bankly response of an operation.
+func (m *PixClaimManager) apply(ctx context.Context, claimID string, response PixClaimResponse, status StatusClaim) (*PixClaim, error) {
+	m.mu.Lock()
+	claim, ok := m.claims[claimID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrPixClaimNotFound
+	}
+
+	previous := claim.Status
+	if response.ClaimId != "" {
+		claim.PixClaimResponse = response
+		claim.Deadlines = pixClaimDeadlines(response)
+	}
+	if response.Status == "" {
+		claim.Status = status
+	}
+	c := *claim
+	m.mu.Unlock()
+
+	m.notify(ctx, c, previous)
+
+	return &c, nil
+}
+
+func (m *PixClaimManager) notify(ctx context.Context, claim PixClaim, previous StatusClaim) {
+	m.mu.Lock()
+	handlers := m.handlers
+	m.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(ctx, PixClaimEvent{Claim: claim, PreviousStatus: previous})
+	}
+}
+
+func newPixClaim(response PixClaimResponse, account string, documentNumber string, role PixClaimRole) *PixClaim {
+	return &PixClaim{
+		PixClaimResponse: response,
+		Account:          grok.OnlyDigits(account),
+		DocumentNumber:   grok.OnlyDigits(documentNumber),
+		Role:             role,
+		Deadlines:        pixClaimDeadlines(response),
+	}
+}
+
+// pixClaimDeadlines uses the limit dates informed by bankly, calculating
+// the missing ones from the claim creation date.
+func pixClaimDeadlines(response PixClaimResponse) PixClaimDeadlines {
+	createdAt, err := time.Parse(time.RFC3339, response.CreatedAt)
+	if err != nil {
+		createdAt = time.Now()
+	}
+
+	deadlines := PixClaimDeadlines{
+		Resolution: createdAt.Add(PixClaimResolutionPeriod),
+		Conclusion: createdAt.Add(PixClaimConclusionPeriod),
+	}
+
+	if resolution, err := time.Parse(time.RFC3339, response.ResolutionLimitDate); err == nil {
+		deadlines.Resolution = resolution
+	}
+
+	if conclusion, err := time.Parse(time.RFC3339, response.ConclusionLimitDate); err == nil {
+		deadlines.Conclusion = conclusion
+	}
+
+	return deadlines
+}
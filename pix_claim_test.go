@@ -0,0 +1,158 @@
+package bankly_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PixClaimTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	ctx        context.Context
+	httpClient *mocks.BanklyHttpClient
+	manager    *bankly.PixClaimManager
+	events     []bankly.PixClaimEvent
+}
+
+func TestPixClaimTestSuite(t *testing.T) {
+	suite.Run(t, new(PixClaimTestSuite))
+}
+
+func (s *PixClaimTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.httpClient = mocks.NewBanklyHttpClient(s.T())
+	s.httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+	s.manager = bankly.NewPixClaimManager(bankly.NewPix(s.httpClient))
+	s.events = nil
+	s.manager.OnChange(func(ctx context.Context, event bankly.PixClaimEvent) {
+		s.events = append(s.events, event)
+	})
+}
+
+func (s *PixClaimTestSuite) TestStatusClaim_CanTransitionTo() {
+	s.assert.True(bankly.Open.CanTransitionTo(bankly.WaitingResolution))
+	s.assert.True(bankly.WaitingResolution.CanTransitionTo(bankly.Confirmed))
+	s.assert.True(bankly.Confirmed.CanTransitionTo(bankly.CompletedClaim))
+	s.assert.True(bankly.Confirmed.CanTransitionTo(bankly.CanceledClaim))
+	s.assert.False(bankly.Open.CanTransitionTo(bankly.CompletedClaim))
+	s.assert.False(bankly.WaitingResolution.CanTransitionTo(bankly.CompletedClaim))
+	s.assert.False(bankly.CompletedClaim.CanTransitionTo(bankly.CanceledClaim))
+	s.assert.False(bankly.CanceledClaim.CanTransitionTo(bankly.Open))
+	s.assert.True(bankly.CanceledClaim.IsFinal())
+}
+
+func (s *PixClaimTestSuite) TestConsume_DonorAutoConfirm() {
+	s.manager.SetDonorPolicy("207802", bankly.PixClaimDonorPolicy{AutoConfirm: true})
+
+	s.httpClient.EXPECT().
+		Patch(mock.Anything, "/pix/claims/claim-1/confirm", &bankly.PixClaimConfirmReason{Reason: "DONOR_REQUEST"}, mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusNoContent, Body: jsonBody(nil)}, nil)
+
+	err := s.manager.Consume(s.ctx, "207802", "16246241620", buildPixClaim("claim-1", bankly.WaitingResolution, "999999"))
+	s.assert.NoError(err)
+
+	claim, err := s.manager.Claim("claim-1")
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.Confirmed, claim.Status)
+	s.assert.Equal(bankly.PixClaimRoleDonor, claim.Role)
+	s.assert.Len(s.events, 2)
+	s.assert.Equal(bankly.WaitingResolution, s.events[1].PreviousStatus)
+}
+
+func (s *PixClaimTestSuite) TestConsume_DonorAutoCancel() {
+	s.manager.SetDonorPolicy("207802", bankly.PixClaimDonorPolicy{AutoCancel: true, CancelReason: bankly.Fraud})
+
+	canceled := buildPixClaim("claim-1", bankly.CanceledClaim, "999999")
+	s.httpClient.EXPECT().
+		Patch(mock.Anything, "/pix/claims/claim-1/cancel", &bankly.PixClaimCancelReason{Reason: "FRAUD"}, mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(canceled)}, nil)
+
+	err := s.manager.Consume(s.ctx, "207802", "16246241620", buildPixClaim("claim-1", bankly.WaitingResolution, "999999"))
+	s.assert.NoError(err)
+
+	claim, _ := s.manager.Claim("claim-1")
+	s.assert.Equal(bankly.CanceledClaim, claim.Status)
+}
+
+func (s *PixClaimTestSuite) TestConsume_IgnoresStaleUpdate() {
+	s.assert.NoError(s.manager.Consume(s.ctx, "207802", "16246241620", buildPixClaim("claim-1", bankly.Confirmed, "207802")))
+	s.assert.NoError(s.manager.Consume(s.ctx, "207802", "16246241620", buildPixClaim("claim-1", bankly.WaitingResolution, "207802")))
+
+	claim, _ := s.manager.Claim("claim-1")
+	s.assert.Equal(bankly.Confirmed, claim.Status)
+	s.assert.Equal(bankly.PixClaimRoleClaimer, claim.Role)
+	s.assert.Len(s.events, 1)
+}
+
+func (s *PixClaimTestSuite) TestInvalidTransitions() {
+	s.assert.NoError(s.manager.Consume(s.ctx, "207802", "16246241620", buildPixClaim("claim-1", bankly.WaitingResolution, "207802")))
+
+	_, err := s.manager.Complete(s.ctx, "claim-1")
+	s.assert.Equal(bankly.ErrInvalidPixClaimTransition, err)
+
+	_, err = s.manager.Confirm(s.ctx, "claim-1", bankly.ConfirmDonorRequest)
+	s.assert.Equal(bankly.ErrPixClaimOperationNotAllowed, err)
+
+	_, err = s.manager.Cancel(s.ctx, "unknown", bankly.ClaimerRequest)
+	s.assert.Equal(bankly.ErrPixClaimNotFound, err)
+}
+
+func (s *PixClaimTestSuite) TestPending() {
+	now := time.Now().UTC()
+
+	donor := buildPixClaim("claim-1", bankly.WaitingResolution, "999999")
+	donor.ResolutionLimitDate = now.Add(24 * time.Hour).Format(time.RFC3339)
+	s.assert.NoError(s.manager.Consume(s.ctx, "207802", "16246241620", donor))
+
+	claimer := buildPixClaim("claim-2", bankly.Confirmed, "207802")
+	claimer.ConclusionLimitDate = now.Add(2 * time.Hour).Format(time.RFC3339)
+	s.assert.NoError(s.manager.Consume(s.ctx, "207802", "16246241620", claimer))
+
+	other := buildPixClaim("claim-3", bankly.WaitingResolution, "207802")
+	s.assert.NoError(s.manager.Consume(s.ctx, "207802", "16246241620", other))
+
+	pending := s.manager.Pending(now, 48*time.Hour)
+	s.assert.Len(pending, 2)
+	s.assert.Equal("claim-2", pending[0].ClaimId)
+	s.assert.Equal("claim-1", pending[1].ClaimId)
+}
+
+func buildPixClaim(claimID string, status bankly.StatusClaim, claimerAccount string) *bankly.PixClaimResponse {
+	return &bankly.PixClaimResponse{
+		ClaimId: claimID,
+		Type:    bankly.Portability,
+		AddressingKey: bankly.PixTypeValue{
+			Type:  bankly.PixPHONE,
+			Value: "+5511987654321",
+		},
+		Claimer: bankly.Claimer{
+			Branch: "0001",
+			Number: claimerAccount,
+		},
+		Status:    status,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func jsonBody(data interface{}) io.ReadCloser {
+	if data == nil {
+		return io.NopCloser(bytes.NewReader(nil))
+	}
+	dataBytes, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	return io.NopCloser(bytes.NewReader(dataBytes))
+}
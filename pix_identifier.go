@@ -0,0 +1,203 @@
+package bankly
+
+import (
+	"crypto/rand"
+	"math/big"
+	"regexp"
+	"time"
+)
+
+const (
+	// BanklyISPB is the ISPB of Bankly (Acesso Soluções de Pagamento)
+	BanklyISPB = "13140088"
+	// EndToEndIDLength ...
+	EndToEndIDLength = 32
+	// StaticTxIDMaxLength is the maximum length of the txid of a static qrcode
+	StaticTxIDMaxLength = 25
+	// DynamicTxIDMinLength is the minimum length of the txid of a dynamic qrcode
+	DynamicTxIDMinLength = 26
+	// DynamicTxIDMaxLength is the maximum length of the txid of a dynamic qrcode
+	DynamicTxIDMaxLength = 35
+	// StaticTxIDEmpty is the txid of a static qrcode without identifier
+	StaticTxIDEmpty = "***"
+
+	endToEndIDLayout   = "200601021504"
+	identifierAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+var (
+	endToEndIDRegex = regexp.MustCompile(`^([ED])([0-9]{8})([0-9]{12})([a-zA-Z0-9]{11})$`)
+	txIDRegex       = regexp.MustCompile(`^[a-zA-Z0-9]+$`)
+)
+
+// EndToEndIDType ...
+type EndToEndIDType string
+
+const (
+	// EndToEndPayment identifies a pix payment
+	EndToEndPayment EndToEndIDType = "E"
+	// EndToEndDevolution identifies a pix devolution
+	EndToEndDevolution EndToEndIDType = "D"
+)
+
+// EndToEndID is a parsed BCB end to end identifier
+type EndToEndID struct {
+	Type      EndToEndIDType
+	ISPB      string
+	CreatedAt time.Time
+	Sequence  string
+}
+
+// String ...
+func (e EndToEndID) String() string {
+	return string(e.Type) + e.ISPB + e.CreatedAt.UTC().Format(endToEndIDLayout) + e.Sequence
+}
+
+// NewEndToEndID generates a payment endToEndId for the participant ISPB at the given time.
+func NewEndToEndID(ispb string, now time.Time) (string, error) {
+	return newEndToEndID(EndToEndPayment, ispb, now)
+}
+
+// NewDevolutionID generates a devolution identifier for the participant ISPB at the given time.
+func NewDevolutionID(ispb string, now time.Time) (string, error) {
+	return newEndToEndID(EndToEndDevolution, ispb, now)
+}
+
+func newEndToEndID(idType EndToEndIDType, ispb string, now time.Time) (string, error) {
+	if len(ispb) != 8 || !IsOnlyDigits(ispb) {
+		return "", ErrInvalidEndToEndID
+	}
+
+	sequence, err := randomIdentifier(11)
+	if err != nil {
+		return "", err
+	}
+
+	return EndToEndID{Type: idType, ISPB: ispb, CreatedAt: now, Sequence: sequence}.String(), nil
+}
+
+// ParseEndToEndID extracts the ISPB and the UTC timestamp of an endToEndId.
+func ParseEndToEndID(value string) (*EndToEndID, error) {
+	match := endToEndIDRegex.FindStringSubmatch(value)
+	if match == nil {
+		return nil, ErrInvalidEndToEndID
+	}
+
+	createdAt, err := time.Parse(endToEndIDLayout, match[3])
+	if err != nil {
+		return nil, ErrInvalidEndToEndID
+	}
+
+	return &EndToEndID{
+		Type:      EndToEndIDType(match[1]),
+		ISPB:      match[2],
+		CreatedAt: createdAt,
+		Sequence:  match[4],
+	}, nil
+}
+
+// ValidateEndToEndID ...
+func ValidateEndToEndID(value string) error {
+	_, err := ParseEndToEndID(value)
+	return err
+}
+
+// NewTxID generates a txid for a dynamic qrcode.
+func NewTxID() (string, error) {
+	return randomIdentifier(DynamicTxIDMaxLength)
+}
+
+// NewStaticTxID generates a txid for a static qrcode.
+func NewStaticTxID() (string, error) {
+	return randomIdentifier(StaticTxIDMaxLength)
+}
+
+// ValidateStaticTxID accepts up to 25 alphanumeric characters or "***".
+func ValidateStaticTxID(value string) error {
+	if value == StaticTxIDEmpty {
+		return nil
+	}
+
+	if len(value) == 0 || len(value) > StaticTxIDMaxLength || !txIDRegex.MatchString(value) {
+		return ErrInvalidTxID
+	}
+
+	return nil
+}
+
+// ValidateDynamicTxID accepts from 26 to 35 alphanumeric characters.
+func ValidateDynamicTxID(value string) error {
+	if len(value) < DynamicTxIDMinLength || len(value) > DynamicTxIDMaxLength || !txIDRegex.MatchString(value) {
+		return ErrInvalidTxID
+	}
+
+	return nil
+}
+
+// IdentifyEndToEnd generates the endToEndId of the cash out for the participant ISPB when empty,
+// otherwise validates it. It is opt-in, CashOut sends the endToEndId as informed.
+func (r *PixCashOutRequest) IdentifyEndToEnd(ispb string, now time.Time) error {
+	if r.EndToEndID != "" {
+		return ValidateEndToEndID(r.EndToEndID)
+	}
+
+	value, err := NewEndToEndID(ispb, now)
+	if err != nil {
+		return err
+	}
+
+	r.EndToEndID = value
+	return nil
+}
+
+// IdentifyConciliation generates the txid of the static qrcode when empty, otherwise validates it.
+// It is opt-in, QrCodeStatic sends the txid as informed.
+func (r *PixQrCodeStaticRequest) IdentifyConciliation() error {
+	if r.ConciliationID != "" {
+		return ValidateStaticTxID(r.ConciliationID)
+	}
+
+	value, err := NewStaticTxID()
+	if err != nil {
+		return err
+	}
+
+	r.ConciliationID = value
+	return nil
+}
+
+// IdentifyConciliation generates the txid of the dynamic qrcode when empty, otherwise validates it.
+// It is opt-in, QrCodeDynamic sends the txid as informed.
+func (r *PixQrCodeDynamicRequest) IdentifyConciliation() error {
+	return ensureDynamicTxID(&r.ConciliationID)
+}
+
+// ensureDynamicTxID generates the txid when empty, otherwise validates it
+func ensureDynamicTxID(txID *string) error {
+	if *txID != "" {
+		return ValidateDynamicTxID(*txID)
+	}
+
+	value, err := NewTxID()
+	if err != nil {
+		return err
+	}
+
+	*txID = value
+	return nil
+}
+
+func randomIdentifier(length int) (string, error) {
+	max := big.NewInt(int64(len(identifierAlphabet)))
+
+	value := make([]byte, length)
+	for i := range value {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		value[i] = identifierAlphabet[n.Int64()]
+	}
+
+	return string(value), nil
+}
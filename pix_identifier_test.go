@@ -0,0 +1,123 @@
+package bankly_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PixIdentifierTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func TestPixIdentifierTestSuite(t *testing.T) {
+	suite.Run(t, new(PixIdentifierTestSuite))
+}
+
+func (s *PixIdentifierTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+}
+
+func (s *PixIdentifierTestSuite) TestNewEndToEndID() {
+	now := time.Date(2026, 10, 19, 13, 45, 30, 0, time.FixedZone("BRT", -3*60*60))
+
+	value, err := bankly.NewEndToEndID(bankly.BanklyISPB, now)
+	s.assert.NoError(err)
+	s.assert.Len(value, bankly.EndToEndIDLength)
+	s.assert.Equal("E13140088202610191645", value[:21])
+
+	parsed, err := bankly.ParseEndToEndID(value)
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.EndToEndPayment, parsed.Type)
+	s.assert.Equal(bankly.BanklyISPB, parsed.ISPB)
+	s.assert.Equal(time.Date(2026, 10, 19, 16, 45, 0, 0, time.UTC), parsed.CreatedAt)
+	s.assert.Equal(value, parsed.String())
+
+	devolution, err := bankly.NewDevolutionID(bankly.BanklyISPB, now)
+	s.assert.NoError(err)
+	s.assert.Equal("D", devolution[:1])
+
+	_, err = bankly.NewEndToEndID("1314", now)
+	s.assert.Equal(bankly.ErrInvalidEndToEndID, err)
+}
+
+func (s *PixIdentifierTestSuite) TestValidateEndToEndID() {
+	s.assert.NoError(bankly.ValidateEndToEndID("E1314008820261019164512345abcdeF"))
+	s.assert.Equal(bankly.ErrInvalidEndToEndID, bankly.ValidateEndToEndID("E1314008820261019164512345abcde"))
+	s.assert.Equal(bankly.ErrInvalidEndToEndID, bankly.ValidateEndToEndID("X1314008820261019164512345abcdeF"))
+	s.assert.Equal(bankly.ErrInvalidEndToEndID, bankly.ValidateEndToEndID("E1314008820261399164512345abcdeF"))
+	s.assert.Equal(bankly.ErrInvalidEndToEndID, bankly.ValidateEndToEndID("E13140088202610191645-2345abcdeF"))
+}
+
+func (s *PixIdentifierTestSuite) TestTxID() {
+	txID, err := bankly.NewTxID()
+	s.assert.NoError(err)
+	s.assert.NoError(bankly.ValidateDynamicTxID(txID))
+
+	staticTxID, err := bankly.NewStaticTxID()
+	s.assert.NoError(err)
+	s.assert.NoError(bankly.ValidateStaticTxID(staticTxID))
+
+	s.assert.NoError(bankly.ValidateStaticTxID("***"))
+	s.assert.NoError(bankly.ValidateStaticTxID("pedido123"))
+	s.assert.Equal(bankly.ErrInvalidTxID, bankly.ValidateStaticTxID(""))
+	s.assert.Equal(bankly.ErrInvalidTxID, bankly.ValidateStaticTxID("pedido-123"))
+	s.assert.Equal(bankly.ErrInvalidTxID, bankly.ValidateStaticTxID("abcdefghijklmnopqrstuvwxyz"))
+
+	s.assert.NoError(bankly.ValidateDynamicTxID("abcdefghijklmnopqrstuvwxyz"))
+	s.assert.Equal(bankly.ErrInvalidTxID, bankly.ValidateDynamicTxID("pedido123"))
+	s.assert.Equal(bankly.ErrInvalidTxID, bankly.ValidateDynamicTxID("abcdefghijklmnopqrstuvwxyz0123456789"))
+}
+
+func (s *PixIdentifierTestSuite) TestQrCodeDynamic_KeepsConciliationID() {
+	httpClient := mocks.NewBanklyHttpClient(s.T())
+	httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+
+	pix := bankly.NewPix(httpClient)
+
+	// the txid is sent as informed, generating and validating it is left to the caller
+	for _, conciliationID := range []string{"", "pedido-123"} {
+		httpClient.EXPECT().Post(mock.Anything, "pix/qrcodes/dynamic/payment", mock.Anything, mock.Anything).
+			Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixQrCodeResponse{})}, nil).Once()
+
+		request := &bankly.PixQrCodeDynamicRequest{ConciliationID: conciliationID}
+
+		_, err := pix.QrCodeDynamic(context.Background(), request, "52998224725")
+		s.assert.NoError(err)
+		s.assert.Equal(conciliationID, request.ConciliationID)
+	}
+}
+
+func (s *PixIdentifierTestSuite) TestIdentify() {
+	now := time.Date(2026, 10, 19, 13, 45, 30, 0, time.UTC)
+
+	cashOut := &bankly.PixCashOutRequest{}
+	s.assert.NoError(cashOut.IdentifyEndToEnd(bankly.BanklyISPB, now))
+	s.assert.NoError(bankly.ValidateEndToEndID(cashOut.EndToEndID))
+	s.assert.Equal("E131400882026101913", cashOut.EndToEndID[:19])
+
+	cashOut.EndToEndID = "E13140088-invalid"
+	s.assert.Equal(bankly.ErrInvalidEndToEndID, cashOut.IdentifyEndToEnd(bankly.BanklyISPB, now))
+
+	static := &bankly.PixQrCodeStaticRequest{}
+	s.assert.NoError(static.IdentifyConciliation())
+	s.assert.NoError(bankly.ValidateStaticTxID(static.ConciliationID))
+
+	static.ConciliationID = "pedido-123"
+	s.assert.Equal(bankly.ErrInvalidTxID, static.IdentifyConciliation())
+
+	dynamic := &bankly.PixQrCodeDynamicRequest{}
+	s.assert.NoError(dynamic.IdentifyConciliation())
+	s.assert.NoError(bankly.ValidateDynamicTxID(dynamic.ConciliationID))
+
+	dynamic.ConciliationID = "pedido123"
+	s.assert.Equal(bankly.ErrInvalidTxID, dynamic.IdentifyConciliation())
+}
@@ -0,0 +1,231 @@
+package bankly
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// pixEmailMaxLength is the maximum length accepted by DICT for email keys
+	pixEmailMaxLength = 77
+)
+
+var (
+	pixEmailRegex   = regexp.MustCompile(`^[a-z0-9.!#$%&'*+/=?^_{|}~-]+@[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?)+$`)
+	pixEVPRegex     = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	pixPhoneRegex   = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+	pixBRPhoneRegex = regexp.MustCompile(`^\+55([1-9]{2})(?:9[0-9]{8}|[2-5][0-9]{7})$`)
+)
+
+// phoneAreaCodes are the brazilian DDDs defined by ANATEL
+var phoneAreaCodes = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"21": true, "22": true, "24": true, "27": true, "28": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "37": true, "38": true,
+	"41": true, "42": true, "43": true, "44": true, "45": true, "46": true, "47": true, "48": true, "49": true,
+	"51": true, "53": true, "54": true, "55": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true, "66": true, "67": true, "68": true, "69": true,
+	"71": true, "73": true, "74": true, "75": true, "77": true, "79": true,
+	"81": true, "82": true, "83": true, "84": true, "85": true, "86": true, "87": true, "88": true, "89": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true, "97": true, "98": true, "99": true,
+}
+
+// ParsePixKey detects the type of a raw key typed by the user and returns it normalized.
+func ParsePixKey(raw string) (*PixTypeValue, error) {
+	keyType, err := DetectPixKeyType(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := NormalizePixKey(keyType, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PixTypeValue{Type: keyType, Value: value}, nil
+}
+
+// PixLookupKey returns the key to look up at DICT. Keys of a type detected without doubt are
+// normalized, the others are kept as informed and left to Bankly. An 11 digits value may
+// be a CPF or a phone without the country code, so it is only normalized when formatted.
+func PixLookupKey(raw string) string {
+	value := strings.TrimSpace(raw)
+
+	if digits := OnlyDigits(value); len(digits) == 11 && !strings.HasPrefix(value, "+") &&
+		!strings.ContainsAny(value, "./") {
+		return value
+	}
+
+	key, err := ParsePixKey(value)
+	if err != nil {
+		return value
+	}
+
+	return key.Value
+}
+
+// DetectPixKeyType returns the key type of a raw key typed by the user.
+// An 11 digits value is a CPF when its check digits match or when it is
+// formatted as a document, otherwise a phone.
+func DetectPixKeyType(raw string) (PixType, error) {
+	value := strings.TrimSpace(raw)
+
+	switch {
+	case value == "":
+		return "", ErrInvalidPixKey
+	case strings.Contains(value, "@"):
+		return PixEMAIL, nil
+	case pixEVPRegex.MatchString(strings.ToLower(value)):
+		return PixEVP, nil
+	case strings.HasPrefix(value, "+"):
+		return PixPHONE, nil
+	}
+
+	if strings.Trim(value, "0123456789 .-/()") != "" {
+		return "", ErrInvalidPixKey
+	}
+
+	digits := OnlyDigits(value)
+
+	switch len(digits) {
+	case 14:
+		return PixCNPJ, nil
+	case 11:
+		if IsValidCPF(digits) || strings.ContainsAny(value, "./") {
+			return PixCPF, nil
+		}
+		return PixPHONE, nil
+	case 10, 12, 13:
+		return PixPHONE, nil
+	}
+
+	return "", ErrInvalidPixKey
+}
+
+// NormalizePixKey returns the value in the format registered at DICT,
+// or ErrInvalidPixKey when the value is not a valid key of the given type.
+func NormalizePixKey(keyType PixType, value string) (string, error) {
+	value = strings.TrimSpace(value)
+
+	switch keyType {
+	case PixCPF:
+		digits := OnlyDigits(value)
+		if !IsValidCPF(digits) {
+			return "", ErrInvalidPixKey
+		}
+		return digits, nil
+	case PixCNPJ:
+		digits := OnlyDigits(value)
+		if !IsValidCNPJ(digits) {
+			return "", ErrInvalidPixKey
+		}
+		return digits, nil
+	case PixEMAIL:
+		email := strings.ToLower(value)
+		if len(email) > pixEmailMaxLength || !pixEmailRegex.MatchString(email) {
+			return "", ErrInvalidPixKey
+		}
+		return email, nil
+	case PixPHONE:
+		return normalizePixPhone(value)
+	case PixEVP:
+		evp := strings.ToLower(value)
+		if !pixEVPRegex.MatchString(evp) {
+			return "", ErrInvalidPixKey
+		}
+		return evp, nil
+	}
+
+	return "", ErrInvalidKeyType
+}
+
+// ValidatePixKey ...
+func ValidatePixKey(keyType PixType, value string) error {
+	_, err := NormalizePixKey(keyType, value)
+	return err
+}
+
+// normalizePixPhone formats a phone number as E.164. Numbers without country
+// code are considered brazilian numbers.
+func normalizePixPhone(value string) (string, error) {
+	digits := OnlyDigits(value)
+	international := strings.HasPrefix(value, "+")
+
+	if !international && (len(digits) == 10 || len(digits) == 11) {
+		digits = "55" + digits
+	}
+
+	phone := "+" + digits
+
+	if strings.HasPrefix(digits, "55") {
+		match := pixBRPhoneRegex.FindStringSubmatch(phone)
+		if match == nil || !phoneAreaCodes[match[1]] {
+			return "", ErrInvalidPixKey
+		}
+		return phone, nil
+	}
+
+	if !international || !pixPhoneRegex.MatchString(phone) {
+		return "", ErrInvalidPixKey
+	}
+
+	return phone, nil
+}
+
+// IsValidCPF checks the length and check digits of a CPF.
+func IsValidCPF(value string) bool {
+	digits, ok := documentDigits(value, 11)
+	if !ok {
+		return false
+	}
+
+	return verify(digits, 9) == digits[9] && verify(digits, 10) == digits[10]
+}
+
+// IsValidCNPJ checks the length and check digits of a CNPJ.
+func IsValidCNPJ(value string) bool {
+	digits, ok := documentDigits(value, 14)
+	if !ok {
+		return false
+	}
+
+	return verifyCNPJ(digits, 12) == digits[12] && verifyCNPJ(digits, 13) == digits[13]
+}
+
+// documentDigits splits a document into digits, rejecting sequences of the same digit.
+func documentDigits(value string, length int) ([]int, bool) {
+	if len(value) != length || !IsOnlyDigits(value) {
+		return nil, false
+	}
+
+	digits := make([]int, length)
+	repeated := true
+	for i, c := range value {
+		digits[i] = int(c - '0')
+		if digits[i] != digits[0] {
+			repeated = false
+		}
+	}
+
+	return digits, !repeated
+}
+
+// verifyCNPJ calculates the CNPJ check digit of the first n digits.
+func verifyCNPJ(data []int, n int) int {
+	var total int
+
+	weight := n - 7
+	for i := 0; i < n; i++ {
+		total += data[i] * weight
+		weight--
+		if weight < 2 {
+			weight = 9
+		}
+	}
+
+	total = total % 11
+	if total < 2 {
+		return 0
+	}
+	return 11 - total
+}
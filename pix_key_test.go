@@ -0,0 +1,133 @@
+package bankly_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PixKeyTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func TestPixKeyTestSuite(t *testing.T) {
+	suite.Run(t, new(PixKeyTestSuite))
+}
+
+func (s *PixKeyTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+}
+
+func (s *PixKeyTestSuite) TestParsePixKey_OK() {
+	cases := []struct {
+		raw      string
+		keyType  bankly.PixType
+		expected string
+	}{
+		{"529.982.247-25", bankly.PixCPF, "52998224725"},
+		{"11.222.333/0001-81", bankly.PixCNPJ, "11222333000181"},
+		{"  Fulano.Tal@Example.COM ", bankly.PixEMAIL, "fulano.tal@example.com"},
+		{"(11) 98765-4321", bankly.PixPHONE, "+5511987654321"},
+		{"+55 21 3456-7890", bankly.PixPHONE, "+552134567890"},
+		{"5511987654321", bankly.PixPHONE, "+5511987654321"},
+		{"+1 415 555 2671", bankly.PixPHONE, "+14155552671"},
+		{"123E4567-E89B-42D3-A456-426614174000", bankly.PixEVP, "123e4567-e89b-42d3-a456-426614174000"},
+	}
+
+	for _, c := range cases {
+		key, err := bankly.ParsePixKey(c.raw)
+		s.assert.NoError(err, c.raw)
+		s.assert.Equal(c.keyType, key.Type, c.raw)
+		s.assert.Equal(c.expected, key.Value, c.raw)
+	}
+}
+
+func (s *PixKeyTestSuite) TestParsePixKey_NOK() {
+	cases := []string{
+		"",
+		"529.982.247-26",
+		"11.222.333/0001-80",
+		"111.111.111-11",
+		"fulano@",
+		"fulano@example",
+		"(11) 8765-432",
+		"+55 11 12345-6789",
+		"123e4567-e89b-42d3-a456-42661417400",
+		"abc123",
+	}
+
+	for _, raw := range cases {
+		key, err := bankly.ParsePixKey(raw)
+		s.assert.Equal(bankly.ErrInvalidPixKey, err, raw)
+		s.assert.Nil(key, raw)
+	}
+}
+
+func (s *PixKeyTestSuite) TestNormalizePixKey_InvalidType() {
+	value, err := bankly.NormalizePixKey("RANDOM", "52998224725")
+	s.assert.Equal(bankly.ErrInvalidKeyType, err)
+	s.assert.Empty(value)
+}
+
+func (s *PixKeyTestSuite) TestValidatePixKey_TypeMismatch() {
+	s.assert.Error(bankly.ValidatePixKey(bankly.PixCNPJ, "52998224725"))
+	s.assert.Error(bankly.ValidatePixKey(bankly.PixEMAIL, "+5511987654321"))
+	s.assert.NoError(bankly.ValidatePixKey(bankly.PixPHONE, "+5511987654321"))
+}
+
+func (s *PixKeyTestSuite) TestDetectPixKeyType_GeneratedDocuments() {
+	keyType, err := bankly.DetectPixKeyType(bankly.GeneratorCPF())
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PixCPF, keyType)
+
+	keyType, err = bankly.DetectPixKeyType(bankly.GeneratorCNPJ())
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PixCNPJ, keyType)
+
+	s.assert.True(bankly.IsValidCNPJ(bankly.OnlyDigits(bankly.GeneratorCNPJ())))
+}
+
+func (s *PixKeyTestSuite) TestPixLookupKey() {
+	cases := []struct {
+		raw      string
+		expected string
+	}{
+		{" New@Example.com ", "new@example.com"},
+		{"529.982.247-25", "52998224725"},
+		{"+55 11 98765-4321", "+5511987654321"},
+		// an 11 digits value may be a cpf or a phone, only the caller knows
+		{"11987654321", "11987654321"},
+		{"52998224725", "52998224725"},
+		// keys that can't be classified offline are left to Bankly
+		{"not a key", "not a key"},
+	}
+
+	for _, c := range cases {
+		s.assert.Equal(c.expected, bankly.PixLookupKey(c.raw), c.raw)
+	}
+}
+
+func (s *PixKeyTestSuite) TestCreateAddressKey_KeepsRequest() {
+	httpClient := mocks.NewBanklyHttpClient(s.T())
+	httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+	httpClient.EXPECT().Post(mock.Anything, "/pix/entries", mock.MatchedBy(func(body interface{}) bool {
+		request, ok := body.(*bankly.PixAddressKeyCreateRequest)
+		return ok && request.AddressingKey.Value == "+5511987654321"
+	}), mock.Anything).Return(&http.Response{StatusCode: http.StatusCreated, Body: jsonBody(bankly.PixAddressKeyCreateResponse{})}, nil).Once()
+
+	request := &bankly.PixAddressKeyCreateRequest{
+		AddressingKey: bankly.PixTypeValue{Type: bankly.PixPHONE, Value: "(11) 98765-4321"},
+		Account:       bankly.Account{Branch: "0001", Number: "189162"},
+	}
+
+	_, err := bankly.NewPix(httpClient).CreateAddressKey(context.Background(), request)
+	s.assert.NoError(err)
+	s.assert.Equal("(11) 98765-4321", request.AddressingKey.Value)
+}
@@ -0,0 +1,325 @@
+package bankly
+
+import (
+	"context"
+
+	"github.com/contbank/grok"
+	"github.com/sirupsen/logrus"
+)
+
+// PixKeySyncOperation ...
+type PixKeySyncOperation string
+
+const (
+	// PixKeySyncKeep key already registered at the account
+	PixKeySyncKeep PixKeySyncOperation = "KEEP"
+	// PixKeySyncCreate key must be registered at the account
+	PixKeySyncCreate PixKeySyncOperation = "CREATE"
+	// PixKeySyncDelete key registered at the account but not desired
+	PixKeySyncDelete PixKeySyncOperation = "DELETE"
+	// PixKeySyncClaim key registered at another account, it requires a claim
+	PixKeySyncClaim PixKeySyncOperation = "CLAIM"
+)
+
+// PixKeySyncStatus ...
+type PixKeySyncStatus string
+
+const (
+	// PixKeySyncPlanned action not executed (dry run or nothing to do)
+	PixKeySyncPlanned PixKeySyncStatus = "PLANNED"
+	// PixKeySyncDone action executed
+	PixKeySyncDone PixKeySyncStatus = "DONE"
+	// PixKeySyncPendingConfirmation code sent to the key owner, waiting the confirmation code
+	PixKeySyncPendingConfirmation PixKeySyncStatus = "PENDING_CONFIRMATION"
+	// PixKeySyncFailed action returned an error
+	PixKeySyncFailed PixKeySyncStatus = "FAILED"
+)
+
+// PixKeysSyncAccount account whose keys are synchronized
+type PixKeysSyncAccount struct {
+	Branch         string
+	Number         string
+	DocumentNumber string
+	AccountType    TransfersAccountType
+}
+
+// PixKeysSyncOptions ...
+type PixKeysSyncOptions struct {
+	// DryRun only plans the actions, from the keys registered at the account and the read-only
+	// lookups at DICT, so keys held by another account are planned as CLAIM. Nothing is written.
+	DryRun bool
+	// DeleteUnlisted deletes the registered keys missing in the desired keys, which are kept by default
+	DeleteUnlisted bool
+	// ConfirmationCode returns the code received by the owner of a PHONE or EMAIL key.
+	// When nil, the code is sent and the action stays pending confirmation.
+	ConfirmationCode func(ctx context.Context, key PixTypeValue, hash TransactionalHash) (string, error)
+}
+
+// PixKeySyncAction ...
+type PixKeySyncAction struct {
+	Operation    PixKeySyncOperation
+	Key          PixTypeValue
+	RequiresTOTP bool
+	ClaimType    PixClaimType
+	Holder       *PixHolder
+	Status       PixKeySyncStatus
+	Hash         *TransactionalHash
+	Error        error
+}
+
+// PixKeysSyncResult ...
+type PixKeysSyncResult struct {
+	DryRun  bool
+	Actions []*PixKeySyncAction
+}
+
+// Pending returns the actions that could not be completed.
+func (r *PixKeysSyncResult) Pending() []*PixKeySyncAction {
+	response := []*PixKeySyncAction{}
+	for _, action := range r.Actions {
+		if action.Operation != PixKeySyncKeep && action.Status != PixKeySyncDone {
+			response = append(response, action)
+		}
+	}
+	return response
+}
+
+// SyncKeys makes the keys registered at the account match the desired keys.
+// An EVP key without value means the account must have one EVP key.
+// Keys registered at another account are reported as claims and are never claimed automatically.
+// Registered keys missing in the desired keys are only deleted with DeleteUnlisted.
+func (p *Pix) SyncKeys(ctx context.Context, account PixKeysSyncAccount, desired []PixTypeValue,
+	options PixKeysSyncOptions) (*PixKeysSyncResult, error) {
+
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"account":    account.Number,
+		"dry_run":    options.DryRun,
+	}
+
+	actions, err := p.planKeys(ctx, account, desired, options)
+	if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error planning pix keys sync")
+		return nil, err
+	}
+
+	result := &PixKeysSyncResult{DryRun: options.DryRun, Actions: actions}
+	if options.DryRun {
+		return result, nil
+	}
+
+	// deletes run first to release the limit of keys per account
+	for _, action := range actions {
+		if action.Operation == PixKeySyncDelete {
+			p.executeKeyAction(ctx, account, action, options)
+		}
+	}
+
+	for _, action := range actions {
+		if action.Operation == PixKeySyncCreate {
+			p.executeKeyAction(ctx, account, action, options)
+		}
+	}
+
+	logrus.WithFields(fields).
+		WithField("pending", len(result.Pending())).
+		Info("pix keys sync finished")
+
+	return result, nil
+}
+
+func (p *Pix) planKeys(ctx context.Context, account PixKeysSyncAccount, desired []PixTypeValue,
+	options PixKeysSyncOptions) ([]*PixKeySyncAction, error) {
+
+	registered, err := p.GetAddressKeysByAccount(ctx, account.Number, account.DocumentNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := []PixTypeValue{}
+	randomKeys := 0
+	for _, key := range desired {
+		if key.Type == PixEVP && key.Value == "" {
+			randomKeys++
+			continue
+		}
+		value, err := NormalizePixKey(key.Type, key.Value)
+		if err != nil {
+			return nil, err
+		}
+		wanted = append(wanted, PixTypeValue{Type: key.Type, Value: value})
+	}
+
+	actions := []*PixKeySyncAction{}
+	matched := map[int]bool{}
+
+	for _, key := range wanted {
+		index := findPixKey(registered, key, matched)
+		if index >= 0 {
+			matched[index] = true
+			actions = append(actions, &PixKeySyncAction{Operation: PixKeySyncKeep, Key: key, Status: PixKeySyncPlanned})
+			continue
+		}
+
+		action, err := p.planMissingKey(ctx, account, key)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	// unnamed evp keys are satisfied by any registered evp key not listed
+	for i, key := range registered {
+		if randomKeys == 0 {
+			break
+		}
+		if key.Type == PixEVP && !matched[i] {
+			matched[i] = true
+			randomKeys--
+			actions = append(actions, &PixKeySyncAction{Operation: PixKeySyncKeep, Key: *key, Status: PixKeySyncPlanned})
+		}
+	}
+
+	for ; randomKeys > 0; randomKeys-- {
+		actions = append(actions, &PixKeySyncAction{
+			Operation: PixKeySyncCreate,
+			Key:       PixTypeValue{Type: PixEVP},
+			Status:    PixKeySyncPlanned,
+		})
+	}
+
+	if options.DeleteUnlisted {
+		for i, key := range registered {
+			if !matched[i] {
+				actions = append(actions, &PixKeySyncAction{Operation: PixKeySyncDelete, Key: *key, Status: PixKeySyncPlanned})
+			}
+		}
+	}
+
+	return actions, nil
+}
+
+// planMissingKey checks at DICT whether a key missing at the account is free or held by another
+// account, also in a dry run as the lookup doesn't change anything.
+func (p *Pix) planMissingKey(ctx context.Context, account PixKeysSyncAccount, key PixTypeValue) (*PixKeySyncAction, error) {
+
+	action := &PixKeySyncAction{
+		Operation:    PixKeySyncCreate,
+		Key:          key,
+		RequiresTOTP: key.Type == PixPHONE || key.Type == PixEMAIL,
+		Status:       PixKeySyncPlanned,
+	}
+
+	entry, err := p.GetAddressKey(ctx, key.Value, grok.OnlyDigits(account.DocumentNumber))
+	if err == ErrKeyNotFound || err == ErrEntryNotFound {
+		return action, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	action.Operation = PixKeySyncClaim
+	action.Holder = &entry.Holder
+	action.ClaimType = Ownership
+	if grok.OnlyDigits(entry.Holder.Document.Value) == grok.OnlyDigits(account.DocumentNumber) {
+		action.ClaimType = Portability
+	}
+
+	return action, nil
+}
+
+func (p *Pix) executeKeyAction(ctx context.Context, account PixKeysSyncAccount, action *PixKeySyncAction, options PixKeysSyncOptions) {
+	var err error
+
+	switch action.Operation {
+	case PixKeySyncDelete:
+		err = p.DeleteAddressKey(ctx, grok.OnlyDigits(account.DocumentNumber), action.Key.Value)
+		if err == nil {
+			action.Status = PixKeySyncDone
+		}
+	case PixKeySyncCreate:
+		err = p.createSyncKey(ctx, account, action, options)
+	}
+
+	if err != nil {
+		logrus.WithField("request_id", GetRequestID(ctx)).
+			WithField("key", action.Key).
+			WithError(err).Error("error executing pix keys sync action")
+		action.Status = PixKeySyncFailed
+		action.Error = err
+	}
+}
+
+func (p *Pix) createSyncKey(ctx context.Context, account PixKeysSyncAccount, action *PixKeySyncAction, options PixKeysSyncOptions) error {
+	request := &PixAddressKeyCreateRequest{
+		AddressingKey: action.Key,
+		Account: Account{
+			Branch:      account.Branch,
+			Number:      account.Number,
+			AccountType: account.AccountType,
+		},
+	}
+
+	if !action.RequiresTOTP {
+		if _, err := p.CreateAddressKey(ctx, request); err != nil {
+			return err
+		}
+		action.Status = PixKeySyncDone
+		return nil
+	}
+
+	identifier := grok.OnlyDigits(account.DocumentNumber)
+	totp := NewTransactionalHashTOTP(p.httpClient)
+
+	hash, err := totp.TransactionalHash(ctx, TransactionalHashRequest{
+		Context:   "Pix",
+		Operation: "RegisterEntry",
+		Data: TransactionalHashData{
+			AddressingKey: TransactionalHashAddressingKey{
+				Type:  action.Key.Type,
+				Value: action.Key.Value,
+			},
+		},
+	}, identifier)
+	if err != nil {
+		return err
+	}
+
+	action.Hash = hash
+	if options.ConfirmationCode == nil {
+		action.Status = PixKeySyncPendingConfirmation
+		return nil
+	}
+
+	code, err := options.ConfirmationCode(ctx, action.Key, *hash)
+	if err != nil {
+		return err
+	}
+
+	validated, err := totp.TransactionalHashValidate(ctx, TransactionalHash{Hash: hash.Hash, Code: code}, identifier)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.CreateAddressKeyWithTOTP(ctx, request, validated.Hash); err != nil {
+		return err
+	}
+
+	action.Status = PixKeySyncDone
+	return nil
+}
+
+func findPixKey(keys []*PixTypeValue, key PixTypeValue, ignore map[int]bool) int {
+	for i, registered := range keys {
+		if ignore[i] || registered.Type != key.Type {
+			continue
+		}
+		value, err := NormalizePixKey(registered.Type, registered.Value)
+		if err != nil {
+			value = registered.Value
+		}
+		if value == key.Value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,155 @@
+package bankly_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PixKeysSyncTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	ctx        context.Context
+	httpClient *mocks.BanklyHttpClient
+	pix        *bankly.Pix
+	account    bankly.PixKeysSyncAccount
+	desired    []bankly.PixTypeValue
+}
+
+func TestPixKeysSyncTestSuite(t *testing.T) {
+	suite.Run(t, new(PixKeysSyncTestSuite))
+}
+
+func (s *PixKeysSyncTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.httpClient = mocks.NewBanklyHttpClient(s.T())
+	s.httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+	s.pix = bankly.NewPix(s.httpClient)
+
+	s.account = bankly.PixKeysSyncAccount{
+		Branch:         "0001",
+		Number:         "207802",
+		DocumentNumber: "529.982.247-25",
+		AccountType:    bankly.CheckingAccount,
+	}
+
+	s.desired = []bankly.PixTypeValue{
+		{Type: bankly.PixCPF, Value: "529.982.247-25"},
+		{Type: bankly.PixEVP},
+		{Type: bankly.PixPHONE, Value: "(11) 98765-4321"},
+		{Type: bankly.PixEMAIL, Value: "New@Example.com"},
+	}
+
+	s.httpClient.EXPECT().Get(mock.Anything, "accounts/207802/addressing-keys", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody([]bankly.PixTypeValue{
+			{Type: bankly.PixCPF, Value: "52998224725"},
+			{Type: bankly.PixEVP, Value: "123e4567-e89b-42d3-a456-426614174000"},
+			{Type: bankly.PixEMAIL, Value: "old@example.com"},
+		})}, nil)
+
+}
+
+func (s *PixKeysSyncTestSuite) expectLookups() {
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/+5511987654321", mock.Anything, mock.Anything).
+		Return(nil, bankly.ErrEntryNotFound)
+
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/new@example.com", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixAddressKeyResponse{
+			AddressingKey: bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "new@example.com"},
+			Holder: bankly.PixHolder{
+				Name:     "Outro Titular",
+				Document: bankly.PixTypeValue{Type: bankly.PixCPF, Value: "16246241620"},
+			},
+		})}, nil)
+}
+
+func (s *PixKeysSyncTestSuite) TestSyncKeys_DryRun() {
+	s.expectLookups()
+
+	result, err := s.pix.SyncKeys(s.ctx, s.account, s.desired, bankly.PixKeysSyncOptions{DryRun: true, DeleteUnlisted: true})
+	s.assert.NoError(err)
+	s.assert.True(result.DryRun)
+
+	operations := map[bankly.PixKeySyncOperation][]bankly.PixTypeValue{}
+	for _, action := range result.Actions {
+		s.assert.Equal(bankly.PixKeySyncPlanned, action.Status)
+		operations[action.Operation] = append(operations[action.Operation], action.Key)
+	}
+
+	// the dry run looks up the missing keys at DICT, the key held elsewhere is claimed
+	s.assert.Len(operations[bankly.PixKeySyncKeep], 2)
+	s.assert.Equal([]bankly.PixTypeValue{{Type: bankly.PixPHONE, Value: "+5511987654321"}}, operations[bankly.PixKeySyncCreate])
+	s.assert.Equal([]bankly.PixTypeValue{{Type: bankly.PixEMAIL, Value: "new@example.com"}}, operations[bankly.PixKeySyncClaim])
+	s.assert.Equal([]bankly.PixTypeValue{{Type: bankly.PixEMAIL, Value: "old@example.com"}}, operations[bankly.PixKeySyncDelete])
+}
+
+func (s *PixKeysSyncTestSuite) TestSyncKeys_KeepsUnlistedByDefault() {
+	s.expectLookups()
+
+	result, err := s.pix.SyncKeys(s.ctx, s.account, s.desired, bankly.PixKeysSyncOptions{DryRun: true})
+	s.assert.NoError(err)
+
+	for _, action := range result.Actions {
+		s.assert.NotEqual(bankly.PixKeySyncDelete, action.Operation)
+	}
+}
+
+func (s *PixKeysSyncTestSuite) TestSyncKeys_SendsConfirmationCode() {
+	s.expectLookups()
+	s.httpClient.EXPECT().Delete(mock.Anything, "/pix/entries/old@example.com", "old@example.com", mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusNoContent, Body: jsonBody(nil)}, nil)
+
+	s.httpClient.EXPECT().Post(mock.Anything, "/totp", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.TransactionalHash{Hash: "hash"})}, nil)
+
+	result, err := s.pix.SyncKeys(s.ctx, s.account, s.desired, bankly.PixKeysSyncOptions{DeleteUnlisted: true})
+	s.assert.NoError(err)
+
+	pending := result.Pending()
+	s.assert.Len(pending, 2)
+	for _, action := range pending {
+		switch action.Operation {
+		case bankly.PixKeySyncCreate:
+			s.assert.Equal(bankly.PixKeySyncPendingConfirmation, action.Status)
+			s.assert.Equal("hash", action.Hash.Hash)
+		case bankly.PixKeySyncClaim:
+			s.assert.Equal(bankly.PixKeySyncPlanned, action.Status)
+			s.assert.Equal(bankly.Ownership, action.ClaimType)
+			s.assert.Equal("Outro Titular", action.Holder.Name)
+		}
+	}
+}
+
+func (s *PixKeysSyncTestSuite) TestSyncKeys_ConfirmsCode() {
+	s.expectLookups()
+	s.httpClient.EXPECT().Delete(mock.Anything, "/pix/entries/old@example.com", "old@example.com", mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusNoContent, Body: jsonBody(nil)}, nil)
+
+	s.httpClient.EXPECT().Post(mock.Anything, "/totp", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.TransactionalHash{Hash: "hash"})}, nil)
+
+	s.httpClient.EXPECT().Patch(mock.Anything, "/totp", bankly.TransactionalHash{Hash: "hash", Code: "123456"}, mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.TransactionalHashValidateResponse{Hash: "validated"})}, nil)
+
+	s.httpClient.EXPECT().Post(mock.Anything, "/pix/entries", mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, url string, body interface{}, header *http.Header) {
+			s.assert.Equal("validated", header.Get("x-bkly-totp"))
+		}).
+		Return(&http.Response{StatusCode: http.StatusCreated, Body: jsonBody(bankly.PixAddressKeyCreateResponse{})}, nil)
+
+	result, err := s.pix.SyncKeys(s.ctx, s.account, s.desired, bankly.PixKeysSyncOptions{
+		DeleteUnlisted: true,
+		ConfirmationCode: func(ctx context.Context, key bankly.PixTypeValue, hash bankly.TransactionalHash) (string, error) {
+			return "123456", nil
+		},
+	})
+	s.assert.NoError(err)
+	s.assert.Len(result.Pending(), 1)
+}
@@ -0,0 +1,261 @@
+package bankly
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultPixLookupTTL ...
+	DefaultPixLookupTTL = 5 * time.Minute
+	// DefaultPixLookupCapacity is the bucket size of each end user
+	DefaultPixLookupCapacity = 100
+	// DefaultPixLookupRefillInterval is the time to give back one lookup to the end user
+	DefaultPixLookupRefillInterval = 30 * time.Second
+	// DefaultPixLookupConversionWindow is how long a lookup can still turn into a cash out
+	DefaultPixLookupConversionWindow = time.Hour
+	// DefaultPixLookupStatsTTL is how long the stats of an end user without lookups are kept
+	DefaultPixLookupStatsTTL = 24 * time.Hour
+)
+
+// PixLookupConfig ...
+type PixLookupConfig struct {
+	// TTL of the cached entries, zero uses DefaultPixLookupTTL
+	TTL time.Duration
+	// Capacity of lookups at DICT per end user, zero uses DefaultPixLookupCapacity
+	Capacity int
+	// RefillInterval to get one lookup back, zero uses DefaultPixLookupRefillInterval
+	RefillInterval time.Duration
+	// ConversionWindow zero uses DefaultPixLookupConversionWindow
+	ConversionWindow time.Duration
+	// StatsTTL of the stats of an end user since the last update, zero uses DefaultPixLookupStatsTTL
+	StatsTTL time.Duration
+}
+
+// PixLookupStats lookups at DICT of an end user
+type PixLookupStats struct {
+	Lookups   int
+	NotFound  int
+	CacheHits int
+	Rejected  int
+	CashOuts  int
+	Available int
+}
+
+// ConversionRate returns the rate of lookups at DICT that turned into a cash out.
+func (s PixLookupStats) ConversionRate() float64 {
+	if s.Lookups == 0 {
+		return 0
+	}
+	return float64(s.CashOuts) / float64(s.Lookups)
+}
+
+// PixLookup caches DICT lookups and limits the lookups of each end user
+// (x-bkly-pix-user-id) with a token bucket. The bucket of an end user expires once
+// it is full again and the stats expire after StatsTTL without updates.
+type PixLookup struct {
+	pix       *Pix
+	config    PixLookupConfig
+	entries   *cache.Cache
+	endToEnds *cache.Cache
+	mutex     sync.Mutex
+	buckets   *cache.Cache
+	stats     *cache.Cache
+}
+
+type pixLookupBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type pixLookupRecord struct {
+	identity string
+	cacheKey string
+}
+
+// NewPixLookup ...
+func NewPixLookup(pix *Pix, config PixLookupConfig) *PixLookup {
+	if config.TTL <= 0 {
+		config.TTL = DefaultPixLookupTTL
+	}
+
+	if config.Capacity <= 0 {
+		config.Capacity = DefaultPixLookupCapacity
+	}
+
+	if config.RefillInterval <= 0 {
+		config.RefillInterval = DefaultPixLookupRefillInterval
+	}
+
+	if config.ConversionWindow <= 0 {
+		config.ConversionWindow = DefaultPixLookupConversionWindow
+	}
+
+	if config.StatsTTL <= 0 {
+		config.StatsTTL = DefaultPixLookupStatsTTL
+	}
+
+	// an empty bucket is full again after capacity refills, an expired bucket starts full
+	refill := time.Duration(config.Capacity) * config.RefillInterval
+
+	return &PixLookup{
+		pix:       pix,
+		config:    config,
+		entries:   cache.New(config.TTL, time.Minute),
+		endToEnds: cache.New(config.ConversionWindow, time.Minute),
+		buckets:   cache.New(refill, time.Minute),
+		stats:     cache.New(config.StatsTTL, time.Minute),
+	}
+}
+
+// GetAddressKey returns the cached entry of the key for the end user or looks it up at DICT.
+// It returns ErrPixLookupLimitExceeded when the end user has no lookups available.
+func (l *PixLookup) GetAddressKey(ctx context.Context, key string, currentIdentity string) (*PixAddressKeyResponse, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"key":        key,
+		"identity":   currentIdentity,
+	}
+
+	lookupKey := PixLookupKey(key)
+	if lookupKey == "" {
+		return nil, ErrInvalidPixKey
+	}
+
+	cacheKey := currentIdentity + ":" + lookupKey
+
+	if entry, found := l.entries.Get(cacheKey); found {
+		l.update(currentIdentity, func(stats *PixLookupStats) { stats.CacheHits++ })
+		return entry.(*PixAddressKeyResponse), nil
+	}
+
+	if !l.take(currentIdentity) {
+		l.update(currentIdentity, func(stats *PixLookupStats) { stats.Rejected++ })
+		logrus.WithFields(fields).
+			WithError(ErrPixLookupLimitExceeded).Error("pix lookup limit exceeded")
+		return nil, ErrPixLookupLimitExceeded
+	}
+
+	response, err := l.pix.GetAddressKey(ctx, lookupKey, currentIdentity)
+	if err != nil {
+		if err == ErrKeyNotFound || err == ErrEntryNotFound {
+			l.update(currentIdentity, func(stats *PixLookupStats) {
+				stats.Lookups++
+				stats.NotFound++
+			})
+		}
+		return nil, err
+	}
+
+	l.update(currentIdentity, func(stats *PixLookupStats) { stats.Lookups++ })
+	l.entries.SetDefault(cacheKey, response)
+
+	if response.EndToEndID != "" {
+		l.endToEnds.SetDefault(response.EndToEndID, pixLookupRecord{identity: currentIdentity, cacheKey: cacheKey})
+	}
+
+	return response, nil
+}
+
+// CashOut sends the pix and records the lookup that originated it. The cached entry
+// is removed because its endToEndId can not be used again.
+func (l *PixLookup) CashOut(ctx context.Context, pix *PixCashOutRequest) (*PixCashOutResponse, error) {
+	response, err := l.pix.CashOut(ctx, pix)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, found := l.endToEnds.Get(pix.EndToEndID); found {
+		record := value.(pixLookupRecord)
+		l.endToEnds.Delete(pix.EndToEndID)
+		l.entries.Delete(record.cacheKey)
+		l.update(record.identity, func(stats *PixLookupStats) { stats.CashOuts++ })
+	}
+
+	return response, nil
+}
+
+// Stats returns the lookups of the end user. It does not keep anything for unknown end users.
+func (l *PixLookup) Stats(currentIdentity string) PixLookupStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	stats := PixLookupStats{}
+	if current, ok := l.stats.Get(currentIdentity); ok {
+		stats = *current.(*PixLookupStats)
+	}
+
+	tokens := float64(l.config.Capacity)
+	if bucket, ok := l.buckets.Get(currentIdentity); ok {
+		tokens = l.available(bucket.(*pixLookupBucket), time.Now())
+	}
+
+	stats.Available = int(math.Floor(tokens))
+
+	return stats
+}
+
+// Reset clears the cache, budget and stats of the end user.
+func (l *PixLookup) Reset(currentIdentity string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.buckets.Delete(currentIdentity)
+	l.stats.Delete(currentIdentity)
+
+	for key := range l.entries.Items() {
+		if strings.HasPrefix(key, currentIdentity+":") {
+			l.entries.Delete(key)
+		}
+	}
+}
+
+func (l *PixLookup) take(currentIdentity string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := time.Now()
+
+	bucket := &pixLookupBucket{tokens: float64(l.config.Capacity), updated: now}
+	if current, ok := l.buckets.Get(currentIdentity); ok {
+		bucket = current.(*pixLookupBucket)
+		bucket.tokens = l.available(bucket, now)
+		bucket.updated = now
+	}
+
+	if bucket.tokens < 1 {
+		return false
+	}
+
+	bucket.tokens--
+
+	// the expiration restarts on every lookup, the bucket is dropped only when full again
+	l.buckets.SetDefault(currentIdentity, bucket)
+
+	return true
+}
+
+// available returns the tokens of the bucket refilled until now.
+func (l *PixLookup) available(bucket *pixLookupBucket, now time.Time) float64 {
+	elapsed := now.Sub(bucket.updated)
+	return math.Min(float64(l.config.Capacity), bucket.tokens+float64(elapsed)/float64(l.config.RefillInterval))
+}
+
+func (l *PixLookup) update(currentIdentity string, fn func(stats *PixLookupStats)) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	stats := &PixLookupStats{}
+	if current, ok := l.stats.Get(currentIdentity); ok {
+		stats = current.(*PixLookupStats)
+	}
+
+	fn(stats)
+	l.stats.SetDefault(currentIdentity, stats)
+}
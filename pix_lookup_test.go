@@ -0,0 +1,127 @@
+package bankly_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PixLookupTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	ctx        context.Context
+	httpClient *mocks.BanklyHttpClient
+	lookup     *bankly.PixLookup
+}
+
+func TestPixLookupTestSuite(t *testing.T) {
+	suite.Run(t, new(PixLookupTestSuite))
+}
+
+func (s *PixLookupTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.httpClient = mocks.NewBanklyHttpClient(s.T())
+	s.httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+	s.lookup = bankly.NewPixLookup(bankly.NewPix(s.httpClient), bankly.PixLookupConfig{
+		Capacity:       2,
+		RefillInterval: time.Hour,
+	})
+}
+
+func (s *PixLookupTestSuite) TestGetAddressKey_Cache() {
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/new@example.com", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixAddressKeyResponse{
+			EndToEndID:    "E1314008820261019164512345abcdeF",
+			AddressingKey: bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "new@example.com"},
+		})}, nil).Once()
+
+	first, err := s.lookup.GetAddressKey(s.ctx, "New@Example.com", "16246241620")
+	s.assert.NoError(err)
+
+	second, err := s.lookup.GetAddressKey(s.ctx, "new@example.com", "16246241620")
+	s.assert.NoError(err)
+	s.assert.Equal(first, second)
+
+	stats := s.lookup.Stats("16246241620")
+	s.assert.Equal(1, stats.Lookups)
+	s.assert.Equal(1, stats.CacheHits)
+	s.assert.Equal(1, stats.Available)
+}
+
+func (s *PixLookupTestSuite) TestGetAddressKey_LimitExceeded() {
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/+5511987654321", mock.Anything, mock.Anything).
+		Return(nil, bankly.ErrKeyNotFound).Twice()
+
+	_, err := s.lookup.GetAddressKey(s.ctx, "+5511987654321", "16246241620")
+	s.assert.Equal(bankly.ErrKeyNotFound, err)
+	_, err = s.lookup.GetAddressKey(s.ctx, "+5511987654321", "16246241620")
+	s.assert.Equal(bankly.ErrKeyNotFound, err)
+
+	_, err = s.lookup.GetAddressKey(s.ctx, "+5511987654321", "16246241620")
+	s.assert.Equal(bankly.ErrPixLookupLimitExceeded, err)
+
+	stats := s.lookup.Stats("16246241620")
+	s.assert.Equal(2, stats.Lookups)
+	s.assert.Equal(2, stats.NotFound)
+	s.assert.Equal(1, stats.Rejected)
+	s.assert.Equal(0, stats.Available)
+
+	s.assert.Equal(2, s.lookup.Stats("52998224725").Available)
+}
+
+func (s *PixLookupTestSuite) TestCashOut_RecordsConversion() {
+	endToEndID := "E1314008820261019164512345abcdeF"
+
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/new@example.com", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixAddressKeyResponse{
+			EndToEndID:    endToEndID,
+			AddressingKey: bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "new@example.com"},
+		})}, nil).Once()
+
+	s.httpClient.EXPECT().Post(mock.Anything, "pix/cash-out", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusAccepted, Body: jsonBody(bankly.PixCashOutResponse{
+			AuthenticationCode: "auth-code",
+		})}, nil).Once()
+
+	_, err := s.lookup.GetAddressKey(s.ctx, "new@example.com", "16246241620")
+	s.assert.NoError(err)
+
+	response, err := s.lookup.CashOut(s.ctx, &bankly.PixCashOutRequest{EndToEndID: endToEndID, Amount: 10})
+	s.assert.NoError(err)
+	s.assert.Equal("auth-code", response.AuthenticationCode)
+
+	stats := s.lookup.Stats("16246241620")
+	s.assert.Equal(1, stats.CashOuts)
+	s.assert.Equal(1.0, stats.ConversionRate())
+}
+
+func (s *PixLookupTestSuite) TestStats_Expire() {
+	s.lookup = bankly.NewPixLookup(bankly.NewPix(s.httpClient), bankly.PixLookupConfig{
+		Capacity:       1,
+		RefillInterval: 20 * time.Millisecond,
+		StatsTTL:       20 * time.Millisecond,
+	})
+
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/+5511987654321", mock.Anything, mock.Anything).
+		Return(nil, bankly.ErrKeyNotFound).Once()
+
+	_, err := s.lookup.GetAddressKey(s.ctx, "+5511987654321", "16246241620")
+	s.assert.Equal(bankly.ErrKeyNotFound, err)
+
+	stats := s.lookup.Stats("16246241620")
+	s.assert.Equal(1, stats.Lookups)
+	s.assert.Equal(0, stats.Available)
+
+	// the bucket is full again and the stats are dropped without new lookups
+	time.Sleep(40 * time.Millisecond)
+
+	s.assert.Equal(bankly.PixLookupStats{Available: 1}, s.lookup.Stats("16246241620"))
+}
@@ -0,0 +1,543 @@
+package bankly
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// PixScheduleFrequency ...
+type PixScheduleFrequency string
+
+const (
+	// PixScheduleOnce single payment at the scheduled date
+	PixScheduleOnce PixScheduleFrequency = "ONCE"
+	// PixScheduleWeekly payment every week
+	PixScheduleWeekly PixScheduleFrequency = "WEEKLY"
+	// PixScheduleMonthly payment every month at the day of the start date
+	PixScheduleMonthly PixScheduleFrequency = "MONTHLY"
+)
+
+// PixScheduleStatus ...
+type PixScheduleStatus string
+
+const (
+	// PixScheduleActive waiting the next run
+	PixScheduleActive PixScheduleStatus = "ACTIVE"
+	// PixScheduleFinished all the payments were executed
+	PixScheduleFinished PixScheduleStatus = "FINISHED"
+	// PixScheduleFailed the single payment failed after all the attempts
+	PixScheduleFailed PixScheduleStatus = "FAILED"
+	// PixScheduleCanceled ...
+	PixScheduleCanceled PixScheduleStatus = "CANCELED"
+)
+
+// PixScheduleNonBusinessDayRule ...
+type PixScheduleNonBusinessDayRule string
+
+const (
+	// PixScheduleNextBusinessDay moves the payment to the next business day
+	PixScheduleNextBusinessDay PixScheduleNonBusinessDayRule = "NEXT_BUSINESS_DAY"
+	// PixSchedulePreviousBusinessDay moves the payment to the previous business day
+	PixSchedulePreviousBusinessDay PixScheduleNonBusinessDayRule = "PREVIOUS_BUSINESS_DAY"
+	// PixScheduleAnyDay pays at the date, pix runs every day
+	PixScheduleAnyDay PixScheduleNonBusinessDayRule = "ANY_DAY"
+)
+
+// PixSchedule is a scheduled or recurring pix cash out
+type PixSchedule struct {
+	ID              string                        `bson:"_id" json:"id"`
+	AccountNumber   string                        `bson:"accountNumber" json:"accountNumber"`
+	CurrentIdentity string                        `bson:"currentIdentity" json:"currentIdentity"`
+	AddressingKey   *PixTypeValue                 `bson:"addressingKey,omitempty" json:"addressingKey,omitempty"`
+	Request         PixCashOutRequest             `bson:"request" json:"request"`
+	Frequency       PixScheduleFrequency          `bson:"frequency" json:"frequency"`
+	NonBusinessDay  PixScheduleNonBusinessDayRule `bson:"nonBusinessDay" json:"nonBusinessDay"`
+	StartDate       time.Time                     `bson:"startDate" json:"startDate"`
+	EndDate         *time.Time                    `bson:"endDate,omitempty" json:"endDate,omitempty"`
+	MaxOccurrences  int                           `bson:"maxOccurrences,omitempty" json:"maxOccurrences,omitempty"`
+	Status          PixScheduleStatus             `bson:"status" json:"status"`
+	Occurrence      int                           `bson:"occurrence" json:"occurrence"`
+	DueDate         time.Time                     `bson:"dueDate" json:"dueDate"`
+	NextRun         time.Time                     `bson:"nextRun" json:"nextRun"`
+	Attempts        int                           `bson:"attempts" json:"attempts"`
+	Executions      []PixScheduleExecution        `bson:"executions" json:"executions"`
+	Sending         *PixScheduleSending           `bson:"sending,omitempty" json:"sending,omitempty"`
+	CreatedAt       time.Time                     `bson:"createdAt" json:"createdAt"`
+	UpdatedAt       time.Time                     `bson:"updatedAt" json:"updatedAt"`
+
+	ScheduleLease `bson:",inline" json:"-"`
+}
+
+// PixScheduleExecution is the result of one occurrence
+type PixScheduleExecution struct {
+	Occurrence         int       `bson:"occurrence" json:"occurrence"`
+	DueDate            time.Time `bson:"dueDate" json:"dueDate"`
+	ExecutedAt         time.Time `bson:"executedAt" json:"executedAt"`
+	Attempts           int       `bson:"attempts" json:"attempts"`
+	AuthenticationCode string    `bson:"authenticationCode,omitempty" json:"authenticationCode,omitempty"`
+	Error              string    `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// PixScheduleSending is kept at the store before the cash out is sent to Bankly. A schedule
+// found with it was sent without an answer, so it is reconciled instead of sent again.
+type PixScheduleSending struct {
+	Occurrence    int       `bson:"occurrence" json:"occurrence"`
+	CorrelationID string    `bson:"correlationId" json:"correlationId"`
+	EndToEndID    string    `bson:"endToEndId,omitempty" json:"endToEndId,omitempty"`
+	StartedAt     time.Time `bson:"startedAt" json:"startedAt"`
+}
+
+// PixScheduleBalance returns the balance of the account before each execution
+type PixScheduleBalance = BalanceReader
+
+// PixSchedulerConfig ...
+type PixSchedulerConfig struct {
+	// Owner identifies the worker instance at the leases, empty generates one
+	Owner string
+	// Lease is how long a worker holds a schedule while executing it
+	Lease time.Duration
+	// MaxAttempts of each occurrence with transient errors
+	MaxAttempts int
+	// RetryDelay between the attempts
+	RetryDelay time.Duration
+	// BatchSize of schedules read from the store at each run
+	BatchSize int
+	// Calendar of business days, nil uses the national calendar
+	Calendar calendar.Calendar
+	// Reconcile finds the cash out of a schedule sent without an answer, by the correlation id
+	// (x-correlation-id) and endToEndId of PixSchedule.Sending. It returns the authentication
+	// code, or ErrPixScheduleNotSent when Bankly never received it. When nil, the occurrence
+	// fails with ErrPixScheduleUnconfirmed and is never sent again.
+	Reconcile func(ctx context.Context, schedule *PixSchedule) (string, error)
+}
+
+// PixScheduler executes the scheduled pix cash outs stored at the PixScheduleStore.
+// Many instances can run with the same store, each schedule is executed by one instance at a time.
+// The lease is checked and renewed right before each cash out, together with the SENDING state.
+type PixScheduler struct {
+	pix      *Pix
+	balance  PixScheduleBalance
+	store    PixScheduleStore
+	config   PixSchedulerConfig
+	before   []func(ctx context.Context, schedule *PixSchedule) bool
+	executed []func(ctx context.Context, schedule *PixSchedule, execution PixScheduleExecution)
+	failed   []func(ctx context.Context, schedule *PixSchedule, execution PixScheduleExecution)
+	canceled []func(ctx context.Context, schedule *PixSchedule)
+}
+
+// NewPixScheduler ...
+func NewPixScheduler(pix *Pix, balance PixScheduleBalance, store PixScheduleStore, config PixSchedulerConfig) *PixScheduler {
+	scheduleDefaults(&config.Owner, &config.Lease, &config.MaxAttempts, &config.RetryDelay, &config.BatchSize)
+
+	if config.Calendar == nil {
+		config.Calendar = calendar.NewNational()
+	}
+
+	return &PixScheduler{
+		pix:     pix,
+		balance: balance,
+		store:   store,
+		config:  config,
+	}
+}
+
+// BeforeExecute registers a hook called before each payment. Returning false cancels the schedule.
+func (s *PixScheduler) BeforeExecute(hook func(ctx context.Context, schedule *PixSchedule) bool) {
+	s.before = append(s.before, hook)
+}
+
+// OnExecuted registers a hook called after each payment sent to Bankly.
+func (s *PixScheduler) OnExecuted(hook func(ctx context.Context, schedule *PixSchedule, execution PixScheduleExecution)) {
+	s.executed = append(s.executed, hook)
+}
+
+// OnFailed registers a hook called when an occurrence fails after all the attempts.
+func (s *PixScheduler) OnFailed(hook func(ctx context.Context, schedule *PixSchedule, execution PixScheduleExecution)) {
+	s.failed = append(s.failed, hook)
+}
+
+// OnCanceled registers a hook called when a schedule is canceled.
+func (s *PixScheduler) OnCanceled(hook func(ctx context.Context, schedule *PixSchedule)) {
+	s.canceled = append(s.canceled, hook)
+}
+
+// Schedule validates and stores a new schedule.
+func (s *PixScheduler) Schedule(ctx context.Context, schedule *PixSchedule) (*PixSchedule, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"object":     schedule,
+	}
+
+	if schedule.AccountNumber == "" || schedule.Request.Amount <= 0 || schedule.StartDate.IsZero() {
+		logrus.WithFields(fields).WithError(ErrInvalidPixSchedule).Error("invalid pix schedule")
+		return nil, ErrInvalidPixSchedule
+	}
+
+	if schedule.Frequency == "" {
+		schedule.Frequency = PixScheduleOnce
+	}
+
+	if schedule.NonBusinessDay == "" {
+		schedule.NonBusinessDay = PixScheduleNextBusinessDay
+	}
+
+	if schedule.EndDate != nil && schedule.EndDate.Before(schedule.StartDate) {
+		logrus.WithFields(fields).WithError(ErrInvalidPixSchedule).Error("invalid pix schedule end date")
+		return nil, ErrInvalidPixSchedule
+	}
+
+	if schedule.AddressingKey != nil {
+		value, err := NormalizePixKey(schedule.AddressingKey.Type, schedule.AddressingKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		schedule.AddressingKey.Value = value
+	}
+
+	// dates are days at Sao Paulo, pix runs at the start of the day
+	schedule.StartDate = startOfDay(schedule.StartDate)
+	if schedule.EndDate != nil {
+		endDate := startOfDay(*schedule.EndDate)
+		schedule.EndDate = &endDate
+	}
+
+	now := time.Now().UTC()
+
+	schedule.ID = uuid.New().String()
+	schedule.Status = PixScheduleActive
+	schedule.Occurrence = 1
+	schedule.DueDate = schedule.StartDate
+	schedule.NextRun = s.runDate(schedule, schedule.StartDate)
+	schedule.Executions = []PixScheduleExecution{}
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	if err := s.store.Create(ctx, schedule); err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error creating pix schedule")
+		return nil, err
+	}
+
+	logrus.WithFields(fields).
+		WithField("next_run", schedule.NextRun).
+		Info("pix schedule created")
+
+	return schedule, nil
+}
+
+// Cancel cancels the next payments of the schedule.
+func (s *PixScheduler) Cancel(ctx context.Context, id string) (*PixSchedule, error) {
+	schedule, err := s.store.Cancel(ctx, id, time.Now().UTC())
+	if err != nil {
+		logrus.WithField("request_id", GetRequestID(ctx)).
+			WithField("schedule_id", id).
+			WithError(err).Error("error canceling pix schedule")
+		return nil, err
+	}
+
+	for _, hook := range s.canceled {
+		hook(ctx, schedule)
+	}
+
+	return schedule, nil
+}
+
+// Start runs the due schedules at each interval until the context is done.
+func (s *PixScheduler) Start(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, interval, s.config.Owner, "error running pix schedules", s.RunOnce)
+}
+
+// RunOnce executes the schedules due at now and returns how many were processed by this instance.
+func (s *PixScheduler) RunOnce(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.store.Due(ctx, now, s.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	items := make([]leasedItem, len(due))
+	for i, item := range due {
+		id := item.ID
+
+		var schedule *PixSchedule
+		items[i] = leasedItem{
+			ID: id,
+			Acquire: func(at time.Time) (bool, error) {
+				var err error
+				schedule, err = s.store.Acquire(ctx, id, s.config.Owner, at, s.config.Lease)
+				return schedule != nil, err
+			},
+			Run: func(started time.Time) error {
+				return s.execute(GenerateNewRequestID(ctx), schedule, now, started)
+			},
+			Release: func() error {
+				return s.store.Release(ctx, schedule, s.config.Owner)
+			},
+		}
+	}
+
+	// a schedule left to the lease sent nothing to Bankly
+	return runLeased(leasedRun{
+		Field:    "schedule_id",
+		Kept:     "error keeping pix schedule before the cash out",
+		Released: "error releasing pix schedule",
+	}, now, items)
+}
+
+// execute runs the occurrence of the schedule. It returns an error only when the SENDING state
+// could not be kept, the cash out is not sent and the schedule must not be released.
+func (s *PixScheduler) execute(ctx context.Context, schedule *PixSchedule, now time.Time, started time.Time) error {
+	fields := logrus.Fields{
+		"request_id":  GetRequestID(ctx),
+		"schedule_id": schedule.ID,
+		"occurrence":  schedule.Occurrence,
+	}
+
+	schedule.UpdatedAt = now
+
+	// a cash out sent without an answer is never sent again before being reconciled
+	if schedule.Sending != nil && !s.reconcile(ctx, schedule, now) {
+		return nil
+	}
+
+	for _, hook := range s.before {
+		if !hook(ctx, schedule) {
+			schedule.Status = PixScheduleCanceled
+			logrus.WithFields(fields).Info("pix schedule canceled by hook")
+			for _, hook := range s.canceled {
+				hook(ctx, schedule)
+			}
+			return nil
+		}
+	}
+
+	schedule.Attempts++
+
+	var authenticationCode string
+	sent := false
+
+	request, err := s.prepare(ctx, schedule)
+	if err == nil {
+		schedule.Sending = &PixScheduleSending{
+			Occurrence:    schedule.Occurrence,
+			CorrelationID: pixScheduleCorrelationID(schedule),
+			EndToEndID:    request.EndToEndID,
+			StartedAt:     now,
+		}
+
+		if err := s.store.Checkpoint(ctx, schedule, s.config.Owner, now.Add(time.Since(started)), s.config.Lease); err != nil {
+			return err
+		}
+
+		sent = true
+		authenticationCode, err = s.send(ctx, schedule, request)
+	}
+
+	if err != nil && sent && !isRejectedRequest(err) {
+		logrus.WithFields(fields).
+			WithField("correlation_id", schedule.Sending.CorrelationID).
+			WithError(err).Warn("pix schedule cash out not confirmed, it will be reconciled")
+		schedule.NextRun = now.Add(s.config.RetryDelay)
+		return nil
+	}
+
+	schedule.Sending = nil
+
+	if err != nil && isTransientPixScheduleError(err, sent) && schedule.Attempts < s.config.MaxAttempts {
+		logrus.WithFields(fields).
+			WithField("attempts", schedule.Attempts).
+			WithError(err).Warn("pix schedule will retry")
+		schedule.NextRun = now.Add(s.config.RetryDelay)
+		return nil
+	}
+
+	s.finish(ctx, schedule, now, authenticationCode, err)
+	return nil
+}
+
+// reconcile resolves a cash out sent without an answer and reports whether the occurrence
+// must be sent, when Bankly never received it.
+func (s *PixScheduler) reconcile(ctx context.Context, schedule *PixSchedule, now time.Time) bool {
+	fields := logrus.Fields{
+		"request_id":     GetRequestID(ctx),
+		"schedule_id":    schedule.ID,
+		"occurrence":     schedule.Occurrence,
+		"correlation_id": schedule.Sending.CorrelationID,
+	}
+
+	if s.config.Reconcile == nil {
+		schedule.Sending = nil
+		s.finish(ctx, schedule, now, "", ErrPixScheduleUnconfirmed)
+		return false
+	}
+
+	authenticationCode, err := s.config.Reconcile(ctx, schedule)
+	if err == ErrPixScheduleNotSent {
+		logrus.WithFields(fields).Info("pix schedule cash out not received by bankly, it will be sent")
+		schedule.Sending = nil
+		return true
+	} else if err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error reconciling pix schedule cash out")
+		schedule.NextRun = now.Add(s.config.RetryDelay)
+		return false
+	}
+
+	schedule.Sending = nil
+	s.finish(ctx, schedule, now, authenticationCode, nil)
+	return false
+}
+
+// finish records the execution of the occurrence and moves the schedule forward.
+func (s *PixScheduler) finish(ctx context.Context, schedule *PixSchedule, now time.Time, authenticationCode string, err error) {
+	fields := logrus.Fields{
+		"request_id":  GetRequestID(ctx),
+		"schedule_id": schedule.ID,
+		"occurrence":  schedule.Occurrence,
+	}
+
+	execution := PixScheduleExecution{
+		Occurrence:         schedule.Occurrence,
+		DueDate:            schedule.DueDate,
+		ExecutedAt:         now,
+		Attempts:           schedule.Attempts,
+		AuthenticationCode: authenticationCode,
+	}
+
+	if err != nil {
+		execution.Error = err.Error()
+		logrus.WithFields(fields).WithError(err).Error("pix schedule occurrence failed")
+	} else {
+		logrus.WithFields(fields).
+			WithField("authentication_code", authenticationCode).
+			Info("pix schedule occurrence executed")
+	}
+
+	schedule.Executions = append(schedule.Executions, execution)
+	s.advance(schedule, err != nil)
+
+	if err != nil {
+		for _, hook := range s.failed {
+			hook(ctx, schedule, execution)
+		}
+		return
+	}
+
+	for _, hook := range s.executed {
+		hook(ctx, schedule, execution)
+	}
+}
+
+// prepare checks the balance and gets the endToEndId of the cash out, nothing is sent yet.
+func (s *PixScheduler) prepare(ctx context.Context, schedule *PixSchedule) (PixCashOutRequest, error) {
+	request := schedule.Request
+
+	if s.balance != nil {
+		account, err := s.balance.Balance(ctx, schedule.AccountNumber)
+		if err != nil {
+			return request, err
+		}
+		if account.Balance == nil || account.Balance.Available.Money().Cents < request.AmountMoney().Cents {
+			return request, ErrInsufficientBalancePix
+		}
+	}
+
+	// the endToEndId of a key payment comes from a new DICT lookup
+	request.EndToEndID = ""
+	if schedule.AddressingKey != nil {
+		entry, err := s.pix.GetAddressKey(ctx, schedule.AddressingKey.Value, schedule.CurrentIdentity)
+		if err != nil {
+			return request, err
+		}
+		request.EndToEndID = entry.EndToEndID
+	}
+
+	return request, nil
+}
+
+// send sends the cash out with the correlation id kept at the SENDING state.
+func (s *PixScheduler) send(ctx context.Context, schedule *PixSchedule, request PixCashOutRequest) (string, error) {
+	ctx = context.WithValue(ctx, "Request-Id", schedule.Sending.CorrelationID)
+
+	response, err := s.pix.CashOut(ctx, &request)
+	if err != nil {
+		return "", err
+	}
+
+	return response.AuthenticationCode, nil
+}
+
+// advance moves the schedule to the next occurrence or finishes it.
+func (s *PixScheduler) advance(schedule *PixSchedule, failed bool) {
+	schedule.Attempts = 0
+
+	if schedule.Frequency == PixScheduleOnce {
+		schedule.Status = PixScheduleFinished
+		if failed {
+			schedule.Status = PixScheduleFailed
+		}
+		return
+	}
+
+	schedule.Occurrence++
+	schedule.DueDate = s.dueDate(schedule, schedule.Occurrence)
+
+	if (schedule.MaxOccurrences > 0 && schedule.Occurrence > schedule.MaxOccurrences) ||
+		(schedule.EndDate != nil && schedule.DueDate.After(*schedule.EndDate)) {
+		schedule.Status = PixScheduleFinished
+		return
+	}
+
+	schedule.NextRun = s.runDate(schedule, schedule.DueDate)
+}
+
+// dueDate returns the date of the occurrence, keeping the day of the start date
+// at the months without that day (31 becomes 30, 28 or 29).
+func (s *PixScheduler) dueDate(schedule *PixSchedule, occurrence int) time.Time {
+	start := schedule.StartDate
+
+	if schedule.Frequency == PixScheduleWeekly {
+		return start.AddDate(0, 0, 7*(occurrence-1))
+	}
+
+	first := time.Date(start.Year(), start.Month()+time.Month(occurrence-1), 1,
+		start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	last := first.AddDate(0, 1, -1).Day()
+
+	day := start.Day()
+	if day > last {
+		day = last
+	}
+
+	return first.AddDate(0, 0, day-1)
+}
+
+func (s *PixScheduler) runDate(schedule *PixSchedule, date time.Time) time.Time {
+	switch schedule.NonBusinessDay {
+	case PixScheduleNextBusinessDay:
+		return calendar.NextBusinessDay(s.config.Calendar, date)
+	case PixSchedulePreviousBusinessDay:
+		return calendar.PreviousBusinessDay(s.config.Calendar, date)
+	}
+	return date
+}
+
+// startOfDay returns the start of the day of the date at Sao Paulo.
+func startOfDay(date time.Time) time.Time {
+	date = date.In(calendar.Location())
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, calendar.Location())
+}
+
+// pixScheduleCorrelationID is the same for every attempt of the occurrence, so Bankly can
+// tell a cash out sent again from a new one.
+func pixScheduleCorrelationID(schedule *PixSchedule) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(schedule.ID+":"+strconv.Itoa(schedule.Occurrence))).String()
+}
+
+// isTransientPixScheduleError also retries the balance checked before the cash out, which
+// may arrive before the next attempt.
+func isTransientPixScheduleError(err error, sent bool) bool {
+	return err == ErrInsufficientBalancePix || isTransientError(err, sent)
+}
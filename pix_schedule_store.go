@@ -0,0 +1,325 @@
+package bankly
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PixScheduleStore persists the schedules and the leases of the workers
+type PixScheduleStore interface {
+	Create(ctx context.Context, schedule *PixSchedule) error
+	Get(ctx context.Context, id string) (*PixSchedule, error)
+	ListByAccount(ctx context.Context, accountNumber string) ([]*PixSchedule, error)
+	// Due returns the active schedules with the next run until now
+	Due(ctx context.Context, now time.Time, limit int) ([]*PixSchedule, error)
+	// Acquire leases the schedule to the owner when it is still due and not leased
+	// by another owner. It returns nil when the lease is not acquired.
+	Acquire(ctx context.Context, id string, owner string, now time.Time, lease time.Duration) (*PixSchedule, error)
+	// Checkpoint saves the schedule and renews the lease while the owner still holds it at now,
+	// otherwise it returns ErrPixScheduleLeaseLost.
+	Checkpoint(ctx context.Context, schedule *PixSchedule, owner string, now time.Time, lease time.Duration) error
+	// Release saves the schedule and releases the lease, it returns ErrPixScheduleLeaseLost
+	// when the owner does not hold the lease anymore.
+	Release(ctx context.Context, schedule *PixSchedule, owner string) error
+	// Cancel cancels an active schedule not leased at the moment and without a cash out
+	// waiting for confirmation.
+	Cancel(ctx context.Context, id string, now time.Time) (*PixSchedule, error)
+}
+
+// memoryPixScheduleStore ...
+type memoryPixScheduleStore struct {
+	mutex     sync.Mutex
+	schedules map[string]*PixSchedule
+}
+
+// NewMemoryPixScheduleStore returns a store for a single instance or tests.
+func NewMemoryPixScheduleStore() PixScheduleStore {
+	return &memoryPixScheduleStore{schedules: map[string]*PixSchedule{}}
+}
+
+func (m *memoryPixScheduleStore) Create(ctx context.Context, schedule *PixSchedule) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.schedules[schedule.ID] = copyPixSchedule(schedule)
+	return nil
+}
+
+func (m *memoryPixScheduleStore) Get(ctx context.Context, id string) (*PixSchedule, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return nil, ErrPixScheduleNotFound
+	}
+	return copyPixSchedule(schedule), nil
+}
+
+func (m *memoryPixScheduleStore) ListByAccount(ctx context.Context, accountNumber string) ([]*PixSchedule, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := []*PixSchedule{}
+	for _, schedule := range m.schedules {
+		if schedule.AccountNumber == accountNumber {
+			response = append(response, copyPixSchedule(schedule))
+		}
+	}
+
+	sort.Slice(response, func(i, j int) bool { return response[i].CreatedAt.Before(response[j].CreatedAt) })
+	return response, nil
+}
+
+func (m *memoryPixScheduleStore) Due(ctx context.Context, now time.Time, limit int) ([]*PixSchedule, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := []*PixSchedule{}
+	for _, schedule := range m.schedules {
+		if schedule.Status == PixScheduleActive && !schedule.NextRun.After(now) {
+			response = append(response, copyPixSchedule(schedule))
+		}
+	}
+
+	sort.Slice(response, func(i, j int) bool { return response[i].NextRun.Before(response[j].NextRun) })
+	if limit > 0 && len(response) > limit {
+		response = response[:limit]
+	}
+	return response, nil
+}
+
+func (m *memoryPixScheduleStore) Acquire(ctx context.Context, id string, owner string, now time.Time,
+	lease time.Duration) (*PixSchedule, error) {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return nil, ErrPixScheduleNotFound
+	}
+
+	if schedule.Status != PixScheduleActive || schedule.NextRun.After(now) {
+		return nil, nil
+	}
+
+	if schedule.leasedByOther(owner, now) {
+		return nil, nil
+	}
+
+	schedule.lock(owner, now, lease)
+
+	return copyPixSchedule(schedule), nil
+}
+
+func (m *memoryPixScheduleStore) Checkpoint(ctx context.Context, schedule *PixSchedule, owner string, now time.Time,
+	lease time.Duration) error {
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current, ok := m.schedules[schedule.ID]
+	if !ok {
+		return ErrPixScheduleNotFound
+	}
+
+	if !current.heldBy(owner, now) {
+		return ErrPixScheduleLeaseLost
+	}
+
+	saved := copyPixSchedule(schedule)
+	saved.lock(owner, now, lease)
+	m.schedules[schedule.ID] = saved
+
+	return nil
+}
+
+func (m *memoryPixScheduleStore) Release(ctx context.Context, schedule *PixSchedule, owner string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	current, ok := m.schedules[schedule.ID]
+	if !ok {
+		return ErrPixScheduleNotFound
+	}
+
+	if current.LockedBy != owner {
+		return ErrPixScheduleLeaseLost
+	}
+
+	released := copyPixSchedule(schedule)
+	released.unlock()
+	m.schedules[schedule.ID] = released
+
+	return nil
+}
+
+func (m *memoryPixScheduleStore) Cancel(ctx context.Context, id string, now time.Time) (*PixSchedule, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return nil, ErrPixScheduleNotFound
+	}
+
+	if schedule.Status != PixScheduleActive {
+		return nil, ErrPixScheduleNotActive
+	}
+
+	if schedule.leased(now) || schedule.Sending != nil {
+		return nil, ErrPixScheduleLocked
+	}
+
+	schedule.Status = PixScheduleCanceled
+	schedule.UpdatedAt = now
+
+	return copyPixSchedule(schedule), nil
+}
+
+func copyPixSchedule(schedule *PixSchedule) *PixSchedule {
+	response := *schedule
+	response.Executions = append([]PixScheduleExecution{}, schedule.Executions...)
+	if schedule.AddressingKey != nil {
+		key := *schedule.AddressingKey
+		response.AddressingKey = &key
+	}
+	if schedule.Sending != nil {
+		sending := *schedule.Sending
+		response.Sending = &sending
+	}
+	return &response
+}
+
+// mongoPixScheduleStore ...
+type mongoPixScheduleStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPixScheduleStore returns a store shared by many worker instances.
+// The collection should be indexed by status and nextRun.
+func NewMongoPixScheduleStore(collection *mongo.Collection) PixScheduleStore {
+	return &mongoPixScheduleStore{collection: collection}
+}
+
+func (m *mongoPixScheduleStore) Create(ctx context.Context, schedule *PixSchedule) error {
+	_, err := m.collection.InsertOne(ctx, schedule)
+	return err
+}
+
+func (m *mongoPixScheduleStore) Get(ctx context.Context, id string) (*PixSchedule, error) {
+	schedule := new(PixSchedule)
+
+	err := m.collection.FindOne(ctx, bson.M{"_id": id}).Decode(schedule)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrPixScheduleNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (m *mongoPixScheduleStore) ListByAccount(ctx context.Context, accountNumber string) ([]*PixSchedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	return m.find(ctx, bson.M{"accountNumber": accountNumber}, opts)
+}
+
+func (m *mongoPixScheduleStore) Due(ctx context.Context, now time.Time, limit int) ([]*PixSchedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "nextRun", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	filter := bson.M{
+		"status":  PixScheduleActive,
+		"nextRun": bson.M{"$lte": now},
+	}
+
+	return m.find(ctx, filter, opts)
+}
+
+func (m *mongoPixScheduleStore) Acquire(ctx context.Context, id string, owner string, now time.Time,
+	lease time.Duration) (*PixSchedule, error) {
+
+	filter := bson.M{
+		"_id":     id,
+		"status":  PixScheduleActive,
+		"nextRun": bson.M{"$lte": now},
+		"$or":     mongoLeaseFree(now, owner),
+	}
+
+	schedule := new(PixSchedule)
+
+	found, err := mongoFindOneAndUpdate(ctx, m.collection, filter, mongoLeaseLock(owner, now, lease), schedule)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (m *mongoPixScheduleStore) Checkpoint(ctx context.Context, schedule *PixSchedule, owner string, now time.Time,
+	lease time.Duration) error {
+
+	saved := copyPixSchedule(schedule)
+	saved.lock(owner, now, lease)
+
+	return mongoReplaceLeased(ctx, m.collection, mongoLeaseHeld(schedule.ID, owner, now), saved, ErrPixScheduleLeaseLost)
+}
+
+func (m *mongoPixScheduleStore) Release(ctx context.Context, schedule *PixSchedule, owner string) error {
+	released := copyPixSchedule(schedule)
+	released.unlock()
+
+	filter := bson.M{"_id": schedule.ID, "lockedBy": owner}
+	return mongoReplaceLeased(ctx, m.collection, filter, released, ErrPixScheduleLeaseLost)
+}
+
+func (m *mongoPixScheduleStore) Cancel(ctx context.Context, id string, now time.Time) (*PixSchedule, error) {
+	filter := bson.M{
+		"_id":     id,
+		"status":  PixScheduleActive,
+		"sending": nil,
+		"$or":     mongoLeaseFree(now),
+	}
+
+	update := bson.M{"$set": bson.M{"status": PixScheduleCanceled, "updatedAt": now}}
+
+	schedule := new(PixSchedule)
+
+	found, err := mongoFindOneAndUpdate(ctx, m.collection, filter, update, schedule)
+	if err != nil {
+		return nil, err
+	} else if found {
+		return schedule, nil
+	}
+
+	// tells apart the reasons the schedule was not canceled
+	current, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Status != PixScheduleActive {
+		return nil, ErrPixScheduleNotActive
+	}
+
+	return nil, ErrPixScheduleLocked
+}
+
+func (m *mongoPixScheduleStore) find(ctx context.Context, filter interface{}, opts *options.FindOptions) ([]*PixSchedule, error) {
+	response := []*PixSchedule{}
+	if err := mongoFind(ctx, m.collection, filter, opts, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
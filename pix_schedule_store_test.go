@@ -0,0 +1,126 @@
+package bankly_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestMongoPixScheduleStore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := context.Background()
+	now := time.Date(2026, time.November, 10, 12, 0, 0, 0, time.UTC)
+
+	mt.Run("acquire", func(mt *mtest.T) {
+		store := bankly.NewMongoPixScheduleStore(mt.Coll)
+
+		mt.AddMockResponses(mongoValue(bson.D{{Key: "_id", Value: "s-1"}, {Key: "lockedBy", Value: "worker-1"}}))
+		schedule, err := store.Acquire(ctx, "s-1", "worker-1", now, time.Minute)
+		assert.NoError(mt, err)
+		assert.Equal(mt, "worker-1", schedule.LockedBy)
+
+		// free, leased by the same owner or with an expired lease
+		command := mt.GetStartedEvent().Command
+		owners, expired := mongoLeaseQuery(command)
+		assert.Equal(mt, []string{"", "worker-1"}, owners)
+		assert.Equal(mt, now, expired)
+		assert.Equal(mt, "worker-1", command.Lookup("update", "$set", "lockedBy").StringValue())
+		assert.Equal(mt, now.Add(time.Minute), command.Lookup("update", "$set", "lockedUntil").Time().UTC())
+
+		mt.AddMockResponses(mongoValue(nil))
+		schedule, err = store.Acquire(ctx, "s-1", "worker-2", now, time.Minute)
+		assert.NoError(mt, err)
+		assert.Nil(mt, schedule)
+	})
+
+	mt.Run("checkpoint", func(mt *mtest.T) {
+		store := bankly.NewMongoPixScheduleStore(mt.Coll)
+		schedule := &bankly.PixSchedule{ID: "s-1", Status: bankly.PixScheduleActive}
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		assert.NoError(mt, store.Checkpoint(ctx, schedule, "worker-1", now, time.Minute))
+
+		command := mt.GetStartedEvent().Command
+		assert.Equal(mt, "worker-1", command.Lookup("updates", "0", "q", "lockedBy").StringValue())
+		assert.Equal(mt, now, command.Lookup("updates", "0", "q", "lockedUntil", "$gt").Time().UTC())
+		assert.Equal(mt, "worker-1", command.Lookup("updates", "0", "u", "lockedBy").StringValue())
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		assert.Equal(mt, bankly.ErrPixScheduleLeaseLost, store.Checkpoint(ctx, schedule, "worker-1", now, time.Minute))
+	})
+
+	mt.Run("release", func(mt *mtest.T) {
+		store := bankly.NewMongoPixScheduleStore(mt.Coll)
+		schedule := &bankly.PixSchedule{ID: "s-1", Status: bankly.PixScheduleActive}
+		schedule.LockedBy = "worker-1"
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		assert.NoError(mt, store.Release(ctx, schedule, "worker-1"))
+
+		_, err := mt.GetStartedEvent().Command.Lookup("updates", "0", "u").Document().LookupErr("lockedBy")
+		assert.Error(mt, err)
+
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		assert.Equal(mt, bankly.ErrPixScheduleLeaseLost, store.Release(ctx, schedule, "worker-1"))
+	})
+
+	mt.Run("cancel", func(mt *mtest.T) {
+		store := bankly.NewMongoPixScheduleStore(mt.Coll)
+
+		mt.AddMockResponses(mongoValue(bson.D{{Key: "_id", Value: "s-1"}, {Key: "status", Value: bankly.PixScheduleCanceled}}))
+		schedule, err := store.Cancel(ctx, "s-1", now)
+		assert.NoError(mt, err)
+		assert.Equal(mt, bankly.PixScheduleCanceled, schedule.Status)
+
+		// only a schedule without a lease at now
+		owners, expired := mongoLeaseQuery(mt.GetStartedEvent().Command)
+		assert.Equal(mt, []string{""}, owners)
+		assert.Equal(mt, now, expired)
+
+		mt.AddMockResponses(mongoValue(nil), mongoCursor(mt, bson.D{{Key: "_id", Value: "s-1"}, {Key: "status", Value: bankly.PixScheduleActive}}))
+		_, err = store.Cancel(ctx, "s-1", now)
+		assert.Equal(mt, bankly.ErrPixScheduleLocked, err)
+
+		mt.AddMockResponses(mongoValue(nil), mongoCursor(mt, bson.D{{Key: "_id", Value: "s-1"}, {Key: "status", Value: bankly.PixScheduleCanceled}}))
+		_, err = store.Cancel(ctx, "s-1", now)
+		assert.Equal(mt, bankly.ErrPixScheduleNotActive, err)
+	})
+
+	mt.Run("due", func(mt *mtest.T) {
+		store := bankly.NewMongoPixScheduleStore(mt.Coll)
+
+		mt.AddMockResponses(mongoCursor(mt, bson.D{{Key: "_id", Value: "s-1"}}, bson.D{{Key: "_id", Value: "s-2"}}))
+		due, err := store.Due(ctx, now, 10)
+		assert.NoError(mt, err)
+		assert.Len(mt, due, 2)
+		assert.Equal(mt, "s-2", due[1].ID)
+	})
+}
+
+// mongoLeaseQuery returns the owners and the expiration time of the lease filter of a findAndModify
+func mongoLeaseQuery(command bson.Raw) ([]string, time.Time) {
+	owners := []string{}
+	values, _ := command.Lookup("query", "$or", "0", "lockedBy", "$in").Array().Values()
+	for _, value := range values {
+		if owner, ok := value.StringValueOK(); ok {
+			owners = append(owners, owner)
+		}
+	}
+
+	return owners, command.Lookup("query", "$or", "1", "lockedUntil", "$lt").Time().UTC()
+}
+
+// mongoValue answers a findAndModify, nil when no document matched
+func mongoValue(document interface{}) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: document})
+}
+
+// mongoCursor answers a find with all the documents in the first batch
+func mongoCursor(mt *mtest.T, documents ...bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, "test."+mt.Coll.Name(), mtest.FirstBatch, documents...)
+}
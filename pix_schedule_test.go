@@ -0,0 +1,302 @@
+package bankly_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/contbank/bankly-sdk/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type PixScheduleTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	ctx        context.Context
+	httpClient *mocks.BanklyHttpClient
+	balance    *fakeBalance
+	store      bankly.PixScheduleStore
+	scheduler  *bankly.PixScheduler
+}
+
+type fakeBalance struct {
+	available float64
+	calls     int
+}
+
+func (f *fakeBalance) Balance(ctx context.Context, account string) (*bankly.AccountResponse, error) {
+	f.calls++
+	return &bankly.AccountResponse{
+		Number:  account,
+		Balance: &bankly.BalanceRespone{Available: bankly.BalanceValue{Amount: f.available}},
+	}, nil
+}
+
+func TestPixScheduleTestSuite(t *testing.T) {
+	suite.Run(t, new(PixScheduleTestSuite))
+}
+
+func (s *PixScheduleTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.httpClient = mocks.NewBanklyHttpClient(s.T())
+	s.httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+	s.balance = &fakeBalance{available: 1000}
+	s.store = bankly.NewMemoryPixScheduleStore()
+	s.scheduler = bankly.NewPixScheduler(bankly.NewPix(s.httpClient), s.balance, s.store, bankly.PixSchedulerConfig{
+		Owner:       "worker-1",
+		MaxAttempts: 2,
+		RetryDelay:  time.Hour,
+	})
+}
+
+func (s *PixScheduleTestSuite) TestMonthlySchedule() {
+	schedule, err := s.scheduler.Schedule(s.ctx, buildPixSchedule(bankly.PixScheduleMonthly, date(2026, time.January, 31)))
+	s.assert.NoError(err)
+	s.assert.Equal(date(2026, time.February, 2), schedule.NextRun) // january 31 is saturday
+
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/new@example.com", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixAddressKeyResponse{
+			EndToEndID: "E1314008820260202030012345abcdeF",
+		})}, nil).Once()
+
+	s.httpClient.EXPECT().Post(mock.Anything, "pix/cash-out", mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, url string, body interface{}, header *http.Header) {
+			s.assert.Equal("E1314008820260202030012345abcdeF", body.(*bankly.PixCashOutRequest).EndToEndID)
+		}).
+		Return(&http.Response{StatusCode: http.StatusAccepted, Body: jsonBody(bankly.PixCashOutResponse{
+			AuthenticationCode: "auth-code",
+		})}, nil).Once()
+
+	var executions []bankly.PixScheduleExecution
+	s.scheduler.OnExecuted(func(ctx context.Context, schedule *bankly.PixSchedule, execution bankly.PixScheduleExecution) {
+		executions = append(executions, execution)
+	})
+
+	processed, err := s.scheduler.RunOnce(s.ctx, date(2026, time.February, 2).Add(time.Hour))
+	s.assert.NoError(err)
+	s.assert.Equal(1, processed)
+	s.assert.Len(executions, 1)
+	s.assert.Equal("auth-code", executions[0].AuthenticationCode)
+
+	stored, err := s.store.Get(s.ctx, schedule.ID)
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PixScheduleActive, stored.Status)
+	s.assert.Equal(2, stored.Occurrence)
+	s.assert.Equal(date(2026, time.February, 28), stored.DueDate)
+	s.assert.Equal(date(2026, time.March, 2), stored.NextRun) // february 28 is saturday
+	s.assert.Empty(stored.LockedBy)
+
+	processed, err = s.scheduler.RunOnce(s.ctx, date(2026, time.February, 3))
+	s.assert.NoError(err)
+	s.assert.Equal(0, processed)
+}
+
+func (s *PixScheduleTestSuite) TestInsufficientBalance_RetriesAndFails() {
+	s.balance.available = 10
+
+	schedule, err := s.scheduler.Schedule(s.ctx, buildPixSchedule(bankly.PixScheduleOnce, date(2026, time.October, 19)))
+	s.assert.NoError(err)
+
+	var failed []bankly.PixScheduleExecution
+	s.scheduler.OnFailed(func(ctx context.Context, schedule *bankly.PixSchedule, execution bankly.PixScheduleExecution) {
+		failed = append(failed, execution)
+	})
+
+	now := date(2026, time.October, 19).Add(time.Hour)
+
+	_, err = s.scheduler.RunOnce(s.ctx, now)
+	s.assert.NoError(err)
+
+	stored, _ := s.store.Get(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.PixScheduleActive, stored.Status)
+	s.assert.Equal(1, stored.Attempts)
+	s.assert.Equal(now.Add(time.Hour), stored.NextRun)
+
+	_, err = s.scheduler.RunOnce(s.ctx, now.Add(time.Hour))
+	s.assert.NoError(err)
+
+	stored, _ = s.store.Get(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.PixScheduleFailed, stored.Status)
+	s.assert.Len(failed, 1)
+	s.assert.Equal(bankly.ErrInsufficientBalancePix.Error(), failed[0].Error)
+	s.assert.Equal(2, s.balance.calls)
+}
+
+func (s *PixScheduleTestSuite) TestLease() {
+	schedule, err := s.scheduler.Schedule(s.ctx, buildPixSchedule(bankly.PixScheduleOnce, date(2026, time.October, 19)))
+	s.assert.NoError(err)
+
+	now := date(2026, time.October, 19).Add(time.Hour)
+
+	// Cancel reads the lease by the wall clock
+	leased, err := s.store.Acquire(s.ctx, schedule.ID, "worker-2", now, time.Since(now)+time.Minute)
+	s.assert.NoError(err)
+	s.assert.NotNil(leased)
+
+	processed, err := s.scheduler.RunOnce(s.ctx, now)
+	s.assert.NoError(err)
+	s.assert.Equal(0, processed)
+
+	_, err = s.scheduler.Cancel(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.ErrPixScheduleLocked, err)
+
+	s.assert.Equal(bankly.ErrPixScheduleLeaseLost, s.store.Release(s.ctx, leased, "worker-1"))
+	s.assert.NoError(s.store.Release(s.ctx, leased, "worker-2"))
+
+	canceled, err := s.scheduler.Cancel(s.ctx, schedule.ID)
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.PixScheduleCanceled, canceled.Status)
+
+	_, err = s.scheduler.Cancel(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.ErrPixScheduleNotActive, err)
+}
+
+func (s *PixScheduleTestSuite) TestBeforeExecute_Cancels() {
+	schedule, err := s.scheduler.Schedule(s.ctx, buildPixSchedule(bankly.PixScheduleWeekly, date(2026, time.October, 19)))
+	s.assert.NoError(err)
+
+	canceled := 0
+	s.scheduler.BeforeExecute(func(ctx context.Context, schedule *bankly.PixSchedule) bool { return false })
+	s.scheduler.OnCanceled(func(ctx context.Context, schedule *bankly.PixSchedule) { canceled++ })
+
+	_, err = s.scheduler.RunOnce(s.ctx, date(2026, time.October, 19).Add(time.Hour))
+	s.assert.NoError(err)
+
+	stored, _ := s.store.Get(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.PixScheduleCanceled, stored.Status)
+	s.assert.Equal(1, canceled)
+	s.assert.Equal(0, s.balance.calls)
+}
+
+func (s *PixScheduleTestSuite) TestUnconfirmedCashOut_Reconciled() {
+	schedule, err := s.scheduler.Schedule(s.ctx, buildPixSchedule(bankly.PixScheduleOnce, date(2026, time.October, 19)))
+	s.assert.NoError(err)
+
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/new@example.com", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixAddressKeyResponse{
+			EndToEndID: "E1314008820261019030012345abcdeF",
+		})}, nil).Once()
+
+	var correlationID string
+	s.httpClient.EXPECT().Post(mock.Anything, "pix/cash-out", mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, url string, body interface{}, header *http.Header) {
+			correlationID = header.Get("x-correlation-id")
+		}).
+		Return(nil, bankly.ErrDefaultPix).Once()
+
+	now := date(2026, time.October, 19).Add(time.Hour)
+
+	_, err = s.scheduler.RunOnce(s.ctx, now)
+	s.assert.NoError(err)
+
+	stored, _ := s.store.Get(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.PixScheduleActive, stored.Status)
+	s.assert.NotNil(stored.Sending)
+	s.assert.Equal(correlationID, stored.Sending.CorrelationID)
+	s.assert.Equal("E1314008820261019030012345abcdeF", stored.Sending.EndToEndID)
+
+	// a cash out waiting for confirmation can't be canceled
+	_, err = s.scheduler.Cancel(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.ErrPixScheduleLocked, err)
+
+	reconciler := bankly.NewPixScheduler(bankly.NewPix(s.httpClient), s.balance, s.store, bankly.PixSchedulerConfig{
+		Owner: "worker-2",
+		Reconcile: func(ctx context.Context, schedule *bankly.PixSchedule) (string, error) {
+			s.assert.Equal(correlationID, schedule.Sending.CorrelationID)
+			return "auth-code", nil
+		},
+	})
+
+	_, err = reconciler.RunOnce(s.ctx, now.Add(time.Hour))
+	s.assert.NoError(err)
+
+	stored, _ = s.store.Get(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.PixScheduleFinished, stored.Status)
+	s.assert.Nil(stored.Sending)
+	s.assert.Len(stored.Executions, 1)
+	s.assert.Equal("auth-code", stored.Executions[0].AuthenticationCode)
+}
+
+func (s *PixScheduleTestSuite) TestUnconfirmedCashOut_NeverSentAgain() {
+	request := buildPixSchedule(bankly.PixScheduleOnce, date(2026, time.October, 19))
+	request.AddressingKey = nil
+
+	schedule, err := s.scheduler.Schedule(s.ctx, request)
+	s.assert.NoError(err)
+
+	s.httpClient.EXPECT().Post(mock.Anything, "pix/cash-out", mock.Anything, mock.Anything).
+		Return(nil, bankly.ErrDefaultPix).Once()
+
+	now := date(2026, time.October, 19).Add(time.Hour)
+
+	_, err = s.scheduler.RunOnce(s.ctx, now)
+	s.assert.NoError(err)
+	_, err = s.scheduler.RunOnce(s.ctx, now.Add(time.Hour))
+	s.assert.NoError(err)
+
+	stored, _ := s.store.Get(s.ctx, schedule.ID)
+	s.assert.Equal(bankly.PixScheduleFailed, stored.Status)
+	s.assert.Nil(stored.Sending)
+	s.assert.Equal(bankly.ErrPixScheduleUnconfirmed.Error(), stored.Executions[0].Error)
+}
+
+func (s *PixScheduleTestSuite) TestLeaseLost_BeforeCashOut() {
+	request := buildPixSchedule(bankly.PixScheduleOnce, date(2026, time.October, 19))
+	request.AddressingKey = nil
+
+	schedule, err := s.scheduler.Schedule(s.ctx, request)
+	s.assert.NoError(err)
+
+	now := date(2026, time.October, 19).Add(time.Hour)
+
+	// another worker takes the schedule after the lease expires, no cash out is sent
+	s.scheduler.BeforeExecute(func(ctx context.Context, schedule *bankly.PixSchedule) bool {
+		leased, err := s.store.Acquire(ctx, schedule.ID, "worker-2", now.Add(time.Hour), time.Minute)
+		s.assert.NoError(err)
+		s.assert.NotNil(leased)
+		return true
+	})
+
+	processed, err := s.scheduler.RunOnce(s.ctx, now)
+	s.assert.NoError(err)
+	s.assert.Equal(0, processed)
+
+	stored, _ := s.store.Get(s.ctx, schedule.ID)
+	s.assert.Equal("worker-2", stored.LockedBy)
+	s.assert.Nil(stored.Sending)
+	s.assert.Equal(0, stored.Attempts)
+}
+
+func (s *PixScheduleTestSuite) TestSchedule_DateAtSaoPaulo() {
+	// 01:00 UTC of october 20 is still october 19 at Sao Paulo
+	schedule, err := s.scheduler.Schedule(s.ctx, buildPixSchedule(bankly.PixScheduleOnce,
+		time.Date(2026, time.October, 20, 1, 0, 0, 0, time.UTC)))
+
+	s.assert.NoError(err)
+	s.assert.Equal(date(2026, time.October, 19), schedule.StartDate)
+}
+
+func buildPixSchedule(frequency bankly.PixScheduleFrequency, startDate time.Time) *bankly.PixSchedule {
+	return &bankly.PixSchedule{
+		AccountNumber:   "207802",
+		CurrentIdentity: "16246241620",
+		AddressingKey:   &bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "new@example.com"},
+		Frequency:       frequency,
+		StartDate:       startDate,
+		Request: bankly.PixCashOutRequest{
+			Amount:             100,
+			Description:        "aluguel",
+			InitializationType: bankly.Key,
+		},
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, calendar.Location())
+}
@@ -0,0 +1,375 @@
+package bankly
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/contbank/bankly-sdk/calendar"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// PaymentRail ...
+type PaymentRail string
+
+const (
+	// RailInternal transfer between Bankly accounts
+	RailInternal PaymentRail = "INTERNAL"
+	// RailPixKey pix by addressing key
+	RailPixKey PaymentRail = "PIX_KEY"
+	// RailPixAccount pix by the account data of the recipient
+	RailPixAccount PaymentRail = "PIX_ACCOUNT"
+	// RailTED transfer to other banks at the TED window
+	RailTED PaymentRail = "TED"
+)
+
+// RoutePolicy ...
+type RoutePolicy string
+
+const (
+	// RouteBySpeed prefers internal, pix by key, pix by account and TED, in this order
+	RouteBySpeed RoutePolicy = "SPEED"
+	// RouteByCost prefers the cheapest rail by RouterConfig.Costs, ties are broken by speed
+	RouteByCost RoutePolicy = "COST"
+)
+
+// speedRank ...
+var speedRank = map[PaymentRail]int{
+	RailInternal:   0,
+	RailPixKey:     1,
+	RailPixAccount: 2,
+	RailTED:        3,
+}
+
+// RouterConfig ...
+type RouterConfig struct {
+	// Sender is the Bankly account paying the recipients
+	Sender SenderRequest
+	// Policy orders the rails, empty uses RouteBySpeed
+	Policy RoutePolicy
+	// Costs of each rail, the missing rails cost zero
+	Costs map[PaymentRail]Money
+	// Rails allowed to the router, nil allows all of them
+	Rails []PaymentRail
+	// Calendar and TEDWindow tell if TED is available, nil uses the national calendar
+	Calendar  calendar.Calendar
+	TEDWindow *calendar.Window
+	// Now is used by tests, nil uses time.Now
+	Now func() time.Time
+}
+
+// RouteRecipient has the pix key or the account data of the recipient, or both
+type RouteRecipient struct {
+	Name        string
+	Document    string
+	PixKey      *PixTypeValue
+	BankCode    string
+	Branch      string
+	Account     string
+	AccountType TransfersAccountType
+	Description string
+}
+
+// RouteOption is a rail considered by the router
+type RouteOption struct {
+	Rail      PaymentRail
+	Cost      Money
+	Available bool
+	// Reason explains why the rail is or is not available, empty when not evaluated
+	Reason string
+}
+
+// RouteDecision explains the rail chosen
+type RouteDecision struct {
+	Rail   PaymentRail
+	Reason string
+	// Options are the rails in the order of the policy
+	Options []RouteOption
+
+	entry *PixAddressKeyResponse
+	bank  *BankDataResponse
+}
+
+// RoutePayment ...
+type RoutePayment struct {
+	Decision           *RouteDecision
+	AuthenticationCode string
+	Transfer           *TransferByCodeResponse
+	PixCashOut         *PixCashOutResponse
+}
+
+// Router chooses how to pay a recipient and pays through the transfers and pix services.
+type Router struct {
+	transfers *Transfers
+	pix       *Pix
+	bank      *Bank
+	config    RouterConfig
+}
+
+// NewRouter ...
+func NewRouter(transfers *Transfers, pix *Pix, bank *Bank, config RouterConfig) *Router {
+	if config.Policy == "" {
+		config.Policy = RouteBySpeed
+	}
+
+	if config.Calendar == nil {
+		config.Calendar = calendar.NewNational()
+	}
+
+	if config.TEDWindow == nil {
+		window := calendar.TEDWindow
+		config.TEDWindow = &window
+	}
+
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	return &Router{
+		transfers: transfers,
+		pix:       pix,
+		bank:      bank,
+		config:    config,
+	}
+}
+
+// Route chooses the rail without paying. The rails are evaluated in the order of
+// the policy until one is available. The lookups at DICT are rate limited and only
+// done to pay, so a pix key is taken as available here and checked by Pay.
+func (r *Router) Route(ctx context.Context, recipient RouteRecipient, amount Money) (*RouteDecision, error) {
+	return r.route(ctx, recipient, amount, false)
+}
+
+func (r *Router) route(ctx context.Context, recipient RouteRecipient, amount Money, lookup bool) (*RouteDecision, error) {
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"policy":     r.config.Policy,
+	}
+
+	if amount.Cents <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	if recipient.PixKey == nil && (recipient.BankCode == "" || recipient.Branch == "" || recipient.Account == "") {
+		logrus.WithFields(fields).Error("recipient without pix key or account data")
+		return nil, ErrInvalidRouteRecipient
+	}
+
+	decision := &RouteDecision{Options: r.options()}
+
+	for i := range decision.Options {
+		option := &decision.Options[i]
+		option.Available, option.Reason = r.evaluate(ctx, decision, option.Rail, recipient, lookup)
+
+		if option.Available {
+			decision.Rail = option.Rail
+			decision.Reason = fmt.Sprintf("%s chosen by %s: %s", option.Rail, r.config.Policy, option.Reason)
+			logrus.WithFields(fields).WithField("rail", option.Rail).Info(decision.Reason)
+			return decision, nil
+		}
+	}
+
+	logrus.WithFields(fields).Error("no rail available to the recipient")
+
+	return decision, ErrNoRouteAvailable
+}
+
+// Pay routes and pays the recipient, looking up the pix key at DICT. A failed payment
+// is not retried on another rail, since it may have been accepted.
+func (r *Router) Pay(ctx context.Context, recipient RouteRecipient, amount Money) (*RoutePayment, error) {
+	decision, err := r.route(ctx, recipient, amount, true)
+	if err != nil {
+		return &RoutePayment{Decision: decision}, err
+	}
+
+	payment := &RoutePayment{Decision: decision}
+
+	switch decision.Rail {
+	case RailInternal, RailTED:
+		request := TransfersRequest{
+			Amount:      amount.Cents,
+			Sender:      r.config.Sender,
+			Description: recipient.Description,
+			Recipient: RecipientRequest{
+				TransfersAccountType: recipient.AccountType,
+				BankCode:             recipient.BankCode,
+				Branch:               recipient.Branch,
+				Account:              recipient.Account,
+				Document:             recipient.Document,
+				Name:                 recipient.Name,
+			},
+		}
+
+		if request.Recipient.TransfersAccountType == "" {
+			request.Recipient.TransfersAccountType = CheckingAccount
+		}
+
+		correlationID := GetRequestID(ctx)
+		if correlationID == "" {
+			correlationID = uuid.New().String()
+		}
+
+		if decision.Rail == RailInternal {
+			payment.Transfer, err = r.transfers.CreateInternalTransfer(ctx, correlationID, request)
+		} else {
+			payment.Transfer, err = r.transfers.CreateExternalTransfer(ctx, correlationID, request)
+		}
+
+		if err != nil {
+			return payment, err
+		}
+		payment.AuthenticationCode = payment.Transfer.AuthenticationCode
+
+	case RailPixKey, RailPixAccount:
+		request := &PixCashOutRequest{
+			Sender: PixCashOutSenderRequest{
+				Account:        PixCashOutAccountRequest{Branch: r.config.Sender.Branch, Number: r.config.Sender.Account},
+				Bank:           PixCashOutBankRequest{Ispb: BanklyISPB},
+				DocumentNumber: r.config.Sender.Document,
+				Name:           r.config.Sender.Name,
+			},
+			Description: recipient.Description,
+		}
+		request.SetAmount(amount)
+
+		if decision.Rail == RailPixKey {
+			request.InitializationType = Key
+			request.EndToEndID = decision.entry.EndToEndID
+			request.Recipient.DocumentNumber = decision.entry.Holder.Document.Value
+			request.Recipient.Name = decision.entry.Holder.Name
+		} else {
+			request.InitializationType = Manual
+			request.Recipient = PixCashOutRecipientRequest{
+				Account: PixCashOutAccountRequest{
+					Branch: recipient.Branch,
+					Number: recipient.Account,
+					Type:   recipient.AccountType,
+				},
+				Bank:           PixCashOutBankRequest{Ispb: decision.bank.ISPB},
+				DocumentNumber: recipient.Document,
+				Name:           recipient.Name,
+			}
+		}
+
+		payment.PixCashOut, err = r.pix.CashOut(ctx, request)
+		if err != nil {
+			return payment, err
+		}
+		payment.AuthenticationCode = payment.PixCashOut.AuthenticationCode
+	}
+
+	return payment, nil
+}
+
+// options returns the allowed rails in the order of the policy
+func (r *Router) options() []RouteOption {
+	rails := r.config.Rails
+	if rails == nil {
+		rails = []PaymentRail{RailInternal, RailPixKey, RailPixAccount, RailTED}
+	}
+
+	options := []RouteOption{}
+	for _, rail := range rails {
+		cost, ok := r.config.Costs[rail]
+		if !ok {
+			cost = NewMoney(0)
+		}
+		options = append(options, RouteOption{Rail: rail, Cost: cost})
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		if r.config.Policy == RouteByCost && options[i].Cost.Cents != options[j].Cost.Cents {
+			return options[i].Cost.Cents < options[j].Cost.Cents
+		}
+		return speedRank[options[i].Rail] < speedRank[options[j].Rail]
+	})
+
+	return options
+}
+
+// evaluate returns whether the rail can pay the recipient now and why. The pix key is
+// looked up at DICT only with lookup.
+func (r *Router) evaluate(ctx context.Context, decision *RouteDecision, rail PaymentRail,
+	recipient RouteRecipient, lookup bool) (bool, string) {
+
+	hasAccount := recipient.BankCode != "" && recipient.Branch != "" && recipient.Account != ""
+
+	switch rail {
+	case RailInternal:
+		if !hasAccount || recipient.BankCode != InternalBankCode {
+			return false, "recipient account is not at Bankly"
+		}
+		return true, "recipient account is at Bankly"
+
+	case RailPixKey:
+		if recipient.PixKey == nil {
+			return false, "recipient without pix key"
+		}
+
+		if !lookup {
+			return true, "recipient with pix key, looked up at DICT to pay"
+		}
+
+		entry, err := r.pix.GetAddressKey(ctx, recipient.PixKey.Value, OnlyDigits(r.config.Sender.Document))
+		if err != nil {
+			return false, "pix key lookup failed: " + err.Error()
+		}
+
+		decision.entry = entry
+		return true, "pix key found at DICT"
+
+	case RailPixAccount:
+		if !hasAccount {
+			return false, "recipient without account data"
+		}
+
+		bank, err := r.bank.GetByID(ctx, recipient.BankCode)
+		if err != nil {
+			return false, "bank lookup failed: " + err.Error()
+		}
+
+		if !isPixParticipant(bank) {
+			return false, "recipient bank is not a pix participant"
+		}
+
+		decision.bank = bank
+		return true, "recipient bank is a pix participant"
+
+	case RailTED:
+		if !hasAccount {
+			return false, "recipient without account data"
+		}
+
+		now := r.config.Now()
+		if !calendar.IsWithinWindow(r.config.Calendar, *r.config.TEDWindow, now) {
+			next := calendar.NextWindowOpening(r.config.Calendar, *r.config.TEDWindow, now)
+			return false, "out of the TED window, it opens at " + next.Format(time.RFC3339)
+		}
+		return true, "within the TED window " + r.config.TEDWindow.String()
+	}
+
+	return false, "unknown rail"
+}
+
+// isPixParticipant reports the banks at pix, directly at SPI or through another participant
+func isPixParticipant(bank *BankDataResponse) bool {
+	if bank.ISPB == "" {
+		return false
+	}
+
+	if bank.IsSPIDirect {
+		return true
+	}
+
+	for _, product := range bank.Products {
+		switch strings.ToUpper(product) {
+		case "PIX", "SPI":
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,201 @@
+package bankly_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/contbank/bankly-sdk/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type RouterTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	ctx        context.Context
+	httpClient *mocks.BanklyHttpClient
+	banks      map[string]bankly.BankDataResponse
+	transfers  []string
+	now        time.Time
+	config     bankly.RouterConfig
+	router     func() *bankly.Router
+}
+
+func TestRouterTestSuite(t *testing.T) {
+	suite.Run(t, new(RouterTestSuite))
+}
+
+func (s *RouterTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.httpClient = mocks.NewBanklyHttpClient(s.T())
+	s.httpClient.EXPECT().SetErrorHandler(mock.Anything).Return()
+	s.banks = map[string]bankly.BankDataResponse{}
+	s.transfers = []string{}
+	s.now = date(2026, time.October, 19).Add(10 * time.Hour)
+
+	client, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		if strings.HasPrefix(req.URL.Path, "/banklist/") {
+			bank, ok := s.banks[strings.TrimPrefix(req.URL.Path, "/banklist/")]
+			if !ok {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: jsonBody(nil)}
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(bank)}
+		}
+
+		var model bankly.TransfersRequest
+		json.NewDecoder(req.Body).Decode(&model)
+		s.transfers = append(s.transfers, model.Recipient.BankCode)
+
+		return &http.Response{StatusCode: http.StatusAccepted, Body: jsonBody(bankly.TransferByCodeResponse{
+			AuthenticationCode: "ted-code",
+		})}
+	})
+
+	pix := bankly.NewPix(s.httpClient)
+	transfers := bankly.NewTransfers(client, *session)
+	bank := bankly.NewBank(client, *session)
+
+	s.config = bankly.RouterConfig{
+		Sender: bankly.SenderRequest{Branch: "0001", Account: "207802", Document: "16246241620", Name: "Sender"},
+		Now:    func() time.Time { return s.now },
+	}
+	s.router = func() *bankly.Router { return bankly.NewRouter(transfers, pix, bank, s.config) }
+}
+
+func (s *RouterTestSuite) TestPay_Internal() {
+	payment, err := s.router().Pay(s.ctx, bankly.RouteRecipient{
+		Name: "Recipient", Document: "76385230056", BankCode: "332", Branch: "0001", Account: "189162",
+		PixKey: &bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "recipient@example.com"},
+	}, bankly.NewMoney(1000))
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.RailInternal, payment.Decision.Rail)
+	s.assert.Equal("ted-code", payment.AuthenticationCode)
+	s.assert.Equal([]string{"332"}, s.transfers)
+}
+
+func (s *RouterTestSuite) TestPay_PixKey() {
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/recipient@example.com", mock.Anything, mock.Anything).
+		Return(&http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.PixAddressKeyResponse{
+			EndToEndID: "E1314008820261019130012345abcdeF",
+			Holder:     bankly.PixHolder{Name: "Recipient", Document: bankly.PixTypeValue{Value: "76385230056"}},
+		})}, nil).Once()
+
+	s.httpClient.EXPECT().Post(mock.Anything, "pix/cash-out", mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, url string, body interface{}, header *http.Header) {
+			request := body.(*bankly.PixCashOutRequest)
+			s.assert.Equal(bankly.Key, request.InitializationType)
+			s.assert.Equal("E1314008820261019130012345abcdeF", request.EndToEndID)
+			s.assert.Equal(10.05, request.Amount)
+		}).
+		Return(&http.Response{StatusCode: http.StatusAccepted, Body: jsonBody(bankly.PixCashOutResponse{
+			AuthenticationCode: "pix-code",
+		})}, nil).Once()
+
+	payment, err := s.router().Pay(s.ctx, bankly.RouteRecipient{
+		PixKey: &bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "recipient@example.com"},
+	}, bankly.NewMoney(1005))
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.RailPixKey, payment.Decision.Rail)
+	s.assert.Equal("pix-code", payment.AuthenticationCode)
+	s.assert.Equal("recipient account is not at Bankly", payment.Decision.Options[0].Reason)
+}
+
+func (s *RouterTestSuite) TestPay_NoRailAvailable() {
+	s.now = date(2026, time.October, 19).Add(18 * time.Hour)
+	s.banks["001"] = bankly.BankDataResponse{Code: "001", ISPB: "00000000", IsSPIDirect: false}
+
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/recipient@example.com", mock.Anything, mock.Anything).
+		Return(nil, bankly.ErrEntryNotFound).Once()
+
+	payment, err := s.router().Pay(s.ctx, bankly.RouteRecipient{
+		BankCode: "001", Branch: "0001", Account: "12345-5",
+		PixKey: &bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "recipient@example.com"},
+	}, bankly.NewMoney(1000))
+
+	s.assert.Equal(bankly.ErrNoRouteAvailable, err)
+	s.assert.Len(payment.Decision.Options, 4)
+	for _, option := range payment.Decision.Options {
+		s.assert.False(option.Available)
+	}
+	s.assert.Equal("recipient bank is not a pix participant", payment.Decision.Options[2].Reason)
+	s.assert.Contains(payment.Decision.Options[3].Reason, "out of the TED window")
+}
+
+func (s *RouterTestSuite) TestRoute_PixKeyNotLookedUp() {
+	s.banks["001"] = bankly.BankDataResponse{Code: "001", ISPB: "00000000", IsSPIDirect: true}
+
+	decision, err := s.router().Route(s.ctx, bankly.RouteRecipient{
+		BankCode: "001", Branch: "0001", Account: "12345-5",
+		PixKey: &bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "recipient@example.com"},
+	}, bankly.NewMoney(1000))
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.RailPixKey, decision.Rail)
+}
+
+func (s *RouterTestSuite) TestPay_PixAccountIndirectParticipant() {
+	s.config.Sender.Document = "162.462.416-20"
+	s.banks["001"] = bankly.BankDataResponse{Code: "001", ISPB: "00000000", Products: []string{"PIX"}}
+
+	s.httpClient.EXPECT().Get(mock.Anything, "pix/entries/recipient@example.com", mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, url string, query map[string]string, header *http.Header) {
+			s.assert.Equal("16246241620", header.Get("x-bkly-pix-user-id"))
+		}).
+		Return(nil, bankly.ErrEntryNotFound).Once()
+
+	s.httpClient.EXPECT().Post(mock.Anything, "pix/cash-out", mock.Anything, mock.Anything).
+		Run(func(ctx context.Context, url string, body interface{}, header *http.Header) {
+			request := body.(*bankly.PixCashOutRequest)
+			s.assert.Equal(bankly.Manual, request.InitializationType)
+			s.assert.Equal(bankly.SavingsAccount, request.Recipient.Account.Type)
+		}).
+		Return(&http.Response{StatusCode: http.StatusAccepted, Body: jsonBody(bankly.PixCashOutResponse{
+			AuthenticationCode: "pix-code",
+		})}, nil).Once()
+
+	payment, err := s.router().Pay(s.ctx, bankly.RouteRecipient{
+		Name: "Recipient", Document: "76385230056", BankCode: "001", Branch: "0001", Account: "12345-5",
+		AccountType: bankly.SavingsAccount,
+		PixKey:      &bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "recipient@example.com"},
+	}, bankly.NewMoney(1000))
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.RailPixAccount, payment.Decision.Rail)
+	s.assert.Equal("recipient bank is a pix participant", payment.Decision.Options[2].Reason)
+}
+
+func (s *RouterTestSuite) TestRoute_ByCost() {
+	s.config.Policy = bankly.RouteByCost
+	s.config.Costs = map[bankly.PaymentRail]bankly.Money{
+		bankly.RailPixKey:     bankly.NewMoney(100),
+		bankly.RailPixAccount: bankly.NewMoney(100),
+	}
+	s.config.Rails = []bankly.PaymentRail{bankly.RailPixKey, bankly.RailPixAccount, bankly.RailTED}
+
+	decision, err := s.router().Route(s.ctx, bankly.RouteRecipient{
+		BankCode: "001", Branch: "0001", Account: "12345-5",
+		PixKey: &bankly.PixTypeValue{Type: bankly.PixEMAIL, Value: "recipient@example.com"},
+	}, bankly.NewMoney(1000))
+
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.RailTED, decision.Rail)
+	s.assert.Equal(bankly.RailTED, decision.Options[0].Rail)
+	s.assert.Contains(decision.Reason, "COST")
+}
+
+func (s *RouterTestSuite) TestRoute_InvalidRecipient() {
+	_, err := s.router().Route(s.ctx, bankly.RouteRecipient{Name: "Recipient"}, bankly.NewMoney(1000))
+	s.assert.Equal(bankly.ErrInvalidRouteRecipient, err)
+
+	_, err = s.router().Route(s.ctx, bankly.RouteRecipient{BankCode: "332", Branch: "0001", Account: "189162"}, bankly.NewMoney(0))
+	s.assert.Equal(bankly.ErrInvalidAmount, err)
+}
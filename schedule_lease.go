@@ -0,0 +1,213 @@
+package bankly
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ScheduleLease is the lease of a worker instance over a scheduled item, shared by the
+// pix schedules, the boleto subscriptions and the scheduled payments
+type ScheduleLease struct {
+	LockedBy    string    `bson:"lockedBy,omitempty" json:"-"`
+	LockedUntil time.Time `bson:"lockedUntil,omitempty" json:"-"`
+}
+
+// leased returns true when any owner holds the lease at now
+func (l ScheduleLease) leased(now time.Time) bool {
+	return l.LockedBy != "" && l.LockedUntil.After(now)
+}
+
+// leasedByOther returns true when another owner holds the lease at now
+func (l ScheduleLease) leasedByOther(owner string, now time.Time) bool {
+	return l.LockedBy != owner && l.leased(now)
+}
+
+// heldBy returns true when the owner still holds the lease at now
+func (l ScheduleLease) heldBy(owner string, now time.Time) bool {
+	return l.LockedBy == owner && l.LockedUntil.After(now)
+}
+
+func (l *ScheduleLease) lock(owner string, now time.Time, lease time.Duration) {
+	l.LockedBy = owner
+	l.LockedUntil = now.Add(lease)
+}
+
+func (l *ScheduleLease) unlock() {
+	l.LockedBy = ""
+	l.LockedUntil = time.Time{}
+}
+
+// scheduleDefaults fills the empty settings shared by the schedulers
+func scheduleDefaults(owner *string, lease *time.Duration, maxAttempts *int, retryDelay *time.Duration,
+	batchSize *int) {
+
+	if *owner == "" {
+		*owner = uuid.New().String()
+	}
+
+	if *lease <= 0 {
+		*lease = 2 * time.Minute
+	}
+
+	if *maxAttempts <= 0 {
+		*maxAttempts = 3
+	}
+
+	if *retryDelay <= 0 {
+		*retryDelay = 10 * time.Minute
+	}
+
+	if *batchSize <= 0 {
+		*batchSize = 50
+	}
+}
+
+// mongoLeaseFree matches the documents not leased at now or leased by one of the owners
+func mongoLeaseFree(now time.Time, owners ...string) bson.A {
+	lockedBy := bson.A{nil, ""}
+	for _, owner := range owners {
+		lockedBy = append(lockedBy, owner)
+	}
+
+	return bson.A{
+		bson.M{"lockedBy": bson.M{"$in": lockedBy}},
+		bson.M{"lockedUntil": bson.M{"$lt": now}},
+	}
+}
+
+// mongoLeaseHeld matches the document still leased by the owner at now
+func mongoLeaseHeld(id string, owner string, now time.Time) bson.M {
+	return bson.M{"_id": id, "lockedBy": owner, "lockedUntil": bson.M{"$gt": now}}
+}
+
+// mongoLeaseLock leases the document to the owner
+func mongoLeaseLock(owner string, now time.Time, lease time.Duration) bson.M {
+	return bson.M{"$set": bson.M{"lockedBy": owner, "lockedUntil": now.Add(lease)}}
+}
+
+// mongoFindOneAndUpdate decodes the updated document, it returns false when nothing matched the filter
+func mongoFindOneAndUpdate(ctx context.Context, collection *mongo.Collection, filter interface{},
+	update interface{}, document interface{}) (bool, error) {
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(document)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// mongoReplaceLeased replaces the document matched by the lease filter, it returns lost when
+// the lease is not held anymore
+func mongoReplaceLeased(ctx context.Context, collection *mongo.Collection, filter interface{},
+	document interface{}, lost error) error {
+
+	result, err := collection.ReplaceOne(ctx, filter, document)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return lost
+	}
+
+	return nil
+}
+
+// mongoFind decodes the documents into the pointer to a slice
+func mongoFind(ctx context.Context, collection *mongo.Collection, filter interface{},
+	opts *options.FindOptions, response interface{}) error {
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+
+	return cursor.All(ctx, response)
+}
+
+// leasedItem is a due item run by runLeased. The scheduler keeps the acquired item at the closures.
+type leasedItem struct {
+	// ID of the item at the logs
+	ID string
+	// Acquire leases the item at now, it returns false when another instance holds it
+	// or it is no longer due
+	Acquire func(now time.Time) (bool, error)
+	// Run executes the acquired item, an error leaves the item to its lease without releasing it
+	Run func(started time.Time) error
+	// Release saves the item and releases its lease
+	Release func() error
+}
+
+// leasedRun names the items of a scheduler at the logs
+type leasedRun struct {
+	// Field of the item id
+	Field string
+	// Kept is logged when the item is left to its lease
+	Kept string
+	// Released is logged when the lease is not released
+	Released string
+}
+
+// runLeased runs the due items in order, each leased to one instance at a time, and returns how
+// many were processed by this instance. It stops at the errors of the store.
+func runLeased(run leasedRun, now time.Time, items []leasedItem) (int, error) {
+	started := time.Now()
+
+	processed := 0
+	for _, item := range items {
+		// the leases follow the time spent since the start of the run
+		acquired, err := item.Acquire(now.Add(time.Since(started)))
+		if err != nil {
+			return processed, err
+		}
+
+		if !acquired {
+			continue
+		}
+
+		if err := item.Run(started); err != nil {
+			logrus.WithField(run.Field, item.ID).WithError(err).Error(run.Kept)
+			continue
+		}
+
+		if err := item.Release(); err != nil {
+			logrus.WithField(run.Field, item.ID).WithError(err).Error(run.Released)
+			return processed, err
+		}
+
+		processed++
+	}
+
+	return processed, nil
+}
+
+// runEvery calls runOnce at each interval until the context is done
+func runEvery(ctx context.Context, interval time.Duration, owner string, message string,
+	runOnce func(ctx context.Context, now time.Time) (int, error)) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := runOnce(ctx, time.Now().UTC()); err != nil {
+			logrus.WithField("owner", owner).WithError(err).Error(message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
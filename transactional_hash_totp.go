@@ -35,6 +35,17 @@ func (p *TransactionalHashTOTP) TransactionalHash(ctx context.Context, transacti
 		"identifier": identifier,
 		"object":     transactional,
 	}
+
+	addressingKey := &transactional.Data.AddressingKey
+	if addressingKey.Type != "" {
+		value, err := NormalizePixKey(addressingKey.Type, addressingKey.Value)
+		if err != nil {
+			logrus.WithFields(fields).WithError(err).Error("invalid pix key")
+			return nil, err
+		}
+		addressingKey.Value = value
+	}
+
 	url := "/totp"
 
 	header := http.Header{}
@@ -10,6 +10,7 @@ import (
 	"path"
 	"strconv"
 
+	"github.com/contbank/bankly-sdk/bankaccount"
 	"github.com/contbank/grok"
 	"github.com/sirupsen/logrus"
 )
@@ -19,6 +20,9 @@ type Transfers struct {
 	session        Session
 	httpClient     *http.Client
 	authentication *Authentication
+	calendarPolicy *CalendarPolicy
+	// validateAccounts checks the recipient check digits offline before sending transfers
+	validateAccounts bool
 }
 
 //NewTransfers ...
@@ -30,6 +34,18 @@ func NewTransfers(httpClient *http.Client, session Session) *Transfers {
 	}
 }
 
+// SetCalendarPolicy checks the TED window before sending transfers to other banks.
+func (t *Transfers) SetCalendarPolicy(policy *CalendarPolicy) {
+	t.calendarPolicy = policy
+}
+
+// SetAccountValidation checks the recipient branch and account check digits offline, for the
+// banks with a rule at the bankaccount package, before sending transfers. The recipient is
+// sent as informed, banks without a rule are left to Bankly.
+func (t *Transfers) SetAccountValidation(enabled bool) {
+	t.validateAccounts = enabled
+}
+
 // CreateTransfer ...
 func (t *Transfers) CreateTransfer(ctx context.Context, correlationID string, model TransfersRequest) (*TransferByCodeResponse, error) {
 	logrus.
@@ -79,6 +95,23 @@ func (t *Transfers) createTransferOperation(ctx context.Context, requestID strin
 		return nil, grok.FromValidationErros(err)
 	}
 
+	if t.validateAccounts {
+		if err := validateRecipientAccount(model.Recipient); err != nil {
+			logrus.
+				WithFields(fields).
+				WithError(err).
+				Error("error validating recipient bank account")
+			return nil, err
+		}
+	}
+
+	// internal transfers are not bound to the TED window
+	if t.calendarPolicy != nil && model.Recipient.BankCode != InternalBankCode {
+		if err := t.calendarPolicy.Check(ctx, model); err != nil {
+			return nil, err
+		}
+	}
+
 	endpoint, err := t.getTransferAPIEndpoint(requestID, nil, nil, nil, nil)
 	if err != nil {
 		logrus.
@@ -174,6 +207,18 @@ func (t *Transfers) createTransferOperation(ctx context.Context, requestID strin
 	return nil, ErrDefaultTransfers
 }
 
+// validateRecipientAccount checks the branch and account check digits offline
+func validateRecipientAccount(recipient RecipientRequest) error {
+	err := bankaccount.Validate(recipient.BankCode, recipient.Branch, recipient.Account)
+	if err == bankaccount.ErrInvalidBranch {
+		return ErrInvalidRecipientBranch
+	} else if err != nil {
+		return ErrInvalidRecipientAccount
+	}
+
+	return nil
+}
+
 // FindTransfers ...
 func (t *Transfers) FindTransfers(ctx context.Context, requestID *string,
 	branch *string, account *string, pageSize *int, nextPage *string) (*TransfersResponse, error) {
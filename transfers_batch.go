@@ -0,0 +1,338 @@
+package bankly
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/contbank/grok"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// transferBatchNamespace namespaces the correlation ids of the batch items
+var transferBatchNamespace = uuid.MustParse("6f1c3a52-8e0b-4d7e-9a43-2b5f0c1d9e87")
+
+// DefaultTransferBatchConcurrency ...
+const DefaultTransferBatchConcurrency = 5
+
+// TransferBatchItemStatus ...
+type TransferBatchItemStatus string
+
+const (
+	// TransferBatchCreated the transfer was accepted by Bankly
+	TransferBatchCreated TransferBatchItemStatus = "CREATED"
+	// TransferBatchRejected the transfer was refused and may be fixed and sent again
+	TransferBatchRejected TransferBatchItemStatus = "REJECTED"
+	// TransferBatchDeferred the transfer was deferred by the calendar policy
+	TransferBatchDeferred TransferBatchItemStatus = "DEFERRED"
+	// TransferBatchUnknown the outcome is not known and must be reconciled
+	TransferBatchUnknown TransferBatchItemStatus = "UNKNOWN"
+	// TransferBatchSkipped the transfer was not sent because the run stopped
+	TransferBatchSkipped TransferBatchItemStatus = "SKIPPED"
+)
+
+// TransferBatchItem ...
+type TransferBatchItem struct {
+	// ID identifies the item inside the batch and must be stable between runs
+	ID      string
+	Request TransfersRequest
+}
+
+// TransferBatchOptions ...
+type TransferBatchOptions struct {
+	// BatchID namespaces the correlation ids, the same batch id and item id always
+	// produce the same correlation id
+	BatchID string
+	// Concurrency of the transfers, zero uses DefaultTransferBatchConcurrency
+	Concurrency int
+	// MaxFailures stops the run when the rejected and unknown items pass it, zero never stops
+	MaxFailures int
+	// Balance checks that each sender account covers its items before sending anything
+	Balance BalanceReader
+	// Previous is the report of an earlier run of the batch. Created items are kept
+	// and not sent again, unknown items are kept unless RetryUnknown is set.
+	Previous *TransferBatchReport
+	// RetryUnknown sends the unknown items of the previous run again with the same correlation id
+	RetryUnknown bool
+	// Store keeps the outcome of each item, saved as unknown before sending it. The results
+	// saved for the batch replace the ones of Previous, so a run repeated without the previous
+	// report, even after a crash, never sends an accepted transfer twice.
+	Store TransferBatchStore
+}
+
+// TransferBatchResult ...
+type TransferBatchResult struct {
+	ItemID        string
+	CorrelationID string
+	Status        TransferBatchItemStatus
+	Transfer      *TransferByCodeResponse
+	Error         error
+}
+
+// TransferBatchReport has the results in the same order of the items
+type TransferBatchReport struct {
+	BatchID  string
+	Results  []*TransferBatchResult
+	Created  int
+	Rejected int
+	Deferred int
+	Unknown  int
+	Skipped  int
+	// Stopped is true when the run stopped because of MaxFailures
+	Stopped bool
+}
+
+// Pending returns the results that must be reconciled before a new run
+func (r *TransferBatchReport) Pending() []*TransferBatchResult {
+	response := []*TransferBatchResult{}
+	for _, result := range r.Results {
+		if result.Status == TransferBatchUnknown {
+			response = append(response, result)
+		}
+	}
+	return response
+}
+
+// TransferBatchCorrelationID returns the correlation id of the item in the batch
+func TransferBatchCorrelationID(batchID, itemID string) string {
+	return uuid.NewSHA1(transferBatchNamespace, []byte(batchID+"/"+itemID)).String()
+}
+
+// CreateBatch creates the transfers of the items with bounded concurrency.
+// Each item is sent with a deterministic correlation id, so a run repeated with
+// the previous report or the same store never sends an accepted transfer twice.
+func (t *Transfers) CreateBatch(ctx context.Context, items []TransferBatchItem,
+	options TransferBatchOptions) (*TransferBatchReport, error) {
+
+	fields := logrus.Fields{
+		"request_id": GetRequestID(ctx),
+		"batch_id":   options.BatchID,
+		"items":      len(items),
+	}
+
+	if err := validateTransferBatch(items); err != nil {
+		logrus.WithFields(fields).WithError(err).Error("error validating transfer batch")
+		return nil, err
+	}
+
+	if options.Concurrency <= 0 {
+		options.Concurrency = DefaultTransferBatchConcurrency
+	}
+
+	previous := map[string]*TransferBatchResult{}
+	if options.Previous != nil {
+		for _, result := range options.Previous.Results {
+			previous[result.ItemID] = result
+		}
+	}
+
+	if options.Store != nil {
+		saved, err := options.Store.ListByBatch(ctx, options.BatchID)
+		if err != nil {
+			logrus.WithFields(fields).WithError(err).Error("error listing transfer batch results")
+			return nil, err
+		}
+		for _, result := range saved {
+			previous[result.ItemID] = result
+		}
+	}
+
+	report := &TransferBatchReport{
+		BatchID: options.BatchID,
+		Results: make([]*TransferBatchResult, len(items)),
+	}
+
+	pending := []int{}
+	for i, item := range items {
+		result, ok := previous[item.ID]
+		if ok && (result.Status == TransferBatchCreated ||
+			(result.Status == TransferBatchUnknown && !options.RetryUnknown)) {
+			kept := *result
+			report.Results[i] = &kept
+			continue
+		}
+
+		report.Results[i] = &TransferBatchResult{
+			ItemID:        item.ID,
+			CorrelationID: TransferBatchCorrelationID(options.BatchID, item.ID),
+			Status:        TransferBatchSkipped,
+		}
+		pending = append(pending, i)
+	}
+
+	if options.Balance != nil {
+		if err := checkTransferBatchBalance(ctx, options.Balance, items, pending); err != nil {
+			logrus.WithFields(fields).WithError(err).Error("error checking transfer batch balance")
+			return nil, err
+		}
+	}
+
+	var (
+		mutex    sync.Mutex
+		failures int
+		wg       sync.WaitGroup
+	)
+
+	jobs := make(chan int)
+
+	for w := 0; w < options.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				mutex.Lock()
+				stopped := options.MaxFailures > 0 && failures > options.MaxFailures
+				mutex.Unlock()
+
+				// the item stays skipped when the threshold passed while it was queued
+				if stopped || ctx.Err() != nil {
+					continue
+				}
+
+				result := report.Results[i]
+
+				// the item is saved as unknown first, a crash while sending it keeps it from a new run
+				if options.Store != nil {
+					sending := TransferBatchResult{ItemID: result.ItemID, CorrelationID: result.CorrelationID, Status: TransferBatchUnknown}
+					if err := options.Store.Save(ctx, options.BatchID, &sending); err != nil {
+						logrus.WithFields(fields).WithField("item_id", result.ItemID).WithError(err).
+							Error("error saving transfer batch item before sending")
+						mutex.Lock()
+						result.Error = err
+						mutex.Unlock()
+						continue
+					}
+				}
+
+				transfer, err := t.createTransferOperation(ctx, result.CorrelationID, items[i].Request)
+
+				mutex.Lock()
+				result.Transfer = transfer
+				result.Error = err
+				result.Status = transferBatchStatus(err)
+				if result.Status == TransferBatchRejected || result.Status == TransferBatchUnknown {
+					failures++
+				}
+				outcome := *result
+				mutex.Unlock()
+
+				// the item stays unknown at the store when its outcome is not saved
+				if options.Store != nil {
+					if err := options.Store.Save(ctx, options.BatchID, &outcome); err != nil {
+						logrus.WithFields(fields).WithField("item_id", result.ItemID).WithError(err).
+							Error("error saving transfer batch item outcome")
+					}
+				}
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		mutex.Lock()
+		stopped := options.MaxFailures > 0 && failures > options.MaxFailures
+		mutex.Unlock()
+
+		if stopped || ctx.Err() != nil {
+			break
+		}
+
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	report.Stopped = options.MaxFailures > 0 && failures > options.MaxFailures
+
+	for _, result := range report.Results {
+		switch result.Status {
+		case TransferBatchCreated:
+			report.Created++
+		case TransferBatchRejected:
+			report.Rejected++
+		case TransferBatchDeferred:
+			report.Deferred++
+		case TransferBatchUnknown:
+			report.Unknown++
+		case TransferBatchSkipped:
+			report.Skipped++
+		}
+	}
+
+	logrus.WithFields(fields).
+		WithField("created", report.Created).
+		WithField("rejected", report.Rejected).
+		WithField("unknown", report.Unknown).
+		WithField("skipped", report.Skipped).
+		Info("transfer batch finished")
+
+	return report, nil
+}
+
+// transferBatchStatus tells apart the errors answered by Bankly from the ones
+// where the transfer may have been created.
+func transferBatchStatus(err error) TransferBatchItemStatus {
+	if err == nil {
+		return TransferBatchCreated
+	}
+
+	if err == ErrOperationDeferred {
+		return TransferBatchDeferred
+	}
+
+	var grokErr *grok.Error
+	if errors.As(err, &grokErr) && err != ErrDefaultTransfers && grokErr.Code < http.StatusInternalServerError {
+		return TransferBatchRejected
+	}
+
+	return TransferBatchUnknown
+}
+
+func validateTransferBatch(items []TransferBatchItem) error {
+	if len(items) == 0 {
+		return ErrInvalidTransferBatch
+	}
+
+	ids := map[string]bool{}
+	for _, item := range items {
+		if item.ID == "" || ids[item.ID] {
+			return ErrInvalidTransferBatch
+		}
+		ids[item.ID] = true
+	}
+
+	return nil
+}
+
+func checkTransferBatchBalance(ctx context.Context, balance BalanceReader, items []TransferBatchItem, pending []int) error {
+	totals := map[string]int64{}
+	accounts := []string{}
+
+	for _, i := range pending {
+		account := items[i].Request.Sender.Account
+		if _, ok := totals[account]; !ok {
+			accounts = append(accounts, account)
+		}
+		totals[account] += items[i].Request.Amount
+	}
+
+	for _, account := range accounts {
+		response, err := balance.Balance(ctx, account)
+		if err != nil {
+			return err
+		}
+
+		available := int64(0)
+		if response != nil && response.Balance != nil {
+			available = response.Balance.Available.Money().Cents
+		}
+
+		if available < totals[account] {
+			return ErrInsufficientBalance
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package bankly
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TransferBatchStore keeps the outcomes of the items of the transfer batches between runs
+type TransferBatchStore interface {
+	// Save creates or replaces the result of the item in the batch
+	Save(ctx context.Context, batchID string, result *TransferBatchResult) error
+	// ListByBatch returns the results saved for the batch
+	ListByBatch(ctx context.Context, batchID string) ([]*TransferBatchResult, error)
+}
+
+// memoryTransferBatchStore ...
+type memoryTransferBatchStore struct {
+	mutex   sync.Mutex
+	batches map[string]map[string]*TransferBatchResult
+}
+
+// NewMemoryTransferBatchStore returns a store for a single instance or tests, the outcomes are lost on restarts.
+func NewMemoryTransferBatchStore() TransferBatchStore {
+	return &memoryTransferBatchStore{batches: map[string]map[string]*TransferBatchResult{}}
+}
+
+func (m *memoryTransferBatchStore) Save(ctx context.Context, batchID string, result *TransferBatchResult) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.batches[batchID] == nil {
+		m.batches[batchID] = map[string]*TransferBatchResult{}
+	}
+
+	saved := *result
+	m.batches[batchID][result.ItemID] = &saved
+	return nil
+}
+
+func (m *memoryTransferBatchStore) ListByBatch(ctx context.Context, batchID string) ([]*TransferBatchResult, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	response := []*TransferBatchResult{}
+	for _, result := range m.batches[batchID] {
+		copied := *result
+		response = append(response, &copied)
+	}
+	return response, nil
+}
+
+// transferBatchDocument is the result saved at Mongo, the error is kept as its message
+type transferBatchDocument struct {
+	BatchID       string                  `bson:"batchId"`
+	ItemID        string                  `bson:"itemId"`
+	CorrelationID string                  `bson:"correlationId"`
+	Status        TransferBatchItemStatus `bson:"status"`
+	Transfer      *TransferByCodeResponse `bson:"transfer,omitempty"`
+	Error         string                  `bson:"error,omitempty"`
+}
+
+// mongoTransferBatchStore ...
+type mongoTransferBatchStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoTransferBatchStore returns a store shared by many instances.
+// The collection should have a unique index by batchId and itemId.
+func NewMongoTransferBatchStore(collection *mongo.Collection) TransferBatchStore {
+	return &mongoTransferBatchStore{collection: collection}
+}
+
+func (m *mongoTransferBatchStore) Save(ctx context.Context, batchID string, result *TransferBatchResult) error {
+	document := transferBatchDocument{
+		BatchID:       batchID,
+		ItemID:        result.ItemID,
+		CorrelationID: result.CorrelationID,
+		Status:        result.Status,
+		Transfer:      result.Transfer,
+	}
+	if result.Error != nil {
+		document.Error = result.Error.Error()
+	}
+
+	filter := bson.M{"batchId": batchID, "itemId": result.ItemID}
+	_, err := m.collection.ReplaceOne(ctx, filter, document, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (m *mongoTransferBatchStore) ListByBatch(ctx context.Context, batchID string) ([]*TransferBatchResult, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"batchId": batchID})
+	if err != nil {
+		return nil, err
+	}
+
+	documents := []*transferBatchDocument{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+
+	response := []*TransferBatchResult{}
+	for _, document := range documents {
+		result := &TransferBatchResult{
+			ItemID:        document.ItemID,
+			CorrelationID: document.CorrelationID,
+			Status:        document.Status,
+			Transfer:      document.Transfer,
+		}
+		if document.Error != "" {
+			result.Error = errors.New(document.Error)
+		}
+		response = append(response, result)
+	}
+
+	return response, nil
+}
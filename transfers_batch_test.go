@@ -0,0 +1,233 @@
+package bankly_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TransfersBatchTestSuite struct {
+	suite.Suite
+	assert    *assert.Assertions
+	ctx       context.Context
+	mutex     sync.Mutex
+	answers   map[string]*http.Response
+	sent      map[string]string
+	saved     map[string]bankly.TransferBatchItemStatus
+	store     bankly.TransferBatchStore
+	transfers *bankly.Transfers
+}
+
+func TestTransfersBatchTestSuite(t *testing.T) {
+	suite.Run(t, new(TransfersBatchTestSuite))
+}
+
+func (s *TransfersBatchTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.answers = map[string]*http.Response{}
+	s.sent = map[string]string{}
+	s.saved = map[string]bankly.TransferBatchItemStatus{}
+	s.store = bankly.NewMemoryTransferBatchStore()
+
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		var model bankly.TransfersRequest
+		json.NewDecoder(req.Body).Decode(&model)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		s.sent[model.Description] = req.Header.Get("x-correlation-id")
+
+		// the status saved at the store while the transfer is sent
+		results, _ := s.store.ListByBatch(req.Context(), "batch-1")
+		for _, result := range results {
+			if result.ItemID == model.Description {
+				s.saved[model.Description] = result.Status
+			}
+		}
+
+		if answer, ok := s.answers[model.Description]; ok {
+			return answer
+		}
+
+		return &http.Response{StatusCode: http.StatusAccepted, Body: jsonBody(bankly.TransferByCodeResponse{
+			AuthenticationCode: "auth-" + model.Description,
+		})}
+	})
+
+	s.transfers = bankly.NewTransfers(httpClient, *session)
+}
+
+func (s *TransfersBatchTestSuite) TestCreateBatch() {
+	s.answers["2"] = &http.Response{StatusCode: http.StatusBadRequest, Body: jsonBody(bankly.TransferErrorResponse{
+		Errors: []bankly.KeyValueErrorModel{{Key: "INSUFFICIENT_BALANCE"}},
+	})}
+	s.answers["3"] = &http.Response{StatusCode: http.StatusBadGateway, Body: jsonBody(nil)}
+
+	items := buildTransferBatch("1", "2", "3")
+
+	report, err := s.transfers.CreateBatch(s.ctx, items, bankly.TransferBatchOptions{BatchID: "batch-1"})
+	s.assert.NoError(err)
+	s.assert.Equal(1, report.Created)
+	s.assert.Equal(1, report.Rejected)
+	s.assert.Equal(1, report.Unknown)
+
+	s.assert.Equal("auth-1", report.Results[0].Transfer.AuthenticationCode)
+	s.assert.Equal(bankly.TransferBatchRejected, report.Results[1].Status)
+	s.assert.Equal(bankly.ErrInsufficientBalance, report.Results[1].Error)
+	s.assert.Equal(bankly.TransferBatchUnknown, report.Results[2].Status)
+	s.assert.Len(report.Pending(), 1)
+
+	s.assert.Equal(bankly.TransferBatchCorrelationID("batch-1", "1"), s.sent["1"])
+	s.assert.NotEqual(s.sent["1"], s.sent["2"])
+
+	// the rerun only sends the rejected item
+	delete(s.answers, "2")
+	s.sent = map[string]string{}
+
+	rerun, err := s.transfers.CreateBatch(s.ctx, items, bankly.TransferBatchOptions{BatchID: "batch-1", Previous: report})
+	s.assert.NoError(err)
+	s.assert.Equal(2, rerun.Created)
+	s.assert.Equal(1, rerun.Unknown)
+	s.assert.Len(s.sent, 1)
+	s.assert.Equal(report.Results[1].CorrelationID, s.sent["2"])
+}
+
+func (s *TransfersBatchTestSuite) TestCreateBatch_Store() {
+	s.answers["2"] = &http.Response{StatusCode: http.StatusBadRequest, Body: jsonBody(bankly.TransferErrorResponse{
+		Errors: []bankly.KeyValueErrorModel{{Key: "INSUFFICIENT_BALANCE"}},
+	})}
+	s.answers["3"] = &http.Response{StatusCode: http.StatusBadGateway, Body: jsonBody(nil)}
+
+	items := buildTransferBatch("1", "2", "3")
+	options := bankly.TransferBatchOptions{BatchID: "batch-1", Store: s.store}
+
+	report, err := s.transfers.CreateBatch(s.ctx, items, options)
+	s.assert.NoError(err)
+	s.assert.Equal(1, report.Created)
+	s.assert.Equal(map[string]bankly.TransferBatchItemStatus{
+		"1": bankly.TransferBatchUnknown,
+		"2": bankly.TransferBatchUnknown,
+		"3": bankly.TransferBatchUnknown,
+	}, s.saved)
+
+	// the rerun without the previous report only sends the rejected item
+	delete(s.answers, "2")
+	s.sent = map[string]string{}
+
+	rerun, err := s.transfers.CreateBatch(s.ctx, items, options)
+	s.assert.NoError(err)
+	s.assert.Equal(2, rerun.Created)
+	s.assert.Equal(1, rerun.Unknown)
+	s.assert.Equal(map[string]string{"2": bankly.TransferBatchCorrelationID("batch-1", "2")}, s.sent)
+	s.assert.Equal("auth-1", rerun.Results[0].Transfer.AuthenticationCode)
+}
+
+func (s *TransfersBatchTestSuite) TestCreateBatch_StoreAfterCrash() {
+	// the process stopped while sending the first item
+	s.assert.NoError(s.store.Save(s.ctx, "batch-1", &bankly.TransferBatchResult{
+		ItemID:        "1",
+		CorrelationID: bankly.TransferBatchCorrelationID("batch-1", "1"),
+		Status:        bankly.TransferBatchUnknown,
+	}))
+
+	report, err := s.transfers.CreateBatch(s.ctx, buildTransferBatch("1", "2"), bankly.TransferBatchOptions{
+		BatchID: "batch-1",
+		Store:   s.store,
+	})
+	s.assert.NoError(err)
+	s.assert.Equal(1, report.Created)
+	s.assert.Equal(1, report.Unknown)
+	s.assert.Len(s.sent, 1)
+	s.assert.Contains(s.sent, "2")
+}
+
+func (s *TransfersBatchTestSuite) TestCreateBatch_StoreError() {
+	report, err := s.transfers.CreateBatch(s.ctx, buildTransferBatch("1", "2"), bankly.TransferBatchOptions{
+		BatchID: "batch-1",
+		Store:   failingTransferBatchStore{},
+	})
+	s.assert.NoError(err)
+	s.assert.Equal(2, report.Skipped)
+	s.assert.EqualError(report.Results[0].Error, "store unavailable")
+	s.assert.Empty(s.sent)
+}
+
+func (s *TransfersBatchTestSuite) TestCreateBatch_MaxFailures() {
+	items := buildTransferBatch("1", "2", "3", "4", "5")
+	for _, item := range items {
+		s.answers[item.ID] = &http.Response{StatusCode: http.StatusBadRequest, Body: jsonBody(bankly.TransferErrorResponse{
+			Errors: []bankly.KeyValueErrorModel{{Key: "INVALID_ACCOUNT"}},
+		})}
+	}
+
+	report, err := s.transfers.CreateBatch(s.ctx, items, bankly.TransferBatchOptions{
+		BatchID:     "batch-1",
+		Concurrency: 1,
+		MaxFailures: 1,
+	})
+	s.assert.NoError(err)
+	s.assert.True(report.Stopped)
+	s.assert.Equal(2, report.Rejected)
+	s.assert.Equal(3, report.Skipped)
+}
+
+func (s *TransfersBatchTestSuite) TestCreateBatch_InsufficientBalance() {
+	_, err := s.transfers.CreateBatch(s.ctx, buildTransferBatch("1", "2"), bankly.TransferBatchOptions{
+		BatchID: "batch-1",
+		Balance: &fakeBalance{available: 9.99},
+	})
+	s.assert.Equal(bankly.ErrInsufficientBalance, err)
+	s.assert.Empty(s.sent)
+}
+
+func (s *TransfersBatchTestSuite) TestCreateBatch_DuplicatedItem() {
+	_, err := s.transfers.CreateBatch(s.ctx, buildTransferBatch("1", "1"), bankly.TransferBatchOptions{})
+	s.assert.Equal(bankly.ErrInvalidTransferBatch, err)
+}
+
+type failingTransferBatchStore struct{}
+
+func (failingTransferBatchStore) Save(ctx context.Context, batchID string, result *bankly.TransferBatchResult) error {
+	return errors.New("store unavailable")
+}
+
+func (failingTransferBatchStore) ListByBatch(ctx context.Context, batchID string) ([]*bankly.TransferBatchResult, error) {
+	return nil, nil
+}
+
+func buildTransferBatch(ids ...string) []bankly.TransferBatchItem {
+	items := []bankly.TransferBatchItem{}
+	for _, id := range ids {
+		items = append(items, bankly.TransferBatchItem{
+			ID: id,
+			Request: bankly.TransfersRequest{
+				Amount:      500,
+				Description: id,
+				Sender: bankly.SenderRequest{
+					Branch:   "0001",
+					Account:  "207802",
+					Document: "16246241620",
+					Name:     "Sender",
+				},
+				Recipient: bankly.RecipientRequest{
+					TransfersAccountType: bankly.CheckingAccount,
+					BankCode:             "001",
+					Branch:               "0001",
+					Account:              "123456",
+					Document:             "76385230056",
+					Name:                 "Recipient",
+				},
+			},
+		})
+	}
+	return items
+}
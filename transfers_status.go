@@ -0,0 +1,255 @@
+package bankly
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// IsFinal returns true when the transfer status does not change anymore.
+func (s TransfersStatus) IsFinal() bool {
+	switch s {
+	case TransfersStatusDone, TransfersStatusCanceled, TransfersStatusReproved, TransfersStatusUndone:
+		return true
+	}
+	return false
+}
+
+// TransferStatusTransition ...
+type TransferStatusTransition struct {
+	From TransfersStatus
+	To   TransfersStatus
+	At   time.Time
+}
+
+// TransferTracking is the transfer at the final status and the statuses it went through
+type TransferTracking struct {
+	Transfer *TransferByCodeResponse
+	History  []TransferStatusTransition
+}
+
+// TransferStatusUpdate is a status change received by webhook
+type TransferStatusUpdate struct {
+	AuthenticationCode string
+	Status             TransfersStatus
+}
+
+// TransferWatchTarget ...
+type TransferWatchTarget struct {
+	AuthenticationCode string
+	Branch             string
+	Account            string
+}
+
+// TransferWaitOptions ...
+type TransferWaitOptions struct {
+	// InitialInterval between the first polls, zero uses 2 seconds
+	InitialInterval time.Duration
+	// MaxInterval between the polls, zero uses 1 minute
+	MaxInterval time.Duration
+	// Multiplier of the interval after each poll without a final status, zero uses 2
+	Multiplier float64
+	// OnTransition is called at each status change
+	OnTransition func(authenticationCode string, transition TransferStatusTransition)
+}
+
+// TransferWatcher tracks transfers until a final status. Polls are made with backoff
+// and a status update received by Notify triggers a poll right away.
+type TransferWatcher struct {
+	transfers   *Transfers
+	options     TransferWaitOptions
+	mutex       sync.Mutex
+	subscribers map[string][]chan TransferStatusUpdate
+}
+
+// NewTransferWatcher ...
+func NewTransferWatcher(transfers *Transfers, options TransferWaitOptions) *TransferWatcher {
+	if options.InitialInterval <= 0 {
+		options.InitialInterval = 2 * time.Second
+	}
+
+	if options.MaxInterval <= 0 {
+		options.MaxInterval = time.Minute
+	}
+
+	if options.MaxInterval < options.InitialInterval {
+		options.MaxInterval = options.InitialInterval
+	}
+
+	if options.Multiplier < 1 {
+		options.Multiplier = 2
+	}
+
+	return &TransferWatcher{
+		transfers:   transfers,
+		options:     options,
+		subscribers: map[string][]chan TransferStatusUpdate{},
+	}
+}
+
+// WaitForFinalStatus polls the transfer with backoff until it is DONE, CANCELED, REPROVED or UNDONE.
+func (t *Transfers) WaitForFinalStatus(ctx context.Context, authenticationCode, branch, account string) (*TransferTracking, error) {
+	return NewTransferWatcher(t, TransferWaitOptions{}).Wait(ctx, authenticationCode, branch, account)
+}
+
+// Notify delivers a status update received by webhook to the waiters of the transfer.
+func (w *TransferWatcher) Notify(update TransferStatusUpdate) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	for _, subscriber := range w.subscribers[update.AuthenticationCode] {
+		select {
+		case subscriber <- update:
+		default:
+			// a poll is already pending for this waiter
+		}
+	}
+}
+
+// Listen delivers the updates of the channel until it is closed or the context is done.
+func (w *TransferWatcher) Listen(ctx context.Context, updates <-chan TransferStatusUpdate) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			w.Notify(update)
+		}
+	}
+}
+
+// Wait blocks until the transfer reaches a final status or the context is done.
+func (w *TransferWatcher) Wait(ctx context.Context, authenticationCode, branch, account string) (*TransferTracking, error) {
+	fields := logrus.Fields{
+		"request_id":          GetRequestID(ctx),
+		"authentication_code": authenticationCode,
+	}
+
+	updates := w.subscribe(authenticationCode)
+	defer w.unsubscribe(authenticationCode, updates)
+
+	tracking := &TransferTracking{History: []TransferStatusTransition{}}
+	interval := w.options.InitialInterval
+
+	for {
+		transfer, err := w.find(ctx, authenticationCode, branch, account)
+		if err != nil && err != ErrEntryNotFound {
+			logrus.WithFields(fields).WithError(err).Error("error tracking transfer status")
+			return tracking, err
+		}
+
+		if transfer != nil {
+			w.record(tracking, authenticationCode, transfer)
+			if transfer.Status.IsFinal() {
+				logrus.WithFields(fields).
+					WithField("status", transfer.Status).
+					Info("transfer reached final status")
+				return tracking, nil
+			}
+		}
+
+		timer := time.NewTimer(interval)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return tracking, ctx.Err()
+		case <-updates:
+			timer.Stop()
+		case <-timer.C:
+			interval = time.Duration(float64(interval) * w.options.Multiplier)
+			if interval > w.options.MaxInterval {
+				interval = w.options.MaxInterval
+			}
+		}
+	}
+}
+
+// Watch tracks many transfers at once and calls the callback when each one finishes.
+// It returns when all the transfers finished or the context is done.
+func (w *TransferWatcher) Watch(ctx context.Context, targets []TransferWatchTarget,
+	callback func(target TransferWatchTarget, tracking *TransferTracking, err error)) {
+
+	var wg sync.WaitGroup
+
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target TransferWatchTarget) {
+			defer wg.Done()
+			tracking, err := w.Wait(ctx, target.AuthenticationCode, target.Branch, target.Account)
+			callback(target, tracking, err)
+		}(target)
+	}
+
+	wg.Wait()
+}
+
+func (w *TransferWatcher) find(ctx context.Context, authenticationCode, branch, account string) (*TransferByCodeResponse, error) {
+	requestID := GetRequestID(ctx)
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+
+	return w.transfers.FindTransfersByCode(ctx, &requestID, &authenticationCode, &branch, &account)
+}
+
+func (w *TransferWatcher) record(tracking *TransferTracking, authenticationCode string, transfer *TransferByCodeResponse) {
+	tracking.Transfer = transfer
+
+	var last TransfersStatus
+	if len(tracking.History) > 0 {
+		last = tracking.History[len(tracking.History)-1].To
+	}
+
+	if transfer.Status == last {
+		return
+	}
+
+	at := transfer.UpdatedAt
+	if at.IsZero() {
+		at = time.Now().UTC()
+	}
+
+	transition := TransferStatusTransition{From: last, To: transfer.Status, At: at}
+	tracking.History = append(tracking.History, transition)
+
+	if w.options.OnTransition != nil {
+		w.options.OnTransition(authenticationCode, transition)
+	}
+}
+
+func (w *TransferWatcher) subscribe(authenticationCode string) chan TransferStatusUpdate {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	updates := make(chan TransferStatusUpdate, 1)
+	w.subscribers[authenticationCode] = append(w.subscribers[authenticationCode], updates)
+
+	return updates
+}
+
+func (w *TransferWatcher) unsubscribe(authenticationCode string, updates chan TransferStatusUpdate) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	subscribers := w.subscribers[authenticationCode]
+	for i, subscriber := range subscribers {
+		if subscriber == updates {
+			subscribers = append(subscribers[:i], subscribers[i+1:]...)
+			break
+		}
+	}
+
+	if len(subscribers) == 0 {
+		delete(w.subscribers, authenticationCode)
+		return
+	}
+
+	w.subscribers[authenticationCode] = subscribers
+}
@@ -0,0 +1,177 @@
+package bankly_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	bankly "github.com/contbank/bankly-sdk"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TransfersStatusTestSuite struct {
+	suite.Suite
+	assert    *assert.Assertions
+	ctx       context.Context
+	mutex     sync.Mutex
+	statuses  map[string][]bankly.TransfersStatus
+	polls     map[string]int
+	transfers *bankly.Transfers
+}
+
+func TestTransfersStatusTestSuite(t *testing.T) {
+	suite.Run(t, new(TransfersStatusTestSuite))
+}
+
+func (s *TransfersStatusTestSuite) SetupTest() {
+	s.assert = assert.New(s.T())
+	s.ctx = context.Background()
+	s.statuses = map[string][]bankly.TransfersStatus{}
+	s.polls = map[string]int{}
+
+	httpClient, session := newMockedHttpClient(func(req *http.Request) *http.Response {
+		code := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		statuses, ok := s.statuses[code]
+		if !ok {
+			return &http.Response{StatusCode: http.StatusNotFound, Body: jsonBody(nil)}
+		}
+
+		index := s.polls[code]
+		if index >= len(statuses) {
+			index = len(statuses) - 1
+		}
+		s.polls[code]++
+
+		return &http.Response{StatusCode: http.StatusOK, Body: jsonBody(bankly.TransferByCodeResponse{
+			AuthenticationCode: code,
+			Status:             statuses[index],
+		})}
+	})
+
+	s.transfers = bankly.NewTransfers(httpClient, *session)
+}
+
+func (s *TransfersStatusTestSuite) TestWaitForFinalStatus() {
+	s.statuses["code-1"] = []bankly.TransfersStatus{
+		bankly.TransfersStatusCreated,
+		bankly.TransfersStatusInProcess,
+		bankly.TransfersStatusInProcess,
+		bankly.TransfersStatusDone,
+	}
+
+	var transitions []bankly.TransferStatusTransition
+	watcher := bankly.NewTransferWatcher(s.transfers, bankly.TransferWaitOptions{
+		InitialInterval: time.Millisecond,
+		OnTransition: func(authenticationCode string, transition bankly.TransferStatusTransition) {
+			transitions = append(transitions, transition)
+		},
+	})
+
+	tracking, err := watcher.Wait(s.ctx, "code-1", "0001", "207802")
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.TransfersStatusDone, tracking.Transfer.Status)
+	s.assert.Len(tracking.History, 3)
+	s.assert.Equal(bankly.TransfersStatus(""), tracking.History[0].From)
+	s.assert.Equal(bankly.TransfersStatusInProcess, tracking.History[2].From)
+	s.assert.Equal(bankly.TransfersStatusDone, tracking.History[2].To)
+	s.assert.Equal(tracking.History, transitions)
+	s.assert.Equal(4, s.polls["code-1"])
+}
+
+func (s *TransfersStatusTestSuite) TestWait_NotifyTriggersPoll() {
+	s.statuses["code-1"] = []bankly.TransfersStatus{bankly.TransfersStatusCreated, bankly.TransfersStatusReproved}
+
+	watcher := bankly.NewTransferWatcher(s.transfers, bankly.TransferWaitOptions{InitialInterval: time.Hour})
+
+	updates := make(chan bankly.TransferStatusUpdate)
+	ctx, cancel := context.WithTimeout(s.ctx, 5*time.Second)
+	defer cancel()
+	go watcher.Listen(ctx, updates)
+
+	go func() {
+		for {
+			s.mutex.Lock()
+			polled := s.polls["code-1"] > 0
+			s.mutex.Unlock()
+			if polled {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		updates <- bankly.TransferStatusUpdate{AuthenticationCode: "code-1", Status: bankly.TransfersStatusReproved}
+	}()
+
+	tracking, err := watcher.Wait(ctx, "code-1", "0001", "207802")
+	s.assert.NoError(err)
+	s.assert.Equal(bankly.TransfersStatusReproved, tracking.Transfer.Status)
+	s.assert.Len(tracking.History, 2)
+}
+
+func (s *TransfersStatusTestSuite) TestWait_ContextDone() {
+	s.statuses["code-1"] = []bankly.TransfersStatus{bankly.TransfersStatusInProcess}
+
+	ctx, cancel := context.WithTimeout(s.ctx, 20*time.Millisecond)
+	defer cancel()
+
+	tracking, err := bankly.NewTransferWatcher(s.transfers, bankly.TransferWaitOptions{
+		InitialInterval: time.Millisecond,
+		MaxInterval:     5 * time.Millisecond,
+	}).Wait(ctx, "code-1", "0001", "207802")
+	s.assert.Equal(context.DeadlineExceeded, err)
+	s.assert.Len(tracking.History, 1)
+}
+
+func (s *TransfersStatusTestSuite) TestWatch() {
+	s.statuses["code-1"] = []bankly.TransfersStatus{bankly.TransfersStatusCreated, bankly.TransfersStatusDone}
+	s.statuses["code-2"] = []bankly.TransfersStatus{bankly.TransfersStatusCanceled}
+
+	var mutex sync.Mutex
+	results := map[string]bankly.TransfersStatus{}
+
+	watcher := bankly.NewTransferWatcher(s.transfers, bankly.TransferWaitOptions{InitialInterval: time.Millisecond})
+	watcher.Watch(s.ctx, []bankly.TransferWatchTarget{
+		{AuthenticationCode: "code-1", Branch: "0001", Account: "207802"},
+		{AuthenticationCode: "code-2", Branch: "0001", Account: "207802"},
+	}, func(target bankly.TransferWatchTarget, tracking *bankly.TransferTracking, err error) {
+		s.assert.NoError(err)
+		mutex.Lock()
+		defer mutex.Unlock()
+		results[target.AuthenticationCode] = tracking.Transfer.Status
+	})
+
+	s.assert.Equal(map[string]bankly.TransfersStatus{
+		"code-1": bankly.TransfersStatusDone,
+		"code-2": bankly.TransfersStatusCanceled,
+	}, results)
+}
+
+type transportFunc func(req *http.Request) *http.Response
+
+func (f transportFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+// newMockedHttpClient returns a client answering with fn and a session with a cached token.
+func newMockedHttpClient(fn func(req *http.Request) *http.Response) (*http.Client, *bankly.Session) {
+	tokenCache := cache.New(cache.NoExpiration, cache.NoExpiration)
+	tokenCache.SetDefault("token", "Bearer token")
+
+	session, _ := bankly.NewSession(bankly.Config{
+		APIEndpoint:   bankly.String("http://bankly.test"),
+		LoginEndpoint: bankly.String("http://login.bankly.test"),
+		ClientID:      bankly.String("client"),
+		ClientSecret:  bankly.String("secret"),
+		Cache:         tokenCache,
+	})
+
+	return &http.Client{Transport: transportFunc(fn)}, session
+}